@@ -0,0 +1,145 @@
+// batch.go
+// Collects the errors added during a batch of sub-operations into one rolled-up diagnostic.
+
+package smarterr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/YakDriver/smarterr/internal"
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
+	sdkdiag "github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+var batchCtxKey = ContextKey("smarterr:batch")
+
+// batch holds the diagnostics AddError and Append collected for a context from BeginBatch.
+type batch struct {
+	mu      sync.Mutex
+	entries []batchEntry
+}
+
+type batchEntry struct {
+	severity string
+	summary  string
+	detail   string
+}
+
+// BeginBatch returns a context that batches errors. AddError and Append calls made with it
+// format each error as usual but collect the diagnostic instead of adding it. Call EndBatch or
+// EndBatchAppend to add them as one rolled-up diagnostic:
+//
+//	ctx = smarterr.BeginBatch(ctx)
+//	for _, rule := range rules {
+//	    if err := createRule(ctx, rule); err != nil {
+//	        smarterr.AddError(ctx, &resp.Diagnostics, err, smarterr.ID, rule.ID)
+//	    }
+//	}
+//	smarterr.EndBatch(ctx, &resp.Diagnostics)
+//
+// It's safe to add errors to a batch from multiple goroutines.
+func BeginBatch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, batchCtxKey, &batch{})
+}
+
+// batchFromContext returns the batch of a context from BeginBatch, or nil.
+func batchFromContext(ctx context.Context) *batch {
+	b, _ := ctx.Value(batchCtxKey).(*batch)
+	return b
+}
+
+func (b *batch) add(severity, summary, detail string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = append(b.entries, batchEntry{severity: severity, summary: summary, detail: detail})
+}
+
+// drain returns the collected entries and empties the batch, so ending a batch twice doesn't
+// add its diagnostics twice.
+func (b *batch) drain() []batchEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries := b.entries
+	b.entries = nil
+	return entries
+}
+
+// EndBatch adds the diagnostics collected since BeginBatch to diags. A single diagnostic is added
+// as is. Several are rolled up into one: its summary counts them, such as "3 errors", and its
+// detail lists each summary and detail. The rolled-up diagnostic is an error if any collected
+// diagnostic is, otherwise a warning. EndBatch does nothing if ctx isn't from BeginBatch or no
+// errors were collected.
+func EndBatch(ctx context.Context, diags *fwdiag.Diagnostics) {
+	severity, summary, detail, ok := rollUp(ctx)
+	if !ok {
+		return
+	}
+	if severity == SeverityWarning {
+		diags.AddWarning(summary, detail)
+		return
+	}
+	diags.AddError(summary, detail)
+}
+
+// EndBatchAppend is EndBatch for Terraform Plugin SDK diagnostics. It returns diags with the
+// rolled-up diagnostic appended.
+func EndBatchAppend(ctx context.Context, diags sdkdiag.Diagnostics) sdkdiag.Diagnostics {
+	severity, summary, detail, ok := rollUp(ctx)
+	if !ok {
+		return diags
+	}
+	return append(diags, sdkdiag.Diagnostic{
+		Severity: sdkSeverity(severity),
+		Summary:  summary,
+		Detail:   detail,
+	})
+}
+
+// rollUp drains the batch of ctx and combines its entries into one diagnostic. It reports false
+// if there's nothing to add.
+func rollUp(ctx context.Context) (severity, summary, detail string, ok bool) {
+	b := batchFromContext(ctx)
+	if b == nil {
+		return "", "", "", false
+	}
+	entries := b.drain()
+	_, callID := globalCallID(ctx)
+	Debugf("[rollUp %s] ending batch with %d diagnostics", callID, len(entries))
+	switch len(entries) {
+	case 0:
+		return "", "", "", false
+	case 1:
+		return entries[0].severity, entries[0].summary, entries[0].detail, true
+	}
+
+	errorCount, warningCount := 0, 0
+	details := make([]string, 0, len(entries))
+	for i, e := range entries {
+		if e.severity == SeverityWarning {
+			warningCount++
+		} else {
+			errorCount++
+		}
+		entry := fmt.Sprintf("%d. %s", i+1, e.summary)
+		if e.detail != "" {
+			entry += "\n" + e.detail
+		}
+		details = append(details, entry)
+	}
+
+	var counts []string
+	if errorCount > 0 {
+		counts = append(counts, internal.Pluralize(errorCount, "error"))
+	}
+	if warningCount > 0 {
+		counts = append(counts, internal.Pluralize(warningCount, "warning"))
+	}
+	severity = SeverityWarning
+	if errorCount > 0 {
+		severity = SeverityError
+	}
+	return severity, strings.Join(counts, " and "), strings.Join(details, "\n\n"), true
+}
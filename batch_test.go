@@ -0,0 +1,156 @@
+package smarterr
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
+	sdkdiag "github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+const batchTestConfig = `
+token "error" {
+  source = "error"
+}
+
+token "id" {
+  arg = "id"
+}
+
+template "error_summary" {
+  format = "creating rule {{.id}}"
+}
+
+template "error_detail" {
+  format = "{{.error}}"
+}
+`
+
+func TestBatch_RollsUpErrors(t *testing.T) {
+	setTestConfig(t, batchTestConfig)
+	ctx := BeginBatch(context.Background())
+
+	var diags fwdiag.Diagnostics
+	for _, id := range []string{"r1", "r2", "r3"} {
+		AddError(ctx, &diags, errors.New("boom "+id), ID, id)
+	}
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics before EndBatch, got %d", len(diags))
+	}
+
+	EndBatch(ctx, &diags)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 rolled-up diagnostic, got %d: %v", len(diags), diags)
+	}
+	if diags[0].Severity() != fwdiag.SeverityError {
+		t.Errorf("severity = %v, want error", diags[0].Severity())
+	}
+	if got, want := diags[0].Summary(), "3 errors"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	want := "1. creating rule r1\nboom r1\n\n2. creating rule r2\nboom r2\n\n3. creating rule r3\nboom r3"
+	if got := diags[0].Detail(); got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	// Ending again adds nothing
+	EndBatch(ctx, &diags)
+	if len(diags) != 1 {
+		t.Errorf("expected EndBatch to drain the batch, got %d diagnostics", len(diags))
+	}
+}
+
+func TestBatch_Append(t *testing.T) {
+	setTestConfig(t, batchTestConfig)
+	ctx := BeginBatch(context.Background())
+
+	var diags sdkdiag.Diagnostics
+	diags = Append(ctx, diags, errors.New("boom"), ID, "r1")
+	diags = Append(ctx, diags, DiagnosticError(fwdiag.NewWarningDiagnostic("slow", "retrying")), ID, "r2")
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics before EndBatchAppend, got %d", len(diags))
+	}
+
+	diags = EndBatchAppend(ctx, diags)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 rolled-up diagnostic, got %d", len(diags))
+	}
+	if got, want := diags[0].Summary, "1 error and 1 warning"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	if diags[0].Severity != sdkdiag.Error {
+		t.Errorf("severity = %v, want error", diags[0].Severity)
+	}
+}
+
+func TestBatch_SingleAndEmpty(t *testing.T) {
+	setTestConfig(t, batchTestConfig)
+
+	ctx := BeginBatch(context.Background())
+	var diags fwdiag.Diagnostics
+	EndBatch(ctx, &diags)
+	if len(diags) != 0 {
+		t.Errorf("expected no diagnostics for an empty batch, got %d", len(diags))
+	}
+
+	AddError(ctx, &diags, errors.New("boom"), ID, "r1")
+	EndBatch(ctx, &diags)
+	if len(diags) != 1 || diags[0].Summary() != "creating rule r1" || diags[0].Detail() != "boom" {
+		t.Errorf("expected a single diagnostic added as is, got %v", diags)
+	}
+
+	// Without BeginBatch, EndBatch does nothing and AddError adds directly
+	diags = nil
+	AddError(context.Background(), &diags, errors.New("boom"), ID, "r1")
+	EndBatch(context.Background(), &diags)
+	if len(diags) != 1 {
+		t.Errorf("expected 1 diagnostic without batching, got %d", len(diags))
+	}
+}
+
+func TestBatch_Concurrent(t *testing.T) {
+	setTestConfig(t, batchTestConfig)
+	ctx := BeginBatch(context.Background())
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			var diags fwdiag.Diagnostics
+			AddError(ctx, &diags, errors.New("boom"), ID, "r")
+		})
+	}
+	wg.Wait()
+
+	var diags fwdiag.Diagnostics
+	EndBatch(ctx, &diags)
+	if len(diags) != 1 || diags[0].Summary() != "10 errors" {
+		t.Errorf("expected one diagnostic for 10 errors, got %v", diags)
+	}
+}
+
+func TestBatch_PanicFallback(t *testing.T) {
+	setTestConfig(t, batchTestConfig)
+	SetStrict(true)
+	t.Cleanup(func() { SetStrict(false) })
+	ctx := BeginBatch(context.Background())
+
+	// With SetStrict, a missing keyval value panics, and the fallback diagnostic joins the batch
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, errors.New("boom r1"), ID)
+	AddError(ctx, &diags, errors.New("boom r2"), ID, "r2")
+	var sdiags sdkdiag.Diagnostics
+	sdiags = Append(ctx, sdiags, errors.New("boom r3"), ID)
+	if len(diags) != 0 || len(sdiags) != 0 {
+		t.Fatalf("expected no diagnostics before EndBatch, got %d and %d", len(diags), len(sdiags))
+	}
+
+	EndBatch(ctx, &diags)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 rolled-up diagnostic, got %d: %v", len(diags), diags)
+	}
+	if got, want := diags[0].Summary(), "3 errors"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
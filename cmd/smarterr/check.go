@@ -1,14 +1,15 @@
 package main
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
-	"text/template"
 
 	"github.com/YakDriver/smarterr"
 	"github.com/YakDriver/smarterr/internal"
@@ -17,142 +18,200 @@ import (
 
 var quietFlag bool
 var silentFlag bool
+var checkFormat string
+var fixFlag bool
+var namingFlag bool
+var namingErrorsFlag bool
+var namingPattern string
+var stackMatchVerbs []string
+var renderCheckFlag bool
 
 func init() {
 	checkCmd.Flags().StringVarP(&startDir, "start-dir", "d", "", "Directory where code using smarterr lives (default: current directory). This is typically where the error occurs.")
 	checkCmd.Flags().StringVarP(&baseDir, "base-dir", "b", "", "Parent directory where go:embed is used (optional, but recommended for proper config layering as in the application). If not set, config applies only to the current directory.")
+	checkCmd.Flags().StringVarP(&configFile, "config-file", "c", "", "Check a single config file directly, bypassing discovery and layering (--base-dir and --start-dir are ignored)")
 	checkCmd.Flags().BoolVarP(&debugFlag, "debug", "D", false, "Enable smarterr debug output (even if config fails to load)")
 	checkCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "Only output errors (suppresses merged config and warnings)")
 	checkCmd.Flags().BoolVarP(&silentFlag, "silent", "S", false, "No output, only exit code (non-zero if errors)")
+	checkCmd.Flags().BoolVarP(&watchFlag, "watch", "w", false, "Watch config files for changes and re-run the check on each change")
+	checkCmd.Flags().StringVarP(&checkFormat, "format", "f", formatText, "Output format: text, json, or sarif (json and sarif output only the results)")
+	checkCmd.Flags().BoolVar(&fixFlag, "fix", false, "Remove unused tokens, transforms, stack_matches, and hints from the --config-file, keeping any used by other config files under --base-dir")
+	checkCmd.Flags().BoolVar(&namingFlag, "naming", false, "Check that token, parameter, transform, hint, and stack_match names follow naming conventions")
+	checkCmd.Flags().BoolVar(&namingErrorsFlag, "naming-errors", false, "Report naming convention violations as errors instead of warnings (implies --naming)")
+	checkCmd.Flags().StringVar(&namingPattern, "naming-pattern", defaultNamingPattern, "Regular expression names must match with --naming (default: snake_case)")
+	checkCmd.Flags().BoolVar(&renderCheckFlag, "render-check", false, "Warn if, with token_error_mode = \"placeholder\", a template would show placeholders such as <id> for tokens without values")
+	checkCmd.Flags().StringSliceVar(&stackMatchVerbs, "stack-match-verbs", defaultStackMatchVerbs, "Action verbs stack_match names must end in with --naming (empty to allow any)")
 	rootCmd.AddCommand(checkCmd)
 }
 
 var checkCmd = &cobra.Command{
-	Use:   "check",
-	Short: "Check smarterr configuration",
-	Long:  `Check the merged smarterr configuration. Checks for parse errors and Config loading issues.`,
+	Use:     "check",
+	Aliases: []string{"validate"},
+	Short:   "Check smarterr configuration",
+	Long:    `Check the merged smarterr configuration. Checks for parse errors and Config loading issues.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if debugFlag {
 			internal.EnableDebugForce()
 		}
-		if baseDir == "" {
-			fmt.Println("WARNING: --base-dir is not set. Config will only apply to the current directory. For proper config layering, set --base-dir to the directory where go:embed is used in your application.")
+		if checkFormat != formatText && checkFormat != formatJSON && checkFormat != formatSARIF {
+			return fmt.Errorf("--format must be one of 'text', 'json', or 'sarif' (got %q)", checkFormat)
 		}
-		// Ensure baseDir and startDir are absolute
+		if fixFlag && configFile == "" {
+			// Definitions in a layered config may be used by configs in other directories
+			return fmt.Errorf("--fix requires --config-file")
+		}
+		if fixFlag && strings.HasSuffix(configFile, ".json") {
+			return fmt.Errorf("--fix only supports HCL config files; remove unused definitions from %s by hand", configFile)
+		}
+		if watchFlag {
+			return watchConfig(cmd, func() error { return runCheck(cmd) })
+		}
+		return runCheck(cmd)
+	},
+}
+
+// runCheck loads and checks the configuration, printing the results.
+func runCheck(cmd *cobra.Command) error {
+	cfg, files, err := loadCheckConfig()
+	if err != nil {
+		return err
+	}
+	if fixFlag {
 		absBaseDir, err := filepath.Abs(baseDir)
 		if err != nil {
 			return fmt.Errorf("failed to get absolute baseDir: %w", err)
 		}
-		absStartDir := startDir
-		if absStartDir == "" {
-			cwd, err := os.Getwd()
-			if err != nil {
-				return fmt.Errorf("failed to get current directory: %w", err)
-			}
-			absStartDir = cwd
-		}
-		absStartDir, err = filepath.Abs(absStartDir)
+		layers, err := otherConfigLayers(absBaseDir, configFile)
 		if err != nil {
-			return fmt.Errorf("failed to get absolute startDir: %w", err)
+			return err
 		}
-
-		if !silentFlag && !quietFlag {
-			fmt.Printf("Checking configuration...\nStart dir: %s\nBase dir: %s\n", absStartDir, absBaseDir)
-		}
-
-		// Compute relative path from baseDir to startDir
-		relStartDir, err := filepath.Rel(absBaseDir, absStartDir)
+		removed, err := fixConfigFile(configFile, cfg, layers)
 		if err != nil {
-			return fmt.Errorf("failed to relativize startDir: %w", err)
+			return err
 		}
-		if strings.HasPrefix(relStartDir, "..") {
-			return fmt.Errorf("startDir must be inside baseDir")
+		if len(removed) > 0 {
+			if !silentFlag && !quietFlag && checkFormat == formatText {
+				for _, d := range removed {
+					fmt.Printf("Removed unused %s\n", d)
+				}
+			}
+			if cfg, err = loadSingleConfigFile(configFile); err != nil {
+				return fmt.Errorf("reloading fixed config: %w", err)
+			}
 		}
+	}
 
-		// Use a real FS rooted at baseDir
-		fsys := smarterr.NewWrappedFS(absBaseDir)
-
-		// Pass the relative stack path
-		relStackPaths := []string{relStartDir}
-		cfg, err := internal.LoadConfig(context.Background(), fsys, relStackPaths, ".")
+	allErrs, allWarnings := runChecks(cfg)
+	if namingFlag || namingErrorsFlag {
+		rules, err := newNamingRules(namingPattern, stackMatchVerbs)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Config load error: %v\n", err)
-			return fmt.Errorf("config check failed")
+			return err
 		}
-
-		var allErrs []error
-		var allWarnings []string
-
-		// --- Smarterr block check ---
-		errs, warnings := checkSmarterrBlock(cfg)
-		allErrs = append(allErrs, errs...)
-		allWarnings = append(allWarnings, warnings...)
-
-		// --- Template name check ---
-		errs, warnings = checkTemplateNames(cfg)
-		allErrs = append(allErrs, errs...)
-		allWarnings = append(allWarnings, warnings...)
-
-		// --- Template vars and tokens check ---
-		errs, warnings = checkTemplateVarsAndTokens(cfg)
-		allErrs = append(allErrs, errs...)
-		allWarnings = append(allWarnings, warnings...)
-
-		// --- Token fields check ---
-		errs, warnings = checkTokenFields(cfg)
-		allErrs = append(allErrs, errs...)
-		allWarnings = append(allWarnings, warnings...)
-
-		// --- Token transforms check ---
-		errs, warnings = checkTokenTransforms(cfg)
-		allErrs = append(allErrs, errs...)
-		allWarnings = append(allWarnings, warnings...)
-
-		// --- Stack matches check ---
-		errs, warnings = checkStackMatches(cfg)
-		allErrs = append(allErrs, errs...)
-		allWarnings = append(allWarnings, warnings...)
-
-		// --- Transform steps check ---
-		errs, warnings = checkTransformSteps(cfg)
-		allErrs = append(allErrs, errs...)
-		allWarnings = append(allWarnings, warnings...)
-
-		if !silentFlag && !quietFlag {
-			fmt.Println("Merged config:")
-			// Convert the configuration to HCL format
-			hclBytes, err := convertConfigToHCL(cfg)
-			if err != nil {
-				return fmt.Errorf("failed to convert config to HCL: %w", err)
+		for _, v := range checkNaming(cfg, rules) {
+			if namingErrorsFlag {
+				allErrs = append(allErrs, errors.New(v))
+			} else {
+				allWarnings = append(allWarnings, v)
 			}
-			// Output the configuration
-			fmt.Println(string(hclBytes))
 		}
+	}
+	if renderCheckFlag {
+		allWarnings = append(allWarnings, checkRenderPlaceholders(cfg)...)
+	}
 
-		// Print warnings and errors
-		if !silentFlag && !quietFlag && len(allWarnings) > 0 {
-			fmt.Println("\nWarnings:")
-			for _, w := range allWarnings {
-				fmt.Printf("  - %s\n", w)
+	if checkFormat != formatText {
+		if !silentFlag {
+			results := buildCheckResults(allErrs, allWarnings, files)
+			if err := writeCheckReport(cmd.OutOrStdout(), checkFormat, results); err != nil {
+				return fmt.Errorf("failed to write %s output: %w", checkFormat, err)
 			}
 		}
 		if len(allErrs) > 0 {
-			if !silentFlag {
-				fmt.Println("\nErrors:")
-				for _, e := range allErrs {
-					fmt.Printf("  - %s\n", e)
-				}
-				return fmt.Errorf("config check failed (%d error(s))", len(allErrs))
+			return fmt.Errorf("config check failed (%d error(s))", len(allErrs))
+		}
+		return nil
+	}
+
+	if !silentFlag && !quietFlag {
+		fmt.Println("Merged config:")
+		// Convert the configuration to HCL format
+		hclBytes, err := convertConfigToHCL(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to convert config to HCL: %w", err)
+		}
+		// Output the configuration
+		fmt.Println(string(hclBytes))
+	}
+
+	// Print warnings and errors
+	if !silentFlag && !quietFlag && len(allWarnings) > 0 {
+		fmt.Println("\nWarnings:")
+		for _, w := range allWarnings {
+			fmt.Printf("  - %s\n", w)
+		}
+	}
+	if len(allErrs) > 0 {
+		if !silentFlag {
+			fmt.Println("\nErrors:")
+			for _, e := range allErrs {
+				fmt.Printf("  - %s\n", e)
 			}
-			// silentFlag: exit non-zero, but no output
-			return fmt.Errorf("")
+			return fmt.Errorf("config check failed (%d error(s))", len(allErrs))
 		}
+		// silentFlag: exit non-zero, but no output
+		return fmt.Errorf("")
+	}
 
+	if !silentFlag && !quietFlag {
+		fmt.Println("Config loaded and checked successfully.")
+	}
+	return nil
+}
+
+// loadCheckConfig loads the configuration to check with loadConfigAs, writing what it loads unless
+// the output is quiet or not text, and the --base-dir warning unless the output is not text. It
+// also returns the paths of the config files that were loaded, least specific first.
+func loadCheckConfig() (*internal.Config, []string, error) {
+	var status, warnings io.Writer
+	if checkFormat == formatText {
+		warnings = os.Stdout
 		if !silentFlag && !quietFlag {
-			fmt.Println("Config loaded and checked successfully.")
+			status = os.Stdout
 		}
-		return nil
-	},
+	}
+	cfg, files, err := loadConfigAs("Checking configuration...", status, warnings)
+	var loadErr *configLoadError
+	if errors.As(err, &loadErr) {
+		fmt.Fprintf(os.Stderr, "Config load error: %v\n", loadErr.err)
+		return nil, nil, fmt.Errorf("config check failed")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, files, nil
+}
+
+// runChecks runs all config checks and returns the collected errors and warnings.
+func runChecks(cfg *internal.Config) (allErrs []error, allWarnings []string) {
+	checks := []func(*internal.Config) ([]error, []string){
+		checkSchemaVersion,
+		checkSmarterrBlock,
+		checkTemplateNames,
+		checkTemplateVarsAndTokens,
+		checkTemplateReferences,
+		checkTokenFields,
+		checkTokenTransforms,
+		checkStackMatches,
+		checkTransformSteps,
+		checkHints,
+		checkParameters,
+	}
+	for _, check := range checks {
+		errs, warnings := check(cfg)
+		allErrs = append(allErrs, errs...)
+		allWarnings = append(allWarnings, warnings...)
+	}
+	return
 }
 
 // Canonical template names (should match smarterr.go)
@@ -166,14 +225,46 @@ var canonicalTemplateNames = []string{
 	smarterr.LogInfoKey,
 }
 
+// Optional template names are recognized but not expected in every config
+var optionalTemplateNames = []string{
+	smarterr.WarningSummaryKey,
+	smarterr.WarningDetailKey,
+}
+
 // checkTemplateNames checks that all template names are canonical and warns if any canonical is missing.
+// A template that another invokes with {{template "name"}} is a helper, so it may have any name.
 func checkTemplateNames(cfg *internal.Config) (errs []error, warnings []string) {
 	templateNames := make(map[string]struct{})
+	// Names invoked with {{template}} or defined with {{define}} in any template
+	helperNames := make(map[string]struct{})
 	for _, tmpl := range cfg.Templates {
 		templateNames[tmpl.Name] = struct{}{}
-		found := slices.Contains(canonicalTemplateNames, tmpl.Name)
+		t, err := internal.NewTemplate(tmpl.Name).Parse(tmpl.Format)
+		if err != nil {
+			continue // Reported by checkTemplateVarsAndTokens
+		}
+		for _, ref := range internal.CollectTemplateReferences(t) {
+			helperNames[ref] = struct{}{}
+		}
+		for _, defined := range t.Templates() {
+			if defined.Name() != tmpl.Name {
+				helperNames[defined.Name()] = struct{}{}
+			}
+		}
+	}
+	for _, tmpl := range cfg.Templates {
+		name := tmpl.Name
+		base, _, variant := internal.SplitLocale(name)
+		if variant {
+			// A locale variant, such as error_detail.ja
+			name = base
+		}
+		_, helper := helperNames[name]
+		found := slices.Contains(canonicalTemplateNames, name) || slices.Contains(optionalTemplateNames, name) || helper
 		if !found {
-			errs = append(errs, fmt.Errorf("template %q is not a recognized canonical template name", tmpl.Name))
+			errs = append(errs, fmt.Errorf("template %q is not a recognized canonical template name, and no template invokes it with {{template}}", tmpl.Name))
+		} else if _, ok := templateNames[base]; variant && !ok {
+			warnings = append(warnings, fmt.Sprintf("template %q is a locale variant, but template %q isn't defined for other locales", tmpl.Name, base))
 		}
 	}
 	// Warn if any canonical template is missing
@@ -188,33 +279,62 @@ func checkTemplateNames(cfg *internal.Config) (errs []error, warnings []string)
 // checkTemplateVarsAndTokens checks for template vars without tokens (error) and tokens unused in templates (warning).
 func checkTemplateVarsAndTokens(cfg *internal.Config) (errs []error, warnings []string) {
 	tokenNames := make(map[string]struct{})
+	diagnosticTokens := make(map[string]struct{})
 	for _, t := range cfg.Tokens {
 		tokenNames[t.Name] = struct{}{}
+		if t.Source == "diagnostic" {
+			diagnosticTokens[t.Name] = struct{}{}
+		}
 	}
 
-	// Collect all template variables used in all templates
+	// Collect all template variables used in all templates. A field of a variable, such as summary
+	// in .diag.summary, is part of the token's value, not a token of its own.
 	templateVars := make(map[string]struct{})
+	// Variables used with a diagnostic field, such as diag in .diag.summary
+	diagnosticVars := make(map[string]struct{})
 	for _, tmpl := range cfg.Templates {
-		t, err := template.New(tmpl.Name).Parse(tmpl.Format)
+		t, err := internal.NewTemplate(tmpl.Name).Parse(tmpl.Format)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("failed to parse template %q: %v", tmpl.Name, err))
 			continue
 		}
-		vars := internal.CollectTemplateVariables(t)
-		for _, v := range vars {
-			templateVars[v] = struct{}{}
+		for _, path := range internal.CollectTemplateFieldPaths(t) {
+			templateVars[path[0]] = struct{}{}
+			if len(path) < 2 {
+				continue
+			}
+			if slices.Contains(internal.DiagnosticFields, path[1]) {
+				diagnosticVars[path[0]] = struct{}{}
+			} else if _, ok := diagnosticTokens[path[0]]; ok {
+				warnings = append(warnings, fmt.Sprintf("template %q uses .%s, but diagnostic token %q has no field %q (fields: %s)", tmpl.Name, strings.Join(path, "."), path[0], path[1], strings.Join(internal.DiagnosticFields, ", ")))
+			}
 		}
 	}
 
 	// Error: template var exists that doesn't correspond to a token
 	for v := range templateVars {
 		if _, ok := tokenNames[v]; !ok {
+			if _, ok := diagnosticVars[v]; ok {
+				errs = append(errs, fmt.Errorf("template variable %q is used in a template but no token with that name exists; to use its diagnostic fields, define token %q with source = \"diagnostic\"", v, v))
+				continue
+			}
 			errs = append(errs, fmt.Errorf("template variable %q is used in a template but no token with that name exists", v))
 		}
 	}
 
+	// Tokens that other tokens are derived from (from_token) are used too
+	derivedFrom := make(map[string]struct{})
+	for _, t := range cfg.Tokens {
+		if t.FromToken != nil {
+			derivedFrom[*t.FromToken] = struct{}{}
+		}
+	}
+
 	// Warning: token exists that's not used in a template
 	for t := range tokenNames {
+		if _, ok := derivedFrom[t]; ok {
+			continue
+		}
 		if _, ok := templateVars[t]; !ok {
 			warnings = append(warnings, fmt.Sprintf("token %q is defined but not used in any template", t))
 		}
@@ -222,7 +342,32 @@ func checkTemplateVarsAndTokens(cfg *internal.Config) (errs []error, warnings []
 	return
 }
 
-// checkStackMatches checks that all stack_matches referenced by tokens exist, and warns if any stack_match is unused.
+// checkTemplateReferences checks that templates invoked with {{template "name"}} are defined and
+// don't form a cycle, which would recurse without end.
+func checkTemplateReferences(cfg *internal.Config) (errs []error, warnings []string) {
+	defined := make(map[string]struct{})
+	for _, tmpl := range cfg.Templates {
+		defined[tmpl.Name] = struct{}{}
+	}
+	for _, tmpl := range cfg.Templates {
+		t, err := internal.NewTemplate(tmpl.Name).Parse(tmpl.Format)
+		if err != nil {
+			continue // Reported by checkTemplateVarsAndTokens
+		}
+		for _, ref := range internal.CollectTemplateReferences(t) {
+			if _, ok := defined[ref]; !ok && t.Lookup(ref) == nil {
+				errs = append(errs, fmt.Errorf("template %q references undefined template %q", tmpl.Name, ref))
+			}
+		}
+	}
+	if cycle := cfg.TemplateCycle(); cycle != nil {
+		errs = append(errs, fmt.Errorf("templates reference each other in a cycle: %s", strings.Join(cycle, " -> ")))
+	}
+	return
+}
+
+// checkStackMatches checks that all stack_matches referenced by tokens exist and have valid regexes,
+// and warns if any stack_match is unused.
 func checkStackMatches(cfg *internal.Config) (errs []error, warnings []string) {
 	// Collect all defined stack_match names
 	defined := make(map[string]struct{})
@@ -246,9 +391,76 @@ func checkStackMatches(cfg *internal.Config) (errs []error, warnings []string) {
 			warnings = append(warnings, fmt.Sprintf("stack_match %q is defined but not used in any token's stack_matches", smName))
 		}
 	}
+	// Check called_from and called_after regexes
+	for _, sm := range cfg.StackMatches {
+		if sm.CalledFrom != "" {
+			if _, err := regexp.Compile(sm.CalledFrom); err != nil {
+				errs = append(errs, fmt.Errorf("stack_match %q has invalid called_from: %v", sm.Name, err))
+			}
+		}
+		if sm.CalledAfter == "" {
+			continue
+		}
+		if sm.CalledFrom == "" {
+			errs = append(errs, fmt.Errorf("stack_match %q sets called_after without called_from, so it never matches", sm.Name))
+		}
+		if _, err := regexp.Compile(sm.CalledAfter); err != nil {
+			errs = append(errs, fmt.Errorf("stack_match %q has invalid called_after: %v", sm.Name, err))
+		}
+	}
+	// Warn if a catch-all stack_match can shadow rules tried after it at a frame. Tried last, it
+	// still matches the frame closest to the error, but then it only decides the display when no
+	// other rule matches that frame. A rule with called_after doesn't match every frame, so it
+	// can't shadow.
+	byName := make(map[string]internal.StackMatch)
+	for _, sm := range cfg.StackMatches {
+		byName[sm.Name] = sm
+	}
+	for _, t := range cfg.Tokens {
+		var tokenMatches []internal.StackMatch
+		for _, smName := range t.StackMatches {
+			if sm, ok := byName[smName]; ok {
+				tokenMatches = append(tokenMatches, sm)
+			}
+		}
+		tokenMatches = internal.SortStackMatchesByPriority(tokenMatches)
+		for i, sm := range tokenMatches {
+			if i == len(tokenMatches)-1 || sm.CalledAfter != "" || !isCatchAllRegex(sm.CalledFrom) {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("stack_match %q has overly broad called_from %q that matches every frame, shadowing the stack_matches tried after it at each frame in token %q; give it the lowest priority, list it last, or narrow the regex", sm.Name, sm.CalledFrom, t.Name))
+		}
+	}
 	return
 }
 
+// catchAllProbes are dissimilar function names; a called_from regex matching all of them is a catch-all.
+var catchAllProbes = []string{
+	"x.y",
+	"Q.Z",
+	"main.main",
+	"github.com/YakDriver/smarterr.Append",
+	"github.com/hashicorp/terraform-provider-aws/internal/service/rds.resourceClusterCreate",
+}
+
+// isCatchAllRegex reports whether a called_from regex matches every function name (for example,
+// ".*", ".+", or "^"). An empty called_from is not a catch-all because it never matches.
+func isCatchAllRegex(calledFrom string) bool {
+	if calledFrom == "" {
+		return false
+	}
+	re, err := regexp.Compile(calledFrom)
+	if err != nil {
+		return false
+	}
+	for _, probe := range catchAllProbes {
+		if !re.MatchString(probe) {
+			return false
+		}
+	}
+	return true
+}
+
 // checkTokenTransforms checks that all token transforms exist, and warns if any transform is unused.
 func checkTokenTransforms(cfg *internal.Config) (errs []error, warnings []string) {
 	// Collect all defined transform names
@@ -288,22 +500,13 @@ func checkTokenTransforms(cfg *internal.Config) (errs []error, warnings []string
 
 // checkTransformSteps checks that all steps referenced by transforms exist, and warns if any step is unused.
 func checkTransformSteps(cfg *internal.Config) (errs []error, warnings []string) {
-	// Supported step types
-	supported := map[string]struct{}{
-		"strip_prefix": {},
-		"strip_suffix": {},
-		"remove":       {},
-		"replace":      {},
-		"trim_space":   {},
-		"fix_space":    {},
-		"lower":        {},
-		"upper":        {},
-	}
 	used := make(map[string]struct{})
 	for _, tr := range cfg.Transforms {
 		for i, step := range tr.Steps {
-			if _, ok := supported[step.Type]; !ok {
-				errs = append(errs, fmt.Errorf("transform %q has step with undefined type %q", tr.Name, step.Type))
+			if !internal.IsTransformStepType(step.Type) {
+				// The smarterr binary only knows the built-in types, not those the application
+				// registers with RegisterTransform
+				warnings = append(warnings, fmt.Sprintf("transform %q has step with undefined type %q; ignore this if the application registers it with RegisterTransform", tr.Name, step.Type))
 				continue
 			} else {
 				used[step.Type] = struct{}{}
@@ -333,7 +536,28 @@ func checkTransformSteps(cfg *internal.Config) (errs []error, warnings []string)
 				if hasValue && hasRegex {
 					errs = append(errs, fmt.Errorf("transform %q step %d (replace) cannot have both 'value' and 'regex' set", tr.Name, i))
 				}
-			case "trim_space", "fix_space", "lower", "upper":
+			case "param_lookup":
+				if step.Regex != nil {
+					warnings = append(warnings, fmt.Sprintf("transform %q step %d (%q) should not have 'regex' set (will be ignored)", tr.Name, i, step.Type))
+				}
+			case "truncate":
+				if step.Max == nil || *step.Max <= 0 {
+					errs = append(errs, fmt.Errorf("transform %q step %d (truncate) must have a positive 'max' set", tr.Name, i))
+				}
+				if step.Value != nil || step.Regex != nil || step.With != nil {
+					warnings = append(warnings, fmt.Sprintf("transform %q step %d (truncate) should only set 'max' and 'ellipsis' (others will be ignored)", tr.Name, i))
+				}
+			case "split":
+				if step.Separator == nil || *step.Separator == "" {
+					errs = append(errs, fmt.Errorf("transform %q step %d (split) must have 'separator' set", tr.Name, i))
+				}
+				if step.Index == nil {
+					warnings = append(warnings, fmt.Sprintf("transform %q step %d (split) has no 'index' set (defaults to 0, the first part)", tr.Name, i))
+				}
+				if step.Value != nil || step.Regex != nil || step.With != nil {
+					warnings = append(warnings, fmt.Sprintf("transform %q step %d (split) should only set 'separator' and 'index' (others will be ignored)", tr.Name, i))
+				}
+			case "trim_space", "fix_space", "lower", "upper", "arn_short", "base64_decode", "url_decode":
 				if step.Value != nil {
 					warnings = append(warnings, fmt.Sprintf("transform %q step %d (%q) should not have 'value' set (will be ignored)", tr.Name, i, step.Type))
 				}
@@ -356,6 +580,96 @@ func checkTransformSteps(cfg *internal.Config) (errs []error, warnings []string)
 	return
 }
 
+// checkHints checks that each hint has match criteria. A hint with neither error_contains nor
+// regex_match never matches; a catch-all hint must opt in with match_all. Locale variants, such as
+// throttling.ja, use their base hint's criteria and only need suggestions.
+func checkHints(cfg *internal.Config) (errs []error, warnings []string) {
+	set := func(s *string) bool { return s != nil && *s != "" }
+	for _, h := range cfg.Hints {
+		if h.Disabled {
+			continue
+		}
+		hasCriteria := set(h.ErrorContains) || set(h.RegexMatch) || set(h.ErrorType) || len(h.AnyOf) > 0 || len(h.AllOf) > 0
+		if cfg.IsHintVariant(h.Name) {
+			// A locale variant only supplies suggestions for its base hint
+			if hasCriteria || h.MatchAll {
+				warnings = append(warnings, fmt.Sprintf("hint %q is a locale variant, so its match criteria are ignored; the base hint's criteria apply", h.Name))
+			}
+			if len(h.SuggestionLines()) == 0 {
+				errs = append(errs, fmt.Errorf("hint %q has no suggestion or suggestions", h.Name))
+			}
+			continue
+		}
+		switch {
+		case h.MatchAll && hasCriteria:
+			warnings = append(warnings, fmt.Sprintf("hint %q sets match_all, so error_contains, regex_match, error_type, any_of, and all_of are ignored", h.Name))
+		case !h.MatchAll && !hasCriteria:
+			errs = append(errs, fmt.Errorf("hint %q has no match criteria (error_contains, regex_match, error_type, any_of, or all_of); set match_all = true for a catch-all hint", h.Name))
+		}
+		errs = append(errs, checkConditions(h.Name, "any_of", h.AnyOf)...)
+		errs = append(errs, checkConditions(h.Name, "all_of", h.AllOf)...)
+		if set(h.ErrorType) {
+			if w := checkErrorType(h.Name, *h.ErrorType, internal.ErrorTypes()); w != "" {
+				warnings = append(warnings, w)
+			}
+		}
+		if len(h.SuggestionLines()) == 0 {
+			errs = append(errs, fmt.Errorf("hint %q has no suggestion or suggestions", h.Name))
+		}
+	}
+	return
+}
+
+// checkErrorType returns a warning if errorType isn't one of known, the registered error types. The
+// smarterr binary doesn't know the types a host registers, so with none registered, any type passes.
+func checkErrorType(hintName, errorType string, known []string) string {
+	if len(known) == 0 || slices.Contains(known, errorType) {
+		return ""
+	}
+	return fmt.Sprintf("hint %q has error_type %q, which isn't a registered error type (%s)", hintName, errorType, strings.Join(known, ", "))
+}
+
+// checkConditions checks that each of a hint's any_of or all_of conditions has criteria and a valid
+// regex_match.
+func checkConditions(hintName, group string, conditions []internal.Condition) (errs []error) {
+	for i, c := range conditions {
+		hasContains := c.ErrorContains != nil && *c.ErrorContains != ""
+		hasRegex := c.RegexMatch != nil && *c.RegexMatch != ""
+		if !hasContains && !hasRegex {
+			errs = append(errs, fmt.Errorf("hint %q %s condition %d has no error_contains or regex_match, so it never matches", hintName, group, i))
+		}
+		if hasRegex {
+			if _, err := regexp.Compile(*c.RegexMatch); err != nil {
+				errs = append(errs, fmt.Errorf("hint %q %s condition %d has invalid regex_match: %v", hintName, group, i, err))
+			}
+		}
+	}
+	return
+}
+
+// checkParameters checks that each parameter sets value or values, but not both. An empty value,
+// value = "", counts as set.
+func checkParameters(cfg *internal.Config) (errs []error, warnings []string) {
+	for _, p := range cfg.Parameters {
+		if p.Value != nil && p.Values != nil {
+			errs = append(errs, fmt.Errorf("parameter %q cannot have both 'value' and 'values' set", p.Name))
+		}
+		if p.Value == nil && p.Values == nil {
+			errs = append(errs, fmt.Errorf("parameter %q must set 'value' or 'values'", p.Name))
+		}
+	}
+	return
+}
+
+// checkSchemaVersion warns if the config declares a schema version this version of smarterr doesn't
+// support. smarterr still loads such a config, so it's a warning rather than an error.
+func checkSchemaVersion(cfg *internal.Config) (errs []error, warnings []string) {
+	if err := internal.CheckSchemaVersion(cfg); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	return
+}
+
 // checkSmarterrBlock checks smarterr block fields for valid values.
 func checkSmarterrBlock(cfg *internal.Config) (errs []error, warnings []string) {
 	if cfg.Smarterr == nil {
@@ -378,12 +692,41 @@ func checkSmarterrBlock(cfg *internal.Config) (errs []error, warnings []string)
 			errs = append(errs, fmt.Errorf("smarterr.hint_match_mode must be 'all' or 'first' (got %q)", mode))
 		}
 	}
+	if cfg.Smarterr.DuplicateKeyvalMode != nil {
+		mode := *cfg.Smarterr.DuplicateKeyvalMode
+		if mode != "last" && mode != "first" && mode != "collect" {
+			errs = append(errs, fmt.Errorf("smarterr.duplicate_keyval_mode must be one of 'last', 'first', or 'collect' (got %q)", mode))
+		}
+	}
+	if cfg.Smarterr.HintMaxSuggestions != nil && *cfg.Smarterr.HintMaxSuggestions < 0 {
+		errs = append(errs, fmt.Errorf("smarterr.hint_max_suggestions must not be negative (got %d); use 0 for no limit", *cfg.Smarterr.HintMaxSuggestions))
+	}
+	if cfg.Smarterr.MaxDetailLength != nil && *cfg.Smarterr.MaxDetailLength <= 0 {
+		errs = append(errs, fmt.Errorf("smarterr.max_detail_length must be greater than 0 (got %d)", *cfg.Smarterr.MaxDetailLength))
+	}
+	for _, name := range cfg.Smarterr.LogFields {
+		if !slices.ContainsFunc(cfg.Tokens, func(t internal.Token) bool { return t.Name == name }) {
+			warnings = append(warnings, fmt.Sprintf("smarterr.log_fields references undefined token %q", name))
+		}
+	}
+	for _, blockType := range slices.Sorted(maps.Keys(cfg.Smarterr.MergePrecedence)) {
+		if !slices.Contains(internal.MergeBlockTypes, blockType) {
+			errs = append(errs, fmt.Errorf("smarterr.merge_precedence has unknown block type %q (must be one of %s)", blockType, strings.Join(internal.MergeBlockTypes, ", ")))
+		}
+		if precedence := cfg.Smarterr.MergePrecedence[blockType]; precedence != internal.MergePrecedenceLocal && precedence != internal.MergePrecedenceBase {
+			errs = append(errs, fmt.Errorf("smarterr.merge_precedence.%s must be 'local' or 'base' (got %q)", blockType, precedence))
+		}
+	}
 	return
 }
 
 // checkTokenFields checks for misconfiguration, missing, or conflicting fields in tokens.
 func checkTokenFields(cfg *internal.Config) (errs []error, warnings []string) {
 	for _, t := range cfg.Tokens {
+		if t.Disabled {
+			// A disabled token only needs disabled = true
+			continue
+		}
 		source := t.Source
 		set := func(s *string) bool { return s != nil && *s != "" }
 		countSet := 0
@@ -396,6 +739,18 @@ func checkTokenFields(cfg *internal.Config) (errs []error, warnings []string) {
 		if set(t.Arg) {
 			countSet++
 		}
+		if set(t.Annotation) {
+			countSet++
+		}
+		if set(t.ResourceData) {
+			countSet++
+		}
+		if set(t.Env) {
+			countSet++
+		}
+		if set(t.FromToken) {
+			countSet++
+		}
 		if len(t.StackMatches) > 0 {
 			countSet++
 		}
@@ -410,13 +765,21 @@ func checkTokenFields(cfg *internal.Config) (errs []error, warnings []string) {
 				inferredSource = "context"
 			case set(t.Arg):
 				inferredSource = "arg"
+			case set(t.Annotation):
+				inferredSource = "annotation"
+			case set(t.ResourceData):
+				inferredSource = "resource_data"
+			case set(t.Env):
+				inferredSource = "env"
+			case set(t.FromToken):
+				inferredSource = "from_token"
 			case len(t.StackMatches) > 0:
 				inferredSource = "call_stack"
 			default:
 				inferredSource = "parameter"
 			}
 			if countSet > 1 {
-				errs = append(errs, fmt.Errorf("token %q: multiple fields set (parameter, context, arg, stack_matches) with no source; this is ambiguous", t.Name))
+				errs = append(errs, fmt.Errorf("token %q: multiple fields set (parameter, context, arg, annotation, resource_data, env, from_token, stack_matches) with no source; this is ambiguous", t.Name))
 			}
 		}
 
@@ -439,10 +802,61 @@ func checkTokenFields(cfg *internal.Config) (errs []error, warnings []string) {
 		case "arg":
 			if !set(t.Arg) {
 				errs = append(errs, fmt.Errorf("token %q: source=arg but 'arg' field is not set", t.Name))
+			} else if key, _, _ := strings.Cut(*t.Arg, "."); slices.Contains(internal.ReservedKeyvals, key) {
+				errs = append(errs, fmt.Errorf("token %q: arg %q is a reserved keyval that smarterr consumes, so the token can't resolve it", t.Name, *t.Arg))
 			}
 			if set(t.Parameter) || set(t.Context) || len(t.StackMatches) > 0 {
 				warnings = append(warnings, fmt.Sprintf("token %q: source=arg should not set parameter, context, or stack_matches", t.Name))
 			}
+		case "annotation":
+			if !set(t.Annotation) {
+				errs = append(errs, fmt.Errorf("token %q: source=annotation but 'annotation' field is not set", t.Name))
+			}
+			if set(t.Parameter) || set(t.Context) || set(t.Arg) || len(t.StackMatches) > 0 {
+				warnings = append(warnings, fmt.Sprintf("token %q: source=annotation should not set parameter, context, arg, or stack_matches", t.Name))
+			}
+		case "from_token":
+			if !set(t.FromToken) {
+				errs = append(errs, fmt.Errorf("token %q: source=from_token but 'from_token' field is not set", t.Name))
+			} else if !slices.ContainsFunc(cfg.Tokens, func(other internal.Token) bool { return other.Name == *t.FromToken }) {
+				errs = append(errs, fmt.Errorf("token %q: from_token references undefined token %q", t.Name, *t.FromToken))
+			}
+			if set(t.Parameter) || set(t.Context) || set(t.Arg) || len(t.StackMatches) > 0 {
+				warnings = append(warnings, fmt.Sprintf("token %q: source=from_token should not set parameter, context, arg, or stack_matches", t.Name))
+			}
+		case "resource_data":
+			if !set(t.ResourceData) {
+				errs = append(errs, fmt.Errorf("token %q: source=resource_data but 'resource_data' field is not set", t.Name))
+			}
+			if set(t.Parameter) || set(t.Context) || set(t.Arg) || len(t.StackMatches) > 0 {
+				warnings = append(warnings, fmt.Sprintf("token %q: source=resource_data should not set parameter, context, arg, or stack_matches", t.Name))
+			}
+		case "env":
+			if !set(t.Env) {
+				errs = append(errs, fmt.Errorf("token %q: source=env but 'env' field is not set", t.Name))
+			} else if !strings.HasPrefix(*t.Env, internal.EnvVarPrefix) {
+				errs = append(errs, fmt.Errorf("token %q: env %q doesn't start with %s; other environment variables are hidden so config can't leak secrets", t.Name, *t.Env, internal.EnvVarPrefix))
+			}
+			if set(t.Parameter) || set(t.Context) || set(t.Arg) || set(t.Annotation) || set(t.ResourceData) || set(t.FromToken) || len(t.StackMatches) > 0 {
+				warnings = append(warnings, fmt.Sprintf("token %q: source=env should not set parameter, context, arg, annotation, resource_data, from_token, or stack_matches", t.Name))
+			}
+		case "regex_capture":
+			if !set(t.CaptureRegex) {
+				errs = append(errs, fmt.Errorf("token %q: source=regex_capture but 'capture_regex' field is not set", t.Name))
+			} else if re, err := regexp.Compile(*t.CaptureRegex); err != nil {
+				errs = append(errs, fmt.Errorf("token %q: invalid capture_regex: %v", t.Name, err))
+			} else {
+				group := internal.DefaultCaptureGroup
+				if t.CaptureGroup != nil {
+					group = *t.CaptureGroup
+				}
+				if group < 0 || group > re.NumSubexp() {
+					errs = append(errs, fmt.Errorf("token %q: capture_group %d not in capture_regex (groups: 0-%d)", t.Name, group, re.NumSubexp()))
+				}
+			}
+			if set(t.Parameter) || set(t.Context) || set(t.Arg) || len(t.StackMatches) > 0 {
+				warnings = append(warnings, fmt.Sprintf("token %q: source=regex_capture should not set parameter, context, arg, or stack_matches", t.Name))
+			}
 		case "call_stack", "error_stack":
 			if len(t.StackMatches) == 0 {
 				errs = append(errs, fmt.Errorf("token %q: source=%s but stack_matches is not set", t.Name, inferredSource))
@@ -454,15 +868,28 @@ func checkTokenFields(cfg *internal.Config) (errs []error, warnings []string) {
 			if set(t.Parameter) || set(t.Context) || set(t.Arg) || len(t.StackMatches) > 0 {
 				warnings = append(warnings, fmt.Sprintf("token %q: source=diagnostic should not set parameter, context, arg, or stack_matches", t.Name))
 			}
-		case "hints", "error":
+		case "package_service":
+			if set(t.Parameter) || set(t.Context) || set(t.Arg) || len(t.StackMatches) > 0 {
+				warnings = append(warnings, fmt.Sprintf("token %q: source=package_service should not set parameter, context, arg, or stack_matches", t.Name))
+			}
+		case "hints", "hint_name", "error", "error_origin", "error_code":
 			if set(t.Parameter) || set(t.Context) || set(t.Arg) || len(t.StackMatches) > 0 {
 				warnings = append(warnings, fmt.Sprintf("token %q: source=%s should not set parameter, context, arg, or stack_matches", t.Name, inferredSource))
 			}
 		}
+		if (t.CaptureRegex != nil || t.CaptureGroup != nil) && inferredSource != "regex_capture" {
+			warnings = append(warnings, fmt.Sprintf("token %q: capture_regex or capture_group is set but source is not regex_capture (actual: %s)", t.Name, inferredSource))
+		}
+		if len(t.ServiceMap) > 0 && inferredSource != "package_service" {
+			warnings = append(warnings, fmt.Sprintf("token %q: service_map is set but source is not package_service (actual: %s)", t.Name, inferredSource))
+		}
 		// If stack_matches is set but source is not call_stack or error_stack, warn
 		if len(t.StackMatches) > 0 && inferredSource != "call_stack" && inferredSource != "error_stack" {
 			warnings = append(warnings, fmt.Sprintf("token %q: stack_matches is set but source is not call_stack or error_stack (actual: %s)", t.Name, inferredSource))
 		}
 	}
+	if _, cycle := internal.TokenOrder(cfg.Tokens); cycle != nil {
+		errs = append(errs, fmt.Errorf("tokens reference each other with from_token in a cycle: %s", strings.Join(cycle, " -> ")))
+	}
 	return
 }
@@ -0,0 +1,720 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+// writeConfig writes content to a smarterr.hcl file in a temporary directory and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "smarterr.hcl")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	return path
+}
+
+func TestCheckCmd_ConfigFile(t *testing.T) {
+	path := writeConfig(t, `
+smarterr {
+  token_error_mode = "bogus"
+}
+`)
+
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	errs, _ := runChecks(cfg)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "token_error_mode") {
+		t.Errorf("expected token_error_mode error, got: %v", errs[0])
+	}
+
+	rootCmd.SetArgs([]string{"validate", "--config-file", path, "--silent"})
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		configFile = ""
+		silentFlag = false
+	})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected validate to fail for config with a known error")
+	}
+}
+
+func TestIsCatchAllRegex(t *testing.T) {
+	tests := map[string]bool{
+		"":                         false,
+		".*":                       true,
+		".+":                       true,
+		"^.*$":                     true,
+		"^":                        true,
+		"(?s).*":                   true,
+		"^$":                       false,
+		"resource[a-zA-Z0-9]*Read": false,
+		"wait.*":                   false,
+		"[":                        false,
+	}
+	for re, want := range tests {
+		if got := isCatchAllRegex(re); got != want {
+			t.Errorf("isCatchAllRegex(%q) = %t, want %t", re, got, want)
+		}
+	}
+}
+
+func TestCheckStackMatches_OverlyBroad(t *testing.T) {
+	cfg := &internal.Config{
+		StackMatches: []internal.StackMatch{
+			{Name: "any", CalledFrom: ".*", Display: "doing something"},
+			{Name: "create", CalledFrom: "Create$", Display: "creating"},
+		},
+		Tokens: []internal.Token{
+			{Name: "shadowing", StackMatches: []string{"any", "create"}},
+			{Name: "fallback", StackMatches: []string{"create", "any"}},
+		},
+	}
+	errs, warnings := checkStackMatches(cfg)
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got: %v", errs)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %d: %v", len(warnings), warnings)
+	}
+	if !strings.Contains(warnings[0], `stack_match "any" has overly broad called_from ".*"`) || !strings.Contains(warnings[0], `token "shadowing"`) {
+		t.Errorf("unexpected warning: %s", warnings[0])
+	}
+}
+
+func TestCheckStackMatches_OverlyBroadLowestPriority(t *testing.T) {
+	cfg := &internal.Config{
+		StackMatches: []internal.StackMatch{
+			{Name: "any", CalledFrom: ".*", Display: "doing something", Priority: -1},
+			{Name: "create", CalledFrom: "Create$", Display: "creating"},
+		},
+		Tokens: []internal.Token{
+			{Name: "listed_first", StackMatches: []string{"any", "create"}},
+		},
+	}
+	_, warnings := checkStackMatches(cfg)
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings for lowest-priority catch-all, got: %v", warnings)
+	}
+}
+
+func TestCheckStackMatches_CalledAfter(t *testing.T) {
+	cfg := &internal.Config{
+		StackMatches: []internal.StackMatch{
+			{Name: "any_create", CalledFrom: ".*", CalledAfter: "Create$", Display: "during create"},
+			{Name: "create", CalledFrom: "Create$", Display: "creating"},
+			{Name: "after_only", CalledAfter: "Create$", Display: "never"},
+			{Name: "bad_after", CalledFrom: "find", CalledAfter: "(", Display: "finding"},
+			{Name: "bad_from", CalledFrom: "[", Display: "broken"},
+		},
+		Tokens: []internal.Token{
+			{Name: "op", StackMatches: []string{"any_create", "create", "after_only", "bad_after", "bad_from"}},
+		},
+	}
+	errs, warnings := checkStackMatches(cfg)
+	want := []string{
+		`stack_match "after_only" sets called_after without called_from`,
+		`stack_match "bad_after" has invalid called_after`,
+		`stack_match "bad_from" has invalid called_from`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		if !strings.Contains(errs[i].Error(), w) {
+			t.Errorf("error %d = %q, want it to contain %q", i, errs[i], w)
+		}
+	}
+	// A catch-all called_from limited by called_after doesn't shadow the rules after it
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got: %v", warnings)
+	}
+}
+
+func TestCheckTransformSteps_RegistryConsistent(t *testing.T) {
+	value := "x"
+	for _, stepType := range internal.TransformStepTypes() {
+		cfg := &internal.Config{
+			Transforms: []internal.Transform{{
+				Name:  "t",
+				Steps: []internal.TransformStep{{Type: stepType, Value: &value, With: &value}},
+			}},
+		}
+		errs, _ := checkTransformSteps(cfg)
+		for _, err := range errs {
+			if strings.Contains(err.Error(), "undefined type") {
+				t.Errorf("registered step type %q reported as undefined: %v", stepType, err)
+			}
+		}
+	}
+
+	cfg := &internal.Config{
+		Transforms: []internal.Transform{{Name: "t", Steps: []internal.TransformStep{{Type: "bogus"}}}},
+	}
+	errs, warnings := checkTransformSteps(cfg)
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got: %v", errs)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `undefined type "bogus"`) {
+		t.Errorf("expected undefined type warning, got: %v", warnings)
+	}
+}
+
+func TestCheckTransformSteps_RegisteredType(t *testing.T) {
+	internal.RegisterTransformStep("test_registered", func(value string, _ internal.TransformStep) string { return value })
+	cfg := &internal.Config{
+		Transforms: []internal.Transform{{Name: "t", Steps: []internal.TransformStep{{Type: "test_registered"}}}},
+	}
+	if errs, _ := checkTransformSteps(cfg); len(errs) != 0 {
+		t.Errorf("expected registered step type to be supported, got: %v", errs)
+	}
+}
+
+func TestCheckTransformSteps_Truncate(t *testing.T) {
+	zero, limit := 0, 200
+	tests := []struct {
+		name    string
+		step    internal.TransformStep
+		wantErr bool
+	}{
+		{name: "max set", step: internal.TransformStep{Type: "truncate", Max: &limit}},
+		{name: "max missing", step: internal.TransformStep{Type: "truncate"}, wantErr: true},
+		{name: "max zero", step: internal.TransformStep{Type: "truncate", Max: &zero}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &internal.Config{
+				Transforms: []internal.Transform{{Name: "short", Steps: []internal.TransformStep{tc.step}}},
+			}
+			errs, _ := checkTransformSteps(cfg)
+			if got := len(errs) > 0; got != tc.wantErr {
+				t.Errorf("checkTransformSteps() errs = %v, want error: %t", errs, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTransformSteps_Split(t *testing.T) {
+	sep, empty, index := ":", "", 5
+	tests := []struct {
+		name        string
+		step        internal.TransformStep
+		wantErr     bool
+		wantWarning bool
+	}{
+		{name: "separator and index", step: internal.TransformStep{Type: "split", Separator: &sep, Index: &index}},
+		{name: "index missing", step: internal.TransformStep{Type: "split", Separator: &sep}, wantWarning: true},
+		{name: "separator missing", step: internal.TransformStep{Type: "split", Index: &index}, wantErr: true},
+		{name: "separator empty", step: internal.TransformStep{Type: "split", Separator: &empty, Index: &index}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &internal.Config{
+				Transforms: []internal.Transform{{Name: "arn_part", Steps: []internal.TransformStep{tc.step}}},
+			}
+			errs, warnings := checkTransformSteps(cfg)
+			if got := len(errs) > 0; got != tc.wantErr {
+				t.Errorf("checkTransformSteps() errs = %v, want error: %t", errs, tc.wantErr)
+			}
+			if got := len(warnings) > 0; got != tc.wantWarning {
+				t.Errorf("checkTransformSteps() warnings = %v, want warning: %t", warnings, tc.wantWarning)
+			}
+		})
+	}
+}
+
+func TestCheckTransformSteps_Decode(t *testing.T) {
+	value := "x"
+	for _, stepType := range []string{"base64_decode", "url_decode"} {
+		t.Run(stepType, func(t *testing.T) {
+			cfg := &internal.Config{
+				Transforms: []internal.Transform{{Name: "decode", Steps: []internal.TransformStep{{Type: stepType}}}},
+			}
+			if errs, warnings := checkTransformSteps(cfg); len(errs) != 0 || len(warnings) != 0 {
+				t.Errorf("checkTransformSteps() = %v, %v, want no errors or warnings", errs, warnings)
+			}
+
+			cfg.Transforms[0].Steps[0].Value = &value
+			cfg.Transforms[0].Steps[0].With = &value
+			errs, warnings := checkTransformSteps(cfg)
+			if len(errs) != 0 {
+				t.Errorf("checkTransformSteps() errs = %v, want none", errs)
+			}
+			if len(warnings) != 2 {
+				t.Errorf("checkTransformSteps() warnings = %v, want 2 for the ignored value and with", warnings)
+			}
+		})
+	}
+}
+
+func TestCheckHints_NoCriteria(t *testing.T) {
+	contains := "throttl"
+	empty := ""
+	cfg := &internal.Config{
+		Hints: []internal.Hint{
+			{Name: "throttle", ErrorContains: &contains, Suggestion: "Retry later."},
+			{Name: "none", Suggestion: "Always shown."},
+			{Name: "empty", ErrorContains: &empty, RegexMatch: &empty, Suggestion: "Also always shown."},
+			{Name: "support", MatchAll: true, Suggestion: "Contact support."},
+			{Name: "ignored", MatchAll: true, ErrorContains: &contains, Suggestion: "Criteria ignored."},
+		},
+	}
+	errs, warnings := checkHints(cfg)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	for i, name := range []string{"none", "empty"} {
+		if !strings.Contains(errs[i].Error(), `hint "`+name+`" has no match criteria`) {
+			t.Errorf("unexpected error: %v", errs[i])
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `hint "ignored" sets match_all`) {
+		t.Errorf("expected match_all criteria warning, got: %v", warnings)
+	}
+}
+
+func TestCheckHints_ErrorType(t *testing.T) {
+	internal.RegisterErrorType("test_check_type", errors.New(""))
+	registered, unregistered := "test_check_type", "test_check_unregistered"
+	cfg := &internal.Config{
+		Hints: []internal.Hint{
+			{Name: "registered", ErrorType: &registered, Suggestion: "Shown for the type."},
+			{Name: "unregistered", ErrorType: &unregistered, Suggestion: "Never shown."},
+		},
+	}
+	errs, warnings := checkHints(cfg)
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got: %v", errs)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `hint "unregistered" has error_type "test_check_unregistered", which isn't a registered error type`) {
+		t.Errorf("expected one unregistered error type warning, got: %v", warnings)
+	}
+
+	// The smarterr binary registers no types, so it can't tell which the host registers
+	if w := checkErrorType("not_found", "ResourceNotFound", nil); w != "" {
+		t.Errorf("expected no warning with no registered types, got: %s", w)
+	}
+}
+
+func TestCheckHints_Conditions(t *testing.T) {
+	contains, empty, badRegex := "Throttling", "", "("
+	cfg := &internal.Config{
+		Hints: []internal.Hint{
+			{Name: "valid", AnyOf: []internal.Condition{{ErrorContains: &contains}}, Suggestion: "Retry later."},
+			{Name: "empty", AllOf: []internal.Condition{{ErrorContains: &empty}}, Suggestion: "Never shown."},
+			{Name: "bad_regex", AnyOf: []internal.Condition{{ErrorContains: &contains}, {RegexMatch: &badRegex}}, Suggestion: "Never shown."},
+		},
+	}
+	errs, _ := checkHints(cfg)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if want := `hint "empty" all_of condition 0 has no error_contains or regex_match`; !strings.Contains(errs[0].Error(), want) {
+		t.Errorf("expected error containing %q, got: %v", want, errs[0])
+	}
+	if want := `hint "bad_regex" any_of condition 1 has invalid regex_match`; !strings.Contains(errs[1].Error(), want) {
+		t.Errorf("expected error containing %q, got: %v", want, errs[1])
+	}
+}
+
+func TestCheckHints_NoSuggestion(t *testing.T) {
+	contains := "throttl"
+	cfg := &internal.Config{
+		Hints: []internal.Hint{
+			{Name: "single", ErrorContains: &contains, Suggestion: "Retry later."},
+			{Name: "steps", ErrorContains: &contains, Suggestions: []string{"Wait a minute.", "Retry."}},
+			{Name: "blank", ErrorContains: &contains, Suggestions: []string{""}},
+		},
+	}
+	errs, _ := checkHints(cfg)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `hint "blank" has no suggestion`) {
+		t.Errorf("expected missing suggestion error, got: %v", errs)
+	}
+}
+
+func TestCheckParameters_ValueAndValues(t *testing.T) {
+	path := writeConfig(t, `
+parameter "service" {
+  value = "EC2"
+}
+
+parameter "empty" {
+  value = ""
+}
+
+parameter "codes" {
+  values = ["Throttling"]
+}
+
+parameter "both" {
+  value  = "Throttling"
+  values = ["Throttling"]
+}
+
+parameter "neither" {}
+
+parameter "empty_list" {
+  values = []
+}
+`)
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	errs, _ := checkParameters(cfg)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), `parameter "both" cannot have both 'value' and 'values' set`) {
+		t.Errorf("expected value and values error, got: %v", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), `parameter "neither" must set 'value' or 'values'`) {
+		t.Errorf("expected missing value error, got: %v", errs[1])
+	}
+}
+
+func TestCheck_DisabledBlocks(t *testing.T) {
+	cfg := &internal.Config{
+		Tokens: []internal.Token{{Name: "identifier", Disabled: true}},
+		Hints:  []internal.Hint{{Name: "throttling", Disabled: true}},
+	}
+	if errs, warnings := checkTokenFields(cfg); len(errs) != 0 || len(warnings) != 0 {
+		t.Errorf("checkTokenFields() = %v, %v; want no errors or warnings for a disabled token", errs, warnings)
+	}
+	if errs, warnings := checkHints(cfg); len(errs) != 0 || len(warnings) != 0 {
+		t.Errorf("checkHints() = %v, %v; want no errors or warnings for a disabled hint", errs, warnings)
+	}
+
+	out, err := convertConfigToHCL(cfg)
+	if err != nil {
+		t.Fatalf("convertConfigToHCL: %v", err)
+	}
+	for _, want := range []string{
+		"token \"identifier\" {\n  disabled = true\n}",
+		"hint \"throttling\" {\n  disabled = true\n}",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestCheckTemplateReferences(t *testing.T) {
+	path := writeConfig(t, `
+template "error_summary" {
+  format = "{{template \"prefix\" .}}"
+}
+
+template "prefix" {
+  format = "{{template \"error_summary\" .}}{{template \"missing\" .}}"
+}
+`)
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	errs, _ := checkTemplateReferences(cfg)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), `template "prefix" references undefined template "missing"`) {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), "cycle: error_summary -> prefix -> error_summary") {
+		t.Errorf("unexpected error: %v", errs[1])
+	}
+}
+
+func TestCheckTemplateNames_Helpers(t *testing.T) {
+	cfg := &internal.Config{
+		Templates: []internal.Template{
+			{Name: "error_summary", Format: `{{template "prefix" .}}: {{.error}}`},
+			{Name: "error_detail", Format: `{{define "inline"}}{{.error}}{{end}}{{template "inline" .}}`},
+			{Name: "diagnostic_summary", Format: "{{.error}}"},
+			{Name: "diagnostic_detail", Format: "{{.error}}"},
+			{Name: "log_error", Format: "{{.error}}"},
+			{Name: "log_warn", Format: "{{.error}}"},
+			{Name: "log_info", Format: "{{.error}}"},
+			{Name: "prefix", Format: `{{template "service" .}}`},
+			{Name: "prefix.ja", Format: `{{template "service" .}}`},
+			{Name: "service", Format: "{{.service}}"},
+			{Name: "inline", Format: "{{.error}}"},
+			{Name: "orphan", Format: "{{.error}}"},
+		},
+	}
+	errs, warnings := checkTemplateNames(cfg)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `template "orphan" is not a recognized canonical template name, and no template invokes it`) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
+
+func TestCheckTokenFields_FromToken(t *testing.T) {
+	id, bogus, loop := "id", "bogus", "loop"
+	cfg := &internal.Config{
+		Tokens: []internal.Token{
+			{Name: "id", Arg: &id},
+			{Name: "id_upper", FromToken: &id},
+			{Name: "bad", FromToken: &bogus},
+			{Name: "loop", Source: "from_token", FromToken: &loop},
+		},
+		Templates: []internal.Template{{Name: "error_summary", Format: "{{.id_upper}}{{.bad}}{{.loop}}"}},
+	}
+	errs, _ := checkTokenFields(cfg)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), `token "bad": from_token references undefined token "bogus"`) {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), "cycle: loop -> loop") {
+		t.Errorf("unexpected error: %v", errs[1])
+	}
+
+	if _, warnings := checkTemplateVarsAndTokens(cfg); len(warnings) != 0 {
+		t.Errorf("expected token used via from_token not to be reported unused, got: %v", warnings)
+	}
+}
+
+func TestCheckTokenFields_Env(t *testing.T) {
+	buildID, secret, id := "SMARTERR_BUILD_ID", "AWS_SECRET_ACCESS_KEY", "id"
+	cfg := &internal.Config{
+		Tokens: []internal.Token{
+			{Name: "build", Env: &buildID},
+			{Name: "secret", Env: &secret},
+			{Name: "explicit", Source: "env"},
+			{Name: "combined", Source: "env", Env: &buildID, Arg: &id},
+			{Name: "ambiguous", Env: &buildID, Arg: &id},
+		},
+	}
+	errs, warnings := checkTokenFields(cfg)
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), `token "secret": env "AWS_SECRET_ACCESS_KEY" doesn't start with SMARTERR_`) {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), `token "explicit": source=env but 'env' field is not set`) {
+		t.Errorf("unexpected error: %v", errs[1])
+	}
+	if !strings.Contains(errs[2].Error(), `token "ambiguous": multiple fields set`) {
+		t.Errorf("unexpected error: %v", errs[2])
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `token "combined": source=env should not set`) {
+		t.Errorf("expected a warning for combined source fields, got: %v", warnings)
+	}
+}
+
+func TestCheckTokenFields_RegexCapture(t *testing.T) {
+	requestID, invalid, group := `RequestID: ([0-9a-f-]+)`, `(`, 2
+	cfg := &internal.Config{
+		Tokens: []internal.Token{
+			{Name: "request_id", Source: "regex_capture", CaptureRegex: &requestID},
+			{Name: "missing", Source: "regex_capture"},
+			{Name: "invalid", Source: "regex_capture", CaptureRegex: &invalid},
+			{Name: "group", Source: "regex_capture", CaptureRegex: &requestID, CaptureGroup: &group},
+			{Name: "unused", Source: "error", CaptureRegex: &requestID},
+		},
+	}
+	errs, warnings := checkTokenFields(cfg)
+	wantErrs := []string{
+		`token "missing": source=regex_capture but 'capture_regex' field is not set`,
+		`token "invalid": invalid capture_regex`,
+		`token "group": capture_group 2 not in capture_regex (groups: 0-1)`,
+	}
+	if len(errs) != len(wantErrs) {
+		t.Fatalf("expected %d errors, got %d: %v", len(wantErrs), len(errs), errs)
+	}
+	for i, want := range wantErrs {
+		if !strings.Contains(errs[i].Error(), want) {
+			t.Errorf("error %d = %v, want it to contain %q", i, errs[i], want)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `token "unused": capture_regex or capture_group is set but source is not regex_capture`) {
+		t.Errorf("expected a warning for capture_regex without regex_capture, got: %v", warnings)
+	}
+}
+
+func TestCheckTokenFields_ReservedArg(t *testing.T) {
+	reserved, nested, id := "summary_override", "summary_override.text", "id"
+	cfg := &internal.Config{
+		Tokens: []internal.Token{
+			{Name: "override", Arg: &reserved},
+			{Name: "nested", Arg: &nested},
+			{Name: "id", Arg: &id},
+		},
+	}
+	errs, _ := checkTokenFields(cfg)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	for i, name := range []string{"override", "nested"} {
+		if !strings.Contains(errs[i].Error(), fmt.Sprintf("token %q: arg", name)) || !strings.Contains(errs[i].Error(), "reserved keyval") {
+			t.Errorf("unexpected error: %v", errs[i])
+		}
+	}
+}
+
+func TestCheckTemplateVarsAndTokens_DiagnosticFields(t *testing.T) {
+	cfg := &internal.Config{
+		Tokens: []internal.Token{{Name: "diag", Source: "diagnostic"}},
+		Templates: []internal.Template{
+			{Name: "diagnostic_summary", Format: "{{.diag.summary}} ({{.diag.severity_level}})"},
+			{Name: "diagnostic_detail", Format: "{{with .diag}}{{.detail}}{{end}}"},
+		},
+	}
+	errs, warnings := checkTemplateVarsAndTokens(cfg)
+	if len(errs) != 0 || len(warnings) != 0 {
+		t.Errorf("expected no errors or warnings, got errors %v, warnings %v", errs, warnings)
+	}
+
+	cfg.Templates = append(cfg.Templates, internal.Template{Name: "error_detail", Format: "{{.diag.message}}"})
+	_, warnings = checkTemplateVarsAndTokens(cfg)
+	want := `template "error_detail" uses .diag.message, but diagnostic token "diag" has no field "message" (fields: summary, detail, severity, severity_level)`
+	if len(warnings) != 1 || warnings[0] != want {
+		t.Errorf("warnings = %v, want [%s]", warnings, want)
+	}
+
+	cfg.Tokens = nil
+	cfg.Templates = cfg.Templates[:1]
+	errs, _ = checkTemplateVarsAndTokens(cfg)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `define token "diag" with source = "diagnostic"`) {
+		t.Errorf("expected an error suggesting a diagnostic token, got: %v", errs)
+	}
+}
+
+func TestCheckTemplateVarsAndTokens_TemplateFuncs(t *testing.T) {
+	service, id := "service_name", "id"
+	cfg := &internal.Config{
+		Tokens: []internal.Token{{Name: "service_name", Parameter: &service}, {Name: "id", Arg: &id}},
+		Templates: []internal.Template{
+			{Name: "error_summary", Format: `{{ upper .service_name }} {{ default "unknown" .id | trimPrefix "vpc-" }}`},
+		},
+	}
+	errs, warnings := checkTemplateVarsAndTokens(cfg)
+	if len(errs) != 0 || len(warnings) != 0 {
+		t.Errorf("expected function names not to be reported, got errors %v, warnings %v", errs, warnings)
+	}
+}
+
+func TestCheckSmarterrBlock_LogFields(t *testing.T) {
+	cfg := &internal.Config{
+		Smarterr: &internal.Smarterr{LogFields: []string{"service", "bogus"}},
+		Tokens:   []internal.Token{{Name: "service"}},
+	}
+	errs, warnings := checkSmarterrBlock(cfg)
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if len(warnings) != 1 || warnings[0] != `smarterr.log_fields references undefined token "bogus"` {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
+
+func TestCheckSmarterrBlock_HintMaxSuggestions(t *testing.T) {
+	for _, tc := range []struct {
+		max     int
+		wantErr bool
+	}{
+		{max: 0},
+		{max: 3},
+		{max: -1, wantErr: true},
+	} {
+		cfg := &internal.Config{Smarterr: &internal.Smarterr{HintMaxSuggestions: &tc.max}}
+		errs, _ := checkSmarterrBlock(cfg)
+		if got := len(errs) > 0; got != tc.wantErr {
+			t.Errorf("hint_max_suggestions = %d: errs = %v, want error: %t", tc.max, errs, tc.wantErr)
+		}
+	}
+}
+
+func TestCheckSmarterrBlock_MergePrecedence(t *testing.T) {
+	cfg := &internal.Config{
+		Smarterr: &internal.Smarterr{MergePrecedence: map[string]string{
+			"template": "base",
+			"token":    "local",
+			"hint":     "global",
+			"tokens":   "base",
+		}},
+	}
+	errs, _ := checkSmarterrBlock(cfg)
+	want := []string{
+		`smarterr.merge_precedence.hint must be 'local' or 'base' (got "global")`,
+		`smarterr.merge_precedence has unknown block type "tokens" (must be one of token, hint, parameter, stack_match, template, transform)`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		if errs[i].Error() != w {
+			t.Errorf("error %d = %q, want %q", i, errs[i], w)
+		}
+	}
+}
+
+func TestCheck_LocaleVariants(t *testing.T) {
+	contains := "Throttling"
+	cfg := &internal.Config{
+		Templates: []internal.Template{
+			{Name: "error_summary", Format: "failed"},
+			{Name: "error_summary.ja", Format: "失敗"},
+			{Name: "error_detail.ja", Format: "詳細"},
+			{Name: "bogus.ja", Format: "x"},
+		},
+		Hints: []internal.Hint{
+			{Name: "throttling", ErrorContains: &contains, Suggestion: "Retry later."},
+			{Name: "throttling.ja", Suggestion: "後で再試行してください。"},
+			{Name: "throttling.de", ErrorContains: &contains},
+		},
+	}
+
+	errs, warnings := checkTemplateNames(cfg)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `template "bogus.ja" is not a recognized`) {
+		t.Errorf("unexpected template name errors: %v", errs)
+	}
+	if !slices.ContainsFunc(warnings, func(w string) bool {
+		return strings.Contains(w, `template "error_detail.ja" is a locale variant, but template "error_detail" isn't defined`)
+	}) {
+		t.Errorf("expected a warning about the missing base template, got: %v", warnings)
+	}
+
+	errs, warnings = checkHints(cfg)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `hint "throttling.de" has no suggestion`) {
+		t.Errorf("unexpected hint errors: %v", errs)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `hint "throttling.de" is a locale variant`) {
+		t.Errorf("unexpected hint warnings: %v", warnings)
+	}
+}
+
+func TestCheckTokenFields_ServiceMap(t *testing.T) {
+	id := "id"
+	cfg := &internal.Config{
+		Tokens: []internal.Token{
+			{Name: "service", Source: "package_service", ServiceMap: map[string]string{"elbv2": "ELBv2"}},
+			{Name: "id", Arg: &id, ServiceMap: map[string]string{"elbv2": "ELBv2"}},
+		},
+	}
+	errs, warnings := checkTokenFields(cfg)
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `token "id": service_map is set but source is not package_service`) {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
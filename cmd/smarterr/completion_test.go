@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompletionCmd(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		t.Run(shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			rootCmd.SetOut(&buf)
+			rootCmd.SetArgs([]string{"completion", shell})
+			t.Cleanup(func() {
+				rootCmd.SetOut(nil)
+				rootCmd.SetArgs(nil)
+			})
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("completion %s failed: %v", shell, err)
+			}
+			out := buf.String()
+			if out == "" {
+				t.Fatalf("expected non-empty %s completion output", shell)
+			}
+			if !strings.Contains(out, "smarterr") {
+				t.Errorf("expected %s completion output to reference smarterr", shell)
+			}
+		})
+	}
+}
+
+func TestCompletionCmd_UnsupportedShell(t *testing.T) {
+	var buf bytes.Buffer
+	if err := genCompletion(rootCmd, &buf, "tcsh"); err == nil {
+		t.Error("expected error for unsupported shell")
+	}
+}
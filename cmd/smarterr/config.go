@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/YakDriver/smarterr"
 	"github.com/YakDriver/smarterr/internal"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/spf13/cobra"
 	"github.com/zclconf/go-cty/cty"
@@ -16,10 +19,15 @@ import (
 
 var startDir string
 var baseDir string
+var configFile string
+var configFormat string
 
 func init() {
 	configCmd.Flags().StringVarP(&startDir, "start-dir", "d", "", "Directory where code using smarterr lives (default: current directory). This is typically where the error occurs.")
 	configCmd.Flags().StringVarP(&baseDir, "base-dir", "b", "", "Parent directory where go:embed is used (optional, but recommended for proper config layering as in the application). If not set, config applies only to the current directory.")
+	configCmd.Flags().StringVarP(&configFile, "config-file", "c", "", "Load a single config file directly, bypassing discovery and layering (--base-dir and --start-dir are ignored)")
+	configCmd.Flags().StringVar(&configFormat, "format", "hcl", `Output format: "hcl" or "json"; json prints only the merged configuration`)
+	configCmd.Flags().BoolVarP(&watchFlag, "watch", "w", false, "Watch config files for changes and print the configuration again on each change")
 	configCmd.Flags().BoolVarP(&debugFlag, "debug", "D", false, "Enable smarterr debug output (even if config fails to load)")
 	rootCmd.AddCommand(configCmd)
 }
@@ -30,100 +38,238 @@ var configCmd = &cobra.Command{
 	Long: `This command prints the merged smarterr configuration that would apply
 at the specified directory path. It helps debug layered config resolution.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if configFormat != "hcl" && configFormat != "json" {
+			return fmt.Errorf("--format must be hcl or json, got %q", configFormat)
+		}
 		if debugFlag {
 			fmt.Printf("Debug mode enabled\n")
 			internal.EnableDebugForce()
 		}
-		if baseDir == "" {
-			fmt.Println("WARNING: --base-dir is not set. Config will only apply to the current directory. For proper config layering, set --base-dir to the directory where go:embed is used in your application.")
+		if watchFlag {
+			return watchConfig(cmd, runConfig)
 		}
-		// Ensure baseDir and startDir are absolute
-		absBaseDir, err := filepath.Abs(baseDir)
+		return runConfig()
+	},
+}
+
+// runConfig loads the configuration and prints it.
+func runConfig() error {
+	status := configStatusOutput()
+	cfg, _, err := loadConfig(status)
+	if err != nil {
+		return err
+	}
+	if configFile != "" {
+		return printMergedConfig(cfg)
+	}
+
+	// Output all config files found under baseDir
+	absBaseDir, _, _, err := resolveDirs()
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(status, "Config files found under baseDir:")
+	err = filepath.Walk(absBaseDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return fmt.Errorf("failed to get absolute baseDir: %w", err)
+			return nil // skip errors
 		}
-		absStartDir := startDir
-		if absStartDir == "" {
-			cwd, err := os.Getwd()
-			if err != nil {
-				return fmt.Errorf("failed to get current directory: %w", err)
-			}
-			absStartDir = cwd
+		if info.IsDir() {
+			return nil
 		}
-		absStartDir, err = filepath.Abs(absStartDir)
-		if err != nil {
-			return fmt.Errorf("failed to get absolute startDir: %w", err)
+		if internal.IsConfigFile(path) {
+			rel, _ := filepath.Rel(absBaseDir, path)
+			_, _ = fmt.Fprintln(status, "  ", rel)
 		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error walking baseDir: %w", err)
+	}
 
-		fmt.Printf("Loading configuration...\nStart dir: %s\nBase dir: %s\n", absStartDir, absBaseDir)
+	return printMergedConfig(cfg)
+}
 
-		// Compute relative path from baseDir to startDir
-		relStartDir, err := filepath.Rel(absBaseDir, absStartDir)
-		if err != nil {
-			return fmt.Errorf("failed to relativize startDir: %w", err)
-		}
-		if strings.HasPrefix(relStartDir, "..") {
-			return fmt.Errorf("startDir must be inside baseDir")
-		}
+// configStatusOutput returns where runConfig writes progress messages: stdout for HCL, or stderr
+// for JSON, so stdout is only the JSON document.
+func configStatusOutput() io.Writer {
+	if configFormat == "json" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
 
-		// Use a real FS rooted at baseDir
-		fsys := smarterr.NewWrappedFS(absBaseDir)
+// printMergedConfig prints the configuration as HCL or, with --format json, as JSON.
+func printMergedConfig(cfg *internal.Config) error {
+	if debugFlag {
+		_, _ = fmt.Fprintf(configStatusOutput(), "Raw merged config: %+v\n", cfg)
+	}
 
-		// Output all config files found under baseDir
-		fmt.Println("Config files found under baseDir:")
-		err = filepath.Walk(absBaseDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil // skip errors
-			}
-			if info.IsDir() {
-				return nil
-			}
-			if filepath.Base(path) == "smarterr.hcl" {
-				rel, _ := filepath.Rel(absBaseDir, path)
-				fmt.Println("  ", rel)
-			}
-			return nil
-		})
+	if configFormat == "json" {
+		jsonBytes, err := convertConfigToJSON(cfg)
 		if err != nil {
-			return fmt.Errorf("error walking baseDir: %w", err)
+			return fmt.Errorf("failed to convert config to JSON: %w", err)
 		}
+		fmt.Println(string(jsonBytes))
+		return nil
+	}
+
+	fmt.Println("Merged config:")
+	// Convert the configuration to HCL format
+	hclBytes, err := convertConfigToHCL(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to convert config to HCL: %w", err)
+	}
 
-		// Pass the relative stack path
-		relStackPaths := []string{relStartDir}
-		cfg, err := internal.LoadConfig(context.Background(), fsys, relStackPaths, ".")
+	// Output the configuration
+	fmt.Println(string(hclBytes))
+	return nil
+}
+
+// resolveDirs returns the absolute --base-dir and --start-dir (default: current directory) and the
+// start directory relative to the base directory.
+func resolveDirs() (absBaseDir, absStartDir, relStartDir string, err error) {
+	// Ensure baseDir and startDir are absolute
+	absBaseDir, err = filepath.Abs(baseDir)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get absolute baseDir: %w", err)
+	}
+	absStartDir = startDir
+	if absStartDir == "" {
+		cwd, err := os.Getwd()
 		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
+			return "", "", "", fmt.Errorf("failed to get current directory: %w", err)
 		}
+		absStartDir = cwd
+	}
+	absStartDir, err = filepath.Abs(absStartDir)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get absolute startDir: %w", err)
+	}
 
-		if debugFlag {
-			fmt.Printf("Raw merged config: %+v\n", cfg)
-		}
+	// Compute relative path from baseDir to startDir
+	relStartDir, err = filepath.Rel(absBaseDir, absStartDir)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to relativize startDir: %w", err)
+	}
+	if strings.HasPrefix(relStartDir, "..") {
+		return "", "", "", fmt.Errorf("startDir must be inside baseDir")
+	}
+	return absBaseDir, absStartDir, relStartDir, nil
+}
+
+// configLoadError is returned by loadConfig when the config itself fails to load, as opposed to
+// bad flags.
+type configLoadError struct {
+	err error
+}
+
+func (e *configLoadError) Error() string {
+	return "failed to load config: " + e.err.Error()
+}
+
+func (e *configLoadError) Unwrap() error {
+	return e.err
+}
+
+// loadConfig loads the config a command works on: the file set with --config-file or, if it isn't
+// set, the merged config for --start-dir layered under --base-dir. It also returns the paths of the
+// config files it loaded, least specific first. If status isn't nil, it writes what it's loading,
+// and a warning if --base-dir isn't set, to status.
+func loadConfig(status io.Writer) (*internal.Config, []string, error) {
+	return loadConfigAs("Loading configuration...", status, status)
+}
 
-		fmt.Println("Merged config:")
-		// Convert the configuration to HCL format
-		hclBytes, err := convertConfigToHCL(cfg)
+// loadConfigAs loads the config like loadConfig, writing heading and what it's loading to status
+// and the warning if --base-dir isn't set to warnings. Either may be nil to discard the output.
+func loadConfigAs(heading string, status, warnings io.Writer) (*internal.Config, []string, error) {
+	if status == nil {
+		status = io.Discard
+	}
+	if warnings == nil {
+		warnings = io.Discard
+	}
+	if configFile != "" {
+		_, _ = fmt.Fprintf(status, "%s\nConfig file: %s\n", heading, configFile)
+		cfg, err := loadSingleConfigFile(configFile)
 		if err != nil {
-			return fmt.Errorf("failed to convert config to HCL: %w", err)
+			return nil, nil, &configLoadError{err}
 		}
+		return cfg, []string{configFile}, nil
+	}
+	if baseDir == "" {
+		_, _ = fmt.Fprintln(warnings, "WARNING: --base-dir is not set. Config will only apply to the current directory. For proper config layering, set --base-dir to the directory where go:embed is used in your application.")
+	}
+	absBaseDir, absStartDir, relStartDir, err := resolveDirs()
+	if err != nil {
+		return nil, nil, err
+	}
+	_, _ = fmt.Fprintf(status, "%s\nStart dir: %s\nBase dir: %s\n", heading, absStartDir, absBaseDir)
+	cfg, relPaths, err := loadLayeredConfig(absBaseDir, relStartDir)
+	if err != nil {
+		return nil, nil, &configLoadError{err}
+	}
+	files := make([]string, len(relPaths))
+	for i, p := range relPaths {
+		files[i] = filepath.Join(baseDir, p)
+	}
+	return cfg, files, nil
+}
 
-		// Output the configuration
-		fmt.Println(string(hclBytes))
-		return nil
-	},
+// loadLayeredConfig loads the merged config that applies at relStartDir, layered the same way as the
+// library does under absBaseDir. It also returns the paths, relative to absBaseDir, of the config
+// files that were merged, least specific first.
+func loadLayeredConfig(absBaseDir, relStartDir string) (*internal.Config, []string, error) {
+	// Use a real FS rooted at baseDir
+	fsys := smarterr.NewWrappedFS(absBaseDir)
+
+	// Pass the relative stack path
+	relStackPaths := []string{relStartDir}
+	cfg, err := internal.LoadConfig(context.Background(), fsys, relStackPaths, ".")
+	if err != nil {
+		return nil, nil, err
+	}
+	paths, err := internal.ConfigPathsForStack(context.Background(), fsys, relStackPaths, ".")
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, paths, nil
+}
+
+// loadSingleConfigFile loads one config file directly, bypassing discovery and layering.
+func loadSingleConfigFile(path string) (*internal.Config, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute config file path: %w", err)
+	}
+	fsys := smarterr.NewWrappedFS(filepath.Dir(absPath))
+	return internal.LoadConfigFile(context.Background(), fsys, filepath.Base(absPath))
+}
+
+// convertConfigToJSON converts cfg to indented JSON in the smarterr.json format, so the output can be
+// used as a config file. Optional fields that are pointers, such as a token's parameter, are null
+// when unset, so they can be told apart from fields the schema doesn't have; other empty fields
+// are omitted. Map keys are sorted, so the output is stable.
+func convertConfigToJSON(cfg *internal.Config) ([]byte, error) {
+	return json.MarshalIndent(cfg, "", "  ")
 }
 
 func convertConfigToHCL(cfg *internal.Config) ([]byte, error) {
 	file := hclwrite.NewEmptyFile()
 	body := file.Body()
 
-	// Smarterr block (debug, token_error_mode, hint_match_mode, hint_join_char)
-	if cfg.Smarterr != nil && (cfg.Smarterr.Debug || (cfg.Smarterr.TokenErrorMode != nil && *cfg.Smarterr.TokenErrorMode != "") || cfg.Smarterr.HintMatchMode != nil || cfg.Smarterr.HintJoinChar != nil) {
+	if cfg.Version != nil {
+		body.SetAttributeValue("version", cty.NumberIntVal(int64(*cfg.Version)))
+		body.AppendNewline()
+	}
+
+	// Smarterr block (debug, token_error_mode, hint_match_mode, hint_join_char, hint_separator, duplicate_keyval_mode, max_detail_length, append_original_detail, append_error_code, log_fields, merge_precedence)
+	if cfg.Smarterr != nil && (cfg.Smarterr.Debug || cfg.Smarterr.TokenErrorMode != nil || cfg.Smarterr.HintMatchMode != nil || cfg.Smarterr.HintJoinChar != nil || cfg.Smarterr.HintSeparator != nil || cfg.Smarterr.HintMaxSuggestions != nil || cfg.Smarterr.HintDedup != nil || cfg.Smarterr.DuplicateKeyvalMode != nil || cfg.Smarterr.MaxDetailLength != nil || cfg.Smarterr.AppendOriginalDetail || cfg.Smarterr.AppendErrorCode || cfg.Smarterr.LogFields != nil || len(cfg.Smarterr.MergePrecedence) > 0) {
 		smarterrBlock := body.AppendNewBlock("smarterr", nil)
 		b := smarterrBlock.Body()
 		if cfg.Smarterr.Debug {
 			b.SetAttributeValue("debug", cty.BoolVal(true))
 		}
-		if cfg.Smarterr.TokenErrorMode != nil && *cfg.Smarterr.TokenErrorMode != "" {
+		if cfg.Smarterr.TokenErrorMode != nil {
 			b.SetAttributeValue("token_error_mode", cty.StringVal(*cfg.Smarterr.TokenErrorMode))
 		}
 		if cfg.Smarterr.HintMatchMode != nil {
@@ -132,10 +278,50 @@ func convertConfigToHCL(cfg *internal.Config) ([]byte, error) {
 		if cfg.Smarterr.HintJoinChar != nil {
 			b.SetAttributeValue("hint_join_char", cty.StringVal(*cfg.Smarterr.HintJoinChar))
 		}
+		if cfg.Smarterr.HintSeparator != nil {
+			b.SetAttributeValue("hint_separator", cty.StringVal(*cfg.Smarterr.HintSeparator))
+		}
+		if cfg.Smarterr.HintMaxSuggestions != nil {
+			b.SetAttributeValue("hint_max_suggestions", cty.NumberIntVal(int64(*cfg.Smarterr.HintMaxSuggestions)))
+		}
+		if cfg.Smarterr.HintDedup != nil {
+			b.SetAttributeValue("hint_dedup", cty.BoolVal(*cfg.Smarterr.HintDedup))
+		}
+		if cfg.Smarterr.DuplicateKeyvalMode != nil {
+			b.SetAttributeValue("duplicate_keyval_mode", cty.StringVal(*cfg.Smarterr.DuplicateKeyvalMode))
+		}
+		if cfg.Smarterr.MaxDetailLength != nil {
+			b.SetAttributeValue("max_detail_length", cty.NumberIntVal(int64(*cfg.Smarterr.MaxDetailLength)))
+		}
+		if cfg.Smarterr.AppendOriginalDetail {
+			b.SetAttributeValue("append_original_detail", cty.BoolVal(true))
+		}
+		if cfg.Smarterr.AppendErrorCode {
+			b.SetAttributeValue("append_error_code", cty.BoolVal(true))
+		}
+		if cfg.Smarterr.LogFields != nil {
+			if len(cfg.Smarterr.LogFields) == 0 {
+				b.SetAttributeValue("log_fields", cty.ListValEmpty(cty.String))
+			} else {
+				vals := make([]cty.Value, len(cfg.Smarterr.LogFields))
+				for i, v := range cfg.Smarterr.LogFields {
+					vals[i] = cty.StringVal(v)
+				}
+				b.SetAttributeValue("log_fields", cty.ListVal(vals))
+			}
+		}
+		if len(cfg.Smarterr.MergePrecedence) > 0 {
+			vals := make(map[string]cty.Value, len(cfg.Smarterr.MergePrecedence))
+			for blockType, precedence := range cfg.Smarterr.MergePrecedence {
+				vals[blockType] = cty.StringVal(precedence)
+			}
+			b.SetAttributeValue("merge_precedence", cty.ObjectVal(vals))
+		}
 	}
 
 	// Tokens
 	for _, token := range cfg.Tokens {
+		appendDescription(body, token.Description)
 		block := body.AppendNewBlock("token", []string{token.Name})
 		b := block.Body()
 		if token.Source != "" {
@@ -150,6 +336,24 @@ func convertConfigToHCL(cfg *internal.Config) ([]byte, error) {
 		if token.Context != nil {
 			b.SetAttributeValue("context", cty.StringVal(*token.Context))
 		}
+		if token.Annotation != nil {
+			b.SetAttributeValue("annotation", cty.StringVal(*token.Annotation))
+		}
+		if token.ResourceData != nil {
+			b.SetAttributeValue("resource_data", cty.StringVal(*token.ResourceData))
+		}
+		if token.Env != nil {
+			b.SetAttributeValue("env", cty.StringVal(*token.Env))
+		}
+		if token.CaptureRegex != nil {
+			b.SetAttributeValue("capture_regex", cty.StringVal(*token.CaptureRegex))
+		}
+		if token.CaptureGroup != nil {
+			b.SetAttributeValue("capture_group", cty.NumberIntVal(int64(*token.CaptureGroup)))
+		}
+		if token.FromToken != nil {
+			b.SetAttributeValue("from_token", cty.StringVal(*token.FromToken))
+		}
 		if len(token.Transforms) > 0 {
 			vals := make([]cty.Value, len(token.Transforms))
 			for i, v := range token.Transforms {
@@ -164,6 +368,23 @@ func convertConfigToHCL(cfg *internal.Config) ([]byte, error) {
 			}
 			b.SetAttributeValue("stack_matches", cty.ListVal(vals))
 		}
+		if len(token.StackCategories) > 0 {
+			vals := make([]cty.Value, len(token.StackCategories))
+			for i, v := range token.StackCategories {
+				vals[i] = cty.StringVal(v)
+			}
+			b.SetAttributeValue("stack_categories", cty.ListVal(vals))
+		}
+		if token.StackJoin != nil {
+			b.SetAttributeValue("stack_join", cty.StringVal(*token.StackJoin))
+		}
+		if len(token.ServiceMap) > 0 {
+			vals := make(map[string]cty.Value, len(token.ServiceMap))
+			for pkg, service := range token.ServiceMap {
+				vals[pkg] = cty.StringVal(service)
+			}
+			b.SetAttributeValue("service_map", cty.MapVal(vals))
+		}
 		if len(token.FieldTransforms) > 0 {
 			ftBlock := b.AppendNewBlock("field_transforms", nil)
 			ftBody := ftBlock.Body()
@@ -175,16 +396,34 @@ func convertConfigToHCL(cfg *internal.Config) ([]byte, error) {
 				ftBody.SetAttributeValue(field, cty.ListVal(vals))
 			}
 		}
+		if token.Disabled {
+			b.SetAttributeValue("disabled", cty.BoolVal(true))
+		}
 	}
 
 	// Parameters
 	for _, param := range cfg.Parameters {
 		block := body.AppendNewBlock("parameter", []string{param.Name})
-		block.Body().SetAttributeValue("value", cty.StringVal(param.Value))
+		if param.Values != nil {
+			if len(param.Values) == 0 {
+				block.Body().SetAttributeValue("values", cty.ListValEmpty(cty.String))
+				continue
+			}
+			vals := make([]cty.Value, len(param.Values))
+			for i, v := range param.Values {
+				vals[i] = cty.StringVal(v)
+			}
+			block.Body().SetAttributeValue("values", cty.ListVal(vals))
+			continue
+		}
+		if param.Value != nil {
+			block.Body().SetAttributeValue("value", cty.StringVal(*param.Value))
+		}
 	}
 
 	// Hints
 	for _, hint := range cfg.Hints {
+		appendDescription(body, hint.Description)
 		block := body.AppendNewBlock("hint", []string{hint.Name})
 		b := block.Body()
 		if hint.ErrorContains != nil {
@@ -193,7 +432,30 @@ func convertConfigToHCL(cfg *internal.Config) ([]byte, error) {
 		if hint.RegexMatch != nil {
 			b.SetAttributeValue("regex_match", cty.StringVal(*hint.RegexMatch))
 		}
-		b.SetAttributeValue("suggestion", cty.StringVal(hint.Suggestion))
+		if hint.ErrorType != nil {
+			b.SetAttributeValue("error_type", cty.StringVal(*hint.ErrorType))
+		}
+		if hint.MatchAll {
+			b.SetAttributeValue("match_all", cty.BoolVal(true))
+		}
+		if hint.Suggestion != "" || (len(hint.Suggestions) == 0 && !hint.Disabled) {
+			b.SetAttributeValue("suggestion", cty.StringVal(hint.Suggestion))
+		}
+		if len(hint.Suggestions) > 0 {
+			vals := make([]cty.Value, len(hint.Suggestions))
+			for i, v := range hint.Suggestions {
+				vals[i] = cty.StringVal(v)
+			}
+			b.SetAttributeValue("suggestions", cty.ListVal(vals))
+		}
+		if hint.Code != "" {
+			b.SetAttributeValue("code", cty.StringVal(hint.Code))
+		}
+		if hint.Disabled {
+			b.SetAttributeValue("disabled", cty.BoolVal(true))
+		}
+		appendConditions(b, "any_of", hint.AnyOf)
+		appendConditions(b, "all_of", hint.AllOf)
 	}
 
 	// StackMatches
@@ -203,11 +465,21 @@ func convertConfigToHCL(cfg *internal.Config) ([]byte, error) {
 		if sm.CalledFrom != "" {
 			b.SetAttributeValue("called_from", cty.StringVal(sm.CalledFrom))
 		}
+		if sm.CalledAfter != "" {
+			b.SetAttributeValue("called_after", cty.StringVal(sm.CalledAfter))
+		}
 		b.SetAttributeValue("display", cty.StringVal(sm.Display))
+		if sm.Priority != 0 {
+			b.SetAttributeValue("priority", cty.NumberIntVal(int64(sm.Priority)))
+		}
+		if sm.Category != "" {
+			b.SetAttributeValue("category", cty.StringVal(sm.Category))
+		}
 	}
 
 	// Templates
 	for _, tmpl := range cfg.Templates {
+		appendDescription(body, tmpl.Description)
 		block := body.AppendNewBlock("template", []string{tmpl.Name})
 		block.Body().SetAttributeValue("format", cty.StringVal(tmpl.Format))
 	}
@@ -230,8 +502,47 @@ func convertConfigToHCL(cfg *internal.Config) ([]byte, error) {
 			if step.Recurse != nil {
 				b.SetAttributeValue("recurse", cty.BoolVal(*step.Recurse))
 			}
+			if step.Max != nil {
+				b.SetAttributeValue("max", cty.NumberIntVal(int64(*step.Max)))
+			}
+			if step.Ellipsis != nil {
+				b.SetAttributeValue("ellipsis", cty.StringVal(*step.Ellipsis))
+			}
+			if step.Separator != nil {
+				b.SetAttributeValue("separator", cty.StringVal(*step.Separator))
+			}
+			if step.Index != nil {
+				b.SetAttributeValue("index", cty.NumberIntVal(int64(*step.Index)))
+			}
 		}
 	}
 
 	return file.Bytes(), nil
 }
+
+// appendConditions appends a hint's any_of or all_of conditions, one block each.
+func appendConditions(body *hclwrite.Body, blockType string, conditions []internal.Condition) {
+	for _, c := range conditions {
+		b := body.AppendNewBlock(blockType, nil).Body()
+		if c.ErrorContains != nil {
+			b.SetAttributeValue("error_contains", cty.StringVal(*c.ErrorContains))
+		}
+		if c.RegexMatch != nil {
+			b.SetAttributeValue("regex_match", cty.StringVal(*c.RegexMatch))
+		}
+	}
+}
+
+// appendDescription appends a block description as leading HCL comment lines. The description is
+// emitted as a comment, rather than an attribute, so it reads as documentation in merged output.
+func appendDescription(body *hclwrite.Body, description string) {
+	if description == "" {
+		return
+	}
+	for line := range strings.SplitSeq(strings.TrimRight(description, "\n"), "\n") {
+		body.AppendUnstructuredTokens(hclwrite.Tokens{{
+			Type:  hclsyntax.TokenComment,
+			Bytes: []byte(strings.TrimRight("# "+line, " ") + "\n"),
+		}})
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+func TestConvertConfigToHCL_Descriptions(t *testing.T) {
+	cfg := &internal.Config{
+		Tokens: []internal.Token{
+			{Name: "error", Source: "error", Description: "The original error message"},
+		},
+		Hints: []internal.Hint{
+			{Name: "throttle", Suggestion: "Retry later.", Description: "Shown for throttling errors\nacross all services"},
+		},
+		Templates: []internal.Template{
+			{Name: "error_summary", Format: "{{.error}}", Description: "Summary for all errors"},
+			{Name: "error_detail", Format: "{{.error}}"},
+		},
+	}
+
+	out, err := convertConfigToHCL(cfg)
+	if err != nil {
+		t.Fatalf("convertConfigToHCL: %v", err)
+	}
+	got := string(out)
+
+	for _, want := range []string{
+		"# The original error message\ntoken \"error\" {",
+		"# Shown for throttling errors\n# across all services\nhint \"throttle\" {",
+		"# Summary for all errors\ntemplate \"error_summary\" {",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "description") {
+		t.Errorf("expected description to be emitted only as a comment, got:\n%s", got)
+	}
+	if strings.Count(got, "#") != 4 {
+		t.Errorf("expected 4 comment lines, got:\n%s", got)
+	}
+}
+
+func TestConvertConfigToHCL_DescriptionRoundTrip(t *testing.T) {
+	path := writeConfig(t, `
+token "error" {
+  source      = "error"
+  description = "The original error message"
+}
+`)
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	out, err := convertConfigToHCL(cfg)
+	if err != nil {
+		t.Fatalf("convertConfigToHCL: %v", err)
+	}
+	if !strings.Contains(string(out), "# The original error message\n") {
+		t.Errorf("expected description comment, got:\n%s", out)
+	}
+}
+
+func TestConvertConfigToHCL_ListParameter(t *testing.T) {
+	service := "EC2"
+	cfg := &internal.Config{
+		Parameters: []internal.Parameter{
+			{Name: "service", Value: &service},
+			{Name: "codes", Values: []string{"Throttling", "RequestLimitExceeded"}},
+		},
+	}
+	out, err := convertConfigToHCL(cfg)
+	if err != nil {
+		t.Fatalf("convertConfigToHCL: %v", err)
+	}
+	got := string(out)
+	for _, want := range []string{
+		"parameter \"service\" {\n  value = \"EC2\"\n}",
+		"parameter \"codes\" {\n  values = [\"Throttling\", \"RequestLimitExceeded\"]\n}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestConvertConfigToHCL_MergePrecedence(t *testing.T) {
+	cfg := &internal.Config{
+		Smarterr: &internal.Smarterr{MergePrecedence: map[string]string{"template": "base"}},
+	}
+	out, err := convertConfigToHCL(cfg)
+	if err != nil {
+		t.Fatalf("convertConfigToHCL: %v", err)
+	}
+	if want := "merge_precedence = {\n    template = \"base\"\n  }"; !strings.Contains(string(out), want) {
+		t.Errorf("expected output to contain %q, got:\n%s", want, out)
+	}
+}
+
+func TestConvertConfigToHCL_ErrorCode(t *testing.T) {
+	contains := "Throttling"
+	cfg := &internal.Config{
+		Smarterr: &internal.Smarterr{AppendErrorCode: true},
+		Hints:    []internal.Hint{{Name: "throttling", ErrorContains: &contains, Suggestion: "Retry later.", Code: "THROTTLING"}},
+	}
+	out, err := convertConfigToHCL(cfg)
+	if err != nil {
+		t.Fatalf("convertConfigToHCL: %v", err)
+	}
+	got := string(out)
+	for _, want := range []string{
+		"append_error_code = true",
+		"code           = \"THROTTLING\"",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestConvertConfigToJSON(t *testing.T) {
+	path := writeConfig(t, `
+version = 1
+
+smarterr {
+  hint_join_char = " "
+}
+
+token "id" {
+  parameter = "service"
+  field_transforms = {
+    zone   = ["upper"]
+    region = ["upper"]
+  }
+}
+
+transform "upper" {
+  step "upper" {}
+}
+`)
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	out, err := convertConfigToJSON(cfg)
+	if err != nil {
+		t.Fatalf("convertConfigToJSON: %v", err)
+	}
+	got := string(out)
+	for _, want := range []string{
+		`"hint_join_char": " "`,
+		`"parameter": "service"`,
+		"\"field_transforms\": {\n        \"region\": [\n          \"upper\"\n        ],\n        \"zone\"",
+		`"step": [`,
+		`"context": null`,
+		`"hint_match_mode": null`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `"description"`) || strings.Contains(got, `"transforms"`) {
+		t.Errorf("expected empty non-pointer fields to be omitted, got:\n%s", got)
+	}
+
+	roundTrip, err := internal.ParseConfig(out, "smarterr.json")
+	if err != nil {
+		t.Fatalf("ParseConfig(JSON output): %v", err)
+	}
+	if !reflect.DeepEqual(roundTrip, cfg) {
+		t.Errorf("round trip = %+v, want %+v", roundTrip, cfg)
+	}
+}
+
+func TestConvertConfigToHCL_HintConditions(t *testing.T) {
+	path := writeConfig(t, `
+hint "throttling" {
+  suggestion = "Retry later."
+  any_of {
+    error_contains = "Throttling"
+  }
+  all_of {
+    regex_match = "status code: 5"
+  }
+}
+`)
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	out, err := convertConfigToHCL(cfg)
+	if err != nil {
+		t.Fatalf("convertConfigToHCL: %v", err)
+	}
+	for _, want := range []string{
+		"any_of {\n    error_contains = \"Throttling\"\n  }",
+		"all_of {\n    regex_match = \"status code: 5\"\n  }",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"smarterr/smarterr.hcl": "token \"base\" {\n  arg = \"base\"\n}\n",
+		"svc/smarterr.hcl":      "token \"svc\" {\n  arg = \"svc\"\n}\n",
+		"other/smarterr.hcl":    "token \"other\" {\n  arg = \"other\"\n}\n",
+		"broken/smarterr.hcl":   "token {\n",
+	} {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		configFile, baseDir, startDir = "", "", ""
+	})
+
+	// Layered config for --start-dir under --base-dir
+	baseDir, startDir = dir, filepath.Join(dir, "svc")
+	var status strings.Builder
+	cfg, files, err := loadConfig(&status)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	var names []string
+	for _, tok := range cfg.Tokens {
+		names = append(names, tok.Name)
+	}
+	if !slices.Equal(names, []string{"base", "svc"}) {
+		t.Errorf("tokens = %v, want [base svc]", names)
+	}
+	if want := []string{filepath.Join(dir, "smarterr", "smarterr.hcl"), filepath.Join(dir, "svc", "smarterr.hcl")}; !slices.Equal(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	if !strings.Contains(status.String(), "Start dir: "+startDir) {
+		t.Errorf("status = %q, want the start dir", status.String())
+	}
+
+	// A single --config-file, without status output
+	configFile = filepath.Join(dir, "other", "smarterr.hcl")
+	cfg, files, err = loadConfig(nil)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if len(cfg.Tokens) != 1 || cfg.Tokens[0].Name != "other" || !slices.Equal(files, []string{configFile}) {
+		t.Errorf("loadConfig() = %v tokens, %v files; want only token other from %s", cfg.Tokens, files, configFile)
+	}
+
+	// Load errors say the config failed to load
+	configFile = filepath.Join(dir, "broken", "smarterr.hcl")
+	if _, _, err := loadConfig(nil); err == nil || !strings.HasPrefix(err.Error(), "failed to load config: ") {
+		t.Errorf("expected a failed to load config error, got %v", err)
+	}
+	var loadErr *configLoadError
+	if _, _, err := loadConfig(nil); !errors.As(err, &loadErr) || strings.Contains(loadErr.err.Error(), "failed to load config") {
+		t.Errorf("expected a configLoadError wrapping the cause, got %v", err)
+	}
+
+	// The --base-dir warning goes to warnings even when status is discarded
+	configFile, baseDir, startDir = "", "", dir
+	t.Chdir(dir)
+	var warnings strings.Builder
+	status.Reset()
+	if _, _, err := loadConfigAs("Checking configuration...", nil, &warnings); err != nil {
+		t.Fatalf("loadConfigAs: %v", err)
+	}
+	if !strings.Contains(warnings.String(), "--base-dir is not set") {
+		t.Errorf("warnings = %q, want the --base-dir warning", warnings.String())
+	}
+	if _, _, err := loadConfigAs("Checking configuration...", &status, nil); err != nil {
+		t.Fatalf("loadConfigAs: %v", err)
+	}
+	if got := status.String(); !strings.HasPrefix(got, "Checking configuration...\n") || strings.Contains(got, "WARNING") {
+		t.Errorf("status = %q, want the heading without the warning", got)
+	}
+}
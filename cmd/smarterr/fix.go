@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/YakDriver/smarterr/internal"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// definition identifies a labeled block in a config file, such as transform "lower".
+type definition struct {
+	blockType string
+	name      string
+}
+
+func (d definition) String() string {
+	return fmt.Sprintf("%s %q", d.blockType, d.name)
+}
+
+// unusedDefinitions returns the definitions in cfg that nothing uses, in cfg itself or in layers,
+// the other config files it may be merged with: tokens no template references, then transforms,
+// stack_matches, and hints that no remaining token uses. Tokens are only considered when some config
+// defines templates, since a config without templates is likely a layer whose tokens serve
+// templates defined elsewhere.
+func unusedDefinitions(cfg *internal.Config, layers []*internal.Config) []definition {
+	var unused []definition
+	all := append([]*internal.Config{cfg}, layers...)
+
+	tokens := cfg.Tokens
+	if templateVars, ok := usedTokenNames(all); ok {
+		tokens = nil
+		for _, t := range cfg.Tokens {
+			if _, ok := templateVars[t.Name]; ok {
+				tokens = append(tokens, t)
+			} else {
+				unused = append(unused, definition{"token", t.Name})
+			}
+		}
+	}
+	// Tokens in other layers may be merged with any template, so they all count as used
+	for _, layer := range layers {
+		tokens = append(tokens, layer.Tokens...)
+	}
+
+	usedTransforms := make(map[string]struct{})
+	usedStackMatches := make(map[string]struct{})
+	hintsUsed := false
+	for _, t := range tokens {
+		for _, name := range t.Transforms {
+			usedTransforms[name] = struct{}{}
+		}
+		for _, names := range t.FieldTransforms {
+			for _, name := range names {
+				usedTransforms[name] = struct{}{}
+			}
+		}
+		for _, name := range t.StackMatches {
+			usedStackMatches[name] = struct{}{}
+		}
+		switch t.InferredSource() {
+		case "hints", "hint_name", "error_code":
+			hintsUsed = true
+		}
+	}
+	for _, c := range all {
+		if c.Smarterr != nil && c.Smarterr.AppendErrorCode {
+			// The appended code comes from the matching hint
+			hintsUsed = true
+		}
+	}
+	for _, tr := range cfg.Transforms {
+		if _, ok := usedTransforms[tr.Name]; !ok {
+			unused = append(unused, definition{"transform", tr.Name})
+		}
+	}
+	for _, sm := range cfg.StackMatches {
+		if _, ok := usedStackMatches[sm.Name]; !ok {
+			unused = append(unused, definition{"stack_match", sm.Name})
+		}
+	}
+	if !hintsUsed {
+		for _, h := range cfg.Hints {
+			unused = append(unused, definition{"hint", h.Name})
+		}
+	}
+	return unused
+}
+
+// usedTokenNames returns the names of the tokens the templates in configs use, directly, through
+// from_token, or as log_fields. It returns false if no config defines templates or a template
+// can't be parsed, since then it can't tell which tokens are used.
+func usedTokenNames(configs []*internal.Config) (map[string]struct{}, bool) {
+	used := make(map[string]struct{})
+	hasTemplates := false
+	for _, c := range configs {
+		for _, tmpl := range c.Templates {
+			hasTemplates = true
+			t, err := internal.NewTemplate(tmpl.Name).Parse(tmpl.Format)
+			if err != nil {
+				return nil, false
+			}
+			for _, v := range internal.CollectTemplateVariables(t) {
+				used[v] = struct{}{}
+			}
+		}
+		if c.Smarterr != nil {
+			for _, name := range c.Smarterr.LogFields {
+				used[name] = struct{}{}
+			}
+		}
+	}
+	if !hasTemplates {
+		return nil, false
+	}
+	// Tokens derived with from_token need the tokens they're derived from
+	for changed := true; changed; {
+		changed = false
+		for _, c := range configs {
+			for _, t := range c.Tokens {
+				if _, ok := used[t.Name]; !ok || t.FromToken == nil {
+					continue
+				}
+				if _, ok := used[*t.FromToken]; !ok {
+					used[*t.FromToken] = struct{}{}
+					changed = true
+				}
+			}
+		}
+	}
+	return used, true
+}
+
+// otherConfigLayers loads every config file under absBaseDir except the one at path. Since layered
+// configs merge, a definition in path may be used by any of them.
+func otherConfigLayers(absBaseDir, path string) ([]*internal.Config, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute config file path: %w", err)
+	}
+	var layers []*internal.Config
+	err = filepath.WalkDir(absBaseDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !internal.IsConfigFile(p) || p == absPath {
+			return nil
+		}
+		cfg, err := loadSingleConfigFile(p)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", p, err)
+		}
+		layers = append(layers, cfg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return layers, nil
+}
+
+// fixConfigFile removes the definitions that neither cfg, loaded from path, nor layers use from the
+// file at path. Other blocks, comments, and formatting are preserved. It returns the removed
+// definitions.
+func fixConfigFile(path string, cfg *internal.Config, layers []*internal.Config) ([]definition, error) {
+	unused := unusedDefinitions(cfg, layers)
+	if len(unused) == 0 {
+		return nil, nil
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	file, diags := hclwrite.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("parsing %s: %s", path, diags.Error())
+	}
+	body := file.Body()
+	var removed []definition
+	for _, block := range body.Blocks() {
+		labels := block.Labels()
+		if len(labels) != 1 {
+			continue
+		}
+		d := definition{block.Type(), labels[0]}
+		if slices.Contains(unused, d) {
+			body.RemoveBlock(block)
+			removed = append(removed, d)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, hclwrite.Format(collapseBlankLines(file.BuildTokens(nil)).Bytes()), info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("writing %s: %w", path, err)
+	}
+	return removed, nil
+}
+
+// collapseBlankLines drops newlines beyond one blank line in a row, such as those left where blocks
+// were removed. Newlines inside heredocs are part of string tokens, so they're preserved.
+func collapseBlankLines(tokens hclwrite.Tokens) hclwrite.Tokens {
+	var out hclwrite.Tokens
+	newlines := 0
+	for _, tok := range tokens {
+		if tok.Type == hclsyntax.TokenNewline {
+			newlines++
+			if newlines > 2 {
+				continue
+			}
+		} else {
+			newlines = 0
+		}
+		out = append(out, tok)
+	}
+	return out
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+func TestFixConfigFile(t *testing.T) {
+	path := writeConfig(t, `# Tokens
+token "error" {
+  source     = "error"
+  transforms = ["lower"]
+}
+
+token "unused" {
+  arg        = "x"
+  transforms = ["upper"]
+}
+
+transform "lower" {
+  step "lower" {}
+}
+
+# Only used by the unused token
+transform "upper" {
+  step "upper" {}
+}
+
+transform "trim" {
+  step "trim_space" {}
+}
+
+hint "throttle" {
+  error_contains = "throttl"
+  suggestion     = "Retry later."
+}
+
+template "error_summary" {
+  format = "{{.error}}"
+}
+`)
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	removed, err := fixConfigFile(path, cfg, nil)
+	if err != nil {
+		t.Fatalf("fixConfigFile: %v", err)
+	}
+	var got []string
+	for _, d := range removed {
+		got = append(got, d.String())
+	}
+	want := []string{`token "unused"`, `transform "upper"`, `transform "trim"`, `hint "throttle"`}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("removed = %v, want %v", got, want)
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, keep := range []string{"# Tokens", `token "error"`, `transform "lower"`, `template "error_summary"`} {
+		if !strings.Contains(string(out), keep) {
+			t.Errorf("expected fixed file to keep %q, got:\n%s", keep, out)
+		}
+	}
+	for _, d := range want {
+		if strings.Contains(string(out), d) {
+			t.Errorf("expected fixed file to remove %s, got:\n%s", d, out)
+		}
+	}
+
+	cfg, err = loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loading fixed config: %v", err)
+	}
+	_, warnings := runChecks(cfg)
+	for _, w := range warnings {
+		if strings.Contains(w, "not used") {
+			t.Errorf("expected no unused warnings after fix, got: %s", w)
+		}
+	}
+	if removed, err := fixConfigFile(path, cfg, nil); err != nil || len(removed) != 0 {
+		t.Errorf("expected second fix to remove nothing, got %v, %v", removed, err)
+	}
+}
+
+func TestFixConfigFile_NoTemplates(t *testing.T) {
+	path := writeConfig(t, `
+token "service" {
+  arg = "service"
+}
+`)
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	if removed := unusedDefinitions(cfg, nil); len(removed) != 0 {
+		t.Errorf("expected tokens to be kept in a config without templates, got: %v", removed)
+	}
+}
+
+func TestFixConfigFile_Layers(t *testing.T) {
+	base := t.TempDir()
+	global := filepath.Join(base, "smarterr", internal.ConfigFileName)
+	service := filepath.Join(base, "service", "ec2", internal.ConfigFileName)
+	for path, content := range map[string]string{
+		global: `
+token "id" {
+  arg = "id"
+}
+
+token "call" {
+  stack_matches = ["create"]
+}
+
+transform "upper" {
+  step "upper" {}
+}
+
+stack_match "create" {
+  called_from = "Create"
+  display     = "creating"
+}
+
+hint "throttle" {
+  error_contains = "throttl"
+  suggestion     = "Retry later."
+}
+
+template "error_summary" {
+  format = "{{.call}}"
+}
+`,
+		service: `
+token "id_upper" {
+  from_token = "id"
+  transforms = ["upper"]
+}
+
+token "suggest" {
+  source = "hints"
+}
+
+template "error_detail" {
+  format = "{{.id_upper}} {{.suggest}}"
+}
+`,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := loadSingleConfigFile(global)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	layers, err := otherConfigLayers(base, global)
+	if err != nil {
+		t.Fatalf("otherConfigLayers: %v", err)
+	}
+	if len(layers) != 1 {
+		t.Fatalf("expected 1 other layer, got %d", len(layers))
+	}
+	if removed, err := fixConfigFile(global, cfg, layers); err != nil || len(removed) != 0 {
+		t.Errorf("expected definitions used by another layer to be kept, got %v, %v", removed, err)
+	}
+
+	// Without the other layer, they're unused
+	var got []string
+	for _, d := range unusedDefinitions(cfg, nil) {
+		got = append(got, d.String())
+	}
+	want := []string{`token "id"`, `transform "upper"`, `hint "throttle"`}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("unusedDefinitions() = %v, want %v", got, want)
+	}
+}
+
+func TestUnusedDefinitions_HintConsumers(t *testing.T) {
+	for name, content := range map[string]string{
+		"hint_name": `
+token "hint" {
+  source = "hint_name"
+}
+`,
+		"error_code": `
+token "code" {
+  source = "error_code"
+}
+`,
+		"append_error_code": `
+smarterr {
+  append_error_code = true
+}
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfig(t, content+`
+hint "throttle" {
+  error_contains = "throttl"
+  suggestion     = "Retry later."
+  code           = "THROTTLING"
+}
+`)
+			cfg, err := loadSingleConfigFile(path)
+			if err != nil {
+				t.Fatalf("loadSingleConfigFile: %v", err)
+			}
+			if unused := unusedDefinitions(cfg, nil); len(unused) != 0 {
+				t.Errorf("expected the hint to be used, got unused: %v", unused)
+			}
+		})
+	}
+}
+
+func TestCheckCmd_FixRequiresConfigFile(t *testing.T) {
+	rootCmd.SetArgs([]string{"validate", "--fix", "--silent"})
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		fixFlag = false
+		silentFlag = false
+	})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--fix requires --config-file") {
+		t.Errorf("expected --fix to require --config-file, got: %v", err)
+	}
+}
+
+func TestCheckCmd_FixRejectsJSON(t *testing.T) {
+	rootCmd.SetArgs([]string{"check", "--fix", "--silent", "--config-file", "smarterr.json"})
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		fixFlag = false
+		silentFlag = false
+		configFile = ""
+	})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--fix only supports HCL config files") {
+		t.Errorf("expected --fix to reject a JSON config file, got: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/YakDriver/smarterr/internal"
+	"github.com/spf13/cobra"
+)
+
+var graphFormat string
+
+func init() {
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", `Output format: "dot" for Graphviz or "text" for an indented tree`)
+	graphCmd.Flags().StringVarP(&startDir, "start-dir", "d", "", "Directory where code using smarterr lives (default: current directory). This is typically where the error occurs.")
+	graphCmd.Flags().StringVarP(&baseDir, "base-dir", "b", "", "Parent directory where go:embed is used (optional, but recommended for proper config layering as in the application). If not set, config applies only to the current directory.")
+	graphCmd.Flags().StringVarP(&configFile, "config-file", "c", "", "Load a single config file directly, bypassing discovery and layering (--base-dir and --start-dir are ignored)")
+	graphCmd.Flags().BoolVarP(&debugFlag, "debug", "D", false, "Enable smarterr debug output (even if config fails to load)")
+	rootCmd.AddCommand(graphCmd)
+}
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Show which tokens feed which templates",
+	Long: `Print the dependency graph of the effective smarterr configuration: the tokens each template
+uses, and the source, parameter, tokens, stack matches, transforms, and hints each token uses.
+Tokens no template uses are listed as roots, after the templates.
+
+The default format is Graphviz DOT; use --format text for an indented tree.
+
+Example:
+  smarterr graph -b ./internal -d ./internal/service/ec2 | dot -Tsvg > smarterr.svg
+  smarterr graph -c smarterr.hcl --format text`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if debugFlag {
+			internal.EnableDebugForce()
+		}
+		if graphFormat != "dot" && graphFormat != "text" {
+			return fmt.Errorf("--format must be dot or text, got %q", graphFormat)
+		}
+		cfg, _, err := loadConfig(nil)
+		if err != nil {
+			return err
+		}
+		g := buildDependencyGraph(cfg)
+		if graphFormat == "text" {
+			g.writeText(cmd.OutOrStdout())
+		} else {
+			g.writeDOT(cmd.OutOrStdout())
+		}
+		return nil
+	},
+}
+
+// dependencyGraph is the graph of what each template and token in a config uses.
+type dependencyGraph struct {
+	roots []definition                // Templates, then tokens nothing uses, in config order
+	edges map[definition][]definition // What each node uses, in order
+}
+
+// buildDependencyGraph builds the dependency graph of cfg. A template uses the tokens its format
+// references. A token uses its source, such as arg "id" or source "error", and the tokens, stack
+// matches, transforms, and hints it names. A hints, hint_name, or error_code token uses every hint,
+// since any may match.
+func buildDependencyGraph(cfg *internal.Config) *dependencyGraph {
+	g := &dependencyGraph{edges: make(map[definition][]definition)}
+	addEdge := func(from, to definition) {
+		if !slices.Contains(g.edges[from], to) {
+			g.edges[from] = append(g.edges[from], to)
+		}
+	}
+
+	tokens := make(map[string]bool, len(cfg.Tokens))
+	for _, t := range cfg.Tokens {
+		tokens[t.Name] = true
+	}
+	used := make(map[string]bool)
+
+	for _, tmpl := range cfg.Templates {
+		node := definition{"template", tmpl.Name}
+		g.roots = append(g.roots, node)
+		t, err := internal.NewTemplate(tmpl.Name).Parse(tmpl.Format)
+		if err != nil {
+			continue
+		}
+		vars := internal.CollectTemplateVariables(t)
+		slices.Sort(vars)
+		for _, v := range vars {
+			if tokens[v] {
+				addEdge(node, definition{"token", v})
+				used[v] = true
+			}
+		}
+	}
+
+	for _, t := range cfg.Tokens {
+		node := definition{"token", t.Name}
+		source := t.InferredSource()
+		switch source {
+		case "parameter":
+			if t.Parameter != nil {
+				addEdge(node, definition{"parameter", *t.Parameter})
+			}
+		case "context":
+			if t.Context != nil {
+				addEdge(node, definition{"context", *t.Context})
+			}
+		case "arg":
+			if t.Arg != nil {
+				addEdge(node, definition{"arg", *t.Arg})
+			}
+		case "annotation":
+			if t.Annotation != nil {
+				addEdge(node, definition{"annotation", *t.Annotation})
+			}
+		case "resource_data":
+			if t.ResourceData != nil {
+				addEdge(node, definition{"resource_data", *t.ResourceData})
+			}
+		case "env":
+			if t.Env != nil {
+				addEdge(node, definition{"env", *t.Env})
+			}
+		case "from_token":
+			if t.FromToken != nil {
+				addEdge(node, definition{"token", *t.FromToken})
+				used[*t.FromToken] = true
+			}
+		default:
+			addEdge(node, definition{"source", source})
+		}
+		for _, name := range t.StackMatches {
+			addEdge(node, definition{"stack_match", name})
+		}
+		for _, name := range t.Transforms {
+			addEdge(node, definition{"transform", name})
+		}
+		for _, field := range slices.Sorted(maps.Keys(t.FieldTransforms)) {
+			for _, name := range t.FieldTransforms[field] {
+				addEdge(node, definition{"transform", name})
+			}
+		}
+		switch source {
+		case "hints", "hint_name", "error_code":
+			for _, h := range cfg.Hints {
+				if !h.Disabled && !cfg.IsHintVariant(h.Name) {
+					addEdge(node, definition{"hint", h.Name})
+				}
+			}
+		}
+	}
+
+	for _, t := range cfg.Tokens {
+		if !used[t.Name] {
+			g.roots = append(g.roots, definition{"token", t.Name})
+		}
+	}
+	return g
+}
+
+// writeDOT writes the graph to w in Graphviz DOT format, one edge per line.
+func (g *dependencyGraph) writeDOT(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "digraph smarterr {")
+	_, _ = fmt.Fprintln(w, "  rankdir=LR;")
+	seen := make(map[definition]bool)
+	var walk func(node definition)
+	walk = func(node definition) {
+		if seen[node] {
+			return
+		}
+		seen[node] = true
+		if len(g.edges[node]) == 0 && slices.Contains(g.roots, node) {
+			_, _ = fmt.Fprintf(w, "  %q;\n", node.String())
+		}
+		for _, to := range g.edges[node] {
+			_, _ = fmt.Fprintf(w, "  %q -> %q;\n", node.String(), to.String())
+			walk(to)
+		}
+	}
+	for _, root := range g.roots {
+		walk(root)
+	}
+	_, _ = fmt.Fprintln(w, "}")
+}
+
+// writeText writes the graph to w as an indented tree under each root. A node already shown
+// under the same root isn't expanded again, and a from_token cycle is marked.
+func (g *dependencyGraph) writeText(w io.Writer) {
+	for _, root := range g.roots {
+		expanded := make(map[definition]bool)
+		var walk func(node definition, depth int, path []definition)
+		walk = func(node definition, depth int, path []definition) {
+			indent := strings.Repeat("  ", depth)
+			switch {
+			case slices.Contains(path, node):
+				_, _ = fmt.Fprintf(w, "%s%s (cycle)\n", indent, node)
+				return
+			case expanded[node] && len(g.edges[node]) > 0:
+				_, _ = fmt.Fprintf(w, "%s%s (see above)\n", indent, node)
+				return
+			}
+			_, _ = fmt.Fprintf(w, "%s%s\n", indent, node)
+			expanded[node] = true
+			for _, to := range g.edges[node] {
+				walk(to, depth+1, append(path, node))
+			}
+		}
+		walk(root, 0, nil)
+	}
+}
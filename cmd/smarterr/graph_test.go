@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const graphTestConfig = `
+parameter "service" {
+  value = "EC2"
+}
+
+token "service" {
+  parameter  = "service"
+  transforms = ["lower"]
+}
+
+token "id" {
+  arg = "id"
+}
+
+token "id_upper" {
+  from_token = "id"
+  transforms = ["upper"]
+}
+
+token "hints" {
+  source = "hints"
+}
+
+token "unused" {
+  context = "request_id"
+}
+
+transform "lower" {
+  step "lower" {}
+}
+
+transform "upper" {
+  step "upper" {}
+}
+
+hint "throttling" {
+  error_contains = "Throttling"
+  suggestion     = "Retry later."
+}
+
+template "error_summary" {
+  format = "{{.service}}: {{.id_upper}}"
+}
+
+template "error_detail" {
+  format = "{{.hints}}"
+}
+`
+
+func TestGraph_DOT(t *testing.T) {
+	cfg, err := loadSingleConfigFile(writeConfig(t, graphTestConfig))
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	var out bytes.Buffer
+	buildDependencyGraph(cfg).writeDOT(&out)
+	got := out.String()
+	for _, want := range []string{
+		`"template \"error_summary\"" -> "token \"service\"";`,
+		`"template \"error_summary\"" -> "token \"id_upper\"";`,
+		`"token \"service\"" -> "parameter \"service\"";`,
+		`"token \"service\"" -> "transform \"lower\"";`,
+		`"token \"id_upper\"" -> "token \"id\"";`,
+		`"token \"id_upper\"" -> "transform \"upper\"";`,
+		`"token \"id\"" -> "arg \"id\"";`,
+		`"template \"error_detail\"" -> "token \"hints\"";`,
+		`"token \"hints\"" -> "source \"hints\"";`,
+		`"token \"hints\"" -> "hint \"throttling\"";`,
+		`"token \"unused\"" -> "context \"request_id\"";`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected edge %s, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `"template \"error_detail\"" -> "token \"id\""`) {
+		t.Errorf("unexpected edge from error_detail to id, got:\n%s", got)
+	}
+}
+
+func TestGraph_Text(t *testing.T) {
+	cfg, err := loadSingleConfigFile(writeConfig(t, graphTestConfig))
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	var out bytes.Buffer
+	buildDependencyGraph(cfg).writeText(&out)
+	want := `template "error_summary"
+  token "id_upper"
+    token "id"
+      arg "id"
+    transform "upper"
+  token "service"
+    parameter "service"
+    transform "lower"
+template "error_detail"
+  token "hints"
+    source "hints"
+    hint "throttling"
+token "unused"
+  context "request_id"
+`
+	if got := out.String(); got != want {
+		t.Errorf("writeText() =\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"fmt"
+	"go/format"
 	"go/parser"
 	"go/token"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,10 +17,18 @@ import (
 
 var dryRunFlag bool
 var verboseFlag bool
+var warnErrorfFlag bool
+var statFlag bool
+var excludeFlag []string
+var includeFlag []string
 
 func init() {
 	migrateCmd.Flags().BoolVarP(&dryRunFlag, "dry-run", "n", false, "Show what would be changed without making changes")
+	migrateCmd.Flags().BoolVar(&statFlag, "stat", false, "With --dry-run, show how many times each pattern matched instead of a diff")
 	migrateCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Show detailed output")
+	migrateCmd.Flags().BoolVar(&warnErrorfFlag, "warn-errorf", false, "Warn about fmt.Errorf calls that format an error with %s or %v instead of wrapping it with %w")
+	migrateCmd.Flags().StringArrayVar(&excludeFlag, "exclude", nil, "Skip files whose base name matches this glob, such as '*_tags_gen.go' (repeatable)")
+	migrateCmd.Flags().StringArrayVar(&includeFlag, "include", nil, "Only migrate files whose base name matches this glob (repeatable)")
 	rootCmd.AddCommand(migrateCmd)
 }
 
@@ -32,39 +42,152 @@ This command will:
 - Transform bare error returns to use smarterr.NewError()
 - Convert diagnostic patterns to use smerr helpers
 
+With --dry-run, it prints a unified diff of each file it would change instead of changing it.
+Add --stat to print how many times each pattern matched instead of the diff.
+
+With --warn-errorf, it also reports fmt.Errorf calls that format an error with
+%s or %v instead of %w, which drops the error from the unwrap chain. These are
+reported, not changed.
+
+Test files and files with "_gen" in their path are always skipped. Use --exclude to skip
+other files, such as sweepers, and --include to migrate only matching files. Both take a glob
+matched against the base file name and can be repeated; a file matching any --exclude pattern
+is skipped even if it matches an --include pattern.
+
 Example:
-  smarterr migrate ./internal/service/myservice/`,
+  smarterr migrate ./internal/service/myservice/
+  smarterr migrate --dry-run --stat ./internal/service/myservice/
+  smarterr migrate --warn-errorf --dry-run ./internal/service/myservice/
+  smarterr migrate --exclude 'sweep.go' --exclude 'exports.go' ./internal/service/myservice/`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if statFlag && !dryRunFlag {
+			return fmt.Errorf("--stat requires --dry-run")
+		}
 		path := "."
 		if len(args) > 0 {
 			path = args[0]
 		}
-		return migrateDirectory(path)
+		filter, err := newFileFilter(excludeFlag, includeFlag)
+		if err != nil {
+			return err
+		}
+		return migrateDirectory(path, filter, defaultFormatter{})
 	},
 }
 
-func migrateDirectory(dir string) error {
+// Formatter formats a migrated Go file in place.
+type Formatter interface {
+	Format(filename string) error
+}
+
+// defaultFormatter runs goimports and gofmt when they're on PATH. Without gofmt, it formats the file
+// in-process with go/format, which doesn't fix imports.
+type defaultFormatter struct{}
+
+func (defaultFormatter) Format(filename string) error {
+	if path, err := exec.LookPath("goimports"); err == nil {
+		if err := exec.Command(path, "-w", filename).Run(); err != nil {
+			return fmt.Errorf("goimports: %w", err)
+		}
+	}
+	if path, err := exec.LookPath("gofmt"); err == nil {
+		if err := exec.Command(path, "-w", filename).Run(); err != nil {
+			return fmt.Errorf("gofmt: %w", err)
+		}
+		return nil
+	}
+	return sourceFormatter{}.Format(filename)
+}
+
+// sourceFormatter formats a file in-process with go/format, without depending on any binaries.
+type sourceFormatter struct{}
+
+func (sourceFormatter) Format(filename string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", filename, err)
+	}
+	formatted, err := format.Source(content)
+	if err != nil {
+		return fmt.Errorf("formatting %s: %w", filename, err)
+	}
+	return writeFile(filename, string(formatted))
+}
+
+// fileFilter selects the files migrate rewrites by glob patterns matched against base file names.
+type fileFilter struct {
+	exclude []string
+	include []string
+}
+
+// newFileFilter returns a filter that skips files matching any exclude pattern and, if include
+// isn't empty, files matching no include pattern. It returns an error if a pattern is malformed.
+func newFileFilter(exclude, include []string) (fileFilter, error) {
+	for _, pattern := range exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fileFilter{}, fmt.Errorf("--exclude %q: %w", pattern, err)
+		}
+	}
+	for _, pattern := range include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fileFilter{}, fmt.Errorf("--include %q: %w", pattern, err)
+		}
+	}
+	return fileFilter{exclude: exclude, include: include}, nil
+}
+
+// skipReason returns why the filter skips the file with base name name, or "" if it doesn't.
+func (f fileFilter) skipReason(name string) string {
+	for _, pattern := range f.exclude {
+		// Patterns were validated in newFileFilter
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return fmt.Sprintf("matches --exclude %q", pattern)
+		}
+	}
+	if len(f.include) == 0 {
+		return ""
+	}
+	for _, pattern := range f.include {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return ""
+		}
+	}
+	return "matches no --include pattern"
+}
+
+func migrateDirectory(dir string, filter fileFilter, formatter Formatter) error {
 	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !isGoFile(path) {
+		if !isGoFile(path, filter) {
 			return nil
 		}
 
-		return migrateFile(path)
+		return migrateFile(path, formatter)
 	})
 }
 
-func isGoFile(path string) bool {
-	return strings.HasSuffix(path, ".go") &&
-		!strings.HasSuffix(path, "_test.go") &&
-		!strings.Contains(path, "_gen")
+// isGoFile reports whether path is a Go file to migrate: not a test or generated file, and not
+// skipped by filter.
+func isGoFile(path string, filter fileFilter) bool {
+	if !strings.HasSuffix(path, ".go") ||
+		strings.HasSuffix(path, "_test.go") ||
+		strings.Contains(path, "_gen") {
+		return false
+	}
+	if reason := filter.skipReason(filepath.Base(path)); reason != "" {
+		if verboseFlag {
+			fmt.Printf("Skipped: %s (%s)\n", path, reason)
+		}
+		return false
+	}
+	return true
 }
 
-func migrateFile(filename string) error {
+func migrateFile(filename string, formatter Formatter) error {
 	if verboseFlag {
 		fmt.Printf("Processing: %s\n", filename)
 	}
@@ -78,6 +201,10 @@ func migrateFile(filename string) error {
 		return err
 	}
 
+	if warnErrorfFlag {
+		reportUnwrappedErrorf(os.Stdout, filename, string(content))
+	}
+
 	if !migrate.NeedsMigration(string(content)) {
 		if verboseFlag {
 			fmt.Printf("Skipped: %s (no migration needed)\n", filename)
@@ -110,7 +237,7 @@ func migrateFile(filename string) error {
 	}
 
 	if dryRunFlag {
-		fmt.Printf("Would migrate: %s\n", filename)
+		reportDryRun(os.Stdout, filename, string(content), migratedContent, migrator.Matches(), statFlag)
 		return nil
 	}
 
@@ -118,7 +245,7 @@ func migrateFile(filename string) error {
 		return err
 	}
 
-	if err := formatFile(filename); err != nil {
+	if err := formatter.Format(filename); err != nil {
 		fmt.Printf("Warning: formatting failed for %s: %v\n", filename, err)
 	}
 
@@ -126,6 +253,34 @@ func migrateFile(filename string) error {
 	return nil
 }
 
+// reportUnwrappedErrorf writes a warning for each fmt.Errorf call in content that formats an error
+// without %w. It returns the number of warnings.
+func reportUnwrappedErrorf(w io.Writer, filename, content string) int {
+	warnings := migrate.UnwrappedErrorfWarnings(content)
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "Warning: %s:%d:%d: %s\n", filename, warning.Line, warning.Column, warning.Message)
+	}
+	return len(warnings)
+}
+
+// reportDryRun writes what migrating filename from original to migrated would change: a unified
+// diff or, with stat, how many times each pattern matched. The diff is against the formatted
+// migrated content, as migrateFile would write it, though without goimports.
+func reportDryRun(w io.Writer, filename, original, migrated string, matches []migrate.PatternMatch, stat bool) {
+	fmt.Fprintf(w, "Would migrate: %s\n", filename)
+	if stat {
+		for _, match := range matches {
+			fmt.Fprintf(w, "  %s: %d\n", match.Pattern, match.Count)
+		}
+		return
+	}
+	if formatted, err := format.Source([]byte(migrated)); err == nil {
+		migrated = string(formatted)
+	}
+	name := filepath.ToSlash(filename)
+	fmt.Fprint(w, migrate.UnifiedDiff(name, name, original, migrated))
+}
+
 func validateGoSyntax(filename string, content []byte) error {
 	fset := token.NewFileSet()
 	_, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
@@ -146,13 +301,3 @@ func writeFile(filename, content string) error {
 	}
 	return nil
 }
-
-func formatFile(filename string) error {
-	if err := exec.Command("goimports", "-w", filename).Run(); err != nil {
-		return fmt.Errorf("goimports: %w", err)
-	}
-	if err := exec.Command("gofmt", "-w", filename).Run(); err != nil {
-		return fmt.Errorf("gofmt: %w", err)
-	}
-	return nil
-}
@@ -1 +1,196 @@
 package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/YakDriver/smarterr/internal/migrate"
+)
+
+// recordingFormatter records the files it's asked to format without changing them.
+type recordingFormatter struct {
+	files []string
+}
+
+func (f *recordingFormatter) Format(filename string) error {
+	f.files = append(f.files, filename)
+	return nil
+}
+
+const unmigratedSource = `package test
+
+func read() error {
+	if err != nil {
+		return sdkdiag.AppendErrorf(diags, "reading VPC: %s", err)
+	}
+	return nil
+}
+`
+
+func TestMigrateFile_Formatter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "read.go")
+	if err := os.WriteFile(path, []byte(unmigratedSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	formatter := &recordingFormatter{}
+	if err := migrateFile(path, formatter); err != nil {
+		t.Fatalf("migrateFile error: %v", err)
+	}
+	if len(formatter.files) != 1 || formatter.files[0] != path {
+		t.Errorf("formatter called with %v, want [%s]", formatter.files, path)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "smerr.Append(ctx, diags, err)") {
+		t.Errorf("expected migrated content, got:\n%s", got)
+	}
+
+	// Files that don't need migration aren't formatted
+	formatter.files = nil
+	if err := migrateFile(path, formatter); err != nil {
+		t.Fatalf("migrateFile error: %v", err)
+	}
+	if len(formatter.files) != 0 {
+		t.Errorf("formatter called with %v for an already migrated file", formatter.files)
+	}
+}
+
+func TestSourceFormatter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messy.go")
+	if err := os.WriteFile(path, []byte("package test\nfunc  f( ) {\nreturn}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := (sourceFormatter{}).Format(path); err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package test\n\nfunc f() {\n\treturn\n}\n"; string(got) != want {
+		t.Errorf("formatted = %q, want %q", got, want)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected file mode to be preserved, got %v (%v)", info.Mode().Perm(), err)
+	}
+
+	if err := os.WriteFile(path, []byte("package test\nfunc {"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := (sourceFormatter{}).Format(path); err == nil {
+		t.Error("expected an error formatting invalid Go")
+	}
+}
+
+func TestReportUnwrappedErrorf(t *testing.T) {
+	content := `package test
+
+import "fmt"
+
+func find(id string) error {
+	err := lookup(id)
+	if err != nil {
+		return fmt.Errorf("finding %s: %s", id, err)
+	}
+	return fmt.Errorf("finding %s: %w", id, err)
+}
+`
+	var buf bytes.Buffer
+	if n := reportUnwrappedErrorf(&buf, "find.go", content); n != 1 {
+		t.Fatalf("reportUnwrappedErrorf() = %d, want 1", n)
+	}
+	want := "Warning: find.go:8:43: fmt.Errorf formats an error with %s; use %w to keep it in the unwrap chain\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if n := reportUnwrappedErrorf(&buf, "find.go", strings.ReplaceAll(content, ": %s\"", ": %w\"")); n != 0 || buf.Len() != 0 {
+		t.Errorf("expected no warnings when errors are wrapped, got %d: %s", n, buf.String())
+	}
+}
+
+func TestMigrateDirectory_Filter(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		exclude []string
+		include []string
+		want    []string
+	}{
+		{name: "no patterns", want: []string{"exports.go", "read.go", "sweep.go"}},
+		{name: "exclude", exclude: []string{"sweep.go", "export*.go"}, want: []string{"read.go"}},
+		{name: "include", include: []string{"*ea*.go"}, want: []string{"read.go"}},
+		{name: "exclude wins", exclude: []string{"read.go"}, include: []string{"read.go", "sweep.go"}, want: []string{"sweep.go"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range []string{"exports.go", "read.go", "sweep.go", "read_test.go", "tags_gen.go"} {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(unmigratedSource), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			filter, err := newFileFilter(tc.exclude, tc.include)
+			if err != nil {
+				t.Fatalf("newFileFilter error: %v", err)
+			}
+			formatter := &recordingFormatter{}
+			if err := migrateDirectory(dir, filter, formatter); err != nil {
+				t.Fatalf("migrateDirectory error: %v", err)
+			}
+			var got []string
+			for _, file := range formatter.files {
+				got = append(got, filepath.Base(file))
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("migrated %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewFileFilter_BadPattern(t *testing.T) {
+	if _, err := newFileFilter([]string{"[sweep.go"}, nil); err == nil || !strings.Contains(err.Error(), `--exclude "[sweep.go"`) {
+		t.Errorf("expected --exclude error, got %v", err)
+	}
+	if _, err := newFileFilter(nil, []string{"read[.go"}); err == nil || !strings.Contains(err.Error(), `--include "read[.go"`) {
+		t.Errorf("expected --include error, got %v", err)
+	}
+}
+
+func TestReportDryRun(t *testing.T) {
+	migrator := migrate.NewMigrator(migrate.MigratorOptions{DryRun: true})
+	migrated := migrator.MigrateContent(unmigratedSource)
+
+	var buf bytes.Buffer
+	reportDryRun(&buf, "read.go", unmigratedSource, migrated, migrator.Matches(), false)
+	wantDiff := `Would migrate: read.go
+--- read.go
++++ read.go
+@@ -2,7 +2,7 @@
+ 
+ func read() error {
+ 	if err != nil {
+-		return sdkdiag.AppendErrorf(diags, "reading VPC: %s", err)
++		return smerr.Append(ctx, diags, err)
+ 	}
+ 	return nil
+ }
+`
+	if got := buf.String(); got != wantDiff {
+		t.Errorf("diff output =\n%s\nwant:\n%s", got, wantDiff)
+	}
+
+	buf.Reset()
+	reportDryRun(&buf, "read.go", unmigratedSource, migrated, migrator.Matches(), true)
+	wantStat := "Would migrate: read.go\n  AppendErrorfSimple: 1\n"
+	if got := buf.String(); got != wantStat {
+		t.Errorf("stat output =\n%s\nwant:\n%s", got, wantStat)
+	}
+}
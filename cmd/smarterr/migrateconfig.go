@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/YakDriver/smarterr/internal"
+	"github.com/spf13/cobra"
+)
+
+var migrateConfigDryRun bool
+
+func init() {
+	migrateConfigCmd.Flags().BoolVarP(&migrateConfigDryRun, "dry-run", "n", false, "Show which files would be upgraded without changing them")
+	rootCmd.AddCommand(migrateConfigCmd)
+}
+
+var migrateConfigCmd = &cobra.Command{
+	Use:   "migrate-config [path...]",
+	Short: "Upgrade config files to the current schema version",
+	Long: `Rewrite smarterr.hcl and smarterr.json files written for an older schema version so they use
+the current schema and declare its version. smarterr upgrades older files in memory when it loads
+them, so they keep working, but this command updates the files themselves. Comments and formatting
+in HCL files are preserved; JSON files are re-indented with their top-level keys sorted.
+
+Each path is a config file or a directory to search for config files (default: current
+directory).
+
+Example:
+  smarterr migrate-config ./internal
+  smarterr migrate-config --dry-run ./internal/service/ec2/smarterr.hcl`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		for _, path := range args {
+			if err := migrateConfigPath(cmd.OutOrStdout(), path, migrateConfigDryRun); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// migrateConfigPath upgrades the config file at path or, if path is a directory, every config file
+// under it, writing a line to w for each file upgraded.
+func migrateConfigPath(w io.Writer, path string, dryRun bool) error {
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (p != path && !internal.IsConfigFile(p)) {
+			return nil
+		}
+		changed, err := migrateConfigFile(p, dryRun)
+		if err != nil {
+			return err
+		}
+		if changed {
+			if dryRun {
+				_, _ = fmt.Fprintf(w, "Would upgrade %s to schema version %d\n", p, internal.SchemaVersion)
+			} else {
+				_, _ = fmt.Fprintf(w, "Upgraded %s to schema version %d\n", p, internal.SchemaVersion)
+			}
+		}
+		return nil
+	})
+}
+
+// migrateConfigFile upgrades the config file at path in place, unless dryRun is set. It reports
+// whether the file needed an upgrade.
+func migrateConfigFile(path string, dryRun bool) (bool, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	upgraded, changed, err := internal.UpgradeConfigSource(src, path)
+	if err != nil {
+		return false, fmt.Errorf("upgrading %s: %w", path, err)
+	}
+	if !changed || dryRun {
+		return changed, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, upgraded, info.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("writing %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "service", "ec2", "smarterr.hcl")
+	currentPath := filepath.Join(dir, "smarterr.hcl")
+	if err := os.MkdirAll(filepath.Dir(oldPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oldConfig := "version = 0\n\nsmarterr {\n  hint_join_char = \" \"\n}\n"
+	currentConfig := "version = 1\n"
+	if err := os.WriteFile(oldPath, []byte(oldConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(currentPath, []byte(currentConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := migrateConfigPath(&out, dir, true); err != nil {
+		t.Fatalf("migrateConfigPath dry run error: %v", err)
+	}
+	if got := out.String(); got != "Would upgrade "+oldPath+" to schema version 1\n" {
+		t.Errorf("unexpected dry run output: %q", got)
+	}
+	if got, _ := os.ReadFile(oldPath); string(got) != oldConfig {
+		t.Errorf("dry run changed the file: %q", got)
+	}
+
+	out.Reset()
+	if err := migrateConfigPath(&out, dir, false); err != nil {
+		t.Fatalf("migrateConfigPath error: %v", err)
+	}
+	if got := out.String(); got != "Upgraded "+oldPath+" to schema version 1\n" {
+		t.Errorf("unexpected output: %q", got)
+	}
+	got, _ := os.ReadFile(oldPath)
+	if want := "version = 1\n\nsmarterr {\n  hint_join_char = \" \"\n}\n"; string(got) != want {
+		t.Errorf("upgraded file = %q, want %q", got, want)
+	}
+	if got, _ := os.ReadFile(currentPath); string(got) != currentConfig {
+		t.Errorf("current config changed: %q", got)
+	}
+
+	// A file argument is upgraded even if it isn't named smarterr.hcl
+	other := writeConfig(t, oldConfig)
+	renamed := filepath.Join(filepath.Dir(other), "shared.hcl")
+	if err := os.Rename(other, renamed); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := migrateConfigPath(&out, renamed, false); err != nil {
+		t.Fatalf("migrateConfigPath error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Upgraded "+renamed) {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestMigrateConfigPath_JSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service", "s3", "smarterr.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"version": 0, "token": [{"name": "id", "arg": "id"}], "smarterr": {"hint_join_char": " "}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := migrateConfigPath(&out, dir, false); err != nil {
+		t.Fatalf("migrateConfigPath error: %v", err)
+	}
+	if got := out.String(); got != "Upgraded "+path+" to schema version 1\n" {
+		t.Errorf("unexpected output: %q", got)
+	}
+	got, _ := os.ReadFile(path)
+	want := `{
+  "smarterr": {
+    "hint_join_char": " "
+  },
+  "token": [
+    {
+      "name": "id",
+      "arg": "id"
+    }
+  ],
+  "version": 1
+}
+`
+	if string(got) != want {
+		t.Errorf("upgraded file =\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := loadSingleConfigFile(path); err != nil {
+		t.Errorf("loading upgraded file: %v", err)
+	}
+
+	// Upgrading again changes nothing
+	out.Reset()
+	if err := migrateConfigPath(&out, dir, false); err != nil || out.Len() != 0 {
+		t.Errorf("expected no second upgrade, got %q, %v", out.String(), err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+// defaultNamingPattern matches snake_case names, such as "resource_type".
+const defaultNamingPattern = `^[a-z][a-z0-9]*(_[a-z0-9]+)*$`
+
+// defaultStackMatchVerbs are the action verbs a stack_match name may end in.
+var defaultStackMatchVerbs = []string{"create", "read", "update", "delete", "import", "list", "find", "get", "set", "wait", "tag"}
+
+// namingRules are the naming conventions checked with --naming.
+type namingRules struct {
+	pattern *regexp.Regexp
+	verbs   []string
+}
+
+// newNamingRules compiles pattern, the regular expression names must match. A stack_match name
+// must also be one of verbs or end in "_" followed by one; with no verbs, any ending is allowed.
+func newNamingRules(pattern string, verbs []string) (namingRules, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return namingRules{}, fmt.Errorf("--naming-pattern: %w", err)
+	}
+	return namingRules{pattern: re, verbs: verbs}, nil
+}
+
+// checkNaming returns a message for each token, parameter, transform, hint, and stack_match name
+// that doesn't follow rules. Template names aren't checked since checkTemplateNames requires
+// canonical names.
+func checkNaming(cfg *internal.Config, rules namingRules) []string {
+	var violations []string
+	checkName := func(blockType, name string) {
+		if !rules.pattern.MatchString(name) {
+			violations = append(violations, fmt.Sprintf("%s %q doesn't match the naming pattern %s", blockType, name, rules.pattern))
+		}
+	}
+	for _, t := range cfg.Tokens {
+		checkName("token", t.Name)
+	}
+	for _, p := range cfg.Parameters {
+		checkName("parameter", p.Name)
+	}
+	for _, tr := range cfg.Transforms {
+		checkName("transform", tr.Name)
+	}
+	for _, h := range cfg.Hints {
+		checkName("hint", h.Name)
+	}
+	for _, sm := range cfg.StackMatches {
+		checkName("stack_match", sm.Name)
+		if len(rules.verbs) > 0 && !endsInVerb(sm.Name, rules.verbs) {
+			violations = append(violations, fmt.Sprintf("stack_match %q doesn't end in an action verb (%s)", sm.Name, strings.Join(rules.verbs, ", ")))
+		}
+	}
+	return violations
+}
+
+// endsInVerb reports whether name is one of verbs or ends in "_" followed by one, such as
+// "vpc_create".
+func endsInVerb(name string, verbs []string) bool {
+	for _, verb := range verbs {
+		if name == verb || strings.HasSuffix(name, "_"+verb) {
+			return true
+		}
+	}
+	return false
+}
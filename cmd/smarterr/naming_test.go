@@ -0,0 +1,63 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+func TestCheckNaming(t *testing.T) {
+	rules, err := newNamingRules(defaultNamingPattern, defaultStackMatchVerbs)
+	if err != nil {
+		t.Fatalf("newNamingRules error: %v", err)
+	}
+	conforming := &internal.Config{
+		Tokens:       []internal.Token{{Name: "resource_type"}, {Name: "id"}},
+		Parameters:   []internal.Parameter{{Name: "service"}},
+		Transforms:   []internal.Transform{{Name: "clean_id2"}},
+		Hints:        []internal.Hint{{Name: "throttled"}},
+		StackMatches: []internal.StackMatch{{Name: "create"}, {Name: "vpc_wait"}},
+	}
+	if got := checkNaming(conforming, rules); len(got) != 0 {
+		t.Errorf("expected no violations, got %v", got)
+	}
+
+	nonConforming := &internal.Config{
+		Tokens:       []internal.Token{{Name: "resourceType"}},
+		Parameters:   []internal.Parameter{{Name: "Service"}},
+		Transforms:   []internal.Transform{{Name: "clean-id"}},
+		Hints:        []internal.Hint{{Name: "_throttled"}},
+		StackMatches: []internal.StackMatch{{Name: "creating"}, {Name: "recreate"}},
+	}
+	want := []string{
+		`token "resourceType" doesn't match the naming pattern ` + defaultNamingPattern,
+		`parameter "Service" doesn't match the naming pattern ` + defaultNamingPattern,
+		`transform "clean-id" doesn't match the naming pattern ` + defaultNamingPattern,
+		`hint "_throttled" doesn't match the naming pattern ` + defaultNamingPattern,
+		`stack_match "creating" doesn't end in an action verb (create, read, update, delete, import, list, find, get, set, wait, tag)`,
+		`stack_match "recreate" doesn't end in an action verb (create, read, update, delete, import, list, find, get, set, wait, tag)`,
+	}
+	if got := checkNaming(nonConforming, rules); !slices.Equal(got, want) {
+		t.Errorf("checkNaming() =\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestCheckNaming_CustomRules(t *testing.T) {
+	rules, err := newNamingRules(`^[a-z]+$`, nil)
+	if err != nil {
+		t.Fatalf("newNamingRules error: %v", err)
+	}
+	cfg := &internal.Config{
+		Tokens:       []internal.Token{{Name: "resource_type"}},
+		StackMatches: []internal.StackMatch{{Name: "anything"}},
+	}
+	want := []string{`token "resource_type" doesn't match the naming pattern ^[a-z]+$`}
+	if got := checkNaming(cfg, rules); !slices.Equal(got, want) {
+		t.Errorf("checkNaming() = %v, want %v", got, want)
+	}
+
+	if _, err := newNamingRules(`[`, nil); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatSARIF = "sarif"
+
+	levelError   = "error"
+	levelWarning = "warning"
+)
+
+// checkResult is a single error or warning produced by a config check.
+type checkResult struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// checkReport is the JSON output of the check command.
+type checkReport struct {
+	Errors   int           `json:"errors"`
+	Warnings int           `json:"warnings"`
+	Results  []checkResult `json:"results"`
+}
+
+// blockRefRegex finds the first block reference (e.g., `token "name"`) in a check message.
+var blockRefRegex = regexp.MustCompile(`\b(token|hint|parameter|stack_match|template|transform) "([^"]+)"`)
+
+// smarterrRefRegex finds a reference to the smarterr block (e.g., `smarterr.token_error_mode`) in a check message.
+var smarterrRefRegex = regexp.MustCompile(`\bsmarterr\.`)
+
+// buildCheckResults converts check errors and warnings into results, locating each at the block it
+// refers to in the given config files. Files are ordered least to most specific, so the location
+// is the most specific file defining the block, matching how configs merge.
+func buildCheckResults(errs []error, warnings []string, files []string) []checkResult {
+	locations := locateBlocks(files)
+	var results []checkResult
+	add := func(level, msg string) {
+		r := checkResult{Level: level, Message: msg}
+		key := ""
+		if m := blockRefRegex.FindStringSubmatch(msg); m != nil {
+			key = blockKey(m[1], m[2])
+		} else if smarterrRefRegex.MatchString(msg) {
+			key = blockKey("smarterr", "")
+		}
+		if loc, ok := locations[key]; ok {
+			r.File, r.Line = loc.File, loc.Line
+		} else if len(files) == 1 {
+			r.File = files[0]
+		}
+		results = append(results, r)
+	}
+	for _, e := range errs {
+		add(levelError, e.Error())
+	}
+	for _, w := range warnings {
+		add(levelWarning, w)
+	}
+	return results
+}
+
+// blockLocation is where a block is defined.
+type blockLocation struct {
+	File string
+	Line int
+}
+
+func blockKey(blockType, name string) string {
+	if name == "" {
+		return blockType
+	}
+	return blockType + " " + name
+}
+
+// locateBlocks parses config files and returns the location of each top-level block, keyed by
+// block type and label. Later files take precedence. Unreadable or unparsable files are skipped.
+func locateBlocks(files []string) map[string]blockLocation {
+	locations := make(map[string]blockLocation)
+	for _, f := range files {
+		src, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		file, diags := hclsyntax.ParseConfig(src, f, hcl.InitialPos)
+		if diags.HasErrors() {
+			continue
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+		for _, block := range body.Blocks {
+			name := ""
+			if len(block.Labels) > 0 {
+				name = block.Labels[0]
+			}
+			locations[blockKey(block.Type, name)] = blockLocation{File: f, Line: block.TypeRange.Start.Line}
+		}
+	}
+	return locations
+}
+
+// writeJSONReport writes check results as JSON.
+func writeJSONReport(w io.Writer, results []checkResult) error {
+	report := checkReport{Results: results}
+	if report.Results == nil {
+		report.Results = []checkResult{}
+	}
+	for _, r := range results {
+		switch r.Level {
+		case levelError:
+			report.Errors++
+		case levelWarning:
+			report.Warnings++
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// SARIF 2.1.0 types, limited to what smarterr emits.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+	Version        string `json:"version,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// writeSARIFReport writes check results as a SARIF 2.1.0 log.
+func writeSARIFReport(w io.Writer, results []checkResult) error {
+	version, _, _ := buildVersion()
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "smarterr",
+			InformationURI: "https://github.com/YakDriver/smarterr",
+			Version:        version,
+		}},
+		Results: []sarifResult{},
+	}
+	for _, r := range results {
+		sr := sarifResult{
+			RuleID:  "smarterr-config",
+			Level:   r.Level,
+			Message: sarifMessage{Text: r.Message},
+		}
+		if r.File != "" {
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: r.File},
+			}}
+			if r.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: r.Line}
+			}
+			sr.Locations = []sarifLocation{loc}
+		}
+		run.Results = append(run.Results, sr)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// writeCheckReport writes check results in the given structured format.
+func writeCheckReport(w io.Writer, format string, results []checkResult) error {
+	switch format {
+	case formatJSON:
+		return writeJSONReport(w, results)
+	case formatSARIF:
+		return writeSARIFReport(w, results)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const outputTestConfig = `smarterr {
+  hint_join_char = "abc"
+}
+
+token "foo" {
+  source = "arg"
+}
+`
+
+func outputTestResults(t *testing.T) ([]checkResult, string) {
+	t.Helper()
+	path := writeConfig(t, outputTestConfig)
+	errs := []error{errors.New(`token "foo": source=arg but 'arg' field is not set`)}
+	warnings := []string{`smarterr.hint_join_char is set to "abc" (longer than 2 characters)`}
+	return buildCheckResults(errs, warnings, []string{path}), path
+}
+
+func TestBuildCheckResults(t *testing.T) {
+	results, path := outputTestResults(t)
+	want := []checkResult{
+		{Level: levelError, Message: `token "foo": source=arg but 'arg' field is not set`, File: path, Line: 5},
+		{Level: levelWarning, Message: `smarterr.hint_join_char is set to "abc" (longer than 2 characters)`, File: path, Line: 1},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %d: %+v", len(want), len(results), results)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	results, path := outputTestResults(t)
+	var buf bytes.Buffer
+	if err := writeCheckReport(&buf, formatJSON, results); err != nil {
+		t.Fatalf("writeCheckReport: %v", err)
+	}
+
+	var report checkReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if report.Errors != 1 || report.Warnings != 1 {
+		t.Errorf("expected 1 error and 1 warning, got %d and %d", report.Errors, report.Warnings)
+	}
+	if len(report.Results) != 2 || report.Results[0].File != path || report.Results[0].Line != 5 {
+		t.Errorf("unexpected results: %+v", report.Results)
+	}
+}
+
+func TestWriteSARIFReport(t *testing.T) {
+	results, path := outputTestResults(t)
+	var buf bytes.Buffer
+	if err := writeCheckReport(&buf, formatSARIF, results); err != nil {
+		t.Fatalf("writeCheckReport: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF envelope: %+v", log)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "smarterr" {
+		t.Errorf("expected driver name smarterr, got %q", run.Tool.Driver.Name)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(run.Results))
+	}
+	levels := []string{run.Results[0].Level, run.Results[1].Level}
+	if levels[0] != "error" || levels[1] != "warning" {
+		t.Errorf("unexpected levels: %v", levels)
+	}
+	loc := run.Results[0].Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != path || loc.Region == nil || loc.Region.StartLine != 5 {
+		t.Errorf("unexpected location: %+v", loc)
+	}
+}
+
+func TestWriteCheckReport_UnsupportedFormat(t *testing.T) {
+	if err := writeCheckReport(&bytes.Buffer{}, "xml", nil); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
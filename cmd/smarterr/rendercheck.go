@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+// renderCheckError is the error templates are rendered with in checkRenderPlaceholders. Every
+// error has a message, so the error token always resolves; other tokens have no values.
+var renderCheckError = errors.New("sample error")
+
+// checkRenderPlaceholders renders each canonical template with no keyvals, context, or
+// diagnostic and returns a warning for each token placeholder, such as <id>, in the output. It only
+// applies with token_error_mode = "placeholder", since with the other modes a token without a
+// value renders as an empty string or a message meant for debugging.
+func checkRenderPlaceholders(cfg *internal.Config) []string {
+	if cfg.Smarterr == nil || cfg.Smarterr.TokenErrorMode == nil || *cfg.Smarterr.TokenErrorMode != "placeholder" {
+		return nil
+	}
+	ctx := context.Background()
+	values := internal.NewRuntime(ctx, cfg, renderCheckError).BuildTokenValueMap(ctx)
+
+	var warnings []string
+	for _, name := range slices.Concat(canonicalTemplateNames, optionalTemplateNames) {
+		if !slices.ContainsFunc(cfg.Templates, func(tmpl internal.Template) bool { return tmpl.Name == name }) {
+			continue
+		}
+		out, err := cfg.RenderTemplate(ctx, name, values)
+		if err != nil {
+			// checkTemplateVarsAndTokens reports templates that don't parse
+			continue
+		}
+		for _, t := range cfg.Tokens {
+			// Diagnostic tokens have a placeholder per field, such as <diag.summary>
+			if strings.Contains(out, "<"+t.Name+">") || strings.Contains(out, "<"+t.Name+".") {
+				warnings = append(warnings, fmt.Sprintf("template %q shows the placeholder <%s> when token %q has no value; make sure the token always resolves, or use token_error_mode = \"empty\"", name, t.Name, t.Name))
+			}
+		}
+	}
+	return warnings
+}
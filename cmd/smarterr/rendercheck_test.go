@@ -0,0 +1,65 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheckRenderPlaceholders(t *testing.T) {
+	cfg, err := loadSingleConfigFile(writeConfig(t, `
+smarterr {
+  token_error_mode = "placeholder"
+}
+
+token "error" {
+  source = "error"
+}
+
+token "service" {
+  parameter = "service"
+}
+
+token "id" {
+  arg = "id"
+}
+
+token "diag" {
+  source = "diagnostic"
+}
+
+parameter "service" {
+  value = "EC2"
+}
+
+template "error_summary" {
+  format = "{{.service}} failed"
+}
+
+template "error_detail" {
+  format = "{{.service}} {{.id}}: {{.error}}"
+}
+
+template "diagnostic_summary" {
+  format = "{{.diag.summary}}"
+}
+`))
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	want := []string{
+		`template "diagnostic_summary" shows the placeholder <diag> when token "diag" has no value; make sure the token always resolves, or use token_error_mode = "empty"`,
+		`template "error_detail" shows the placeholder <id> when token "id" has no value; make sure the token always resolves, or use token_error_mode = "empty"`,
+	}
+	got := checkRenderPlaceholders(cfg)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("checkRenderPlaceholders() =\n%v\nwant:\n%v", got, want)
+	}
+
+	// Other modes don't show placeholders
+	mode := "empty"
+	cfg.Smarterr.TokenErrorMode = &mode
+	if got := checkRenderPlaceholders(cfg); len(got) != 0 {
+		t.Errorf("checkRenderPlaceholders() with token_error_mode = %q = %v, want none", mode, got)
+	}
+}
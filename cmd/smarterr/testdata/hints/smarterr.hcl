@@ -0,0 +1,18 @@
+hint "throttling" {
+  error_contains = "ThrottlingException"
+  suggestion     = "Retry later or request a quota increase."
+}
+
+hint "not_found" {
+  regex_match = "(?i)not ?found"
+  suggestion  = "Check that the resource exists in this Region."
+}
+
+hint "access_denied" {
+  error_contains = "AccessDenied"
+  suggestion     = "Check the IAM permissions of the caller."
+}
+
+hint "access_denied.ja" {
+  suggestion = "呼び出し元の IAM 権限を確認してください。"
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/YakDriver/smarterr/internal"
+	"github.com/spf13/cobra"
+)
+
+var corpusFile string
+var expectFile string
+
+func init() {
+	testHintsCmd.Flags().StringVar(&corpusFile, "corpus", "", "File with one error message per line (required)")
+	testHintsCmd.Flags().StringVar(&expectFile, "expect", "", "JSON file mapping error messages to the names of the hints expected to match (required)")
+	testHintsCmd.Flags().StringVarP(&startDir, "start-dir", "d", "", "Directory where code using smarterr lives (default: current directory). This is typically where the error occurs.")
+	testHintsCmd.Flags().StringVarP(&baseDir, "base-dir", "b", "", "Parent directory where go:embed is used (optional, but recommended for proper config layering as in the application). If not set, config applies only to the current directory.")
+	testHintsCmd.Flags().StringVarP(&configFile, "config-file", "c", "", "Load a single config file directly, bypassing discovery and layering (--base-dir and --start-dir are ignored)")
+	testHintsCmd.Flags().BoolVarP(&debugFlag, "debug", "D", false, "Enable smarterr debug output (even if config fails to load)")
+	_ = testHintsCmd.MarkFlagRequired("corpus")
+	_ = testHintsCmd.MarkFlagRequired("expect")
+	rootCmd.AddCommand(testHintsCmd)
+}
+
+var testHintsCmd = &cobra.Command{
+	Use:   "test-hints",
+	Short: "Check which hints match a corpus of error messages",
+	Long: `Match the hints in the effective smarterr configuration against each error message in a corpus
+file and compare the matching hint names to expectations. Use it as a regression test when
+changing hints.
+
+The corpus has one error message per line; blank lines and lines starting with # are skipped.
+The expectations file is a JSON object mapping error messages to the names of the hints expected
+to match, in order. An error message without an entry is expected to match no hints.
+
+Example:
+  smarterr test-hints -b ./internal --corpus errors.txt --expect expectations.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if debugFlag {
+			internal.EnableDebugForce()
+		}
+		cfg, _, err := loadConfig(nil)
+		if err != nil {
+			return err
+		}
+		corpus, err := os.Open(corpusFile)
+		if err != nil {
+			return fmt.Errorf("reading corpus: %w", err)
+		}
+		defer func() { _ = corpus.Close() }()
+		expectations, err := readHintExpectations(expectFile)
+		if err != nil {
+			return err
+		}
+		return testHints(cmd.OutOrStdout(), cfg, corpus, expectations)
+	},
+}
+
+// readHintExpectations reads a JSON object mapping error messages to expected hint names.
+func readHintExpectations(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading expectations: %w", err)
+	}
+	var expectations map[string][]string
+	if err := json.Unmarshal(data, &expectations); err != nil {
+		return nil, fmt.Errorf("parsing expectations %s: %w", path, err)
+	}
+	return expectations, nil
+}
+
+// testHints matches cfg's hints against each error message in corpus and writes a line to w for
+// each message whose matching hints differ from expectations, followed by a summary. It returns an
+// error if any message doesn't match its expectations.
+func testHints(w io.Writer, cfg *internal.Config, corpus io.Reader, expectations map[string][]string) error {
+	ctx := context.Background()
+	scanner := bufio.NewScanner(corpus)
+	lineNum, total, failed := 0, 0, 0
+	for scanner.Scan() {
+		lineNum++
+		msg := scanner.Text()
+		if strings.TrimSpace(msg) == "" || strings.HasPrefix(msg, "#") {
+			continue
+		}
+		total++
+		got := cfg.MatchingHints(ctx, msg)
+		want := expectations[msg]
+		if !slices.Equal(got, want) {
+			failed++
+			_, _ = fmt.Fprintf(w, "line %d: %q: matched %s, want %s\n", lineNum, msg, formatHintNames(got), formatHintNames(want))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading corpus: %w", err)
+	}
+	_, _ = fmt.Fprintf(w, "%d of %d errors matched the expected hints\n", total-failed, total)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d errors didn't match the expected hints", failed, total)
+	}
+	return nil
+}
+
+// formatHintNames formats hint names for a test-hints report, such as [throttling, retry].
+func formatHintNames(names []string) string {
+	return "[" + strings.Join(names, ", ") + "]"
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTestHints(t *testing.T) {
+	cfg, err := loadSingleConfigFile("testdata/hints/smarterr.hcl")
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	expectations, err := readHintExpectations("testdata/hints/expectations.json")
+	if err != nil {
+		t.Fatalf("reading expectations: %v", err)
+	}
+	corpus, err := os.ReadFile("testdata/hints/errors.txt")
+	if err != nil {
+		t.Fatalf("reading corpus: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := testHints(&out, cfg, bytes.NewReader(corpus), expectations); err != nil {
+		t.Fatalf("testHints error: %v\n%s", err, out.String())
+	}
+	if got, want := out.String(), "4 of 4 errors matched the expected hints\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	// A hint change that breaks expectations is reported by line
+	delete(expectations, "ThrottlingException: Rate exceeded")
+	out.Reset()
+	err = testHints(&out, cfg, bytes.NewReader(corpus), expectations)
+	if err == nil || err.Error() != "1 of 4 errors didn't match the expected hints" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if want := `line 2: "ThrottlingException: Rate exceeded": matched [throttling], want []`; !strings.Contains(out.String(), want) {
+		t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+	}
+}
+
+func TestReadHintExpectations_Invalid(t *testing.T) {
+	path := writeConfig(t, `["not", "an", "object"]`)
+	if _, err := readHintExpectations(path); err == nil || !strings.Contains(err.Error(), "parsing expectations") {
+		t.Errorf("expected parse error, got: %v", err)
+	}
+}
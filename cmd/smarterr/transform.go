@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/YakDriver/smarterr/internal"
+	"github.com/spf13/cobra"
+)
+
+var transformName string
+var transformInput string
+
+func init() {
+	transformCmd.Flags().StringVar(&transformName, "name", "", "Name of the transform to apply (required)")
+	transformCmd.Flags().StringVarP(&transformInput, "input", "i", "", "Input value to transform")
+	transformCmd.Flags().StringVarP(&startDir, "start-dir", "d", "", "Directory where code using smarterr lives (default: current directory). This is typically where the error occurs.")
+	transformCmd.Flags().StringVarP(&baseDir, "base-dir", "b", "", "Parent directory where go:embed is used (optional, but recommended for proper config layering as in the application). If not set, config applies only to the current directory.")
+	transformCmd.Flags().StringVarP(&configFile, "config-file", "c", "", "Load a single config file directly, bypassing discovery and layering (--base-dir and --start-dir are ignored)")
+	transformCmd.Flags().BoolVarP(&debugFlag, "debug", "D", false, "Enable smarterr debug output (even if config fails to load)")
+	_ = transformCmd.MarkFlagRequired("name")
+	rootCmd.AddCommand(transformCmd)
+}
+
+var transformCmd = &cobra.Command{
+	Use:   "transform",
+	Short: "Preview the output of a named transform",
+	Long: `Apply a named transform from the effective smarterr configuration to an input value and
+print the value after each step. It helps debug transform chains without writing Go.
+
+Example:
+  smarterr transform -b ./internal --name clean --input "  PRE_ Foo "`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if debugFlag {
+			internal.EnableDebugForce()
+		}
+		cfg, _, err := loadConfig(nil)
+		if err != nil {
+			return err
+		}
+		return previewTransform(cmd.OutOrStdout(), cfg, transformName, transformInput)
+	},
+}
+
+// previewTransform applies the named transform to input, writing the value after each step to w.
+func previewTransform(w io.Writer, cfg *internal.Config, name, input string) error {
+	tr := cfg.FindTransform(name)
+	if tr == nil {
+		return fmt.Errorf("transform %q not found", name)
+	}
+	_, _ = fmt.Fprintf(w, "Input:  %q\n", input)
+	value := input
+	for i, step := range tr.Steps {
+		value = cfg.ApplyTransformStep(value, step)
+		_, _ = fmt.Fprintf(w, "  step %d (%s): %q\n", i+1, step.Type, value)
+	}
+	// The output is the last step's value, as shown, rather than a second run of the steps
+	_, _ = fmt.Fprintf(w, "Output: %q\n", value)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+func TestTransformCmd_MultiStep(t *testing.T) {
+	path := writeConfig(t, `
+transform "clean" {
+  step "trim_space" {}
+  step "strip_prefix" {
+    value = "PRE_"
+  }
+  step "lower" {}
+}
+`)
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetArgs([]string{"transform", "--config-file", path, "--name", "clean", "--input", "  PRE_ Foo "})
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+		configFile = ""
+		transformName = ""
+		transformInput = ""
+	})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("transform command failed: %v", err)
+	}
+
+	want := `Input:  "  PRE_ Foo "
+  step 1 (trim_space): "PRE_ Foo"
+  step 2 (strip_prefix): "Foo"
+  step 3 (lower): "foo"
+Output: "foo"
+`
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTransformCmd_NotFound(t *testing.T) {
+	path := writeConfig(t, `transform "clean" {
+  step "lower" {}
+}
+`)
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	err = previewTransform(&bytes.Buffer{}, cfg, "missing", "x")
+	if err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+}
+
+func TestPreviewTransform_RunsStepsOnce(t *testing.T) {
+	calls := 0
+	internal.RegisterTransformStep("preview_counter", func(value string, _ internal.TransformStep) string {
+		calls++
+		return fmt.Sprintf("%s%d", value, calls)
+	})
+	path := writeConfig(t, `transform "count" {
+  step "preview_counter" {}
+}
+`)
+	cfg, err := loadSingleConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadSingleConfigFile: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := previewTransform(&buf, cfg, "count", "x"); err != nil {
+		t.Fatalf("previewTransform: %v", err)
+	}
+	want := `Input:  "x"
+  step 1 (preview_counter): "x1"
+Output: "x1"
+`
+	if got := buf.String(); got != want || calls != 1 {
+		t.Errorf("got %d calls and output:\n%s\nwant 1 call and:\n%s", calls, got, want)
+	}
+}
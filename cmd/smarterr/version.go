@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// commit is the VCS revision the binary was built from. It is set at build time, for example:
+//
+//	go build -ldflags "-X main.commit=$(git rev-parse --short HEAD)"
+var commit = ""
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the smarterr version",
+	Long:  `Print the smarterr module version and the commit it was built from. Include this output in bug reports.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printVersion(cmd.OutOrStdout())
+		return nil
+	},
+}
+
+// printVersion writes the module version, commit, and Go version to w.
+func printVersion(w io.Writer) {
+	version, rev, goVersion := buildVersion()
+	_, _ = fmt.Fprintf(w, "smarterr %s\n", version)
+	_, _ = fmt.Fprintf(w, "commit: %s\n", rev)
+	_, _ = fmt.Fprintf(w, "go: %s\n", goVersion)
+}
+
+// buildVersion returns the module version, commit, and Go version from the embedded build info.
+// The compiled-in commit takes precedence over the VCS revision recorded by the Go toolchain.
+func buildVersion() (version, rev, goVersion string) {
+	version, rev, goVersion = "(devel)", "unknown", "unknown"
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		if commit != "" {
+			rev = commit
+		}
+		return
+	}
+	if info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	goVersion = info.GoVersion
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && s.Value != "" {
+			rev = s.Value
+		}
+	}
+	if commit != "" {
+		rev = commit
+	}
+	return
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestVersionCmd(t *testing.T) {
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	rootCmd.SetArgs([]string{"version"})
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("version command failed: %v", err)
+	}
+
+	out := buf.String()
+	if !regexp.MustCompile(`(?m)^smarterr (v\d+\.\d+\.\d+\S*|\(devel\))$`).MatchString(out) {
+		t.Errorf("expected version line, got:\n%s", out)
+	}
+	if !regexp.MustCompile(`(?m)^commit: \S+$`).MatchString(out) {
+		t.Errorf("expected commit line, got:\n%s", out)
+	}
+}
+
+func TestVersionCmd_CompiledCommit(t *testing.T) {
+	orig := commit
+	commit = "abc1234"
+	t.Cleanup(func() { commit = orig })
+
+	var buf bytes.Buffer
+	printVersion(&buf)
+	if !bytes.Contains(buf.Bytes(), []byte("commit: abc1234\n")) {
+		t.Errorf("expected compiled-in commit, got:\n%s", buf.String())
+	}
+}
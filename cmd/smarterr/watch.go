@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/YakDriver/smarterr/internal"
+	"github.com/spf13/cobra"
+)
+
+var watchFlag bool
+
+// watchInterval is how often watched config files are polled for changes.
+const watchInterval = time.Second
+
+// configPoller reports a fingerprint of the watched config files. A change in the fingerprint
+// between polls means the configuration changed.
+type configPoller interface {
+	Poll() (string, error)
+}
+
+// filePoller fingerprints config files by path, size, and modification time. If root is a file,
+// only that file is watched; otherwise, all config files under root are watched.
+type filePoller struct {
+	root string
+}
+
+func (p filePoller) Poll() (string, error) {
+	var sb strings.Builder
+	err := filepath.WalkDir(p.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip unreadable entries; they'll show up as a change if they become readable
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if path != p.root && !internal.IsConfigFile(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(&sb, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return sb.String(), err
+}
+
+// watchConfig runs run once, then re-runs it each time the watched config files change, until
+// interrupted. Errors from run are printed rather than ending the watch.
+func watchConfig(cmd *cobra.Command, run func() error) error {
+	root := configFile
+	if root == "" {
+		root = baseDir
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute watch path: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	runAndReport := func() {
+		if err := run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+	runAndReport()
+	fmt.Printf("\nWatching %s for changes (Ctrl+C to stop)...\n", absRoot)
+	return watchLoop(ctx, filePoller{root: absRoot}, watchInterval, func() {
+		fmt.Println("\nChange detected, re-running...")
+		runAndReport()
+	})
+}
+
+// watchLoop polls p every interval and calls onChange when the fingerprint changes. It returns
+// when ctx is done.
+func watchLoop(ctx context.Context, p configPoller, interval time.Duration, onChange func()) error {
+	last, err := p.Poll()
+	if err != nil {
+		return fmt.Errorf("failed to poll config files: %w", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current, err := p.Poll()
+			if err != nil {
+				return fmt.Errorf("failed to poll config files: %w", err)
+			}
+			if current != last {
+				last = current
+				onChange()
+			}
+		}
+	}
+}
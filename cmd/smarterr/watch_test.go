@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakePoller returns fingerprints in order, repeating the last one.
+type fakePoller struct {
+	fingerprints []string
+	calls        int
+}
+
+func (p *fakePoller) Poll() (string, error) {
+	i := min(p.calls, len(p.fingerprints)-1)
+	p.calls++
+	return p.fingerprints[i], nil
+}
+
+func TestWatchLoop_ChangeTriggersRerun(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &fakePoller{fingerprints: []string{"a", "a", "b", "b", "c"}}
+	runs := 0
+	err := watchLoop(ctx, p, time.Millisecond, func() {
+		runs++
+		if runs == 2 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("watchLoop: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("expected 2 re-runs (a->b, b->c), got %d", runs)
+	}
+}
+
+func TestFilePoller(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "smarterr.hcl")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`token "a" {}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := filePoller{root: dir}
+	before, err := p.Poll()
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+
+	// Non-config files are ignored
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := p.Poll(); after != before {
+		t.Error("expected non-config file to be ignored")
+	}
+
+	if err := os.WriteFile(path, []byte(`token "a" {}
+token "b" {}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := p.Poll(); after == before {
+		t.Error("expected config change to change the fingerprint")
+	}
+}
@@ -3,17 +3,28 @@ package smarterr
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"runtime"
+	"sync"
+
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
 )
 
 // Error is the enriched smarterr error type.
 // It wraps a base error and includes structured annotations
 // that can be used by Append or AddError to construct clear, user-friendly diagnostics.
+// Use it through a pointer, as NewError and Errorf return it; an Error must not be copied, since
+// it holds the lock that guards its rendered summary and detail.
 type Error struct {
 	Err           error             // The original or wrapped error
 	Message       string            // Optional developer-provided message (from Errorf)
 	Annotations   map[string]string // Arbitrary key-value annotations (e.g., subaction, resource_id)
+	Code          string            // Optional stable machine code (e.g., THROTTLING) for automation
 	CapturedStack []runtime.Frame   // Captured call stack for stack matching
+
+	renderMu        sync.Mutex // Guards the rendered fields, since an error may be reported from several goroutines
+	renderedSummary string
+	renderedDetail  string
 }
 
 // Error implements the error interface.
@@ -34,6 +45,122 @@ func (e *Error) Stack() []runtime.Frame {
 	return e.CapturedStack
 }
 
+// RenderedSummary returns the diagnostic summary AddError or Append last rendered for the error, or
+// "" if it hasn't been reported. Handlers that inspect the error later see the enriched text.
+func (e *Error) RenderedSummary() string {
+	e.renderMu.Lock()
+	defer e.renderMu.Unlock()
+	return e.renderedSummary
+}
+
+// RenderedDetail returns the diagnostic detail AddError or Append last rendered for the error, or
+// "" if it hasn't been reported.
+func (e *Error) RenderedDetail() string {
+	e.renderMu.Lock()
+	defer e.renderMu.Unlock()
+	return e.renderedDetail
+}
+
+// setRendered stores the summary and detail rendered for the error.
+func (e *Error) setRendered(summary, detail string) {
+	e.renderMu.Lock()
+	defer e.renderMu.Unlock()
+	e.renderedSummary, e.renderedDetail = summary, detail
+}
+
+// Annotation returns the value of the named annotation and whether it was set.
+func (e *Error) Annotation(key string) (string, bool) {
+	v, ok := e.Annotations[key]
+	return v, ok
+}
+
+// WithField sets a key-value annotation on the error, as WithAnnotation does, and returns the
+// error, so calls can be chained:
+//
+//	se.WithField("subaction", "tagging").WithField("tag_key", key)
+func (e *Error) WithField(key, value string) *Error {
+	if e.Annotations == nil {
+		e.Annotations = map[string]string{}
+	}
+	e.Annotations[key] = value
+	return e
+}
+
+// WithFields sets each of fields as an annotation on the error, replacing annotations with the
+// same keys, and returns the error.
+func (e *Error) WithFields(fields map[string]string) *Error {
+	for key, value := range fields {
+		e.WithField(key, value)
+	}
+	return e
+}
+
+// Annotations returns a copy of the annotations of the smarterr *Error in err's chain, or nil if
+// err doesn't wrap one or it has no annotations.
+func Annotations(err error) map[string]string {
+	var se *Error
+	if !errors.As(err, &se) || len(se.Annotations) == 0 {
+		return nil
+	}
+	return maps.Clone(se.Annotations)
+}
+
+// ErrorCode returns the error's machine code, or "" if it has none.
+func (e *Error) ErrorCode() string {
+	return e.Code
+}
+
+// WithCode sets a stable machine code, such as "THROTTLING", on a smarterr error. The code takes
+// precedence over a matching hint's code for the "error_code" token and for append_error_code. If
+// err already wraps a smarterr *Error, the code is set on it and err is returned unchanged;
+// otherwise, err is wrapped like NewError.
+//
+// Example:
+//
+//	return nil, smarterr.WithCode(err, "THROTTLING")
+func WithCode(err error, code string) error {
+	if err == nil {
+		return nil
+	}
+	var se *Error
+	if !errors.As(err, &se) {
+		se = &Error{
+			Err:           err,
+			CapturedStack: captureStack(3), // skip 3 to get the caller of WithCode
+		}
+		err = se
+	}
+	se.Code = code
+	return err
+}
+
+// WithAnnotation sets a key-value annotation on a smarterr error so that an "annotation" token can
+// resolve it. If err already wraps a smarterr *Error, the annotation is set on it and err is
+// returned unchanged; otherwise, err is wrapped like NewError.
+//
+// Example:
+//
+//	return nil, smarterr.WithAnnotation(err, "subaction", "waiting for replication")
+func WithAnnotation(err error, key, value string) error {
+	if err == nil {
+		return nil
+	}
+	var se *Error
+	if !errors.As(err, &se) {
+		se = &Error{
+			Err:           err,
+			Annotations:   map[string]string{},
+			CapturedStack: captureStack(3), // skip 3 to get the caller of WithAnnotation
+		}
+		err = se
+	}
+	if se.Annotations == nil {
+		se.Annotations = map[string]string{}
+	}
+	se.Annotations[key] = value
+	return err
+}
+
 // NewError wraps an existing error with smarterr metadata derived from the call stack.
 // It automatically annotates the error with context-aware information (e.g., sub-action)
 // without requiring developer input, reducing fragility and promoting consistent error enrichment.
@@ -57,6 +184,37 @@ func NewError(err error) error {
 	}
 }
 
+// Wrap wraps an existing error like NewError and sets keyvals, alternating string keys and values,
+// as annotations, so "annotation" tokens can resolve them. Values that aren't strings are
+// formatted with fmt.Sprint. A key without a value or a key that isn't a string is skipped. It's a
+// one-line alternative to NewError followed by WithAnnotation.
+//
+// Example:
+//
+//	return nil, smarterr.Wrap(err, "subaction", "finding VPC", "vpc_id", id)
+func Wrap(err error, keyvals ...any) error {
+	if err == nil {
+		return nil
+	}
+	annotations := make(map[string]string, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			Debugf("Wrap skipped keyval at index %d: key is %T, not string", i, keyvals[i])
+			continue
+		}
+		annotations[key] = fmt.Sprint(keyvals[i+1])
+	}
+	if len(keyvals)%2 != 0 {
+		Debugf("Wrap skipped key %v at index %d: it has no value", keyvals[len(keyvals)-1], len(keyvals)-1)
+	}
+	return &Error{
+		Err:           err,
+		Annotations:   annotations,
+		CapturedStack: captureStack(3), // skip 3 to get the caller of Wrap
+	}
+}
+
 // Errorf formats according to a format specifier and returns a smarterr-enriched error.
 // It behaves like fmt.Errorf, but also captures contextual metadata based on the call site.
 // This ensures consistent DX and structured diagnostics with minimal developer effort.
@@ -75,6 +233,57 @@ func Errorf(format string, args ...any) error {
 	}
 }
 
+// diagnosticError is an error carrying a Framework diagnostic (see DiagnosticError).
+type diagnosticError struct {
+	diag fwdiag.Diagnostic
+}
+
+// DiagnosticError converts a Framework diagnostic, such as one produced by another library, into an
+// error so it can be passed through AddError or Append. smarterr recognizes it, even when wrapped:
+// "diagnostic" tokens resolve its summary, detail, and severity, and a warning diagnostic is
+// rendered with the warning templates and added as a warning. It returns nil if diag is nil.
+//
+// Example:
+//
+//	smarterr.AddError(ctx, &resp.Diagnostics, smarterr.DiagnosticError(diag), smarterr.ID, id)
+func DiagnosticError(diag fwdiag.Diagnostic) error {
+	if diag == nil {
+		return nil
+	}
+	return &diagnosticError{diag: diag}
+}
+
+// Error returns the diagnostic's summary and detail.
+func (e *diagnosticError) Error() string {
+	if e.diag.Detail() == "" {
+		return e.diag.Summary()
+	}
+	return e.diag.Summary() + ": " + e.diag.Detail()
+}
+
+// Diagnostic returns the converted diagnostic.
+func (e *diagnosticError) Diagnostic() fwdiag.Diagnostic {
+	return e.diag
+}
+
+// asDiagnostic returns the diagnostic carried by err, if err wraps one from DiagnosticError.
+func asDiagnostic(err error) (fwdiag.Diagnostic, bool) {
+	var de *diagnosticError
+	if errors.As(err, &de) {
+		return de.diag, true
+	}
+	return nil, false
+}
+
+// errorSeverity returns the severity to report err with: that of the diagnostic carried by err,
+// if any, otherwise SeverityError.
+func errorSeverity(err error) string {
+	if diag, ok := asDiagnostic(err); ok && diag.Severity() == fwdiag.SeverityWarning {
+		return SeverityWarning
+	}
+	return SeverityError
+}
+
 // Assert wraps a call returning (T, error) with smarterr.NewError on failure.
 // Go doesn't yet support generics-based tuple unpacking, so this form works well for now.
 func Assert[T any](val T, err error) (T, error) {
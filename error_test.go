@@ -0,0 +1,302 @@
+package smarterr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
+	sdkdiag "github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+func TestWithAnnotation(t *testing.T) {
+	base := NewError(errors.New("boom"))
+	wrapped := fmt.Errorf("creating cluster: %w", base)
+
+	if got := WithAnnotation(wrapped, "subaction", "waiting"); got != wrapped {
+		t.Errorf("expected existing smarterr error chain to be returned unchanged")
+	}
+	var se *Error
+	if !errors.As(wrapped, &se) {
+		t.Fatal("expected *Error in chain")
+	}
+	if v, ok := se.Annotation("subaction"); !ok || v != "waiting" {
+		t.Errorf("Annotation(subaction) = %q, %t; want %q, true", v, ok, "waiting")
+	}
+
+	plain := errors.New("plain")
+	annotated := WithAnnotation(plain, "resource_id", "db-1")
+	if !errors.As(annotated, &se) {
+		t.Fatal("expected plain error to be wrapped in *Error")
+	}
+	if !errors.Is(annotated, plain) {
+		t.Error("expected wrapped error to unwrap to the original")
+	}
+	if v, _ := se.Annotation("resource_id"); v != "db-1" {
+		t.Errorf("Annotation(resource_id) = %q, want %q", v, "db-1")
+	}
+	if len(se.Stack()) == 0 {
+		t.Error("expected stack to be captured")
+	}
+
+	if WithAnnotation(nil, "k", "v") != nil {
+		t.Error("expected nil error to stay nil")
+	}
+}
+
+func TestError_WithFields(t *testing.T) {
+	var se *Error
+	if !errors.As(NewError(errors.New("boom")), &se) {
+		t.Fatal("expected *Error")
+	}
+	se.Annotations = nil
+	if got := se.WithField("subaction", "tagging").WithFields(map[string]string{"tag_key": "Name", "subaction": "untagging"}); got != se {
+		t.Error("expected WithField and WithFields to return the receiver")
+	}
+
+	wrapped := fmt.Errorf("updating VPC: %w", se)
+	want := map[string]string{"subaction": "untagging", "tag_key": "Name"}
+	got := Annotations(wrapped)
+	if !maps.Equal(got, want) {
+		t.Errorf("Annotations = %v, want %v", got, want)
+	}
+	got["tag_key"] = "changed"
+	if v, _ := se.Annotation("tag_key"); v != "Name" {
+		t.Errorf("expected Annotations to return a copy, but tag_key = %q", v)
+	}
+
+	if got := Annotations(errors.New("plain")); got != nil {
+		t.Errorf("Annotations(plain) = %v, want nil", got)
+	}
+	if got := Annotations(nil); got != nil {
+		t.Errorf("Annotations(nil) = %v, want nil", got)
+	}
+}
+
+func TestError_WithFields_AnnotationToken(t *testing.T) {
+	setTestConfig(t, `
+token "subaction" {
+  annotation = "subaction"
+}
+
+template "error_summary" {
+  format = "{{.subaction}}"
+}
+
+template "error_detail" {
+  format = "detail"
+}
+`)
+	var se *Error
+	if !errors.As(NewError(errors.New("boom")), &se) {
+		t.Fatal("expected *Error")
+	}
+	err := fmt.Errorf("creating VPC: %w", se.WithField("subaction", "tagging"))
+
+	var diags fwdiag.Diagnostics
+	AddError(context.Background(), &diags, err)
+	if got, want := diags[0].Summary(), "tagging"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	plain := errors.New("boom")
+	_, file, line, _ := runtime.Caller(0)
+	err := Wrap(plain, "subaction", "finding VPC", "attempts", 3, 42, "skipped", "dangling") // Wrapped on line+1
+
+	var se *Error
+	if !errors.As(err, &se) {
+		t.Fatal("expected *Error")
+	}
+	if !errors.Is(err, plain) {
+		t.Error("expected wrapped error to unwrap to the original")
+	}
+	want := map[string]string{"subaction": "finding VPC", "attempts": "3"}
+	if !maps.Equal(se.Annotations, want) {
+		t.Errorf("Annotations = %v, want %v", se.Annotations, want)
+	}
+	if len(se.Stack()) == 0 {
+		t.Fatal("expected stack to be captured")
+	}
+	if frame := se.Stack()[0]; frame.File != file || frame.Line != line+1 {
+		t.Errorf("first frame = %s:%d, want the caller of Wrap, %s:%d", frame.File, frame.Line, file, line+1)
+	}
+
+	if Wrap(nil, "k", "v") != nil {
+		t.Error("expected nil error to stay nil")
+	}
+}
+
+func TestDiagnosticError(t *testing.T) {
+	if DiagnosticError(nil) != nil {
+		t.Error("expected nil error for nil diagnostic")
+	}
+	err := DiagnosticError(fwdiag.NewErrorDiagnostic("Value Conversion Error", "expected string"))
+	if got, want := err.Error(), "Value Conversion Error: expected string"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got, want := DiagnosticError(fwdiag.NewErrorDiagnostic("Summary only", "")).Error(), "Summary only"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestDiagnosticError_Enrich(t *testing.T) {
+	setTestConfig(t, `
+token "diag" {
+  source = "diagnostic"
+}
+
+token "identifier" {
+  arg = "id"
+}
+
+template "error_summary" {
+  format = "{{.diag.summary}} for {{.identifier}}"
+}
+
+template "error_detail" {
+  format = "{{.diag.detail}}"
+}
+
+template "warning_summary" {
+  format = "Warning: {{.diag.summary}}"
+}
+`)
+	ctx := context.Background()
+
+	var diags fwdiag.Diagnostics
+	err := fmt.Errorf("reading: %w", DiagnosticError(fwdiag.NewErrorDiagnostic("Value Conversion Error", "expected string")))
+	AddError(ctx, &diags, err, ID, "vpc-1")
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diags))
+	}
+	if got, want := diags[0].Summary(), "Value Conversion Error for vpc-1"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	if got, want := diags[0].Detail(), "expected string"; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+	if got := diags[0].Severity(); got != fwdiag.SeverityError {
+		t.Errorf("severity = %v, want error", got)
+	}
+
+	warning := DiagnosticError(fwdiag.NewWarningDiagnostic("Deprecated Attribute", "use name instead"))
+	diags = nil
+	AddError(ctx, &diags, warning)
+	if got := diags[0].Severity(); got != fwdiag.SeverityWarning {
+		t.Errorf("severity = %v, want warning", got)
+	}
+	if got, want := diags[0].Summary(), "Warning: Deprecated Attribute"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+
+	sdiags := Append(ctx, nil, warning)
+	if got := sdiags[0].Severity; got != sdkdiag.Warning {
+		t.Errorf("Append severity = %v, want warning", got)
+	}
+	if got, want := sdiags[0].Detail, "use name instead"; got != want {
+		t.Errorf("Append detail = %q, want %q", got, want)
+	}
+}
+
+func TestError_Rendered(t *testing.T) {
+	setTestConfig(t, `
+token "identifier" {
+  arg = "id"
+}
+
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "reading VPC ({{.identifier}})"
+}
+
+template "error_detail" {
+  format = "cause: {{.error}}"
+}
+`)
+	ctx := context.Background()
+	err := NewError(errors.New("not found"))
+	var se *Error
+	if !errors.As(err, &se) {
+		t.Fatal("expected a smarterr *Error")
+	}
+	if se.RenderedSummary() != "" || se.RenderedDetail() != "" {
+		t.Errorf("expected no rendered text before AddError, got %q, %q", se.RenderedSummary(), se.RenderedDetail())
+	}
+
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, fmt.Errorf("wrapped: %w", err), ID, "vpc-1")
+	if got, want := se.RenderedSummary(), "reading VPC (vpc-1)"; got != want {
+		t.Errorf("RenderedSummary() = %q, want %q", got, want)
+	}
+	if got, want := se.RenderedDetail(), "cause: wrapped: not found"; got != want {
+		t.Errorf("RenderedDetail() = %q, want %q", got, want)
+	}
+
+	// A shared error reported concurrently ends up with one of the rendered results
+	var wg sync.WaitGroup
+	for _, id := range []string{"vpc-2", "vpc-3", "vpc-4"} {
+		wg.Go(func() {
+			_ = Append(ctx, nil, err, ID, id)
+			_ = se.RenderedSummary()
+		})
+	}
+	wg.Wait()
+	if got := se.RenderedSummary(); !slices.Contains([]string{"reading VPC (vpc-2)", "reading VPC (vpc-3)", "reading VPC (vpc-4)"}, got) {
+		t.Errorf("unexpected RenderedSummary() after concurrent reports: %q", got)
+	}
+}
+
+func TestErrorOriginToken(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+token "origin" {
+  source     = "error_origin"
+  transforms = ["base_name"]
+}
+
+transform "base_name" {
+  step "replace" {
+    regex = "^.*/"
+    with  = ""
+  }
+}
+
+template "error_summary" {
+  format = "{{.error}}"
+}
+
+template "error_detail" {
+  format = "created at {{.origin}}"
+}
+`)
+	ctx := context.Background()
+
+	_, _, line, _ := runtime.Caller(0)
+	err := fmt.Errorf("reading VPC: %w", NewError(errors.New("boom"))) // Created on line+1
+
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, err)
+	if got, want := diags[0].Detail(), fmt.Sprintf("created at error_test.go:%d", line+1); got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	// An error smarterr didn't create has no origin
+	diags = nil
+	AddError(ctx, &diags, errors.New("boom"))
+	if got, want := diags[0].Detail(), "created at "; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,16 @@
+package smarterr
+
+import "github.com/YakDriver/smarterr/internal"
+
+// RegisterErrorType registers the type of sample under name so hints can match errors of that type
+// with error_type, rather than by message, for example, for AWS SDK for Go v2 errors:
+//
+//	smarterr.RegisterErrorType("ResourceNotFound", &types.ResourceNotFoundException{})
+//
+// A hint with error_type = "ResourceNotFound" then matches an error with that type anywhere in its
+// chain, as errors.As finds it. Call it during initialization, before smarterr formats errors.
+// Registering a name again replaces the earlier registration. It panics if name is empty or sample
+// is nil.
+func RegisterErrorType(name string, sample error) {
+	internal.RegisterErrorType(name, sample)
+}
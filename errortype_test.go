@@ -0,0 +1,46 @@
+package smarterr
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+type quotaError struct{}
+
+func (e *quotaError) Error() string { return "limit reached" }
+
+func TestRegisterErrorType(t *testing.T) {
+	RegisterErrorType("test_quota", &quotaError{})
+	setTestConfig(t, `
+token "hints" {
+  source = "hints"
+}
+
+hint "quota" {
+  error_type = "test_quota"
+  suggestion = "Request a quota increase."
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "error_detail" {
+  format = "{{.hints}}"
+}
+`)
+	var diags fwdiag.Diagnostics
+	AddError(context.Background(), &diags, fmt.Errorf("creating VPC: %w", &quotaError{}))
+	if got, want := diags[0].Detail(), "Request a quota increase."; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	diags = nil
+	AddError(context.Background(), &diags, fmt.Errorf("creating VPC: limit reached"))
+	if got, want := diags[0].Detail(), ""; got != want {
+		t.Errorf("detail for another error type = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+// configcache.go
+// Cache of parsed config files, so reporting a diagnostic doesn't re-parse every layer
+package internal
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+)
+
+// configCacheKey identifies a config file by the filesystem it was read from and its path.
+type configCacheKey struct {
+	fsys FileSystem
+	path string
+}
+
+// configCacheEntry is a parsed config and the content it was parsed from. A file whose content
+// changed, as on a real filesystem, is parsed again.
+type configCacheEntry struct {
+	src []byte
+	cfg *Config
+}
+
+var (
+	configCacheMu sync.RWMutex
+	configCache   = map[configCacheKey]configCacheEntry{}
+)
+
+// ClearConfigCache discards all cached config files, such as when the filesystem changes.
+func ClearConfigCache() {
+	configCacheMu.Lock()
+	defer configCacheMu.Unlock()
+	clear(configCache)
+}
+
+// cacheable reports whether configs read from fsys can be cached. The filesystem must be usable as
+// a map key, as pointers such as *WrappedFS are.
+func cacheable(fsys FileSystem) bool {
+	return fsys != nil && reflect.TypeOf(fsys).Comparable()
+}
+
+// cachedConfig returns the config cached for path in fsys if it was parsed from src.
+func cachedConfig(fsys FileSystem, path string, src []byte) (*Config, bool) {
+	if !cacheable(fsys) {
+		return nil, false
+	}
+	configCacheMu.RLock()
+	defer configCacheMu.RUnlock()
+	entry, ok := configCache[configCacheKey{fsys, path}]
+	if !ok || !bytes.Equal(entry.src, src) {
+		return nil, false
+	}
+	return entry.cfg, true
+}
+
+// cacheConfig caches cfg, parsed from src, for path in fsys. Callers must not modify a cached
+// config; mergeConfigs merges into a copy.
+func cacheConfig(fsys FileSystem, path string, src []byte, cfg *Config) {
+	if !cacheable(fsys) {
+		return
+	}
+	configCacheMu.Lock()
+	defer configCacheMu.Unlock()
+	configCache[configCacheKey{fsys, path}] = configCacheEntry{src: src, cfg: cfg}
+}
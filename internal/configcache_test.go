@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadConfigFile_Cache(t *testing.T) {
+	t.Cleanup(ClearConfigCache)
+	mapFS := fstest.MapFS{
+		"smarterr.hcl": &fstest.MapFile{Data: []byte(`parameter "service" { value = "EC2" }`)},
+	}
+	fsys := &WrappedFS{FS: mapFS}
+	ctx := context.Background()
+
+	first, err := loadConfigFile(ctx, fsys, "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	second, err := loadConfigFile(ctx, fsys, "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if first != second {
+		t.Error("expected the second load to return the cached config")
+	}
+
+	// Another filesystem with the same path doesn't share the entry
+	other, err := loadConfigFile(ctx, &WrappedFS{FS: mapFS}, "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if other == first {
+		t.Error("expected a different filesystem not to use the cached config")
+	}
+
+	// Changed content is parsed again
+	mapFS["smarterr.hcl"] = &fstest.MapFile{Data: []byte(`parameter "service" { value = "RDS" }`)}
+	changed, err := loadConfigFile(ctx, fsys, "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if changed == first || changed.Parameters[0].Text() != "RDS" {
+		t.Errorf("expected changed content to be parsed again, got value %q", changed.Parameters[0].Text())
+	}
+
+	ClearConfigCache()
+	cleared, err := loadConfigFile(ctx, fsys, "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if cleared == changed {
+		t.Error("expected ClearConfigCache to discard the cached config")
+	}
+}
+
+func TestLoadConfig_CachedLayersNotModified(t *testing.T) {
+	t.Cleanup(ClearConfigCache)
+	fsys := &WrappedFS{FS: fstest.MapFS{
+		"smarterr/smarterr.hcl":    &fstest.MapFile{Data: []byte("parameter \"service\" {\n  value = \"global\"\n}\n")},
+		"service/rds/smarterr.hcl": &fstest.MapFile{Data: []byte("parameter \"service\" {\n  value = \"RDS\"\n}\nsmarterr {\n  merge_precedence = { token = \"base\" }\n}\n")},
+	}}
+	ctx := context.Background()
+	stack := []string{"x/y/z/internal/service/rds/instance.go"}
+	for range 2 {
+		cfg, err := LoadConfig(ctx, fsys, stack, "internal")
+		if err != nil {
+			t.Fatalf("LoadConfig error: %v", err)
+		}
+		if len(cfg.Parameters) != 1 || cfg.Parameters[0].Text() != "RDS" {
+			t.Errorf("merged parameters = %+v, want service = RDS", cfg.Parameters)
+		}
+	}
+	global, err := loadConfigFile(ctx, fsys, "smarterr/smarterr.hcl")
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if global.Parameters[0].Text() != "global" || global.Smarterr != nil {
+		t.Errorf("cached global config was modified by merging: %+v", global)
+	}
+}
+
+// unhashableFS is a FileSystem that can't be a map key, so its configs aren't cached.
+type unhashableFS struct {
+	FileSystem
+	_ []string
+}
+
+func TestLoadConfigFile_UncacheableFS(t *testing.T) {
+	fsys := unhashableFS{FileSystem: &WrappedFS{FS: fstest.MapFS{
+		"smarterr.hcl": &fstest.MapFile{Data: []byte(`parameter "service" { value = "EC2" }`)},
+	}}}
+	ctx := context.Background()
+	first, err := loadConfigFile(ctx, fsys, "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	second, err := loadConfigFile(ctx, fsys, "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if first == second {
+		t.Error("expected configs from an uncacheable filesystem to be parsed each time")
+	}
+}
@@ -3,11 +3,16 @@
 package internal
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -36,6 +41,7 @@ func loadConfigMultiStack(ctx context.Context, fsys FileSystem, relStackPaths []
 		return &Config{}, nil
 	}
 	merged := mergeConfigs(ctx, configs)
+	merged.cycleMemo = &templateCycleMemo{}
 	EnableDebug(merged) // Enable internal debug output based on config
 	return merged, nil
 }
@@ -45,29 +51,66 @@ func loadConfigMultiStack(ctx context.Context, fsys FileSystem, relStackPaths []
 func collectConfigsForStack(ctx context.Context, fsys FileSystem, relStackPaths []string, baseDir string) ([]*Config, error) {
 	callID := globalCallID(ctx)
 	Debugf("[collectConfigsForStack %s] called with baseDir=%q relStackPaths=%v", callID, baseDir, relStackPaths)
-	// Find all config files in
-	type configWithPath struct {
-		cfg  *Config
-		path string
-	}
-	var cfgsWithPaths []configWithPath
-	globalConfigPath, candidateConfigs, err := findAllConfigPaths(ctx, fsys)
+	paths, globalConfigPath, err := configPathsForStack(ctx, fsys, relStackPaths, baseDir)
 	if err != nil {
 		return nil, err
 	}
-
-	// Always include the global config if present
-	if globalConfigPath != "" {
-		cfg, err := loadConfigFile(ctx, fsys, globalConfigPath)
+	var configs []*Config
+	for _, configPath := range paths {
+		cfg, err := loadConfigFile(ctx, fsys, configPath)
 		if err != nil {
-			return nil, fmt.Errorf("error loading global config: %w", err)
+			if configPath == globalConfigPath {
+				return nil, fmt.Errorf("error loading global config: %w", err)
+			}
+			Debugf("[collectConfigsForStack %s] error loading config %s: %v", callID, configPath, err)
+			return nil, fmt.Errorf("error loading config %s: %w", configPath, err)
 		}
-		cfgsWithPaths = append(cfgsWithPaths, configWithPath{cfg, globalConfigPath})
+		if err := CheckSchemaVersion(cfg); err != nil {
+			Debugf("[collectConfigsForStack %s] warning: config %s: %v", callID, configPath, err)
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// CheckSchemaVersion returns an error if cfg declares a schema version outside the range this
+// version of smarterr supports. A config without a version is always accepted. smarterr still
+// loads a config with an unsupported version, so callers should treat the error as a warning.
+func CheckSchemaVersion(cfg *Config) error {
+	if cfg == nil || cfg.Version == nil {
+		return nil
+	}
+	v := *cfg.Version
+	switch {
+	case v < MinSchemaVersion:
+		return fmt.Errorf("schema version %d is no longer supported (supported: %d to %d)", v, MinSchemaVersion, SchemaVersion)
+	case v > SchemaVersion:
+		return fmt.Errorf("schema version %d is newer than this version of smarterr supports (supported: %d to %d); upgrade smarterr", v, MinSchemaVersion, SchemaVersion)
+	}
+	return nil
+}
+
+// ConfigPathsForStack returns the paths of the config files that apply to the provided stack paths,
+// ordered from least to most specific (the order in which they are merged).
+func ConfigPathsForStack(ctx context.Context, fsys FileSystem, relStackPaths []string, baseDir string) ([]string, error) {
+	paths, _, err := configPathsForStack(ctx, fsys, relStackPaths, baseDir)
+	return paths, err
+}
+
+// configPathsForStack finds the config files relevant to the provided stack paths, sorted by path
+// depth (least specific first) after the global config, which is always first. It also returns
+// the global config path, if any.
+func configPathsForStack(ctx context.Context, fsys FileSystem, relStackPaths []string, baseDir string) ([]string, string, error) {
+	callID := globalCallID(ctx)
+	globalConfigPath, candidateConfigs, err := findAllConfigPaths(ctx, fsys)
+	if err != nil {
+		return nil, "", err
 	}
 
+	var paths []string
 	sep := string(filepath.Separator)
 	for _, configPath := range candidateConfigs {
-		Debugf("[collectConfigsForStack %s] checking candidate config %q", callID, configPath)
+		Debugf("[configPathsForStack %s] checking candidate config %q", callID, configPath)
 		configDir := filepath.Dir(configPath)
 		needle := baseDir + sep + configDir
 		if baseDir == "." {
@@ -75,72 +118,162 @@ func collectConfigsForStack(ctx context.Context, fsys FileSystem, relStackPaths
 		}
 		for _, stackPath := range relStackPaths {
 			if strings.Contains(stackPath, needle) {
-				cfg, err := loadConfigFile(ctx, fsys, configPath)
-				if err != nil {
-					Debugf("[collectConfigsForStack %s] error loading config %s: %v", callID, configPath, err)
-					return nil, fmt.Errorf("error loading config %s: %w", configPath, err)
-				}
-				cfgsWithPaths = append(cfgsWithPaths, configWithPath{cfg, configPath})
-				Debugf("[collectConfigsForStack %s] matched config %q for stack path %q", callID, configPath, stackPath)
+				paths = append(paths, configPath)
+				Debugf("[configPathsForStack %s] matched config %q for stack path %q", callID, configPath, stackPath)
 				break // Only need to match once per config
 			}
-			Debugf("[collectConfigsForStack %s] config %q did not match, stackPath (%s) does not contain needle (%s)", callID, configPath, stackPath, needle)
+			Debugf("[configPathsForStack %s] config %q did not match, stackPath (%s) does not contain needle (%s)", callID, configPath, stackPath, needle)
 		}
 	}
 	// Sort by path depth (least specific first, most specific last)
-	sort.Slice(cfgsWithPaths, func(i, j int) bool {
-		return strings.Count(cfgsWithPaths[i].path, sep) < strings.Count(cfgsWithPaths[j].path, sep)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return strings.Count(paths[i], sep) < strings.Count(paths[j], sep)
 	})
-	var configs []*Config
-	for _, c := range cfgsWithPaths {
-		configs = append(configs, c.cfg)
+	// Always include the global config if present, as the base that every other config overrides,
+	// even if a config is at the same or a shallower depth
+	if globalConfigPath != "" {
+		paths = append([]string{globalConfigPath}, paths...)
 	}
-	return configs, nil
+	return paths, globalConfigPath, nil
 }
 
-// findAllConfigPaths scans the FS for all smarterr.hcl files, returning the global config path and other candidates.
+// findAllConfigPaths scans the FS for all smarterr.hcl and smarterr.json files, returning the global
+// config path and other candidates. If a directory has both, only smarterr.hcl is returned.
 func findAllConfigPaths(ctx context.Context, fsys FileSystem) (globalConfig string, candidateConfigs []string, err error) {
 	callID := globalCallID(ctx)
 	Debugf("[findAllConfigPaths %s] scanning filesystem for config files", callID)
+	var paths []string
 	err = fsys.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil || d.IsDir() {
 			return nil
 		}
-		if !strings.HasSuffix(path, ConfigFileName) {
-			return nil
+		if IsConfigFile(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	for _, path := range paths {
+		if strings.HasSuffix(path, JSONConfigFileName) && slices.Contains(paths, strings.TrimSuffix(path, JSONConfigFileName)+ConfigFileName) {
+			Debugf("[findAllConfigPaths %s] skipping %q in favor of %s", callID, path, ConfigFileName)
+			continue
 		}
 		if strings.HasPrefix(path, "smarterr/") {
 			globalConfig = path
 		} else {
 			candidateConfigs = append(candidateConfigs, path)
 		}
-		return nil
-	})
+	}
 	Debugf("[findAllConfigPaths %s] found globalConfig=%q candidateConfigs=%v", callID, globalConfig, candidateConfigs)
 	return
 }
 
-// loadConfigFile loads a single config file from the FS and parses it into a Config struct.
+// IsConfigFile reports whether path names a config file, smarterr.hcl or smarterr.json.
+func IsConfigFile(path string) bool {
+	return strings.HasSuffix(path, ConfigFileName) || strings.HasSuffix(path, JSONConfigFileName)
+}
+
+// LoadConfigFile loads a single config file without discovery or merging. This is useful for
+// tooling that needs to inspect one file in isolation.
+func LoadConfigFile(ctx context.Context, fsys FileSystem, path string) (*Config, error) {
+	callID := globalCallID(ctx)
+	Debugf("[LoadConfigFile %s] called with path=%q", callID, path)
+	return loadConfigFile(ctx, fsys, path)
+}
+
+// loadConfigFile loads a single config file from the FS and parses it into a Config struct. Parsed
+// files are cached by filesystem and path until their content changes, so the returned Config
+// must not be modified.
 func loadConfigFile(ctx context.Context, fsys FileSystem, path string) (*Config, error) {
 	callID := globalCallID(ctx)
 	Debugf("[loadConfigFile %s] loading config file %q", callID, path)
-	parser := hclparse.NewParser()
 	fileBytes, err := fsys.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	file, diags := parser.ParseHCL(fileBytes, path)
+	if cfg, ok := cachedConfig(fsys, path, fileBytes); ok {
+		Debugf("[loadConfigFile %s] using cached config for %q", callID, path)
+		return cfg, nil
+	}
+	cfg, err := ParseConfig(fileBytes, path)
+	if err != nil {
+		return nil, err
+	}
+	cacheConfig(fsys, path, fileBytes, cfg)
+	return cfg, nil
+}
+
+// ParseConfig parses config file content into a Config struct. The filename is used in error
+// messages, and a filename ending in .json is parsed as JSON instead of HCL. Content written for an
+// older schema version is upgraded in memory first.
+func ParseConfig(src []byte, filename string) (*Config, error) {
+	if strings.HasSuffix(filename, ".json") {
+		return parseJSONConfig(src, filename)
+	}
+	parser := hclparse.NewParser()
+	file, diags := parser.ParseHCL(upgradeSource(src, filename), filename)
 	if diags.HasErrors() {
 		return nil, fmt.Errorf("parse error: %s", diags.Error())
 	}
 	var partial Config
-	decodeDiags := gohcl.DecodeBody(file.Body, nil, &partial)
+	decodeDiags := gohcl.DecodeBody(file.Body, configEvalContext(), &partial)
 	if decodeDiags.HasErrors() {
 		return nil, fmt.Errorf("decode error: %s", decodeDiags.Error())
 	}
 	return &partial, nil
 }
 
+// parseJSONConfig parses JSON config content. Each block type is a list of objects whose label is
+// a field, such as {"token": [{"name": "id", "arg": "id"}]}. As in HCL, unknown fields are errors.
+func parseJSONConfig(src []byte, filename string) (*Config, error) {
+	dec := json.NewDecoder(bytes.NewReader(upgradeJSONSource(src, filename)))
+	dec.DisallowUnknownFields()
+	var partial Config
+	if err := dec.Decode(&partial); err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("parse error: unexpected content after the config object")
+	}
+	if err := checkJSONLabels(&partial); err != nil {
+		return nil, fmt.Errorf("decode error: %w", err)
+	}
+	return &partial, nil
+}
+
+// checkJSONLabels returns an error if a block in a JSON config is missing the name or type HCL
+// requires as its label.
+func checkJSONLabels(cfg *Config) error {
+	check := func(blockType string, i int, label, field string) error {
+		if label == "" {
+			return fmt.Errorf("%s %d: missing %q", blockType, i, field)
+		}
+		return nil
+	}
+	var errs []error
+	for i, t := range cfg.Tokens {
+		errs = append(errs, check("token", i, t.Name, "name"))
+	}
+	for i, h := range cfg.Hints {
+		errs = append(errs, check("hint", i, h.Name, "name"))
+	}
+	for i, p := range cfg.Parameters {
+		errs = append(errs, check("parameter", i, p.Name, "name"))
+	}
+	for i, sm := range cfg.StackMatches {
+		errs = append(errs, check("stack_match", i, sm.Name, "name"))
+	}
+	for i, tmpl := range cfg.Templates {
+		errs = append(errs, check("template", i, tmpl.Name, "name"))
+	}
+	for i, tr := range cfg.Transforms {
+		errs = append(errs, check("transform", i, tr.Name, "name"))
+		for j, step := range tr.Steps {
+			errs = append(errs, check(fmt.Sprintf("transform %q step", tr.Name), j, step.Type, "type"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // FileSystem defines an interface for filesystem operations, including file existence checks.
 type FileSystem interface {
 	Open(name string) (fs.File, error)
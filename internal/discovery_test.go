@@ -1,7 +1,12 @@
 package internal
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"testing/fstest"
 )
@@ -127,3 +132,331 @@ parameter "bar" {
 		t.Errorf("expected resolved token value 'child', got: %q", val)
 	}
 }
+
+func TestLoadConfig_GlobalIsBase(t *testing.T) {
+	global := `
+template "error_summary" {
+  format = "global summary"
+}
+template "error_detail" {
+  format = "global detail"
+}
+`
+	service := `
+template "error_detail" {
+  format = "service detail"
+}
+`
+	tests := map[string]struct {
+		servicePath   string
+		relStackPaths []string
+	}{
+		"nested service config": {
+			servicePath:   "service/rds/smarterr.hcl",
+			relStackPaths: []string{"x/y/z/internal/service/rds/instance.go"},
+		},
+		"service config at global depth": {
+			servicePath:   "rds/smarterr.hcl",
+			relStackPaths: []string{"x/y/z/internal/rds/instance.go"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fsys := &WrappedFS{FS: fstest.MapFS{
+				"smarterr/smarterr.hcl": &fstest.MapFile{Data: []byte(global)},
+				tc.servicePath:          &fstest.MapFile{Data: []byte(service)},
+			}}
+			ctx := context.Background()
+			paths, err := ConfigPathsForStack(ctx, fsys, tc.relStackPaths, "internal")
+			if err != nil {
+				t.Fatalf("ConfigPathsForStack error: %v", err)
+			}
+			if len(paths) != 2 || paths[0] != "smarterr/smarterr.hcl" || paths[1] != tc.servicePath {
+				t.Fatalf("ConfigPathsForStack() = %v, want [smarterr/smarterr.hcl %s]", paths, tc.servicePath)
+			}
+
+			cfg, err := LoadConfig(ctx, fsys, tc.relStackPaths, "internal")
+			if err != nil {
+				t.Fatalf("LoadConfig error: %v", err)
+			}
+			for tmpl, want := range map[string]string{
+				"error_summary": "global summary",
+				"error_detail":  "service detail",
+			} {
+				got, err := cfg.RenderTemplate(ctx, tmpl, nil)
+				if err != nil {
+					t.Fatalf("RenderTemplate(%s) error: %v", tmpl, err)
+				}
+				if got != want {
+					t.Errorf("RenderTemplate(%s) = %q, want %q", tmpl, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfig_MergePrecedence(t *testing.T) {
+	global := `
+smarterr {
+  merge_precedence = {
+    template = "base"
+  }
+}
+
+token "service" {
+  parameter = "service"
+}
+
+parameter "service" {
+  value = "global"
+}
+
+template "error_summary" {
+  format = "global summary {{.service}}"
+}
+`
+	service := `
+parameter "service" {
+  value = "RDS"
+}
+
+template "error_summary" {
+  format = "service summary {{.service}}"
+}
+
+template "error_detail" {
+  format = "service detail"
+}
+`
+	fsys := &WrappedFS{FS: fstest.MapFS{
+		"smarterr/smarterr.hcl":    &fstest.MapFile{Data: []byte(global)},
+		"service/rds/smarterr.hcl": &fstest.MapFile{Data: []byte(service)},
+	}}
+	ctx := context.Background()
+	cfg, err := LoadConfig(ctx, fsys, []string{"x/y/z/internal/service/rds/instance.go"}, "internal")
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	values := NewRuntime(ctx, cfg, nil).BuildTokenValueMap(ctx)
+	for tmpl, want := range map[string]string{
+		// Global templates win, but local parameters still do
+		"error_summary": "global summary RDS",
+		// Templates only the local config defines are still added
+		"error_detail": "service detail",
+	} {
+		got, err := cfg.RenderTemplate(ctx, tmpl, values)
+		if err != nil {
+			t.Fatalf("RenderTemplate(%s) error: %v", tmpl, err)
+		}
+		if got != want {
+			t.Errorf("RenderTemplate(%s) = %q, want %q", tmpl, got, want)
+		}
+	}
+}
+
+func TestLoadConfig_DisabledInheritedBlocks(t *testing.T) {
+	global := `
+smarterr {
+  token_error_mode = "detailed"
+}
+
+token "identifier" {
+  arg = "id"
+}
+
+token "suggestions" {
+  source = "hints"
+}
+
+hint "throttling" {
+  error_contains = "Throttling"
+  suggestion     = "Retry later."
+}
+
+template "error_detail" {
+  format = "{{.identifier}}: {{.suggestions}}"
+}
+`
+	service := `
+token "identifier" {
+  disabled = true
+}
+
+hint "throttling" {
+  disabled = true
+}
+`
+	fsys := &WrappedFS{FS: fstest.MapFS{
+		"smarterr/smarterr.hcl":    &fstest.MapFile{Data: []byte(global)},
+		"service/rds/smarterr.hcl": &fstest.MapFile{Data: []byte(service)},
+	}}
+	ctx := context.Background()
+	render := func(relStackPath string) string {
+		t.Helper()
+		cfg, err := LoadConfig(ctx, fsys, []string{relStackPath}, "internal")
+		if err != nil {
+			t.Fatalf("LoadConfig error: %v", err)
+		}
+		values := NewRuntime(ctx, cfg, errors.New("Throttling: rate exceeded"), "id", "db-1").BuildTokenValueMap(ctx)
+		got, err := cfg.RenderTemplate(ctx, "error_detail", values)
+		if err != nil {
+			t.Fatalf("RenderTemplate error: %v", err)
+		}
+		return got
+	}
+
+	if got, want := render("x/y/z/internal/service/rds/instance.go"), "[unresolved token: identifier] (token is disabled): [unresolved token: suggestions] (no matching hint found)"; got != want {
+		t.Errorf("with disabled blocks, detail = %q, want %q", got, want)
+	}
+	// Other directories still inherit the blocks
+	if got, want := render("x/y/z/internal/service/ec2/vpc.go"), "db-1: Retry later."; got != want {
+		t.Errorf("without disabled blocks, detail = %q, want %q", got, want)
+	}
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version *int
+		wantErr string
+	}{
+		{name: "unversioned"},
+		{name: "supported", version: intPtr(SchemaVersion)},
+		{name: "too old", version: intPtr(MinSchemaVersion - 1), wantErr: "no longer supported"},
+		{name: "too new", version: intPtr(SchemaVersion + 1), wantErr: "upgrade smarterr"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckSchemaVersion(&Config{Version: tc.version})
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_SchemaVersion(t *testing.T) {
+	var out bytes.Buffer
+	debugMutex.Lock()
+	prevEnabled, prevOutput := globalDebugEnabled, globalDebugOutput
+	globalDebugEnabled, globalDebugOutput = true, &out
+	debugMutex.Unlock()
+	t.Cleanup(func() {
+		debugMutex.Lock()
+		globalDebugEnabled, globalDebugOutput = prevEnabled, prevOutput
+		debugMutex.Unlock()
+	})
+
+	fsys := &WrappedFS{FS: fstest.MapFS{
+		"smarterr/smarterr.hcl":        &fstest.MapFile{Data: []byte("version = 1\ntoken \"foo\" {}")},
+		"service/project/smarterr.hcl": &fstest.MapFile{Data: []byte("version = 99\ntoken \"bar\" {}")},
+	}}
+	cfg, err := LoadConfig(context.Background(), fsys, []string{"x/internal/service/project/a.go"}, "internal")
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	if len(cfg.Tokens) != 2 {
+		t.Errorf("expected the unsupported config to load anyway, got %d tokens", len(cfg.Tokens))
+	}
+	if cfg.Version == nil || *cfg.Version != 99 {
+		t.Errorf("expected merged version 99, got %v", cfg.Version)
+	}
+	if got := out.String(); !strings.Contains(got, "service/project/smarterr.hcl: schema version 99 is newer") {
+		t.Errorf("expected a warning for the unsupported version, got debug output: %q", got)
+	}
+	if strings.Contains(out.String(), "smarterr/smarterr.hcl: schema version") {
+		t.Errorf("unexpected warning for the supported version: %q", out.String())
+	}
+}
+
+func TestParseConfig_JSON(t *testing.T) {
+	hclCfg, err := ParseConfig([]byte(`
+version = 1
+
+smarterr {
+  hint_match_mode = "first"
+}
+
+token "id" {
+  arg        = "id"
+  transforms = ["upper"]
+}
+
+hint "throttling" {
+  error_contains = "Throttling"
+  suggestion     = "Retry later."
+}
+
+transform "upper" {
+  step "upper" {}
+}
+
+template "error_summary" {
+  format = "{{.id}}"
+}
+`), "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("ParseConfig(HCL) error: %v", err)
+	}
+	jsonCfg, err := ParseConfig([]byte(`{
+  "version": 1,
+  "smarterr": {"hint_match_mode": "first"},
+  "token": [{"name": "id", "arg": "id", "transforms": ["upper"]}],
+  "hint": [{"name": "throttling", "error_contains": "Throttling", "suggestion": "Retry later."}],
+  "transform": [{"name": "upper", "step": [{"type": "upper"}]}],
+  "template": [{"name": "error_summary", "format": "{{.id}}"}]
+}`), "smarterr.json")
+	if err != nil {
+		t.Fatalf("ParseConfig(JSON) error: %v", err)
+	}
+	if !reflect.DeepEqual(jsonCfg, hclCfg) {
+		t.Errorf("JSON config = %+v, want the same as HCL, %+v", jsonCfg, hclCfg)
+	}
+
+	for name, src := range map[string]string{
+		"syntax error":  `{"token": [`,
+		"unknown field": `{"token": [{"name": "id", "argument": "id"}]}`,
+		"missing label": `{"token": [{"arg": "id"}]}`,
+		"trailing data": `{} {}`,
+	} {
+		if _, err := ParseConfig([]byte(src), "smarterr.json"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadConfig_JSON(t *testing.T) {
+	fsys := &WrappedFS{FS: fstest.MapFS{
+		"smarterr/smarterr.json":        &fstest.MapFile{Data: []byte(`{"token": [{"name": "global"}]}`)},
+		"service/smarterr.hcl":          &fstest.MapFile{Data: []byte(`token "hcl" {}`)},
+		"service/smarterr.json":         &fstest.MapFile{Data: []byte(`{"token": [{"name": "ignored"}]}`)},
+		"service/project/smarterr.json": &fstest.MapFile{Data: []byte(`{"token": [{"name": "json"}]}`)},
+	}}
+	paths, err := ConfigPathsForStack(context.Background(), fsys, []string{"x/internal/service/project/a.go"}, "internal")
+	if err != nil {
+		t.Fatalf("ConfigPathsForStack error: %v", err)
+	}
+	want := []string{"smarterr/smarterr.json", "service/smarterr.hcl", "service/project/smarterr.json"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+
+	cfg, err := LoadConfig(context.Background(), fsys, []string{"x/internal/service/project/a.go"}, "internal")
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	var names []string
+	for _, tok := range cfg.Tokens {
+		names = append(names, tok.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"global", "hcl", "json"}; !slices.Equal(names, want) {
+		t.Errorf("tokens = %v, want %v", names, want)
+	}
+}
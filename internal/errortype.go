@@ -0,0 +1,56 @@
+// errortype.go
+// Registry of error types that hints match with error_type
+package internal
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// errorTypeRegistry maps the names hints use in error_type to the error types the host registered.
+var (
+	errorTypeRegistryMu sync.RWMutex
+	errorTypeRegistry   = map[string]reflect.Type{}
+)
+
+// RegisterErrorType registers the type of sample under name so a hint's error_type can match it.
+// Registering a name again replaces the earlier registration. It panics if name is empty or sample
+// is nil.
+func RegisterErrorType(name string, sample error) {
+	if name == "" || sample == nil {
+		panic("smarterr: error type registration requires a name and a sample error")
+	}
+	errorTypeRegistryMu.Lock()
+	defer errorTypeRegistryMu.Unlock()
+	errorTypeRegistry[name] = reflect.TypeOf(sample)
+}
+
+// ErrorTypes returns the names of the registered error types, sorted.
+func ErrorTypes() []string {
+	errorTypeRegistryMu.RLock()
+	defer errorTypeRegistryMu.RUnlock()
+	return slices.Sorted(maps.Keys(errorTypeRegistry))
+}
+
+// IsErrorType reports whether name is a registered error type.
+func IsErrorType(name string) bool {
+	errorTypeRegistryMu.RLock()
+	defer errorTypeRegistryMu.RUnlock()
+	_, ok := errorTypeRegistry[name]
+	return ok
+}
+
+// errorMatchesType reports whether err, or an error in its chain, has the type registered as name.
+// An unregistered name matches nothing.
+func errorMatchesType(err error, name string) bool {
+	errorTypeRegistryMu.RLock()
+	typ, ok := errorTypeRegistry[name]
+	errorTypeRegistryMu.RUnlock()
+	if !ok || err == nil {
+		return false
+	}
+	return errors.As(err, reflect.New(typ).Interface())
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+type notFoundError struct{ id string }
+
+func (e *notFoundError) Error() string { return "couldn't find " + e.id }
+
+func TestHintErrorType(t *testing.T) {
+	RegisterErrorType("test_not_found", &notFoundError{})
+	if !slices.Contains(ErrorTypes(), "test_not_found") || !IsErrorType("test_not_found") {
+		t.Fatalf("expected test_not_found to be registered, got %v", ErrorTypes())
+	}
+
+	cfg := &Config{
+		Hints: []Hint{
+			{Name: "not_found", ErrorType: strPtr("test_not_found"), Suggestion: "Check that the resource exists."},
+			{Name: "not_found_vpc", ErrorType: strPtr("test_not_found"), ErrorContains: strPtr("vpc-"), Suggestion: "Check the VPC ID."},
+			{Name: "unregistered", ErrorType: strPtr("test_unregistered"), Suggestion: "Never shown."},
+		},
+	}
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{name: "direct", err: &notFoundError{id: "subnet-1"}, want: []string{"not_found"}},
+		{name: "wrapped", err: fmt.Errorf("reading: %w", &notFoundError{id: "vpc-1"}), want: []string{"not_found", "not_found_vpc"}},
+		{name: "joined", err: errors.Join(errors.New("other"), &notFoundError{id: "subnet-1"}), want: []string{"not_found"}},
+		{name: "other type with same message", err: errors.New("couldn't find vpc-1"), want: nil},
+		{name: "nil", err: nil, want: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := resolveHints(context.Background(), tc.err, cfg).Names; !slices.Equal(got, tc.want) {
+				t.Errorf("resolveHints().Names = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	if got := cfg.MatchingHints(context.Background(), "couldn't find vpc-1"); len(got) != 0 {
+		t.Errorf("MatchingHints() = %q, want no matches for a message", got)
+	}
+}
+
+func TestRegisterErrorType_Invalid(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"empty name": func() { RegisterErrorType("", &notFoundError{}) },
+		"nil sample": func() { RegisterErrorType("test_nil", nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
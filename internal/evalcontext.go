@@ -0,0 +1,55 @@
+// evalcontext.go
+// HCL evaluation context for expressions in smarterr config values
+package internal
+
+import (
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// EnvVarPrefix is the prefix of environment variables exposed to config expressions as env.<NAME>.
+// Other environment variables are hidden so config can't leak secrets into diagnostics.
+const EnvVarPrefix = "SMARTERR_"
+
+// configFunctions are the HCL functions available in config expressions. They are limited to pure
+// string and collection functions; nothing reads files, runs commands, or reaches the network.
+var configFunctions = map[string]function.Function{
+	"coalesce":   stdlib.CoalesceFunc,
+	"concat":     stdlib.ConcatFunc,
+	"format":     stdlib.FormatFunc,
+	"join":       stdlib.JoinFunc,
+	"length":     stdlib.LengthFunc,
+	"lower":      stdlib.LowerFunc,
+	"replace":    stdlib.ReplaceFunc,
+	"split":      stdlib.SplitFunc,
+	"title":      stdlib.TitleFunc,
+	"trim":       stdlib.TrimFunc,
+	"trimprefix": stdlib.TrimPrefixFunc,
+	"trimspace":  stdlib.TrimSpaceFunc,
+	"trimsuffix": stdlib.TrimSuffixFunc,
+	"upper":      stdlib.UpperFunc,
+}
+
+// configEvalContext returns the HCL evaluation context used to decode config files. It provides
+// the curated configFunctions and an env variable holding environment variables prefixed with
+// EnvVarPrefix.
+func configEvalContext() *hcl.EvalContext {
+	env := make(map[string]cty.Value)
+	for _, kv := range os.Environ() {
+		name, value, ok := strings.Cut(kv, "=")
+		if ok && strings.HasPrefix(name, EnvVarPrefix) {
+			env[name] = cty.StringVal(value)
+		}
+	}
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"env": cty.ObjectVal(env),
+		},
+		Functions: configFunctions,
+	}
+}
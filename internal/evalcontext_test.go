@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadConfigFile_Expressions(t *testing.T) {
+	t.Setenv("SMARTERR_PROVIDER", "aws")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	fsys := &WrappedFS{FS: fstest.MapFS{
+		"smarterr.hcl": &fstest.MapFile{Data: []byte(`
+parameter "service" {
+  value = upper("rds")
+}
+
+parameter "services" {
+  value = join(", ", ["rds", "ec2"])
+}
+
+parameter "provider" {
+  value = "terraform-provider-${env.SMARTERR_PROVIDER}"
+}
+`)},
+		"secret/smarterr.hcl": &fstest.MapFile{Data: []byte(`
+parameter "secret" {
+  value = env.AWS_SECRET_ACCESS_KEY
+}
+`)},
+		"unsafe/smarterr.hcl": &fstest.MapFile{Data: []byte(`
+parameter "file" {
+  value = file("/etc/passwd")
+}
+`)},
+	}}
+	ctx := context.Background()
+
+	cfg, err := LoadConfigFile(ctx, fsys, "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("LoadConfigFile error: %v", err)
+	}
+	want := map[string]string{
+		"service":  "RDS",
+		"services": "rds, ec2",
+		"provider": "terraform-provider-aws",
+	}
+	for _, p := range cfg.Parameters {
+		if p.Text() != want[p.Name] {
+			t.Errorf("parameter %q = %q, want %q", p.Name, p.Text(), want[p.Name])
+		}
+	}
+
+	if _, err := LoadConfigFile(ctx, fsys, "secret/smarterr.hcl"); err == nil || !strings.Contains(err.Error(), "AWS_SECRET_ACCESS_KEY") {
+		t.Errorf("expected unprefixed env var to be unavailable, got: %v", err)
+	}
+	if _, err := LoadConfigFile(ctx, fsys, "unsafe/smarterr.hcl"); err == nil || !strings.Contains(err.Error(), "file") {
+		t.Errorf("expected unsafe function to be unavailable, got: %v", err)
+	}
+}
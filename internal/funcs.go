@@ -0,0 +1,118 @@
+// funcs.go
+// Template functions available to smarterr templates
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// irregularPlurals maps singular nouns to plural forms that don't follow the suffix rules in plural.
+var irregularPlurals = map[string]string{
+	"child":  "children",
+	"index":  "indices",
+	"matrix": "matrices",
+	"person": "people",
+}
+
+// TemplateFuncs returns the functions available to templates, such as pluralize. Functions that
+// take a value take it last, so it can be piped, as in {{.service | upper}}.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"pluralize":  Pluralize,
+		"join":       Join,
+		"upper":      func(value any) string { return strings.ToUpper(fmt.Sprint(value)) },
+		"lower":      func(value any) string { return strings.ToLower(fmt.Sprint(value)) },
+		"title":      func(value any) string { return Title(fmt.Sprint(value)) },
+		"trim":       func(value any) string { return strings.TrimSpace(fmt.Sprint(value)) },
+		"trimPrefix": func(prefix string, value any) string { return strings.TrimPrefix(fmt.Sprint(value), prefix) },
+		"trimSuffix": func(suffix string, value any) string { return strings.TrimSuffix(fmt.Sprint(value), suffix) },
+		"default":    Default,
+	}
+}
+
+// NewTemplate returns a new template with smarterr's template functions.
+func NewTemplate(name string) *template.Template {
+	return template.New(name).Funcs(TemplateFuncs())
+}
+
+// Join joins the elements of list, such as a list parameter's values, with sep. Elements that
+// aren't strings are formatted with %v. A list that isn't a []string or []any, such as a string, is
+// formatted with %v.
+func Join(list any, sep string) string {
+	switch l := list.(type) {
+	case []string:
+		return strings.Join(l, sep)
+	case []any:
+		parts := make([]string, len(l))
+		for i, v := range l {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, sep)
+	}
+	return fmt.Sprint(list)
+}
+
+// Pluralize returns the count followed by the singular or plural form of word, e.g.,
+// "1 subnet" or "3 subnets". Since token values are usually strings, count may be any value
+// whose text is an integer; otherwise, word is pluralized without a count.
+func Pluralize(count any, word string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(count)))
+	if err != nil {
+		return plural(word)
+	}
+	if n == 1 || n == -1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %s", n, plural(word))
+}
+
+// plural returns the plural form of a singular noun.
+func plural(word string) string {
+	if p, ok := irregularPlurals[word]; ok {
+		return p
+	}
+	lower := strings.ToLower(word)
+	for _, suffix := range []string{"s", "x", "z", "ch", "sh"} {
+		if strings.HasSuffix(lower, suffix) {
+			return word + "es"
+		}
+	}
+	if len(lower) > 1 && strings.HasSuffix(lower, "y") && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])) {
+		return word[:len(word)-1] + "ies"
+	}
+	return word + "s"
+}
+
+// Title returns s with the first letter of each space-separated word in upper case, leaving the
+// rest of each word as is, e.g., "elastic IP address" becomes "Elastic IP Address".
+func Title(s string) string {
+	words := strings.Split(s, " ")
+	for i, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		if size > 0 {
+			words[i] = string(unicode.ToUpper(r)) + word[size:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Default returns value, or def if value is empty: nil, an empty string, or an empty list or map.
+// A token that doesn't resolve is empty unless token_error_mode makes it a placeholder.
+func Default(def, value any) any {
+	if value == nil {
+		return def
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	}
+	return value
+}
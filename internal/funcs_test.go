@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestPluralize(t *testing.T) {
+	tests := []struct {
+		count any
+		word  string
+		want  string
+	}{
+		{count: 0, word: "subnet", want: "0 subnets"},
+		{count: 1, word: "subnet", want: "1 subnet"},
+		{count: 2, word: "subnet", want: "2 subnets"},
+		{count: "1", word: "subnet", want: "1 subnet"},
+		{count: "3", word: "address", want: "3 addresses"},
+		{count: 2, word: "policy", want: "2 policies"},
+		{count: 2, word: "key", want: "2 keys"},
+		{count: 2, word: "child", want: "2 children"},
+		{count: 1, word: "child", want: "1 child"},
+		{count: "many", word: "subnet", want: "subnets"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := Pluralize(tc.count, tc.word); got != tc.want {
+				t.Errorf("pluralize(%v, %q) = %q, want %q", tc.count, tc.word, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		list any
+		want string
+	}{
+		{list: []string{"a", "b", "c"}, want: "a, b, c"},
+		{list: []string{}, want: ""},
+		{list: []any{"a", 2}, want: "a, 2"},
+		{list: "single", want: "single"},
+	}
+	for _, tc := range tests {
+		if got := Join(tc.list, ", "); got != tc.want {
+			t.Errorf("Join(%#v) = %q, want %q", tc.list, got, tc.want)
+		}
+	}
+}
+
+func TestConfig_RenderTemplate_Pluralize(t *testing.T) {
+	cfg := &Config{
+		Templates: []Template{{
+			Name:   "error_summary",
+			Format: `deleting {{pluralize .count "subnet"}}`,
+		}},
+	}
+	for count, want := range map[string]string{
+		"0": "deleting 0 subnets",
+		"1": "deleting 1 subnet",
+		"2": "deleting 2 subnets",
+	} {
+		out, err := cfg.RenderTemplate(context.Background(), "error_summary", map[string]any{"count": count})
+		if err != nil {
+			t.Fatalf("RenderTemplate error: %v", err)
+		}
+		if out != want {
+			t.Errorf("RenderTemplate output = %q, want %q", out, want)
+		}
+	}
+
+	tmpl, err := NewTemplate("vars").Parse(cfg.Templates[0].Format)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if vars := CollectTemplateVariables(tmpl); len(vars) != 1 || vars[0] != "count" {
+		t.Errorf("CollectTemplateVariables() = %v, want [count]", vars)
+	}
+}
+
+func TestConfig_RenderTemplate_StringFuncs(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: `{{ upper .service_name }}`, want: "EC2 VPC"},
+		{format: `{{ .service_name | lower }}`, want: "ec2 vpc"},
+		{format: `{{ title .happening }}`, want: "Creating Elastic IP"},
+		{format: `[{{ trim .padded }}]`, want: "[vpc-123]"},
+		{format: `{{ trimPrefix "arn:" .arn }}`, want: "aws:ec2"},
+		{format: `{{ .arn | trimSuffix ":ec2" }}`, want: "arn:aws"},
+		{format: `{{ default "unknown" .id }}`, want: "unknown"},
+		{format: `{{ default "unknown" .service_name }}`, want: "EC2 VPC"},
+		{format: `{{ default "none" .codes }}`, want: "none"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			cfg := &Config{Templates: []Template{{Name: "error_summary", Format: tc.format}}}
+			values := map[string]any{
+				"service_name": "EC2 VPC",
+				"happening":    "creating elastic IP",
+				"padded":       "  vpc-123 ",
+				"arn":          "arn:aws:ec2",
+				"codes":        []string{},
+			}
+			out, err := cfg.RenderTemplate(context.Background(), "error_summary", values)
+			if err != nil {
+				t.Fatalf("RenderTemplate error: %v", err)
+			}
+			if out != tc.want {
+				t.Errorf("RenderTemplate output = %q, want %q", out, tc.want)
+			}
+		})
+	}
+
+	tmpl, err := NewTemplate("vars").Parse(`{{ upper .service_name }} {{ default "unknown" .id | lower }}`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	vars := CollectTemplateVariables(tmpl)
+	slices.Sort(vars)
+	if want := []string{"id", "service_name"}; !slices.Equal(vars, want) {
+		t.Errorf("CollectTemplateVariables() = %v, want %v", vars, want)
+	}
+}
@@ -0,0 +1,95 @@
+// locale.go
+// Locale selection for localized template and hint variants
+package internal
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// LocaleSeparator separates a template or hint name from the locale of a variant, as in
+// error_detail.ja.
+const LocaleSeparator = "."
+
+var localeCtxKey = ContextKey("smarterr:locale")
+
+// WithLocale returns a context that selects template and hint variants for locale, such as "ja"
+// or "pt-BR".
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeCtxKey, locale)
+}
+
+// localeFromContext returns the locale set with WithLocale, or "" if none.
+func localeFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeCtxKey).(string)
+	return locale
+}
+
+// localeNames returns the variant names to try for name in locale, most specific first. For
+// locale pt-BR, these are name.pt-BR and name.pt. It returns nil if locale is empty.
+func localeNames(name, locale string) []string {
+	if locale == "" {
+		return nil
+	}
+	names := []string{name + LocaleSeparator + locale}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		names = append(names, name+LocaleSeparator+locale[:i])
+	}
+	return names
+}
+
+// localePattern matches a locale that looks like a language tag, such as ja, pt-BR, or zh_Hant.
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}([-_][A-Za-z]{2,4})?$`)
+
+// SplitLocale splits a variant name, such as error_detail.ja, into its base name and locale. It
+// reports false if name has no locale suffix. Only a suffix that looks like a language tag is a
+// locale, so a dotted name such as ec2.throttling isn't a variant of ec2.
+func SplitLocale(name string) (base, locale string, ok bool) {
+	i := strings.LastIndex(name, LocaleSeparator)
+	if i <= 0 || !localePattern.MatchString(name[i+1:]) {
+		return "", "", false
+	}
+	return name[:i], name[i+1:], true
+}
+
+// findTemplate returns the template named name, preferring the variant for the context's locale.
+// It returns nil if there's no such template.
+func (cfg *Config) findTemplate(ctx context.Context, name string) *Template {
+	for _, candidate := range append(localeNames(name, localeFromContext(ctx)), name) {
+		if i := slices.IndexFunc(cfg.Templates, func(t Template) bool { return t.Name == candidate }); i >= 0 {
+			return &cfg.Templates[i]
+		}
+	}
+	return nil
+}
+
+// IsHintVariant reports whether the hint named name is a locale variant of another hint in cfg,
+// such as throttling.ja for throttling. Variants supply localized suggestions for their base hint
+// and aren't matched on their own.
+func (cfg *Config) IsHintVariant(name string) bool {
+	base, _, ok := SplitLocale(name)
+	if !ok {
+		return false
+	}
+	for _, h := range cfg.Hints {
+		if h.Name == base {
+			return true
+		}
+	}
+	return false
+}
+
+// localizedSuggestions returns the suggestions of hint's variant for the context's locale, or the
+// hint's own suggestions if it has no variant with suggestions.
+func (cfg *Config) localizedSuggestions(ctx context.Context, hint Hint) []string {
+	for _, name := range localeNames(hint.Name, localeFromContext(ctx)) {
+		for _, h := range cfg.Hints {
+			if h.Name == name && len(h.SuggestionLines()) > 0 {
+				return h.SuggestionLines()
+			}
+		}
+	}
+	return hint.SuggestionLines()
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRenderTemplate_Locale(t *testing.T) {
+	cfg := &Config{
+		Templates: []Template{
+			{Name: "error_detail", Format: "failed: {{.id}}"},
+			{Name: "error_detail.ja", Format: "失敗しました: {{.id}}"},
+			{Name: "error_detail.pt-BR", Format: "falhou: {{.id}}"},
+		},
+	}
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{locale: "", want: "failed: vpc-1"},
+		{locale: "ja", want: "失敗しました: vpc-1"},
+		{locale: "ja-JP", want: "失敗しました: vpc-1"},
+		{locale: "pt-BR", want: "falhou: vpc-1"},
+		{locale: "pt", want: "failed: vpc-1"},
+		{locale: "fr", want: "failed: vpc-1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.locale, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.locale != "" {
+				ctx = WithLocale(ctx, tc.locale)
+			}
+			got, err := cfg.RenderTemplate(ctx, "error_detail", map[string]any{"id": "vpc-1"})
+			if err != nil {
+				t.Fatalf("RenderTemplate error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveHints_Locale(t *testing.T) {
+	contains := "Throttling"
+	cfg := &Config{
+		Hints: []Hint{
+			{Name: "throttling", ErrorContains: &contains, Suggestion: "Retry later."},
+			{Name: "throttling.ja", Suggestion: "後で再試行してください。"},
+			{Name: "throttling.de", ErrorContains: &contains},
+			// Not a variant: the suffix isn't a language tag
+			{Name: "throttling.quota", ErrorContains: &contains, Suggestion: "Request a quota increase."},
+		},
+	}
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{locale: "", want: "Retry later.\nRequest a quota increase."},
+		{locale: "ja", want: "後で再試行してください。\nRequest a quota increase."},
+		{locale: "ja_JP", want: "後で再試行してください。\nRequest a quota increase."},
+		{locale: "de", want: "Retry later.\nRequest a quota increase."}, // variant without suggestions
+		{locale: "fr", want: "Retry later.\nRequest a quota increase."},
+	}
+	for _, tc := range tests {
+		t.Run(tc.locale, func(t *testing.T) {
+			ctx := WithLocale(context.Background(), tc.locale)
+			if got := resolveHints(ctx, errors.New("Throttling: slow down"), cfg).Suggestions; got != tc.want {
+				t.Errorf("resolveHints() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSplitLocale(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantBase   string
+		wantLocale string
+		wantOK     bool
+	}{
+		{name: "error_detail.ja", wantBase: "error_detail", wantLocale: "ja", wantOK: true},
+		{name: "error_detail.pt-BR", wantBase: "error_detail", wantLocale: "pt-BR", wantOK: true},
+		{name: "error_detail"},
+		{name: ".ja"},
+		{name: "error_detail."},
+		{name: "ec2.throttling"},
+		{name: "error_detail.JA"},
+	}
+	for _, tc := range tests {
+		base, locale, ok := SplitLocale(tc.name)
+		if base != tc.wantBase || locale != tc.wantLocale || ok != tc.wantOK {
+			t.Errorf("SplitLocale(%q) = %q, %q, %t, want %q, %q, %t", tc.name, base, locale, ok, tc.wantBase, tc.wantLocale, tc.wantOK)
+		}
+	}
+}
@@ -2,7 +2,11 @@
 // Config merging logic for smarterr
 package internal
 
-import "context"
+import (
+	"context"
+	"maps"
+	"slices"
+)
 
 // mergeConfigs merges a slice of Configs, from least to most specific.
 func mergeConfigs(ctx context.Context, configs []*Config) *Config {
@@ -11,18 +15,42 @@ func mergeConfigs(ctx context.Context, configs []*Config) *Config {
 	if len(configs) == 0 {
 		return &Config{}
 	}
-	merged := configs[0]
+	// Merge into a copy, since the configs may be cached
+	merged := configs[0].clone()
 	for i := 1; i < len(configs); i++ {
 		mergeConfigsPair(merged, configs[i])
 	}
 	return merged
 }
 
+// clone returns a copy of cfg that merging can modify without changing cfg. Blocks are copied by
+// value, so only the slices and maps merging writes to are cloned.
+func (cfg *Config) clone() *Config {
+	c := *cfg
+	c.Tokens = slices.Clone(cfg.Tokens)
+	c.Hints = slices.Clone(cfg.Hints)
+	c.Parameters = slices.Clone(cfg.Parameters)
+	c.StackMatches = slices.Clone(cfg.StackMatches)
+	c.Templates = slices.Clone(cfg.Templates)
+	c.Transforms = slices.Clone(cfg.Transforms)
+	if cfg.Smarterr != nil {
+		s := *cfg.Smarterr
+		s.MergePrecedence = maps.Clone(cfg.Smarterr.MergePrecedence)
+		c.Smarterr = &s
+	}
+	return &c
+}
+
 // mergeConfigsPair merges two Config objects: add takes precedence over base.
 //
-// - Smarterr (debug, token_error_mode) is overwritten by add if set.
-// - Tokens, Hints, Parameters, StackMatches, Templates, and Transforms are merged by name (add replaces base).
+// - Smarterr settings (e.g., debug, token_error_mode, hint_match_mode) are overwritten by add if set, even to ""; merge_precedence is merged by block type.
+// - Tokens, Hints, Parameters, StackMatches, Templates, and Transforms are merged by name (add replaces base, unless merge_precedence is "base" for the block type).
 func mergeConfigsPair(base *Config, add *Config) {
+	// Keep the highest schema version, since the merged config uses every layer's features
+	if add.Version != nil && (base.Version == nil || *add.Version > *base.Version) {
+		base.Version = add.Version
+	}
+
 	// Overwrite Smarterr fields if set in add
 	if add.Smarterr != nil {
 		if base.Smarterr == nil {
@@ -31,92 +59,74 @@ func mergeConfigsPair(base *Config, add *Config) {
 		if add.Smarterr.Debug {
 			base.Smarterr.Debug = true
 		}
-		if add.Smarterr.TokenErrorMode != nil && *add.Smarterr.TokenErrorMode != "" {
+		if add.Smarterr.TokenErrorMode != nil {
 			base.Smarterr.TokenErrorMode = add.Smarterr.TokenErrorMode
 		}
-	}
-
-	// Merge tokens by name (add replaces base)
-	tokenMap := make(map[string]int)
-	for i, t := range base.Tokens {
-		tokenMap[t.Name] = i
-	}
-	for _, t := range add.Tokens {
-		if i, ok := tokenMap[t.Name]; ok {
-			base.Tokens[i] = t
-		} else {
-			base.Tokens = append(base.Tokens, t)
+		if add.Smarterr.HintJoinChar != nil {
+			base.Smarterr.HintJoinChar = add.Smarterr.HintJoinChar
 		}
-		tokenMap[t.Name] = len(base.Tokens) - 1
-	}
-
-	// Merge hints by name (add replaces base)
-	hintMap := make(map[string]int)
-	for i, h := range base.Hints {
-		hintMap[h.Name] = i
-	}
-	for _, h := range add.Hints {
-		if i, ok := hintMap[h.Name]; ok {
-			base.Hints[i] = h
-		} else {
-			base.Hints = append(base.Hints, h)
+		if add.Smarterr.HintMatchMode != nil {
+			base.Smarterr.HintMatchMode = add.Smarterr.HintMatchMode
 		}
-		hintMap[h.Name] = len(base.Hints) - 1
-	}
-
-	// Merge parameters by name (add replaces base)
-	paramMap := make(map[string]int)
-	for i, p := range base.Parameters {
-		paramMap[p.Name] = i
-	}
-	for _, p := range add.Parameters {
-		if i, ok := paramMap[p.Name]; ok {
-			base.Parameters[i] = p
-		} else {
-			base.Parameters = append(base.Parameters, p)
+		if add.Smarterr.HintSeparator != nil {
+			base.Smarterr.HintSeparator = add.Smarterr.HintSeparator
 		}
-		paramMap[p.Name] = len(base.Parameters) - 1
-	}
-
-	// Merge stack matches by name (add replaces base)
-	stackMatchMap := make(map[string]int)
-	for i, sm := range base.StackMatches {
-		stackMatchMap[sm.Name] = i
-	}
-	for _, sm := range add.StackMatches {
-		if i, ok := stackMatchMap[sm.Name]; ok {
-			base.StackMatches[i] = sm
-		} else {
-			base.StackMatches = append(base.StackMatches, sm)
+		if add.Smarterr.HintMaxSuggestions != nil {
+			base.Smarterr.HintMaxSuggestions = add.Smarterr.HintMaxSuggestions
 		}
-		stackMatchMap[sm.Name] = len(base.StackMatches) - 1
-	}
-
-	// Merge templates by name (add replaces base)
-	tmplMap := make(map[string]int)
-	for i, tmpl := range base.Templates {
-		tmplMap[tmpl.Name] = i
-	}
-	for _, tmpl := range add.Templates {
-		if i, ok := tmplMap[tmpl.Name]; ok {
-			base.Templates[i] = tmpl
-		} else {
-			base.Templates = append(base.Templates, tmpl)
+		if add.Smarterr.HintDedup != nil {
+			base.Smarterr.HintDedup = add.Smarterr.HintDedup
+		}
+		if add.Smarterr.DuplicateKeyvalMode != nil {
+			base.Smarterr.DuplicateKeyvalMode = add.Smarterr.DuplicateKeyvalMode
+		}
+		if add.Smarterr.MaxDetailLength != nil {
+			base.Smarterr.MaxDetailLength = add.Smarterr.MaxDetailLength
+		}
+		if add.Smarterr.AppendOriginalDetail {
+			base.Smarterr.AppendOriginalDetail = true
+		}
+		if add.Smarterr.AppendErrorCode {
+			base.Smarterr.AppendErrorCode = true
+		}
+		if add.Smarterr.LogFields != nil {
+			base.Smarterr.LogFields = add.Smarterr.LogFields
+		}
+		for blockType, precedence := range add.Smarterr.MergePrecedence {
+			if base.Smarterr.MergePrecedence == nil {
+				base.Smarterr.MergePrecedence = map[string]string{}
+			}
+			base.Smarterr.MergePrecedence[blockType] = precedence
 		}
-		tmplMap[tmpl.Name] = len(base.Templates) - 1
 	}
 
-	// Merge transforms by name (add replaces base)
-	trMap := make(map[string]int)
-	for i, tr := range base.Transforms {
-		trMap[tr.Name] = i
+	// Merge blocks by name; add replaces base unless smarterr.merge_precedence says base wins
+	precedence := base.Smarterr.MergePrecedenceFor
+	base.Tokens = mergeByName(base.Tokens, add.Tokens, func(t Token) string { return t.Name }, precedence("token"))
+	base.Hints = mergeByName(base.Hints, add.Hints, func(h Hint) string { return h.Name }, precedence("hint"))
+	base.Parameters = mergeByName(base.Parameters, add.Parameters, func(p Parameter) string { return p.Name }, precedence("parameter"))
+	base.StackMatches = mergeByName(base.StackMatches, add.StackMatches, func(sm StackMatch) string { return sm.Name }, precedence("stack_match"))
+	base.Templates = mergeByName(base.Templates, add.Templates, func(tmpl Template) string { return tmpl.Name }, precedence("template"))
+	base.Transforms = mergeByName(base.Transforms, add.Transforms, func(tr Transform) string { return tr.Name }, precedence("transform"))
+}
+
+// mergeByName merges add's blocks into base's by name. A block whose name isn't in base is
+// appended. A block whose name is replaces base's, unless precedence is MergePrecedenceBase, in
+// which case base's block is kept.
+func mergeByName[T any](base, add []T, name func(T) string, precedence string) []T {
+	index := make(map[string]int, len(base))
+	for i, b := range base {
+		index[name(b)] = i
 	}
-	for _, tr := range add.Transforms {
-		if i, ok := trMap[tr.Name]; ok {
-			base.Transforms[i] = tr
-		} else {
-			base.Transforms = append(base.Transforms, tr)
+	for _, a := range add {
+		i, ok := index[name(a)]
+		switch {
+		case !ok:
+			index[name(a)] = len(base)
+			base = append(base, a)
+		case precedence != MergePrecedenceBase:
+			base[i] = a
 		}
-		trMap[tr.Name] = len(base.Transforms) - 1
 	}
+	return base
 }
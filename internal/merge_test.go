@@ -25,8 +25,8 @@ func TestMergeConfigs(t *testing.T) {
 					{Name: "hint1", Suggestion: "value1"},
 				},
 				Parameters: []Parameter{
-					{Name: "param1", Value: "value1"},
-					{Name: "param2", Value: "value2"},
+					{Name: "param1", Value: strPtr("value1")},
+					{Name: "param2", Value: strPtr("value2")},
 				},
 				StackMatches: []StackMatch{
 					{Name: "match1", CalledFrom: "func1", Display: "Match 1"},
@@ -47,8 +47,8 @@ func TestMergeConfigs(t *testing.T) {
 					{Name: "hint1", Suggestion: "value1"},
 				},
 				Parameters: []Parameter{
-					{Name: "param1", Value: "value1"},
-					{Name: "param2", Value: "value2"},
+					{Name: "param1", Value: strPtr("value1")},
+					{Name: "param2", Value: strPtr("value2")},
 				},
 				StackMatches: []StackMatch{
 					{Name: "match1", CalledFrom: "func1", Display: "Match 1"},
@@ -67,7 +67,7 @@ func TestMergeConfigs(t *testing.T) {
 			base: Config{
 				Tokens:       []Token{{Name: "token1", Source: "base"}},
 				Hints:        []Hint{{Name: "hint1", Suggestion: "base"}},
-				Parameters:   []Parameter{{Name: "param1", Value: "base"}},
+				Parameters:   []Parameter{{Name: "param1", Value: strPtr("base")}},
 				StackMatches: []StackMatch{{Name: "match1", CalledFrom: "base", Display: "base"}},
 				Templates:    []Template{{Name: "tmpl1", Format: "base"}},
 				Transforms:   []Transform{{Name: "tr1", Steps: []TransformStep{{Type: "upper"}}}},
@@ -75,7 +75,7 @@ func TestMergeConfigs(t *testing.T) {
 			add: Config{
 				Tokens:       []Token{{Name: "token1", Source: "add"}},
 				Hints:        []Hint{{Name: "hint1", Suggestion: "add"}},
-				Parameters:   []Parameter{{Name: "param1", Value: "add"}},
+				Parameters:   []Parameter{{Name: "param1", Value: strPtr("add")}},
 				StackMatches: []StackMatch{{Name: "match1", CalledFrom: "add", Display: "add"}},
 				Templates:    []Template{{Name: "tmpl1", Format: "add"}},
 				Transforms:   []Transform{{Name: "tr1", Steps: []TransformStep{{Type: "lower"}}}},
@@ -83,13 +83,45 @@ func TestMergeConfigs(t *testing.T) {
 			expected: Config{
 				Tokens:       []Token{{Name: "token1", Source: "add"}},
 				Hints:        []Hint{{Name: "hint1", Suggestion: "add"}},
-				Parameters:   []Parameter{{Name: "param1", Value: "add"}},
+				Parameters:   []Parameter{{Name: "param1", Value: strPtr("add")}},
 				StackMatches: []StackMatch{{Name: "match1", CalledFrom: "add", Display: "add"}},
 				Templates:    []Template{{Name: "tmpl1", Format: "add"}},
 				Transforms:   []Transform{{Name: "tr1", Steps: []TransformStep{{Type: "lower"}}}},
 			},
 			description: "Should overwrite by name for all blocks",
 		},
+		{
+			name:        "List parameter replaces value parameter",
+			base:        Config{Parameters: []Parameter{{Name: "codes", Value: strPtr("Throttling")}}},
+			add:         Config{Parameters: []Parameter{{Name: "codes", Values: []string{"Throttling", "RequestLimitExceeded"}}}},
+			expected:    Config{Parameters: []Parameter{{Name: "codes", Values: []string{"Throttling", "RequestLimitExceeded"}}}},
+			description: "Should replace a parameter by name whether it sets value or values",
+		},
+		{
+			name: "Merge precedence base keeps base blocks",
+			base: Config{
+				Smarterr:   &Smarterr{MergePrecedence: map[string]string{"template": "base", "hint": "base"}},
+				Templates:  []Template{{Name: "tmpl1", Format: "base"}},
+				Tokens:     []Token{{Name: "token1", Source: "base"}},
+				Hints:      []Hint{{Name: "hint1", Suggestion: "base"}},
+				Parameters: []Parameter{{Name: "param1", Value: strPtr("base")}},
+			},
+			add: Config{
+				Smarterr:   &Smarterr{MergePrecedence: map[string]string{"hint": "local"}},
+				Templates:  []Template{{Name: "tmpl1", Format: "add"}, {Name: "tmpl2", Format: "add"}},
+				Tokens:     []Token{{Name: "token1", Source: "add"}},
+				Hints:      []Hint{{Name: "hint1", Suggestion: "add"}},
+				Parameters: []Parameter{{Name: "param1", Value: strPtr("add")}},
+			},
+			expected: Config{
+				Smarterr:   &Smarterr{MergePrecedence: map[string]string{"template": "base", "hint": "local"}},
+				Templates:  []Template{{Name: "tmpl1", Format: "base"}, {Name: "tmpl2", Format: "add"}},
+				Tokens:     []Token{{Name: "token1", Source: "add"}},
+				Hints:      []Hint{{Name: "hint1", Suggestion: "add"}},
+				Parameters: []Parameter{{Name: "param1", Value: strPtr("add")}},
+			},
+			description: "Should keep base templates, add new ones, and let add change a block type's precedence",
+		},
 		{
 			name:        "Merge Smarterr debug and token_error_mode",
 			base:        Config{Smarterr: &Smarterr{Debug: false, TokenErrorMode: strPtr("detailed")}},
@@ -97,13 +129,55 @@ func TestMergeConfigs(t *testing.T) {
 			expected:    Config{Smarterr: &Smarterr{Debug: true, TokenErrorMode: strPtr("placeholder")}},
 			description: "Should overwrite Smarterr debug and token_error_mode",
 		},
+		{
+			name:        "Merge Smarterr hint_join_char and hint_match_mode",
+			base:        Config{Smarterr: &Smarterr{HintJoinChar: strPtr("\n"), HintMatchMode: strPtr("all")}},
+			add:         Config{Smarterr: &Smarterr{HintJoinChar: strPtr(" "), HintMatchMode: strPtr("first")}},
+			expected:    Config{Smarterr: &Smarterr{HintJoinChar: strPtr(" "), HintMatchMode: strPtr("first")}},
+			description: "Should overwrite Smarterr hint_join_char and hint_match_mode",
+		},
+		{
+			name:        "Keep base hint settings when add leaves them unset",
+			base:        Config{Smarterr: &Smarterr{HintJoinChar: strPtr(" "), HintMatchMode: strPtr("first")}},
+			add:         Config{Smarterr: &Smarterr{Debug: true}},
+			expected:    Config{Smarterr: &Smarterr{Debug: true, HintJoinChar: strPtr(" "), HintMatchMode: strPtr("first")}},
+			description: "Should keep base hint_join_char and hint_match_mode when unset in add",
+		},
+		{
+			name:        "Overwrite modes with an explicit empty string",
+			base:        Config{Smarterr: &Smarterr{TokenErrorMode: strPtr("placeholder"), HintMatchMode: strPtr("first"), DuplicateKeyvalMode: strPtr("collect")}},
+			add:         Config{Smarterr: &Smarterr{TokenErrorMode: strPtr(""), HintMatchMode: strPtr(""), DuplicateKeyvalMode: strPtr("")}},
+			expected:    Config{Smarterr: &Smarterr{TokenErrorMode: strPtr(""), HintMatchMode: strPtr(""), DuplicateKeyvalMode: strPtr("")}},
+			description: "Should overwrite modes set in add, like every other pointer setting",
+		},
+		{
+			name:        "Keep append_original_detail enabled in base",
+			base:        Config{Smarterr: &Smarterr{AppendOriginalDetail: true}},
+			add:         Config{Smarterr: &Smarterr{Debug: true}},
+			expected:    Config{Smarterr: &Smarterr{Debug: true, AppendOriginalDetail: true}},
+			description: "Should keep append_original_detail like debug, since either layer can enable it",
+		},
+		{
+			name:        "Merge Smarterr log_fields",
+			base:        Config{Smarterr: &Smarterr{LogFields: []string{"service", "identifier"}}},
+			add:         Config{Smarterr: &Smarterr{LogFields: []string{}}},
+			expected:    Config{Smarterr: &Smarterr{LogFields: []string{}}},
+			description: "Should replace log_fields when add sets it, even to an empty list",
+		},
+		{
+			name:        "Keep base log_fields when add leaves it unset",
+			base:        Config{Smarterr: &Smarterr{LogFields: []string{"service"}}},
+			add:         Config{Smarterr: &Smarterr{Debug: true}},
+			expected:    Config{Smarterr: &Smarterr{Debug: true, LogFields: []string{"service"}}},
+			description: "Should keep base log_fields when unset in add",
+		},
 		{
 			name: "No changes when add is empty",
 			base: Config{
 				Smarterr:     &Smarterr{Debug: true, TokenErrorMode: strPtr("detailed")},
 				Tokens:       []Token{{Name: "token1", Source: "base"}},
 				Hints:        []Hint{{Name: "hint1", Suggestion: "base"}},
-				Parameters:   []Parameter{{Name: "param1", Value: "base"}},
+				Parameters:   []Parameter{{Name: "param1", Value: strPtr("base")}},
 				StackMatches: []StackMatch{{Name: "match1", CalledFrom: "base", Display: "base"}},
 				Templates:    []Template{{Name: "tmpl1", Format: "base"}},
 				Transforms:   []Transform{{Name: "tr1", Steps: []TransformStep{{Type: "upper"}}}},
@@ -113,7 +187,7 @@ func TestMergeConfigs(t *testing.T) {
 				Smarterr:     &Smarterr{Debug: true, TokenErrorMode: strPtr("detailed")},
 				Tokens:       []Token{{Name: "token1", Source: "base"}},
 				Hints:        []Hint{{Name: "hint1", Suggestion: "base"}},
-				Parameters:   []Parameter{{Name: "param1", Value: "base"}},
+				Parameters:   []Parameter{{Name: "param1", Value: strPtr("base")}},
 				StackMatches: []StackMatch{{Name: "match1", CalledFrom: "base", Display: "base"}},
 				Templates:    []Template{{Name: "tmpl1", Format: "base"}},
 				Transforms:   []Transform{{Name: "tr1", Steps: []TransformStep{{Type: "upper"}}}},
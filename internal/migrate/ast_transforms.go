@@ -6,28 +6,29 @@ import (
 	"go/parser"
 	"go/token"
 	"slices"
+	"strconv"
 	"strings"
 )
 
 // replaceSDKResourceNotFoundAST uses AST to transform SDK v2 resource not found patterns
-func replaceSDKResourceNotFoundAST(content string) string {
+func replaceSDKResourceNotFoundAST(content string) (string, int) {
 	fset := token.NewFileSet()
 	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
 	if err != nil {
 		// Fallback to original content if parsing fails
-		return content
+		return content, 0
 	}
 
 	transformer := &sdkResourceNotFoundTransformer{}
 	ast.Walk(transformer, file)
 
-	if !transformer.modified {
-		return content
+	if transformer.count == 0 {
+		return content, 0
 	}
 
 	var buf strings.Builder
 	if err := format.Node(&buf, fset, file); err != nil {
-		return content
+		return content, 0
 	}
 
 	result := buf.String()
@@ -48,11 +49,11 @@ func replaceSDKResourceNotFoundAST(content string) string {
 		cleaned = append(cleaned, line)
 	}
 
-	return strings.Join(cleaned, "\n")
+	return strings.Join(cleaned, "\n"), transformer.count
 }
 
 type sdkResourceNotFoundTransformer struct {
-	modified bool
+	count int // Number of if statements transformed
 }
 
 func (t *sdkResourceNotFoundTransformer) Visit(node ast.Node) ast.Visitor {
@@ -64,7 +65,7 @@ func (t *sdkResourceNotFoundTransformer) Visit(node ast.Node) ast.Visitor {
 	// Check if this matches our pattern
 	if t.isSDKResourceNotFoundPattern(ifStmt) {
 		t.transformIfStatement(ifStmt)
-		t.modified = true
+		t.count++
 	}
 
 	return t
@@ -320,3 +321,136 @@ func (t *sdkResourceNotFoundTransformer) transformIfStatement(ifStmt *ast.IfStmt
 	// Update the body while preserving the original block structure
 	originalBody.List = newStmts
 }
+
+// fragmentPrefix wraps a snippet of statements so it parses as a Go file
+const fragmentPrefix = "package p\nfunc _() {\n"
+
+// parseGoSource parses content as a Go file or, failing that, as a snippet of statements, such as
+// the body of a function. offset is the number of bytes added before content, so the byte offset
+// of a node in content is fset.Position(pos).Offset - offset.
+func parseGoSource(content string) (fset *token.FileSet, file *ast.File, offset int, ok bool) {
+	fset = token.NewFileSet()
+	if file, err := parser.ParseFile(fset, "", content, 0); err == nil {
+		return fset, file, 0, true
+	}
+	fset = token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", fragmentPrefix+content+"\n}", 0)
+	if err != nil {
+		return nil, nil, 0, false
+	}
+	return fset, file, len(fragmentPrefix), true
+}
+
+// replaceCreateAddErrorAST uses AST to transform create.AddError(&diags, service, action,
+// resource, id, err) calls into smerr.AddError(ctx, &diags, err, smerr.ID, id). Unlike a regex,
+// it handles calls spanning several lines and carries over the actual id and error expressions.
+func replaceCreateAddErrorAST(content string) (string, int) {
+	return replaceCreateCallAST(content, "AddError", "smerr.AddError")
+}
+
+// replaceCreateAppendDiagErrorAST uses AST to transform create.AppendDiagError(diags, service,
+// action, resource, id, err) calls into smerr.Append(ctx, diags, err, smerr.ID, id), carrying over
+// the actual id expression, such as aws.ToString(output.VpcId).
+func replaceCreateAppendDiagErrorAST(content string) (string, int) {
+	return replaceCreateCallAST(content, "AppendDiagError", "smerr.Append")
+}
+
+// replaceCreateCallAST rewrites each call to create.<name>, whose first argument is the
+// diagnostics and whose last two are the id and the error, as a call to smerrFunc with ctx, the
+// diagnostics, the error, and smerr.ID with the id. Only the calls are rewritten; the rest of
+// content is left as is. It returns the content and the number of calls rewritten.
+func replaceCreateCallAST(content, name, smerrFunc string) (string, int) {
+	if !strings.Contains(content, "create."+name+"(") {
+		return content, 0
+	}
+	fset, file, offset, ok := parseGoSource(content)
+	if !ok {
+		return content, 0
+	}
+
+	var calls []*ast.CallExpr
+	ast.Inspect(file, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok && isCreateCall(call, name) {
+			calls = append(calls, call)
+			return false
+		}
+		return true
+	})
+
+	// Replace from the end so earlier offsets stay valid
+	source := func(node ast.Node) string {
+		return content[fset.Position(node.Pos()).Offset-offset : fset.Position(node.End()).Offset-offset]
+	}
+	for _, call := range slices.Backward(calls) {
+		n := len(call.Args)
+		diags, id, err := call.Args[0], call.Args[n-2], call.Args[n-1]
+		replacement := smerrFunc + "(ctx, " + source(diags) + ", " + source(err) + ", smerr.ID, " + source(id) + ")"
+		start, end := fset.Position(call.Pos()).Offset-offset, fset.Position(call.End()).Offset-offset
+		content = content[:start] + replacement + content[end:]
+	}
+	return content, len(calls)
+}
+
+// isCreateCall reports whether call is create.<name> with its six arguments: the diagnostics,
+// service, action, resource name, id, and error.
+func isCreateCall(call *ast.CallExpr, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name || len(call.Args) != 6 {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	return ok && ident.Name == "create"
+}
+
+// replaceFmtErrorfAST uses AST to wrap fmt.Errorf calls returned as the last result, such as
+// return nil, fmt.Errorf("reading VPC (%s): %w", id, err), in smarterr.NewError. Only calls whose
+// format wraps an error with %w are wrapped, so calls without an error argument are left alone.
+// Unlike a regex, it handles several %w verbs, nested parentheses in the arguments, and calls
+// spanning several lines, and it carries over the call exactly. It returns the content and the
+// number of calls wrapped.
+func replaceFmtErrorfAST(content string) (string, int) {
+	if !strings.Contains(content, "fmt.Errorf(") {
+		return content, 0
+	}
+	fset, file, offset, ok := parseGoSource(content)
+	if !ok {
+		return content, 0
+	}
+
+	var calls []*ast.CallExpr
+	ast.Inspect(file, func(node ast.Node) bool {
+		ret, ok := node.(*ast.ReturnStmt)
+		if !ok || len(ret.Results) == 0 {
+			return true
+		}
+		if call, ok := ret.Results[len(ret.Results)-1].(*ast.CallExpr); ok && isFmtErrorf(call) && wrapsError(call) {
+			calls = append(calls, call)
+		}
+		return true
+	})
+
+	// Replace from the end so earlier offsets stay valid
+	for _, call := range slices.Backward(calls) {
+		start, end := fset.Position(call.Pos()).Offset-offset, fset.Position(call.End()).Offset-offset
+		content = content[:start] + "smarterr.NewError(" + content[start:end] + ")" + content[end:]
+	}
+	return content, len(calls)
+}
+
+// wrapsError reports whether call, a call to fmt.Errorf, has a literal format with a %w verb and
+// an argument for it.
+func wrapsError(call *ast.CallExpr) bool {
+	if len(call.Args) < 2 {
+		return false
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return false
+	}
+	format, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return false
+	}
+	verbs, ok := formatVerbs(format)
+	return ok && slices.Contains(verbs, 'w')
+}
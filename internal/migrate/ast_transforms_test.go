@@ -193,7 +193,7 @@ func test() {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := replaceSDKResourceNotFoundAST(tt.input)
+			result, _ := replaceSDKResourceNotFoundAST(tt.input)
 
 			// Normalize whitespace for comparison
 			normalizeWhitespace := func(s string) string {
@@ -265,7 +265,7 @@ func TestSDKResourceNotFoundTransformer_isSDKResourceNotFoundPattern(t *testing.
 func test() {
 	` + tt.input + `
 }`
-			result := replaceSDKResourceNotFoundAST(fullInput)
+			result, _ := replaceSDKResourceNotFoundAST(fullInput)
 
 			// If pattern should match, result should be different from input
 			// If pattern shouldn't match, result should be same as input
@@ -276,3 +276,210 @@ func test() {
 		})
 	}
 }
+
+func TestReplaceCreateAddErrorAST(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "single line",
+			input:    "\tcreate.AddError(&response.Diagnostics, names.EC2, create.ErrActionCreating, ResNameVPC, id, err)\n",
+			expected: "\tsmerr.AddError(ctx, &response.Diagnostics, err, smerr.ID, id)\n",
+		},
+		{
+			name: "multiline",
+			input: `	if err != nil {
+		create.AddError(
+			&response.Diagnostics,
+			names.EC2,
+			create.ErrActionCreating,
+			ResNameVPC,
+			id,
+			err,
+		)
+		return
+	}
+`,
+			expected: `	if err != nil {
+		smerr.AddError(ctx, &response.Diagnostics, err, smerr.ID, id)
+		return
+	}
+`,
+		},
+		{
+			name:     "non-id identifiers",
+			input:    "\tcreate.AddError(&resp.Diagnostics, names.AppSync, create.ErrActionReading, ResNameAPI, plan.ID.ValueString(), readErr)\n",
+			expected: "\tsmerr.AddError(ctx, &resp.Diagnostics, readErr, smerr.ID, plan.ID.ValueString())\n",
+		},
+		{
+			name: "whole file with several calls",
+			input: `package test
+
+func (r *resource) Create(ctx context.Context, request resource.CreateRequest, response *resource.CreateResponse) {
+	if err != nil {
+		create.AddError(&response.Diagnostics, names.EC2, create.ErrActionCreating, ResNameVPC, name, err)
+		return
+	}
+	if waitErr != nil {
+		create.AddError(&response.Diagnostics, names.EC2, create.ErrActionWaitingForCreation, ResNameVPC,
+			aws.ToString(output.VpcId), waitErr)
+		return
+	}
+}
+`,
+			expected: `package test
+
+func (r *resource) Create(ctx context.Context, request resource.CreateRequest, response *resource.CreateResponse) {
+	if err != nil {
+		smerr.AddError(ctx, &response.Diagnostics, err, smerr.ID, name)
+		return
+	}
+	if waitErr != nil {
+		smerr.AddError(ctx, &response.Diagnostics, waitErr, smerr.ID, aws.ToString(output.VpcId))
+		return
+	}
+}
+`,
+		},
+		{
+			name:     "no transformation - different arguments",
+			input:    "\tcreate.AddError(&response.Diagnostics, err)\n",
+			expected: "\tcreate.AddError(&response.Diagnostics, err)\n",
+		},
+		{
+			name:     "no transformation - doesn't parse",
+			input:    "\tcreate.AddError(&response.Diagnostics, names.EC2, create.ErrActionCreating, ResNameVPC, id, err\n",
+			expected: "\tcreate.AddError(&response.Diagnostics, names.EC2, create.ErrActionCreating, ResNameVPC, id, err\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result, _ := replaceCreateAddErrorAST(tt.input); result != tt.expected {
+				t.Errorf("replaceCreateAddErrorAST() =\n%s\nwant:\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReplaceCreateAppendDiagErrorAST(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "id variable",
+			input:    "\treturn create.AppendDiagError(diags, names.EC2, create.ErrActionCreating, ResNameVPC, id, err)\n",
+			expected: "\treturn smerr.Append(ctx, diags, err, smerr.ID, id)\n",
+		},
+		{
+			name:     "aws.ToString id",
+			input:    "\treturn create.AppendDiagError(diags, names.EC2, create.ErrActionWaitingForCreation, ResNameVPC, aws.ToString(output.VpcId), err)\n",
+			expected: "\treturn smerr.Append(ctx, diags, err, smerr.ID, aws.ToString(output.VpcId))\n",
+		},
+		{
+			name:     "literal id",
+			input:    "\treturn create.AppendDiagError(diags, names.EC2, create.ErrActionReading, ResNameVPC, \"default\", readErr)\n",
+			expected: "\treturn smerr.Append(ctx, diags, readErr, smerr.ID, \"default\")\n",
+		},
+		{
+			name: "multiline",
+			input: `	if err != nil {
+		return create.AppendDiagError(diags, names.EC2, create.ErrActionSetting, ResNameVPC,
+			d.Id(), err)
+	}
+`,
+			expected: `	if err != nil {
+		return smerr.Append(ctx, diags, err, smerr.ID, d.Id())
+	}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result, _ := replaceCreateAppendDiagErrorAST(tt.input); result != tt.expected {
+				t.Errorf("replaceCreateAppendDiagErrorAST() =\n%s\nwant:\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReplaceFmtErrorfAST(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "single %w",
+			input:    "\treturn nil, fmt.Errorf(\"reading VPC (%s): %w\", id, err)\n",
+			expected: "\treturn nil, smarterr.NewError(fmt.Errorf(\"reading VPC (%s): %w\", id, err))\n",
+		},
+		{
+			name:     "several %w",
+			input:    "\treturn nil, fmt.Errorf(\"a: %w, b: %w\", e1, e2)\n",
+			expected: "\treturn nil, smarterr.NewError(fmt.Errorf(\"a: %w, b: %w\", e1, e2))\n",
+		},
+		{
+			name:     "nested parentheses",
+			input:    "\treturn nil, \"\", fmt.Errorf(\"reading VPC (%s): %w\", aws.ToString(output.VpcId), errors.Join(err, cleanup()))\n",
+			expected: "\treturn nil, \"\", smarterr.NewError(fmt.Errorf(\"reading VPC (%s): %w\", aws.ToString(output.VpcId), errors.Join(err, cleanup())))\n",
+		},
+		{
+			name:     "single result",
+			input:    "\treturn fmt.Errorf(\"deleting VPC: %w\", err)\n",
+			expected: "\treturn smarterr.NewError(fmt.Errorf(\"deleting VPC: %w\", err))\n",
+		},
+		{
+			name: "multiline",
+			input: `	if err != nil {
+		return nil, fmt.Errorf(
+			"waiting for VPC (%s): %w",
+			id,
+			err,
+		)
+	}
+`,
+			expected: `	if err != nil {
+		return nil, smarterr.NewError(fmt.Errorf(
+			"waiting for VPC (%s): %w",
+			id,
+			err,
+		))
+	}
+`,
+		},
+		{
+			name:     "no transformation - no error argument",
+			input:    "\treturn nil, fmt.Errorf(\"reading VPC (%s)\", id)\n",
+			expected: "\treturn nil, fmt.Errorf(\"reading VPC (%s)\", id)\n",
+		},
+		{
+			name:     "no transformation - %s",
+			input:    "\treturn nil, fmt.Errorf(\"reading VPC: %s\", err)\n",
+			expected: "\treturn nil, fmt.Errorf(\"reading VPC: %s\", err)\n",
+		},
+		{
+			name:     "no transformation - already wrapped",
+			input:    "\treturn nil, smarterr.NewError(fmt.Errorf(\"reading VPC: %w\", err))\n",
+			expected: "\treturn nil, smarterr.NewError(fmt.Errorf(\"reading VPC: %w\", err))\n",
+		},
+		{
+			name:     "no transformation - not returned",
+			input:    "\terr = fmt.Errorf(\"reading VPC: %w\", err)\n",
+			expected: "\terr = fmt.Errorf(\"reading VPC: %w\", err)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result, _ := replaceFmtErrorfAST(tt.input); result != tt.expected {
+				t.Errorf("replaceFmtErrorfAST() =\n%s\nwant:\n%s", result, tt.expected)
+			}
+		})
+	}
+}
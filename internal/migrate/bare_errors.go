@@ -55,15 +55,8 @@ func CreateBareErrorPatterns() PatternGroup {
 			},
 			{
 				Name:        "FmtErrorfNewError",
-				Description: "return ..., fmt.Errorf(...) -> return ..., smarterr.NewError(fmt.Errorf(...))",
-				Regex:       regexp.MustCompile(`(\s+)return (.+), (fmt\.Errorf\([^)]+\))`),
-				Template:    `${1}return $2, smarterr.NewError($3)`,
-			},
-			{
-				Name:        "FmtErrorf",
-				Description: "return fmt.Errorf(..., err) -> return smarterr.NewError(err)",
-				Regex:       regexp.MustCompile(`(\s+)return fmt\.Errorf\(.*,\s*([^)]+)\)`),
-				Template:    `${1}return smarterr.NewError($2)`,
+				Description: "return ..., fmt.Errorf(... %w ...) -> return ..., smarterr.NewError(fmt.Errorf(...))",
+				Replace:     replaceFmtErrorfAST,
 			},
 			{
 				Name:        "StateRefreshFunc",
@@ -87,19 +80,21 @@ func CreateBareErrorPatterns() PatternGroup {
 }
 
 // replaceDiagsAddError converts helper functions that use diags.AddError to return smarterr.NewError
-func replaceDiagsAddError(content string) string {
+func replaceDiagsAddError(content string) (string, int) {
 	// Add missing var diags diag.Diagnostics declarations for functions that use diags but don't declare it
 	funcPattern := regexp.MustCompile(`(?s)(func\s+[^{]*\([^)]*\)\s*\([^,)]+,\s*diag\.Diagnostics\s*\)\s*\{\s*)(\s*switch|\s*case|\s*[a-zA-Z])`)
+	count := 0
 	content = funcPattern.ReplaceAllStringFunc(content, func(match string) string {
 		// Check if diags is used but not declared
 		if strings.Contains(match, "diags") && !strings.Contains(match, "var diags") {
 			submatches := funcPattern.FindStringSubmatch(match)
 			if len(submatches) >= 3 {
+				count++
 				return submatches[1] + "var diags diag.Diagnostics\n\t" + submatches[2]
 			}
 		}
 		return match
 	})
 
-	return content
+	return content, count
 }
@@ -1,6 +1,9 @@
 package migrate
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func TestCreateBareErrorPatterns(t *testing.T) {
 	patterns := CreateBareErrorPatterns()
@@ -102,3 +105,54 @@ func TestNonNilReturn(t *testing.T) {
 		})
 	}
 }
+
+func TestMigrator_Matches(t *testing.T) {
+	input := `package test
+
+func read() error {
+	if err != nil {
+		return sdkdiag.AppendErrorf(diags, "reading VPC: %s", err)
+	}
+	if err != nil {
+		return sdkdiag.AppendErrorf(diags, "reading subnet: %s", err)
+	}
+	return nil
+}
+`
+	migrator := NewMigrator(MigratorOptions{})
+	migrator.MigrateContent(input)
+	want := []PatternMatch{{Pattern: "AppendErrorfSimple", Count: 2}}
+	if got := migrator.Matches(); !slices.Equal(got, want) {
+		t.Errorf("Matches() = %v, want %v", got, want)
+	}
+
+	// Replace patterns count each call site they rewrite
+	migrator.MigrateContent(`package test
+
+func read() error {
+	if err != nil {
+		return nil, fmt.Errorf("reading VPC: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading subnet: %w", err)
+	}
+	create.AddError(&response.Diagnostics, names.EC2, create.ErrActionCreating, ResNameVPC, id, err)
+	create.AddError(&response.Diagnostics, names.EC2, create.ErrActionReading, ResNameVPC, id, err)
+	create.AddError(&response.Diagnostics, names.EC2, create.ErrActionDeleting, ResNameVPC, id, err)
+	return nil
+}
+`)
+	counts := make(map[string]int)
+	for _, m := range migrator.Matches() {
+		counts[m.Pattern] = m.Count
+	}
+	if counts["FmtErrorfNewError"] != 2 || counts["CreateAddError"] != 3 {
+		t.Errorf("Matches() = %v, want FmtErrorfNewError: 2 and CreateAddError: 3", migrator.Matches())
+	}
+
+	// Matches are reset for each call
+	migrator.MigrateContent("package test\n")
+	if got := migrator.Matches(); len(got) != 0 {
+		t.Errorf("Matches() = %v after migrating unchanged content", got)
+	}
+}
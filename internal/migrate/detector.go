@@ -1,6 +1,14 @@
 package migrate
 
-import "regexp"
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 // MigrationDetector handles detection of code that needs migration
 type MigrationDetector struct{}
@@ -34,12 +42,37 @@ var MigrationPatterns = []string{
 	`return tfresource\.AssertSingleValueResult`,
 }
 
-// NeedsMigration checks if the given content contains patterns that need migration
+// migrationRegexps are the compiled MigrationPatterns
+var migrationRegexps = compilePatterns(MigrationPatterns)
+
+// migratedLineRegexp matches lines that migration has already handled, such as
+// "return nil, smarterr.NewError(fmt.Errorf(...))", which would otherwise match patterns like
+// return.*fmt\.Errorf. Deprecated calls, such as smerr.EnrichAppend, aren't included so they're
+// still detected.
+var migratedLineRegexp = regexp.MustCompile(`\b(smarterr|smerr)\.(NewError|Assert|Append|AppendOne|AppendEnrich|AddError|AddOne|AddEnrich)\(`)
+
+// compilePatterns compiles each pattern
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		res[i] = regexp.MustCompile(pattern)
+	}
+	return res
+}
+
+// NeedsMigration checks if the given content contains patterns that need migration. Lines that
+// already use smarterr, such as those a previous migration wrapped, are skipped, so migrating a
+// fully-migrated file again is a no-op.
 func (md *MigrationDetector) NeedsMigration(content string) bool {
-	for _, pattern := range MigrationPatterns {
-		matched, _ := regexp.MatchString(pattern, content)
-		if matched {
-			return true
+	for line := range strings.Lines(content) {
+		line = strings.TrimRight(line, "\r\n")
+		if migratedLineRegexp.MatchString(line) {
+			continue
+		}
+		for _, re := range migrationRegexps {
+			if re.MatchString(line) {
+				return true
+			}
 		}
 	}
 	return false
@@ -50,3 +83,122 @@ func NeedsMigration(content string) bool {
 	detector := NewMigrationDetector()
 	return detector.NeedsMigration(content)
 }
+
+// Warning describes code that migration leaves alone but that authors should look at
+type Warning struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// UnwrappedErrorfWarnings finds fmt.Errorf calls that format an error with %s or %v instead of %w,
+// which drops the error from the unwrap chain so errors.Is, errors.As, and smarterr can't see it.
+// For an argument such as err.Error(), the warning suggests passing err itself, since %w needs an
+// error rather than a string. It checks each error argument on its own, so a call that wraps one
+// error with %w still reports another formatted with %s. Calls without an error argument, such as
+// fmt.Errorf("static"), aren't reported. It returns nil if content doesn't parse.
+func (md *MigrationDetector) UnwrappedErrorfWarnings(content string) []Warning {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, 0)
+	if err != nil {
+		return nil
+	}
+
+	var warnings []Warning
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || !isFmtErrorf(call) || len(call.Args) == 0 {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		format, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+		verbs, ok := formatVerbs(format)
+		if !ok {
+			return true
+		}
+		for i, verb := range verbs {
+			if i+1 >= len(call.Args) || (verb != 's' && verb != 'v') {
+				continue
+			}
+			if arg := call.Args[i+1]; isErrorExpr(arg) {
+				pos := fset.Position(arg.Pos())
+				message := "fmt.Errorf formats an error with %" + string(verb) + "; use %w to keep it in the unwrap chain"
+				if errCall, ok := arg.(*ast.CallExpr); ok {
+					// err.Error() is a string, and %w needs the error itself
+					errExpr := types.ExprString(errCall.Fun.(*ast.SelectorExpr).X)
+					message = "fmt.Errorf formats " + types.ExprString(arg) + " with %" + string(verb) + "; use %w with " + errExpr + " instead to keep it in the unwrap chain"
+				}
+				warnings = append(warnings, Warning{
+					Line:    pos.Line,
+					Column:  pos.Column,
+					Message: message,
+				})
+			}
+		}
+		return true
+	})
+	return warnings
+}
+
+// UnwrappedErrorfWarnings is a convenience function for finding fmt.Errorf calls that don't wrap errors
+func UnwrappedErrorfWarnings(content string) []Warning {
+	detector := NewMigrationDetector()
+	return detector.UnwrappedErrorfWarnings(content)
+}
+
+// isFmtErrorf reports whether call is a call to fmt.Errorf
+func isFmtErrorf(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	return ok && ident.Name == "fmt" && sel.Sel.Name == "Errorf"
+}
+
+// formatVerbs returns the verb for each argument consumed by format, in order. It returns false if
+// format uses explicit argument indexes or * widths, since verbs can't be matched to arguments
+// by position then.
+func formatVerbs(format string) ([]rune, bool) {
+	var verbs []rune
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		// Skip flags, width, and precision
+		for i < len(format) && strings.ContainsRune("+-# 0123456789.", rune(format[i])) {
+			i++
+		}
+		if i >= len(format) {
+			break
+		}
+		switch format[i] {
+		case '%':
+			continue
+		case '[', '*':
+			return nil, false
+		}
+		verbs = append(verbs, rune(format[i]))
+	}
+	return verbs, true
+}
+
+// isErrorExpr reports whether expr looks like an error value, such as err, lastErr, or
+// err.Error(), judging by name since the detector doesn't type-check
+func isErrorExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name == "err" || strings.HasSuffix(e.Name, "Err")
+	case *ast.CallExpr:
+		sel, ok := e.Fun.(*ast.SelectorExpr)
+		return ok && sel.Sel.Name == "Error" && len(e.Args) == 0 && isErrorExpr(sel.X)
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package migrate
 
-import "testing"
+import (
+	"slices"
+	"strings"
+	"testing"
+)
 
 func TestMigrationDetector_NeedsMigration(t *testing.T) {
 	tests := []struct {
@@ -76,6 +80,56 @@ func TestMigrationDetector_NeedsMigration(t *testing.T) {
 	}
 }
 
+func TestMigrationDetector_NeedsMigration_AlreadyMigrated(t *testing.T) {
+	migrated := `package ec2
+
+func findVPCByID(ctx context.Context, conn *ec2.Client, id string) (*awstypes.Vpc, error) {
+	output, err := findVPC(ctx, conn, input)
+	if err != nil {
+		return nil, smarterr.NewError(err)
+	}
+	if output == nil {
+		return nil, smarterr.NewError(tfresource.NewEmptyResultError(input))
+	}
+	if aws.ToString(output.VpcId) != id {
+		return nil, smarterr.NewError(fmt.Errorf("unexpected format for ID (%s)", id))
+	}
+	return smarterr.Assert(tfresource.AssertSingleValueResult(output.Vpcs))
+}
+
+func resourceVPCRead(ctx context.Context, d *schema.ResourceData, meta any) diag.Diagnostics {
+	var diags diag.Diagnostics
+	vpc, err := findVPCByID(ctx, conn, d.Id())
+	if err != nil {
+		return smerr.Append(ctx, diags, err, smerr.ID, d.Id())
+	}
+	return smerr.AppendEnrich(ctx, diags, resourceVPCCustomizeDiff(ctx, d, meta))
+}
+`
+
+	detector := NewMigrationDetector()
+	if detector.NeedsMigration(migrated) {
+		t.Error("NeedsMigration() = true for a fully-migrated file, want false")
+	}
+
+	// Migrating again finds nothing to do
+	if got := NewMigrator(MigratorOptions{}).MigrateContent(migrated); got != migrated {
+		t.Errorf("MigrateContent() changed a fully-migrated file:\n%s", got)
+	}
+
+	// A single un-migrated line is still detected
+	partial := strings.Replace(migrated, "return nil, smarterr.NewError(err)", "return nil, err", 1)
+	if !detector.NeedsMigration(partial) {
+		t.Error("NeedsMigration() = false for a file with an un-migrated return, want true")
+	}
+
+	// So are deprecated calls
+	deprecated := strings.Replace(migrated, "smerr.AppendEnrich(", "smerr.EnrichAppend(", 1)
+	if !detector.NeedsMigration(deprecated) {
+		t.Error("NeedsMigration() = false for a file using smerr.EnrichAppend, want true")
+	}
+}
+
 func TestNeedsMigration(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -103,3 +157,100 @@ func TestNeedsMigration(t *testing.T) {
 		})
 	}
 }
+
+func TestUnwrappedErrorfWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []int // lines with warnings, counting from the function body's first line
+	}{
+		{
+			name:     "%s with err",
+			body:     `return fmt.Errorf("reading VPC (%s): %s", id, err)`,
+			expected: []int{1},
+		},
+		{
+			name:     "%v with err.Error()",
+			body:     `return fmt.Errorf("reading VPC: %v", err.Error())`,
+			expected: []int{1},
+		},
+		{
+			name: "named error variable",
+			body: `e := fmt.Errorf("giving up: %s", lastErr)
+	return e`,
+			expected: []int{1},
+		},
+		{
+			name: "%w wraps err",
+			body: `return fmt.Errorf("reading VPC (%s): %w", id, err)`,
+		},
+		{
+			name:     "%w with another error formatted with %s",
+			body:     `return fmt.Errorf("%w: %s", err, otherErr)`,
+			expected: []int{1},
+		},
+		{
+			name: "several %w",
+			body: `return fmt.Errorf("%w: %w", err, otherErr)`,
+		},
+		{
+			name: "%s with a non-error argument",
+			body: `return fmt.Errorf("reading VPC (%s)", id)`,
+		},
+		{
+			name: "static message",
+			body: `return fmt.Errorf("static")`,
+		},
+		{
+			name:     "literal percent before err",
+			body:     `return fmt.Errorf("100%% failed: %d %s", n, err)`,
+			expected: []int{1},
+		},
+		{
+			name: "explicit argument index is skipped",
+			body: `return fmt.Errorf("%[2]s: %[1]s", err, id)`,
+		},
+		{
+			name: "multiple calls",
+			body: `if n > 0 {
+		return fmt.Errorf("first: %s", err)
+	}
+	return fmt.Errorf("second: %+v", err)`,
+			expected: []int{2, 4},
+		},
+	}
+
+	detector := NewMigrationDetector()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "package test\n\nfunc test() error {\n\t" + tt.body + "\n}\n"
+			var lines []int
+			for _, w := range detector.UnwrappedErrorfWarnings(content) {
+				lines = append(lines, w.Line-3)
+				if !strings.Contains(w.Message, "use %w") {
+					t.Errorf("unexpected message: %s", w.Message)
+				}
+			}
+			if !slices.Equal(lines, tt.expected) {
+				t.Errorf("UnwrappedErrorfWarnings() lines = %v, want %v", lines, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnwrappedErrorfWarnings_ErrorCall(t *testing.T) {
+	content := "package test\n\nfunc test() error {\n\treturn fmt.Errorf(\"reading VPC: %v\", lastErr.Error())\n}\n"
+	warnings := UnwrappedErrorfWarnings(content)
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	if want := "fmt.Errorf formats lastErr.Error() with %v; use %w with lastErr instead to keep it in the unwrap chain"; warnings[0].Message != want {
+		t.Errorf("message = %q, want %q", warnings[0].Message, want)
+	}
+}
+
+func TestUnwrappedErrorfWarnings_InvalidGo(t *testing.T) {
+	if got := UnwrappedErrorfWarnings(`return fmt.Errorf("x: %s", err)`); got != nil {
+		t.Errorf("UnwrappedErrorfWarnings() = %v, want nil for content that doesn't parse", got)
+	}
+}
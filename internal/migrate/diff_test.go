@@ -0,0 +1,110 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	numbered := func(n int) string {
+		var sb strings.Builder
+		for i := 1; i <= n; i++ {
+			sb.WriteString("line " + string(rune('a'+i-1)) + "\n")
+		}
+		return sb.String()
+	}
+	base := numbered(12)
+
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    base,
+			b:    base,
+		},
+		{
+			name: "change",
+			a:    base,
+			b:    strings.Replace(base, "line f\n", "line F\n", 1),
+			want: `--- old.go
++++ new.go
+@@ -3,7 +3,7 @@
+ line c
+ line d
+ line e
+-line f
++line F
+ line g
+ line h
+ line i
+`,
+		},
+		{
+			name: "separate hunks",
+			a:    base,
+			b:    strings.Replace(strings.Replace(base, "line a\n", "", 1), "line l\n", "line l\nline m\n", 1),
+			want: `--- old.go
++++ new.go
+@@ -1,4 +1,3 @@
+-line a
+ line b
+ line c
+ line d
+@@ -10,3 +9,4 @@
+ line j
+ line k
+ line l
++line m
+`,
+		},
+		{
+			name: "insert into empty",
+			a:    "",
+			b:    "package a\n",
+			want: `--- old.go
++++ new.go
+@@ -0,0 +1 @@
++package a
+`,
+		},
+		{
+			name: "no newline at end",
+			a:    "a\nb",
+			b:    "a\nc",
+			want: `--- old.go
++++ new.go
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("old.go", "new.go", tt.a, tt.b); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLines_Minimal(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+	changes := 0
+	for _, e := range diffLines(a, b) {
+		if e.op != ' ' {
+			changes++
+		}
+	}
+	// The shortest edit script between these sequences from Myers' paper has 5 edits
+	if changes != 5 {
+		t.Errorf("diffLines() made %d edits, want 5", changes)
+	}
+}
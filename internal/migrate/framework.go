@@ -47,15 +47,16 @@ func CreateFrameworkPatterns() PatternGroup {
 }
 
 // replaceDeprecatedEnrichAppend handles deprecated smerr.EnrichAppend usage
-func replaceDeprecatedEnrichAppend(content string) string {
+func replaceDeprecatedEnrichAppend(content string) (string, int) {
 	re := regexp.MustCompile(`(?m)(\s+)smerr\.EnrichAppend\(`)
-	return re.ReplaceAllString(content, `${1}smerr.AddEnrich(`)
+	return replaceAllCount(re, content, `${1}smerr.AddEnrich(`)
 }
 
 // replaceVariadicAppend handles response.Diagnostics.Append with ... variadic operator
-func replaceVariadicAppend(content string) string {
+func replaceVariadicAppend(content string) (string, int) {
 	re := regexp.MustCompile(`(?m)(\s+)(resp|response)\.Diagnostics\.Append\((.+)\.\.\.\)$`)
-	return re.ReplaceAllStringFunc(content, func(match string) string {
+	count := 0
+	content = re.ReplaceAllStringFunc(content, func(match string) string {
 		// Extract the parts
 		submatches := re.FindStringSubmatch(match)
 		if len(submatches) != 4 {
@@ -66,16 +67,19 @@ func replaceVariadicAppend(content string) string {
 		arg := submatches[3]
 
 		// Replace with smerr.AddEnrich (updated from deprecated EnrichAppend)
+		count++
 		return indent + "smerr.AddEnrich(ctx, &" + respVar + ".Diagnostics, " + arg + ")"
 	})
+	return content, count
 }
 
 // replaceFwdiagAppend handles response.Diagnostics.Append with fwdiag patterns
-func replaceFwdiagAppend(content string) string {
+func replaceFwdiagAppend(content string) (string, int) {
 	// Handle nested parentheses for fwdiag calls
 	re := regexp.MustCompile(`(?m)(\s+)response\.Diagnostics\.Append\((fwdiag\.[^(]+\([^)]*\))\)$`)
 
-	return re.ReplaceAllStringFunc(content, func(match string) string {
+	count := 0
+	content = re.ReplaceAllStringFunc(content, func(match string) string {
 		submatches := re.FindStringSubmatch(match)
 		if len(submatches) != 3 {
 			return match
@@ -85,31 +89,34 @@ func replaceFwdiagAppend(content string) string {
 
 		// Check if it's a single diagnostic call
 		if strings.Contains(fwdiagCall, "fwdiag.New") {
+			count++
 			return indent + "smerr.AddOne(ctx, &response.Diagnostics, " + fwdiagCall + ")"
 		}
 
 		return match // Return unchanged if we can't handle it
 	})
+	return content, count
 }
 
 // replaceCreateProblemStandardMessage handles create.ProblemStandardMessage patterns
-func replaceCreateProblemStandardMessage(content string) string {
+func replaceCreateProblemStandardMessage(content string) (string, int) {
 	// Handle cases with err.Error() - both simple and complex nested parentheses
 	re1 := regexp.MustCompile(`(?s)(\s+)response\.Diagnostics\.AddError\(\s*create\.ProblemStandardMessage\([^)]*(?:\([^)]*\)[^)]*)*\),\s*([a-zA-Z_][a-zA-Z0-9_]*)\.Error\(\)\s*,?\s*\)`)
-	content = re1.ReplaceAllString(content, `${1}smerr.AddError(ctx, &response.Diagnostics, $2)`)
+	content, count1 := replaceAllCount(re1, content, `${1}smerr.AddError(ctx, &response.Diagnostics, $2)`)
 
 	// Handle cases with errors.New("...").Error()
 	re2 := regexp.MustCompile(`(?s)(\s+)response\.Diagnostics\.AddError\(\s*create\.ProblemStandardMessage\([^)]*(?:\([^)]*\)[^)]*)*\),\s*(errors\.New\([^)]*\))\.Error\(\)\s*,?\s*\)`)
-	content = re2.ReplaceAllString(content, `${1}smerr.AddError(ctx, &response.Diagnostics, $2)`)
+	content, count2 := replaceAllCount(re2, content, `${1}smerr.AddError(ctx, &response.Diagnostics, $2)`)
 
-	return content
+	return content, count1 + count2
 }
 
 // replaceAddErrorFmtSprintf handles response.Diagnostics.AddError with fmt.Sprintf patterns
-func replaceAddErrorFmtSprintf(content string) string {
+func replaceAddErrorFmtSprintf(content string) (string, int) {
 	// Handle multiline response.Diagnostics.AddError calls
 	re := regexp.MustCompile(`(?s)(\s+)(resp|response)\.Diagnostics\.AddError\(\s*"[^"]*",\s*(fmt\.Sprintf\([^)]*(?:\([^)]*\)[^)]*)*\)|"[^"]*")\s*,?\s*\)`)
-	return re.ReplaceAllStringFunc(content, func(match string) string {
+	count := 0
+	content = re.ReplaceAllStringFunc(content, func(match string) string {
 		submatches := re.FindStringSubmatch(match)
 		if len(submatches) != 4 {
 			return match
@@ -125,6 +132,8 @@ func replaceAddErrorFmtSprintf(content string) string {
 			errorArg = "fmt.Errorf(" + errorArg + ")"
 		}
 
+		count++
 		return indent + "smerr.AddError(ctx, &" + respVar + ".Diagnostics, " + errorArg + ")"
 	})
+	return content, count
 }
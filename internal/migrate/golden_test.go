@@ -0,0 +1,76 @@
+package migrate
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files with the current migration output")
+
+// goldenDir holds whole-file migration fixtures: each NAME.go is migrated and compared to
+// NAME.go.golden. Run `go test ./internal/migrate -run TestMigrateGolden -update` to regenerate
+// the golden files, then review the diff.
+var goldenDir = filepath.Join("testdata", "migrate")
+
+func TestMigrateGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join(goldenDir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no migration fixtures found in %s", goldenDir)
+	}
+
+	for _, input := range inputs {
+		t.Run(strings.TrimSuffix(filepath.Base(input), ".go"), func(t *testing.T) {
+			src, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := NewMigrator(MigratorOptions{}).MigrateContent(string(src))
+			assertGolden(t, input+".golden", got)
+		})
+	}
+}
+
+// assertGolden compares got to the content of the golden file at path, or writes got to path when
+// the -update flag is set.
+func assertGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("migration output doesn't match %s (run with -update to accept it)\n%s", path, lineDiff(string(want), got))
+	}
+}
+
+// lineDiff describes the first line where want and got differ.
+func lineDiff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("first difference at line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+	return ""
+}
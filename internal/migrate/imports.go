@@ -238,8 +238,13 @@ func CreateImportPatterns() PatternGroup {
 	}
 }
 
-// addRequiredImports is the pattern function that adds required imports
-func addRequiredImports(content string) string {
+// addRequiredImports is the pattern function that adds required imports. It counts one rewrite if
+// any import was added.
+func addRequiredImports(content string) (string, int) {
 	im := NewImportManager(content)
-	return im.AddRequiredImports()
+	result := im.AddRequiredImports()
+	if result == content {
+		return content, 0
+	}
+	return result, 1
 }
@@ -11,8 +11,8 @@ type Pattern struct {
 	Name        string
 	Description string
 	Regex       *regexp.Regexp
-	Replace     func(string) string // For complex replacements
-	Template    string              // For simple replacements
+	Replace     func(string) (string, int) // For complex replacements; returns the content and the number of rewrites
+	Template    string                     // For simple replacements
 }
 
 // PatternGroup represents a logical group of related patterns
@@ -28,10 +28,17 @@ type MigratorOptions struct {
 	Verbose bool
 }
 
+// PatternMatch counts how many times a pattern matched during a migration
+type PatternMatch struct {
+	Pattern string
+	Count   int
+}
+
 // Migrator handles the overall migration process
 type Migrator struct {
 	patterns []PatternGroup
 	options  MigratorOptions
+	matches  []PatternMatch
 }
 
 // NewMigrator creates a new migrator with the given options
@@ -49,6 +56,8 @@ func (m *Migrator) MigrateContent(content string) string {
 		return cmp.Compare(a.Order, b.Order)
 	})
 
+	m.matches = nil
+
 	// Apply pattern transformations
 	for _, group := range m.patterns {
 		content = m.applyPatternGroup(content, group)
@@ -61,16 +70,47 @@ func (m *Migrator) MigrateContent(content string) string {
 	return content
 }
 
+// Matches returns the patterns that matched during the last MigrateContent call, in the order they
+// were applied. A regex pattern counts each match; a Replace pattern counts each rewrite it reports.
+func (m *Migrator) Matches() []PatternMatch {
+	return m.matches
+}
+
 // applyPatternGroup applies all patterns in a group to the content
 func (m *Migrator) applyPatternGroup(content string, group PatternGroup) string {
 	for _, pattern := range group.Patterns {
+		count := 0
 		if pattern.Replace != nil {
 			// Use custom replacement function
-			content = pattern.Replace(content)
+			content, count = pattern.Replace(content)
 		} else if pattern.Regex != nil && pattern.Template != "" {
 			// Use regex replacement with template
-			content = pattern.Regex.ReplaceAllString(content, pattern.Template)
+			content, count = replaceAllCount(pattern.Regex, content, pattern.Template)
+		}
+		if count > 0 {
+			m.addMatches(pattern.Name, count)
 		}
 	}
 	return content
 }
+
+// addMatches adds count matches of the named pattern
+func (m *Migrator) addMatches(name string, count int) {
+	for i := range m.matches {
+		if m.matches[i].Pattern == name {
+			m.matches[i].Count += count
+			return
+		}
+	}
+	m.matches = append(m.matches, PatternMatch{Pattern: name, Count: count})
+}
+
+// replaceAllCount replaces each match of re in content with template, like ReplaceAllString, and
+// returns the number of matches replaced
+func replaceAllCount(re *regexp.Regexp, content, template string) (string, int) {
+	count := len(re.FindAllStringIndex(content, -1))
+	if count == 0 {
+		return content, 0
+	}
+	return re.ReplaceAllString(content, template), count
+}
@@ -29,14 +29,12 @@ func CreateSDKv2Patterns() PatternGroup {
 			{
 				Name:        "CreateAppendDiagError",
 				Description: "create.AppendDiagError -> smerr.Append",
-				Regex:       regexp.MustCompile(`(?m)create\.AppendDiagError\(([^,]+),\s*[^)]*\)$`),
-				Template:    `smerr.Append(ctx, $1, err, smerr.ID, id)`,
+				Replace:     replaceCreateAppendDiagErrorAST,
 			},
 			{
 				Name:        "CreateAddError",
 				Description: "create.AddError -> smerr.AddError",
-				Regex:       regexp.MustCompile(`(?m)create\.AddError\(&([^,]+),\s*[^)]*\)$`),
-				Template:    `smerr.AddError(ctx, &$1, err, smerr.ID, id)`,
+				Replace:     replaceCreateAddErrorAST,
 			},
 			{
 				Name:        "SDKResourceNotFoundPattern",
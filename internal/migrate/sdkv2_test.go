@@ -63,3 +63,13 @@ func TestSDKv2_AppendErrorf(t *testing.T) {
 		})
 	}
 }
+
+func TestSDKv2_CreateAppendDiagError(t *testing.T) {
+	migrator := NewMigrator(MigratorOptions{})
+
+	input := "\treturn create.AppendDiagError(diags, names.EC2, create.ErrActionCreating, ResNameVPC, aws.ToString(output.VpcId), err)\n"
+	expected := "\treturn smerr.Append(ctx, diags, err, smerr.ID, aws.ToString(output.VpcId))\n"
+	if result := migrator.MigrateContent(input); result != expected {
+		t.Errorf("MigrateContent() =\n%q\nwant:\n%q", result, expected)
+	}
+}
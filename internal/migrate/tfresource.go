@@ -26,15 +26,20 @@ func CreateTfresourcePatterns() PatternGroup {
 	}
 }
 
-// replaceTfresourceNotFound handles tfresource.NotFound anti-patterns and import aliasing
-func replaceTfresourceNotFound(content string) string {
+// replaceTfresourceNotFound handles tfresource.NotFound anti-patterns and import aliasing. Adding
+// the aliased import counts as one rewrite.
+func replaceTfresourceNotFound(content string) (string, int) {
 	im := NewImportManager(content)
+	count := 0
 
 	// Use the specialized retry prefix logic (preserves exact original behavior)
 	retryPrefix := im.GetRetryPrefix()
 	if im.HasConflictingRetryImport() {
 		// Need to add aliased import and use intretry prefix
-		content = im.AddAliasedRetryImport()
+		if aliased := im.AddAliasedRetryImport(); aliased != content {
+			content = aliased
+			count++
+		}
 	}
 
 	// Pattern 1: with diagnostic
@@ -49,6 +54,7 @@ func replaceTfresourceNotFound(content string) string {
 		baseIndent := strings.TrimLeft(indent, "\n")
 		// Preserve the original leading newlines
 		leadingNewlines := strings.TrimSuffix(indent, baseIndent)
+		count++
 		return leadingNewlines + baseIndent + `if ` + retryPrefix + `.NotFound(err) {` + "\n" + baseIndent + "\t" + `smerr.AddOne(ctx, &response.Diagnostics, fwdiag.NewResourceNotFoundWarningDiagnostic(err))` + "\n" + baseIndent + "\t" + `response.State.RemoveResource(ctx)` + "\n" + baseIndent + "\t" + `return` + "\n" + baseIndent + `}`
 	})
 
@@ -64,12 +70,13 @@ func replaceTfresourceNotFound(content string) string {
 		baseIndent := strings.TrimLeft(indent, "\n")
 		// Preserve the original leading newlines
 		leadingNewlines := strings.TrimSuffix(indent, baseIndent)
+		count++
 		return leadingNewlines + baseIndent + `if ` + retryPrefix + `.NotFound(err) {` + "\n" + baseIndent + "\t" + `smerr.AddOne(ctx, &response.Diagnostics, fwdiag.NewResourceNotFoundWarningDiagnostic(err))` + "\n" + baseIndent + "\t" + `response.State.RemoveResource(ctx)` + "\n" + baseIndent + "\t" + `return` + "\n" + baseIndent + `}`
 	})
 
 	// Handle standalone fwdiag.NewResourceNotFoundWarningDiagnostic calls
-	content = regexp.MustCompile(`(?m)(\s+)response\.Diagnostics\.Append\(fwdiag\.NewResourceNotFoundWarningDiagnostic\(([^)]+)\)\)$`).
-		ReplaceAllString(content, `${1}smerr.AddOne(ctx, &response.Diagnostics, fwdiag.NewResourceNotFoundWarningDiagnostic($2))`)
+	standalone := regexp.MustCompile(`(?m)(\s+)response\.Diagnostics\.Append\(fwdiag\.NewResourceNotFoundWarningDiagnostic\(([^)]+)\)\)$`)
+	content, n := replaceAllCount(standalone, content, `${1}smerr.AddOne(ctx, &response.Diagnostics, fwdiag.NewResourceNotFoundWarningDiagnostic($2))`)
 
-	return content
+	return content, count + n
 }
@@ -0,0 +1,32 @@
+// resourcedata.go
+// Resource data made available to resource_data tokens via context
+package internal
+
+import "context"
+
+// ResourceData reads resource attribute values. It matches the Get method of the Terraform Plugin
+// SDK's *schema.ResourceData, so smarterr doesn't depend on the SDK's schema package. smarterr
+// doesn't use GetOk, which treats false, 0, and "" as unset, so an attribute in the schema resolves
+// to its value even when that's the zero value.
+type ResourceData interface {
+	Get(key string) any
+}
+
+var resourceDataCtxKey = ContextKey("smarterr:resource_data")
+
+// WithResourceData returns a context carrying d for resource_data tokens.
+func WithResourceData(ctx context.Context, d ResourceData) context.Context {
+	return context.WithValue(ctx, resourceDataCtxKey, d)
+}
+
+// resourceDataValue returns the value of the key attribute from the context's resource data. It
+// reports false if the context has no resource data or Get returns nil, as *schema.ResourceData
+// does for a key that isn't in the schema.
+func resourceDataValue(ctx context.Context, key string) (any, bool) {
+	d, ok := ctx.Value(resourceDataCtxKey).(ResourceData)
+	if !ok || d == nil {
+		return nil, false
+	}
+	v := d.Get(key)
+	return v, v != nil
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+// mockResourceData implements ResourceData with only Get, like a minimal accessor.
+type mockResourceData map[string]any
+
+func (d mockResourceData) Get(key string) any { return d[key] }
+
+// mockResourceDataOk also implements GetOk, which treats zero values as unset like
+// *schema.ResourceData.
+type mockResourceDataOk struct {
+	mockResourceData
+}
+
+func (d mockResourceDataOk) GetOk(key string) (any, bool) {
+	v, ok := d.mockResourceData[key]
+	return v, ok && v != "" && v != false
+}
+
+func TestTokenResolve_ResourceDataSource(t *testing.T) {
+	cfg := &Config{Smarterr: &Smarterr{TokenErrorMode: strPtr("placeholder")}}
+	data := map[string]any{"name": "main", "port": 443, "description": "", "enabled": false}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		attr string
+		want string
+	}{
+		{name: "get", ctx: WithResourceData(context.Background(), mockResourceData(data)), attr: "name", want: "main"},
+		{name: "get non-string", ctx: WithResourceData(context.Background(), mockResourceData(data)), attr: "port", want: "443"},
+		{name: "get missing", ctx: WithResourceData(context.Background(), mockResourceData(data)), attr: "vpc_id", want: "<token>"},
+		{name: "get zero value", ctx: WithResourceData(context.Background(), mockResourceData(data)), attr: "description", want: ""},
+		{name: "get ok", ctx: WithResourceData(context.Background(), mockResourceDataOk{data}), attr: "name", want: "main"},
+		{name: "get ok zero value", ctx: WithResourceData(context.Background(), mockResourceDataOk{data}), attr: "description", want: ""},
+		{name: "get ok false", ctx: WithResourceData(context.Background(), mockResourceDataOk{data}), attr: "enabled", want: "false"},
+		{name: "get ok missing", ctx: WithResourceData(context.Background(), mockResourceDataOk{data}), attr: "vpc_id", want: "<token>"},
+		{name: "no resource data", ctx: context.Background(), attr: "name", want: "<token>"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			token := Token{Name: "token", ResourceData: &tc.attr}
+			if got := token.Resolve(tc.ctx, NewRuntime(tc.ctx, cfg, nil)); got != tc.want {
+				t.Errorf("Resolve() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
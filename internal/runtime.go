@@ -4,14 +4,26 @@ package internal
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"net/url"
+	"os"
+	"reflect"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
 	"text/template/parse"
+	"time"
+	"unicode/utf8"
 
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 )
@@ -20,22 +32,44 @@ const (
 	SeverityError   = "Error"
 	SeverityWarning = "Warning"
 	SeverityInfo    = "Info"
+
+	// SummaryOverrideKey is the reserved keyval key whose value replaces the rendered summary.
+	SummaryOverrideKey = "summary_override"
 )
 
+// DiagnosticFields are the fields of a token with source = "diagnostic", such as .diag.summary.
+var DiagnosticFields = []string{"summary", "detail", "severity", "severity_level"}
+
+// ReservedKeyvals are keyval keys that control smarterr's behavior rather than supply token args.
+// NewRuntime keeps their values in Runtime.Reserved instead of Runtime.Args.
+var ReservedKeyvals = []string{SummaryOverrideKey}
+
 type ContextKey string
 
 type Runtime struct {
 	Config     *Config
 	Args       map[string]any
+	Reserved   map[string]any // values of ReservedKeyvals passed as keyvals
 	Error      error
 	Diagnostic diag.Diagnostic // single diagnostic for enrichment context
+
+	// Call stack gathered by the first call_stack token and shared by the rest
+	stackGathered bool
+	stackFrames   []runtime.Frame
+	stackErr      error
+
+	// Token values resolved so far, for from_token tokens, and tokens being resolved, to catch cycles
+	values    map[string]any
+	resolving map[string]bool
 }
 
 func NewRuntime(ctx context.Context, cfg *Config, err error, kv ...any) *Runtime {
 	callID := globalCallID(ctx)
 
 	// Parse key-value pairs
-	args := parseKeyvals(ctx, kv...)
+	checkKeyvals(kv)
+	kv, reserved := splitReserved(kv)
+	args := parseKeyvalsMode(ctx, duplicateKeyvalMode(cfg), kv...)
 	// Emit debug output if config or error is nil
 	if cfg == nil {
 		Debugf("[NewRuntime %s] Runtime configuration is nil", callID)
@@ -44,15 +78,18 @@ func NewRuntime(ctx context.Context, cfg *Config, err error, kv ...any) *Runtime
 		Debugf("[NewRuntime %s] Runtime initialized with error: %v", callID, err)
 	}
 	return &Runtime{
-		Config: cfg,
-		Error:  err,
-		Args:   args,
+		Config:   cfg,
+		Error:    err,
+		Args:     args,
+		Reserved: reserved,
 	}
 }
 
 func NewRuntimeForDiagnostic(ctx context.Context, cfg *Config, diagnostic diag.Diagnostic, kv ...any) *Runtime {
 	callID := globalCallID(ctx)
-	args := parseKeyvals(ctx, kv...)
+	checkKeyvals(kv)
+	kv, reserved := splitReserved(kv)
+	args := parseKeyvalsMode(ctx, duplicateKeyvalMode(cfg), kv...)
 	if cfg == nil {
 		Debugf("[NewRuntimeForDiagnostic %s] Runtime configuration is nil", callID)
 	}
@@ -64,9 +101,62 @@ func NewRuntimeForDiagnostic(ctx context.Context, cfg *Config, diagnostic diag.D
 		Config:     cfg,
 		Diagnostic: diagnostic,
 		Args:       args,
+		Reserved:   reserved,
+	}
+}
+
+// strictKeyvals makes checkKeyvals panic on malformed keyvals instead of letting parseKeyvals
+// drop them.
+var strictKeyvals atomic.Bool
+
+// SetStrictKeyvals turns strict keyval checking on or off.
+func SetStrictKeyvals(strict bool) {
+	strictKeyvals.Store(strict)
+}
+
+// checkKeyvals panics, in strict mode, if kv has a non-string key or an odd number of elements,
+// identifying the bad element. In lenient mode, it does nothing; parseKeyvals logs and drops the
+// keyvals instead.
+func checkKeyvals(kv []any) {
+	if !strictKeyvals.Load() {
+		return
+	}
+	for i := 0; i < len(kv); i += 2 {
+		if _, ok := kv[i].(string); !ok {
+			panic(fmt.Sprintf("strict keyvals: key at index %d is %T (%v), not string", i, kv[i], kv[i]))
+		}
+	}
+	if len(kv)%2 != 0 {
+		panic(fmt.Sprintf("strict keyvals: odd number of keyvals (%d); key %q at index %d has no value", len(kv), kv[len(kv)-1], len(kv)-1))
 	}
 }
 
+// splitReserved removes the ReservedKeyvals and their values from kv, before parsing, so arg
+// tokens can't resolve them and duplicate_keyval_mode doesn't apply to them. It returns the
+// remaining keyvals and the reserved values, where a later value for the same key wins. The
+// returned reserved map is nil if there are none.
+func splitReserved(kv []any) ([]any, map[string]any) {
+	var reserved map[string]any
+	var rest []any
+	for i := 0; i < len(kv); i += 2 {
+		if key, ok := kv[i].(string); ok && i+1 < len(kv) && slices.Contains(ReservedKeyvals, key) {
+			if reserved == nil {
+				reserved = make(map[string]any)
+				rest = append(make([]any, 0, len(kv)), kv[:i]...)
+			}
+			reserved[key] = kv[i+1]
+			continue
+		}
+		if reserved != nil {
+			rest = append(rest, kv[i:min(i+2, len(kv))]...)
+		}
+	}
+	if reserved == nil {
+		return kv, nil
+	}
+	return rest, reserved
+}
+
 // applyTransforms applies named transforms (from config) to a value, in order.
 func (rt *Runtime) applyTransforms(ctx context.Context, token *Token, value string) string {
 	callID := globalCallID(ctx)
@@ -87,26 +177,7 @@ func (rt *Runtime) applyTransforms(ctx context.Context, token *Token, value stri
 			continue // skip missing transforms
 		}
 		for _, step := range tdef.Steps {
-			switch step.Type {
-			case "strip_prefix":
-				value = applyStripPrefix(value, step)
-			case "strip_suffix":
-				value = applyStripSuffix(value, step)
-			case "remove":
-				value = applyRemove(value, step)
-			case "replace":
-				value = applyReplace(value, step)
-			case "trim_space":
-				value = strings.TrimSpace(value)
-			case "fix_space":
-				value = strings.TrimSpace(value)
-				value = regexp.MustCompile(`\s+`).ReplaceAllString(value, " ")
-			case "lower":
-				value = strings.ToLower(value)
-			case "upper":
-				value = strings.ToUpper(value)
-				// Add more transform types as needed
-			}
+			value = rt.Config.ApplyTransformStep(value, step)
 		}
 	}
 	Debugf("[applyTransforms %s] %s transformed value: %q", callID, token.Name, value)
@@ -212,6 +283,101 @@ func applyReplace(value string, step TransformStep) string {
 	return value
 }
 
+// applyARNShort returns the resource ID from an ARN, arn:partition:service:region:account:resource,
+// dropping a leading resource type up to the first "/" or ":". For example, a VPC ARN ending in
+// vpc/vpc-123 returns "vpc-123". An S3 bucket or object ARN, which has no account or resource
+// type, returns the whole resource, such as "bucket/key". Values that aren't ARNs are returned
+// unchanged.
+func applyARNShort(value string, _ TransformStep) string {
+	parts := strings.SplitN(value, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[1] == "" || parts[2] == "" || parts[5] == "" {
+		return value
+	}
+	resource := parts[5]
+	if parts[2] == "s3" && parts[4] == "" {
+		// The bucket, not a resource type, comes before the first "/"
+		return resource
+	}
+	if i := strings.IndexAny(resource, "/:"); i >= 0 && i < len(resource)-1 {
+		return resource[i+1:]
+	}
+	return resource
+}
+
+// DefaultEllipsis is appended to a value the truncate step cuts, unless the step sets ellipsis.
+const DefaultEllipsis = "…"
+
+// applyTruncate cuts a value longer than max characters to max, at a rune boundary, and appends
+// the ellipsis. Shorter values, and any value when max isn't positive, are returned unchanged.
+func applyTruncate(value string, step TransformStep) string {
+	if step.Max == nil || *step.Max <= 0 {
+		return value
+	}
+	runes := []rune(value)
+	if len(runes) <= *step.Max {
+		return value
+	}
+	ellipsis := DefaultEllipsis
+	if step.Ellipsis != nil {
+		ellipsis = *step.Ellipsis
+	}
+	return string(runes[:*step.Max]) + ellipsis
+}
+
+// applySplit splits a value on the separator and returns the part at index, counting from the end
+// if index is negative, such as -1 for the last part. The value is returned unchanged if the
+// separator is empty or the index is out of range.
+func applySplit(value string, step TransformStep) string {
+	if step.Separator == nil || *step.Separator == "" {
+		return value
+	}
+	parts := strings.Split(value, *step.Separator)
+	index := 0
+	if step.Index != nil {
+		index = *step.Index
+	}
+	if index < 0 {
+		index += len(parts)
+	}
+	if index < 0 || index >= len(parts) {
+		return value
+	}
+	return parts[index]
+}
+
+// base64Encodings are the encodings base64_decode tries, in order.
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
+
+// applyBase64Decode decodes a standard or URL-safe base64 value, with or without padding. The value
+// is returned unchanged if it isn't valid base64 or doesn't decode to valid UTF-8 text.
+func applyBase64Decode(value string, _ TransformStep) string {
+	encoded := strings.TrimSpace(value)
+	if encoded == "" {
+		return value
+	}
+	for _, enc := range base64Encodings {
+		if decoded, err := enc.DecodeString(encoded); err == nil && utf8.Valid(decoded) {
+			return string(decoded)
+		}
+	}
+	return value
+}
+
+// applyURLDecode decodes a percent-encoded value, such as "a%20b". A "+" is kept as is, not
+// decoded as a space. The value is returned unchanged if it has a malformed escape.
+func applyURLDecode(value string, _ TransformStep) string {
+	decoded, err := url.PathUnescape(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
 func globalCallID(ctx context.Context) string {
 	var callID string
 	if v := ctx.Value(any("smarterrCallID")); v != nil {
@@ -220,29 +386,46 @@ func globalCallID(ctx context.Context) string {
 	return callID
 }
 
+// InferredSource returns the token's source, inferring it from the fields set when source isn't
+// set: parameter, context, arg, annotation, resource_data, env, from_token, then stack_matches.
+func (t *Token) InferredSource() string {
+	if t.Source != "" {
+		return t.Source
+	}
+	switch {
+	case t.Parameter != nil:
+		return "parameter"
+	case t.Context != nil:
+		return "context"
+	case t.Arg != nil:
+		return "arg"
+	case t.Annotation != nil:
+		return "annotation"
+	case t.ResourceData != nil:
+		return "resource_data"
+	case t.Env != nil:
+		return "env"
+	case t.FromToken != nil:
+		return "from_token"
+	case len(t.StackMatches) > 0:
+		return "call_stack"
+	default:
+		return "parameter" // fallback for backward compatibility
+	}
+}
+
 // Resolve takes a token and resolves it based on the runtime information.
 // It supports various source types such as parameters, context values,
 // error inspection, call stack inspection, and runtime arguments.
 func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 	callID := globalCallID(ctx)
-	Debugf("[Token.Resolve %s] Resolving token: %s, source: %s, parameter: %v, context: %v, arg: %v, stack_matches: %v",
-		callID, t.Name, t.Source, t.Parameter, t.Context, t.Arg, t.StackMatches)
-	// Infer source if not set
-	source := t.Source
-	if source == "" {
-		switch {
-		case t.Parameter != nil:
-			source = "parameter"
-		case t.Context != nil:
-			source = "context"
-		case t.Arg != nil:
-			source = "arg"
-		case len(t.StackMatches) > 0:
-			source = "call_stack"
-		default:
-			source = "parameter" // fallback for backward compatibility
-		}
+	Debugf("[Token.Resolve %s] Resolving token: %s, source: %s, parameter: %v, context: %v, arg: %v, annotation: %v, resource_data: %v, env: %v, stack_matches: %v",
+		callID, t.Name, t.Source, t.Parameter, t.Context, t.Arg, t.Annotation, t.ResourceData, t.Env, t.StackMatches)
+	if t.Disabled {
+		Debugf("[Token.Resolve %s] Fallback for token %q: token is disabled", callID, t.Name)
+		return fallbackMessage(rt.Config, t.Name, "token is disabled")
 	}
+	source := t.InferredSource()
 
 	switch source {
 	case "diagnostic":
@@ -252,6 +435,8 @@ func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 			result["summary"] = diag.Summary()
 			result["detail"] = diag.Detail()
 			result["severity"] = diag.Severity().String()
+			// Normalized for templates that branch on severity, such as "error" or "warning"
+			result["severity_level"] = strings.ToLower(diag.Severity().String())
 			// Apply field transforms if present
 			if t.FieldTransforms != nil {
 				for field, transforms := range t.FieldTransforms {
@@ -267,19 +452,31 @@ func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 		}
 		Debugf("[Token.Resolve %s] Fallback for token %q: diagnostic info not found in Runtime.Diagnostic", callID, t.Name)
 		return map[string]any{
-			"summary":  fallbackMessage(rt.Config, t.Name+".summary", "diagnostic summary not found"),
-			"detail":   fallbackMessage(rt.Config, t.Name+".detail", "diagnostic detail not found"),
-			"severity": fallbackMessage(rt.Config, t.Name+".severity", "diagnostic severity not found"),
+			"summary":        fallbackMessage(rt.Config, t.Name+".summary", "diagnostic summary not found"),
+			"detail":         fallbackMessage(rt.Config, t.Name+".detail", "diagnostic detail not found"),
+			"severity":       fallbackMessage(rt.Config, t.Name+".severity", "diagnostic severity not found"),
+			"severity_level": fallbackMessage(rt.Config, t.Name+".severity_level", "diagnostic severity not found"),
 		}
 	case "parameter":
+		if p := rt.findParameter(t.Parameter); p != nil && p.Values != nil {
+			// A list parameter resolves to a list, with transforms applied to each value
+			values := make([]string, len(p.Values))
+			for i, v := range p.Values {
+				values[i] = rt.applyTransforms(ctx, t, v)
+			}
+			return values
+		}
 		var value string
 		if t.Parameter == nil {
 			Debugf("[Token.Resolve %s] Fallback for token %q: token.Parameter is nil", callID, t.Name)
 			value = fallbackMessage(rt.Config, t.Name, "token.Parameter is nil")
+		} else if rt.Config == nil {
+			Debugf("[Token.Resolve %s] Fallback for token %q: runtime configuration is nil", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "runtime configuration is nil")
 		} else {
 			for _, p := range rt.Config.Parameters {
 				if p.Name == *t.Parameter {
-					value = p.Value
+					value = p.Text()
 					break
 				}
 			}
@@ -319,21 +516,13 @@ func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 		return value
 	case "call_stack":
 		var value string
-		var filteredStackMatches []StackMatch
-		for _, name := range t.StackMatches {
-			for _, sm := range rt.Config.StackMatches {
-				if sm.Name == name {
-					filteredStackMatches = append(filteredStackMatches, sm)
-					break
-				}
-			}
-		}
-		frames, err := gatherCallStack(3)
+		filteredStackMatches := rt.tokenStackMatches(t)
+		frames, err := rt.callStack()
 		if err != nil {
 			Debugf("[Token.Resolve %s] Fallback for token %q: call stack unavailable", callID, t.Name)
 			value = fallbackMessage(rt.Config, t.Name, "call stack unavailable")
 		} else {
-			display, err := processStackMatches(filteredStackMatches, frames)
+			display, err := t.stackDisplay(filteredStackMatches, frames)
 			if err != nil {
 				Debugf("[Token.Resolve %s] Fallback for token %q: stack match error: %s", callID, t.Name, err.Error())
 				value = fallbackMessage(rt.Config, t.Name, "stack match error: "+err.Error())
@@ -350,15 +539,7 @@ func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 		return value
 	case "error_stack":
 		var value string
-		var filteredStackMatches []StackMatch
-		for _, name := range t.StackMatches {
-			for _, sm := range rt.Config.StackMatches {
-				if sm.Name == name {
-					filteredStackMatches = append(filteredStackMatches, sm)
-					break
-				}
-			}
-		}
+		filteredStackMatches := rt.tokenStackMatches(t)
 		var frames []runtime.Frame
 		Debugf("[Token.Resolve %s] err type: %T", callID, rt.Error)
 		var stackProvider interface{ Stack() []runtime.Frame }
@@ -369,7 +550,7 @@ func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 			Debugf("[Token.Resolve %s] Fallback for token %q: error_stack unavailable", callID, t.Name)
 			value = fallbackMessage(rt.Config, t.Name, "error_stack unavailable")
 		} else {
-			display, err := processStackMatches(filteredStackMatches, frames)
+			display, err := t.stackDisplay(filteredStackMatches, frames)
 			if err != nil {
 				Debugf("[Token.Resolve %s] Fallback for token %q: error_stack match error: %s", callID, t.Name, err.Error())
 				value = fallbackMessage(rt.Config, t.Name, "error_stack match error: "+err.Error())
@@ -384,6 +565,31 @@ func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 			value = rt.applyTransforms(ctx, t, value)
 		}
 		return value
+	case "error_origin":
+		// The first captured frame is where NewError or Errorf was called
+		var value string
+		var stackProvider interface{ Stack() []runtime.Frame }
+		if errors.As(rt.Error, &stackProvider) && stackProvider != nil && len(stackProvider.Stack()) > 0 {
+			frame := stackProvider.Stack()[0]
+			value = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		} else {
+			Debugf("[Token.Resolve %s] Fallback for token %q: error_origin unavailable", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "error_origin unavailable")
+		}
+		if len(t.Transforms) > 0 {
+			value = rt.applyTransforms(ctx, t, value)
+		}
+		return value
+	case "error_code":
+		value := rt.ErrorCode(ctx)
+		if value == "" {
+			Debugf("[Token.Resolve %s] Fallback for token %q: no error code", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "no error code")
+		}
+		if len(t.Transforms) > 0 {
+			value = rt.applyTransforms(ctx, t, value)
+		}
+		return value
 	case "error":
 		var value string
 		Debugf("[Token.Resolve %s] Resolving error token: %s, err: %s", callID, t.Name, rt.Error)
@@ -403,10 +609,12 @@ func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 			Debugf("[Token.Resolve %s] Fallback for token %q: token.Arg is nil", callID, t.Name)
 			value = fallbackMessage(rt.Config, t.Name, "token.Arg is nil")
 		} else {
-			argVal, ok := rt.Args[*t.Arg]
+			argVal, ok := lookupArg(rt.Args, *t.Arg)
 			if !ok {
 				Debugf("[Token.Resolve %s] Fallback for token %q: argument (%s) not found in runtime args", callID, t.Name, *t.Arg)
 				value = fallbackMessage(rt.Config, t.Name, fmt.Sprintf("argument (%s) not found in runtime args", *t.Arg))
+			} else if d, ok := argVal.(time.Duration); ok {
+				value = formatDuration(d)
 			} else {
 				value = fmt.Sprintf("%v", argVal)
 			}
@@ -415,11 +623,129 @@ func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 			value = rt.applyTransforms(ctx, t, value)
 		}
 		return value
+	case "annotation":
+		var value string
+		if t.Annotation == nil {
+			Debugf("[Token.Resolve %s] Fallback for token %q: token.Annotation is nil", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "token.Annotation is nil")
+		} else {
+			var annotated interface {
+				Annotation(key string) (string, bool)
+			}
+			annotation, ok := "", false
+			if errors.As(rt.Error, &annotated) && annotated != nil {
+				annotation, ok = annotated.Annotation(*t.Annotation)
+			}
+			if !ok {
+				Debugf("[Token.Resolve %s] Fallback for token %q: annotation (%s) not found on error", callID, t.Name, *t.Annotation)
+				value = fallbackMessage(rt.Config, t.Name, fmt.Sprintf("annotation (%s) not found on error", *t.Annotation))
+			} else {
+				value = annotation
+			}
+		}
+		if len(t.Transforms) > 0 {
+			value = rt.applyTransforms(ctx, t, value)
+		}
+		return value
+	case "from_token":
+		var value string
+		if t.FromToken == nil {
+			Debugf("[Token.Resolve %s] Fallback for token %q: token.FromToken is nil", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "token.FromToken is nil")
+		} else if v, err := rt.fromTokenValue(ctx, *t.FromToken); err != nil {
+			Debugf("[Token.Resolve %s] Fallback for token %q: %s", callID, t.Name, err)
+			value = fallbackMessage(rt.Config, t.Name, err.Error())
+		} else {
+			value = fmt.Sprintf("%v", v)
+		}
+		if len(t.Transforms) > 0 {
+			value = rt.applyTransforms(ctx, t, value)
+		}
+		return value
+	case "package_service":
+		var value string
+		frames, err := rt.callStack()
+		if err != nil {
+			Debugf("[Token.Resolve %s] Fallback for token %q: call stack unavailable", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "call stack unavailable")
+		} else if pkg, ok := packageService(frames); !ok {
+			Debugf("[Token.Resolve %s] Fallback for token %q: no service package in call stack", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "no service package in call stack")
+		} else if service, ok := t.ServiceMap[pkg]; ok {
+			value = service
+		} else {
+			value = pkg
+		}
+		if len(t.Transforms) > 0 {
+			value = rt.applyTransforms(ctx, t, value)
+		}
+		return value
+	case "resource_data":
+		var value string
+		if t.ResourceData == nil {
+			Debugf("[Token.Resolve %s] Fallback for token %q: token.ResourceData is nil", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "token.ResourceData is nil")
+		} else if v, ok := resourceDataValue(ctx, *t.ResourceData); !ok {
+			Debugf("[Token.Resolve %s] Fallback for token %q: resource data attribute (%s) not found", callID, t.Name, *t.ResourceData)
+			value = fallbackMessage(rt.Config, t.Name, fmt.Sprintf("resource data attribute (%s) not found", *t.ResourceData))
+		} else {
+			value = fmt.Sprintf("%v", v)
+		}
+		if len(t.Transforms) > 0 {
+			value = rt.applyTransforms(ctx, t, value)
+		}
+		return value
+	case "env":
+		var value string
+		if t.Env == nil {
+			Debugf("[Token.Resolve %s] Fallback for token %q: token.Env is nil", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "token.Env is nil")
+		} else if !strings.HasPrefix(*t.Env, EnvVarPrefix) {
+			// Like env in expressions, only SMARTERR_ variables are exposed, so config can't leak secrets
+			Debugf("[Token.Resolve %s] Fallback for token %q: environment variable (%s) doesn't start with %s", callID, t.Name, *t.Env, EnvVarPrefix)
+			value = fallbackMessage(rt.Config, t.Name, fmt.Sprintf("environment variable (%s) doesn't start with %s", *t.Env, EnvVarPrefix))
+		} else if value = os.Getenv(*t.Env); value == "" {
+			Debugf("[Token.Resolve %s] Fallback for token %q: environment variable (%s) not set", callID, t.Name, *t.Env)
+			value = fallbackMessage(rt.Config, t.Name, fmt.Sprintf("environment variable (%s) not set", *t.Env))
+		}
+		if len(t.Transforms) > 0 {
+			value = rt.applyTransforms(ctx, t, value)
+		}
+		return value
+	case "regex_capture":
+		value, reason := t.regexCapture(rt.Error)
+		if reason != "" {
+			Debugf("[Token.Resolve %s] Fallback for token %q: %s", callID, t.Name, reason)
+			value = fallbackMessage(rt.Config, t.Name, reason)
+		}
+		if len(t.Transforms) > 0 {
+			value = rt.applyTransforms(ctx, t, value)
+		}
+		return value
 	case "hints":
 		var value string
 		Debugf("[Token.Resolve %s] Resolving hints token: %s", callID, t.Name)
 		if rt.Error != nil {
-			value = resolveHints(ctx, rt.Error.Error(), rt.Config)
+			value = resolveHints(ctx, rt.Error, rt.Config).Suggestions
+		}
+		matched := value != ""
+		if !matched {
+			Debugf("[Token.Resolve %s] Fallback for token %q: no matching hint found", callID, t.Name)
+			value = fallbackMessage(rt.Config, t.Name, "no matching hint found")
+		}
+		if len(t.Transforms) > 0 {
+			value = rt.applyTransforms(ctx, t, value)
+		}
+		// Separate matched hints from the rest of the detail (e.g., a blank line before suggestions)
+		if matched && rt.Config.Smarterr != nil && rt.Config.Smarterr.HintSeparator != nil {
+			value = *rt.Config.Smarterr.HintSeparator + value
+		}
+		return value
+	case "hint_name":
+		var value string
+		Debugf("[Token.Resolve %s] Resolving hint_name token: %s", callID, t.Name)
+		if rt.Error != nil {
+			value = strings.Join(resolveHints(ctx, rt.Error, rt.Config).Names, ListSeparator)
 		}
 		if value == "" {
 			Debugf("[Token.Resolve %s] Fallback for token %q: no matching hint found", callID, t.Name)
@@ -440,6 +766,161 @@ func (t *Token) Resolve(ctx context.Context, rt *Runtime) any {
 	}
 }
 
+// DefaultCaptureGroup is the capture group a regex_capture token returns if it doesn't set
+// capture_group.
+const DefaultCaptureGroup = 1
+
+// regexCapture returns the capture group of the token's capture_regex in err's message. If there's
+// no value, reason says why, such as that the regex didn't match.
+func (t *Token) regexCapture(err error) (value, reason string) {
+	if t.CaptureRegex == nil {
+		return "", "token.CaptureRegex is nil"
+	}
+	if err == nil {
+		return "", "error is nil"
+	}
+	re, compileErr := regexp.Compile(*t.CaptureRegex)
+	if compileErr != nil {
+		return "", fmt.Sprintf("invalid capture_regex: %v", compileErr)
+	}
+	group := DefaultCaptureGroup
+	if t.CaptureGroup != nil {
+		group = *t.CaptureGroup
+	}
+	if group < 0 || group > re.NumSubexp() {
+		return "", fmt.Sprintf("capture_group %d not in capture_regex", group)
+	}
+	match := re.FindStringSubmatch(err.Error())
+	if match == nil {
+		return "", "capture_regex didn't match the error"
+	}
+	return match[group], ""
+}
+
+// lookupArg finds an arg by key. If no arg has the exact key, a dotted key (e.g., "id.primary")
+// walks nested maps and the exported fields of structs, e.g., "output.VpcId". Pointers along the
+// path, including the one at the end (as for *string fields), are dereferenced; a nil one or an
+// unexported field means the arg isn't found.
+func lookupArg(args map[string]any, key string) (any, bool) {
+	if v, ok := args[key]; ok {
+		return v, true
+	}
+	parts := strings.Split(key, ".")
+	if len(parts) < 2 {
+		return nil, false
+	}
+	var current any = args
+	for _, part := range parts {
+		var ok bool
+		if current, ok = argField(current, part); !ok {
+			return nil, false
+		}
+	}
+	v := reflect.ValueOf(current)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+// argField returns the named entry of a map with string keys or the named exported field of a
+// struct, dereferencing pointers and interfaces first.
+func argField(current any, name string) (any, bool) {
+	if m, ok := current.(map[string]any); ok {
+		v, ok := m[name]
+		return v, ok
+	}
+	v := reflect.ValueOf(current)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		f, ok := v.Type().FieldByName(name)
+		if !ok || !f.IsExported() {
+			return nil, false
+		}
+		field, err := v.FieldByIndexErr(f.Index)
+		if err != nil || !field.CanInterface() {
+			// A nil embedded struct pointer, or a field promoted from an unexported embedded struct
+			return nil, false
+		}
+		return field.Interface(), true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		e := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		if !e.IsValid() {
+			return nil, false
+		}
+		return e.Interface(), true
+	}
+	return nil, false
+}
+
+// formatDuration formats a duration for people, e.g., "5 minutes" or "1 hour 30 minutes".
+// Durations under a second keep Go's format (e.g., "500ms"); longer ones are rounded to the second.
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		if d == math.MinInt64 {
+			// -d would overflow back to d; a nanosecond is lost to rounding anyway
+			d++
+		}
+		return "-" + formatDuration(-d)
+	}
+	if d < time.Second {
+		return d.String()
+	}
+	d = d.Round(time.Second)
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+		{"second", time.Second},
+	}
+	var parts []string
+	for _, u := range units {
+		n := d / u.size
+		d -= n * u.size
+		switch {
+		case n == 1:
+			parts = append(parts, "1 "+u.name)
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%d %ss", n, u.name))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// tokenStackMatches returns the config's StackMatch rules named by the token, in the token's order.
+func (rt *Runtime) tokenStackMatches(t *Token) []StackMatch {
+	if rt.Config == nil {
+		return nil
+	}
+	var stackMatches []StackMatch
+	for _, name := range t.StackMatches {
+		for _, sm := range rt.Config.StackMatches {
+			if sm.Name == name {
+				stackMatches = append(stackMatches, sm)
+				break
+			}
+		}
+	}
+	return stackMatches
+}
+
 // Helper to apply a named transform to a value (for field transforms)
 func (rt *Runtime) applyTransformByName(name, value string) string {
 	if rt.Config == nil {
@@ -448,25 +929,7 @@ func (rt *Runtime) applyTransformByName(name, value string) string {
 	for i := range rt.Config.Transforms {
 		if rt.Config.Transforms[i].Name == name {
 			for _, step := range rt.Config.Transforms[i].Steps {
-				switch step.Type {
-				case "strip_prefix":
-					value = applyStripPrefix(value, step)
-				case "strip_suffix":
-					value = applyStripSuffix(value, step)
-				case "remove":
-					value = applyRemove(value, step)
-				case "replace":
-					value = applyReplace(value, step)
-				case "trim_space":
-					value = strings.TrimSpace(value)
-				case "fix_space":
-					value = strings.TrimSpace(value)
-					value = regexp.MustCompile(`\s+`).ReplaceAllString(value, " ")
-				case "lower":
-					value = strings.ToLower(value)
-				case "upper":
-					value = strings.ToUpper(value)
-				}
+				value = rt.Config.ApplyTransformStep(value, step)
 			}
 			break
 		}
@@ -474,9 +937,173 @@ func (rt *Runtime) applyTransformByName(name, value string) string {
 	return value
 }
 
+// TransformFunc applies a transform step to a value.
+type TransformFunc func(value string, step TransformStep) string
+
+// configTransformFunc applies a transform step to a value with access to the config, which may be nil.
+type configTransformFunc func(cfg *Config, value string, step TransformStep) string
+
+// withoutConfig adapts a TransformFunc that doesn't need the config.
+func withoutConfig(fn TransformFunc) configTransformFunc {
+	return func(_ *Config, value string, step TransformStep) string {
+		return fn(value, step)
+	}
+}
+
+// builtinTransforms maps each built-in transform step type to its implementation.
+var builtinTransforms = map[string]configTransformFunc{
+	"strip_prefix": withoutConfig(applyStripPrefix),
+	"strip_suffix": withoutConfig(applyStripSuffix),
+	"remove":       withoutConfig(applyRemove),
+	"replace":      withoutConfig(applyReplace),
+	"trim_space": withoutConfig(func(value string, _ TransformStep) string {
+		return strings.TrimSpace(value)
+	}),
+	"fix_space": withoutConfig(func(value string, _ TransformStep) string {
+		return regexp.MustCompile(`\s+`).ReplaceAllString(strings.TrimSpace(value), " ")
+	}),
+	"lower": withoutConfig(func(value string, _ TransformStep) string {
+		return strings.ToLower(value)
+	}),
+	"upper": withoutConfig(func(value string, _ TransformStep) string {
+		return strings.ToUpper(value)
+	}),
+	"param_lookup":  applyParamLookup,
+	"arn_short":     withoutConfig(applyARNShort),
+	"truncate":      withoutConfig(applyTruncate),
+	"split":         withoutConfig(applySplit),
+	"base64_decode": withoutConfig(applyBase64Decode),
+	"url_decode":    withoutConfig(applyURLDecode),
+}
+
+// transformRegistry maps each supported transform step type, built-in or registered by the host,
+// to its implementation. It is the single source of truth for applying and validating steps.
+var (
+	transformRegistryMu sync.RWMutex
+	transformRegistry   = maps.Clone(builtinTransforms)
+)
+
+// RegisterTransformStep adds a custom transform step type. Registering a type again replaces the
+// earlier registration. It panics if stepType is empty or a built-in type, or if fn is nil.
+func RegisterTransformStep(stepType string, fn TransformFunc) {
+	if stepType == "" || fn == nil {
+		panic("smarterr: transform registration requires a step type and a function")
+	}
+	if _, ok := builtinTransforms[stepType]; ok {
+		panic(fmt.Sprintf("smarterr: cannot register built-in transform step type %q", stepType))
+	}
+	transformRegistryMu.Lock()
+	defer transformRegistryMu.Unlock()
+	transformRegistry[stepType] = withoutConfig(fn)
+}
+
+// TransformStepTypes returns the supported transform step types, sorted.
+func TransformStepTypes() []string {
+	transformRegistryMu.RLock()
+	defer transformRegistryMu.RUnlock()
+	return slices.Sorted(maps.Keys(transformRegistry))
+}
+
+// IsTransformStepType reports whether stepType is a supported transform step type.
+func IsTransformStepType(stepType string) bool {
+	transformRegistryMu.RLock()
+	defer transformRegistryMu.RUnlock()
+	_, ok := transformRegistry[stepType]
+	return ok
+}
+
+// ApplyTransformStep applies a single transform step to a value. Steps that read the config, such
+// as param_lookup, use cfg, which may be nil. Unknown step types leave the value unchanged.
+func (cfg *Config) ApplyTransformStep(value string, step TransformStep) string {
+	transformRegistryMu.RLock()
+	fn, ok := transformRegistry[step.Type]
+	transformRegistryMu.RUnlock()
+	if ok {
+		return fn(cfg, value, step)
+	}
+	return value
+}
+
+// findParameter returns the config parameter with the given name, or nil if name is nil or the
+// config doesn't define it.
+func (rt *Runtime) findParameter(name *string) *Parameter {
+	if name == nil || rt.Config == nil {
+		return nil
+	}
+	for i := range rt.Config.Parameters {
+		if rt.Config.Parameters[i].Name == *name {
+			return &rt.Config.Parameters[i]
+		}
+	}
+	return nil
+}
+
+// ListSeparator joins the values of a list parameter where a single string is needed, such as in
+// param_lookup.
+const ListSeparator = ", "
+
+// Text returns the parameter's value, or for a list parameter, its values joined with
+// ListSeparator.
+func (p Parameter) Text() string {
+	if p.Values != nil {
+		return strings.Join(p.Values, ListSeparator)
+	}
+	if p.Value == nil {
+		return ""
+	}
+	return *p.Value
+}
+
+// applyParamLookup treats the value as the name of a config parameter, optionally prefixed by the
+// step's value (e.g., "error_code."), and returns the parameter's value, or a list parameter's
+// values joined with ListSeparator. On a miss, it returns the step's with, if set, or the value
+// unchanged.
+func applyParamLookup(cfg *Config, value string, step TransformStep) string {
+	key := strings.TrimSpace(value)
+	if step.Value != nil {
+		key = *step.Value + key
+	}
+	if cfg != nil {
+		for _, p := range cfg.Parameters {
+			if p.Name == key {
+				return p.Text()
+			}
+		}
+	}
+	if step.With != nil {
+		return *step.With
+	}
+	return value
+}
+
+// ApplyTransform applies the named transform from the config to a value.
+// It returns an error if the transform is not defined.
+func (cfg *Config) ApplyTransform(name, value string) (string, error) {
+	if cfg.FindTransform(name) == nil {
+		return "", fmt.Errorf("transform %q not found", name)
+	}
+	rt := &Runtime{Config: cfg}
+	return rt.applyTransformByName(name, value), nil
+}
+
+// FindTransform returns the named transform from the config, or nil if it is not defined.
+func (cfg *Config) FindTransform(name string) *Transform {
+	for i := range cfg.Transforms {
+		if cfg.Transforms[i].Name == name {
+			return &cfg.Transforms[i]
+		}
+	}
+	return nil
+}
+
 // BuildTokenValueMap resolves all tokens in the config and returns a map of token name to value.
 func (rt *Runtime) BuildTokenValueMap(ctx context.Context) map[string]any {
 	callID := globalCallID(ctx)
+	values := make(map[string]any)
+	if rt.Config == nil {
+		Debugf("[BuildTokenValueMap %s] Runtime configuration is nil; returning empty token map", callID)
+		return values
+	}
 	Debugf("[BuildTokenValueMap %s] Building token value map, %+v", callID, rt.Config.Tokens)
 	// Debug: print live call stack
 	pcs := make([]uintptr, 10)
@@ -490,20 +1117,168 @@ func (rt *Runtime) BuildTokenValueMap(ctx context.Context) map[string]any {
 			break
 		}
 	}
-	values := make(map[string]any)
-	if rt.Config == nil {
-		Debugf("[BuildTokenValueMap %s] Runtime configuration is nil; returning empty token map", callID)
-		return values
+	// Resolve tokens after the tokens they're derived from (from_token)
+	order, cycle := TokenOrder(rt.Config.Tokens)
+	if cycle != nil {
+		Debugf("[BuildTokenValueMap %s] from_token cycle: %s", callID, strings.Join(cycle, " -> "))
 	}
-	for _, t := range rt.Config.Tokens {
-		values[t.Name] = t.Resolve(ctx, rt)
+	for _, t := range order {
+		values[t.Name] = rt.resolveToken(ctx, t)
 	}
 	return values
 }
 
-// gatherCallStack retrieves the call stack frames, skipping the specified number of frames.
-func gatherCallStack(skip int) ([]runtime.Frame, error) {
-	callers := make([]uintptr, 10) // Adjust size as needed
+// resolveToken resolves t, caching its value for tokens derived from it.
+func (rt *Runtime) resolveToken(ctx context.Context, t *Token) any {
+	if v, ok := rt.values[t.Name]; ok {
+		return v
+	}
+	if rt.resolving == nil {
+		rt.resolving = make(map[string]bool)
+	}
+	rt.resolving[t.Name] = true
+	v := t.Resolve(ctx, rt)
+	delete(rt.resolving, t.Name)
+	if rt.values == nil {
+		rt.values = make(map[string]any)
+	}
+	rt.values[t.Name] = v
+	return v
+}
+
+// fromTokenValue returns the resolved value of the named token for a from_token token.
+func (rt *Runtime) fromTokenValue(ctx context.Context, name string) (any, error) {
+	if v, ok := rt.values[name]; ok {
+		return v, nil
+	}
+	if rt.resolving[name] {
+		return nil, fmt.Errorf("from_token cycle at token %q", name)
+	}
+	if rt.Config != nil {
+		for i := range rt.Config.Tokens {
+			if rt.Config.Tokens[i].Name == name {
+				return rt.resolveToken(ctx, &rt.Config.Tokens[i]), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("token %q not found in config", name)
+}
+
+// TokenOrder returns tokens ordered so that each token comes after the token it's derived from
+// with from_token, keeping the config order otherwise. If from_token references form a cycle, it
+// also returns the cycle, such as [a b a]; the tokens in it are still included.
+func TokenOrder(tokens []Token) (order []*Token, cycle []string) {
+	byName := make(map[string]*Token, len(tokens))
+	for i := range tokens {
+		byName[tokens[i].Name] = &tokens[i]
+	}
+	const (
+		visiting = iota + 1
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(t *Token)
+	visit = func(t *Token) {
+		switch state[t.Name] {
+		case visiting:
+			if cycle == nil {
+				i := slices.Index(path, t.Name)
+				cycle = append(slices.Clone(path[i:]), t.Name)
+			}
+			return
+		case done:
+			return
+		}
+		state[t.Name] = visiting
+		path = append(path, t.Name)
+		if t.FromToken != nil {
+			if dep, ok := byName[*t.FromToken]; ok {
+				visit(dep)
+			}
+		}
+		path = path[:len(path)-1]
+		state[t.Name] = done
+		order = append(order, t)
+	}
+	for i := range tokens {
+		visit(&tokens[i])
+	}
+	return order, cycle
+}
+
+// callStack returns the call stack for call_stack tokens, gathering it on first use so that tokens
+// resolved for the same call share one stack walk. The stack starts at the caller of Token.Resolve.
+func (rt *Runtime) callStack() ([]runtime.Frame, error) {
+	if !rt.stackGathered {
+		depth := callStackDepth
+		if rt.Config.hasCalledAfter() {
+			depth = calledAfterStackDepth
+		}
+		rt.stackFrames, rt.stackErr = gatherCallStack(4, depth)
+		rt.stackGathered = true
+	}
+	return rt.stackFrames, rt.stackErr
+}
+
+// smarterrModule is the import path of smarterr's module. package_service tokens skip frames in
+// its packages.
+const smarterrModule = "github.com/YakDriver/smarterr"
+
+// packageService returns the name of the service package of the first frame outside smarterr
+// whose package path has a service directory, such as ec2 for a function in
+// github.com/hashicorp/terraform-provider-aws/internal/service/ec2. It reports false if no frame
+// does.
+func packageService(frames []runtime.Frame) (string, bool) {
+	for _, frame := range frames {
+		pkg := framePackage(frame.Function)
+		if pkg == "" || pkg == smarterrModule || strings.HasPrefix(pkg, smarterrModule+"/") {
+			continue
+		}
+		segments := strings.Split(pkg, "/")
+		for i := len(segments) - 2; i >= 0; i-- {
+			if segments[i] == "service" {
+				return segments[i+1], true
+			}
+		}
+	}
+	return "", false
+}
+
+// framePackage returns the import path of the package of a frame's function name, such as
+// example.com/service/ec2 for example.com/service/ec2.resourceVPCCreate.func1.
+func framePackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	dot := strings.Index(function[slash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+	return function[:slash+1+dot]
+}
+
+// callStackDepth is how many frames call_stack tokens see. calledAfterStackDepth is the deeper
+// stack gathered when a stack_match sets called_after, so it can reach the resource function.
+const (
+	callStackDepth        = 10
+	calledAfterStackDepth = 32
+)
+
+// hasCalledAfter reports whether any stack_match sets called_after.
+func (cfg *Config) hasCalledAfter() bool {
+	if cfg == nil {
+		return false
+	}
+	for _, sm := range cfg.StackMatches {
+		if sm.CalledAfter != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// gatherCallStack retrieves up to depth call stack frames, skipping the specified number of frames.
+func gatherCallStack(skip, depth int) ([]runtime.Frame, error) {
+	callers := make([]uintptr, depth)
 	n := runtime.Callers(skip, callers)
 	if n == 0 {
 		return nil, fmt.Errorf("no call stack available")
@@ -522,11 +1297,36 @@ func gatherCallStack(skip int) ([]runtime.Frame, error) {
 }
 
 // processStackMatches processes the stack frames and matches them against the StackMatch rules.
-// If a match is found, it returns the Display value of the matching rule.
+// If a match is found, it returns the Display value of the matching rule. Within a frame, rules are
+// tried in priority order (highest first), then in the order given. A rule with CalledAfter only
+// matches a frame if a frame further up the stack, toward the root, matches CalledAfter.
 func processStackMatches(stackMatches []StackMatch, frames []runtime.Frame) (string, error) {
-	for _, frame := range frames {
-		for _, sm := range stackMatches {
-			if sm.CalledFrom == "" {
+	stackMatches = SortStackMatchesByPriority(stackMatches)
+
+	// afterLimits[i] is the index of the outermost frame matching rule i's CalledAfter; the rule
+	// can only match frames before it. Rules without CalledAfter can match any frame.
+	afterLimits := make([]int, len(stackMatches))
+	for i, sm := range stackMatches {
+		afterLimits[i] = len(frames)
+		if sm.CalledAfter == "" {
+			continue
+		}
+		re, err := regexp.Compile(sm.CalledAfter)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex in CalledAfter for StackMatch %q: %w", sm.Name, err)
+		}
+		afterLimits[i] = -1
+		for j := len(frames) - 1; j >= 0; j-- {
+			if re.MatchString(frames[j].Function) {
+				afterLimits[i] = j
+				break
+			}
+		}
+	}
+
+	for f, frame := range frames {
+		for i, sm := range stackMatches {
+			if sm.CalledFrom == "" || f >= afterLimits[i] {
 				continue
 			}
 			matched, err := regexp.MatchString(sm.CalledFrom, frame.Function)
@@ -541,6 +1341,61 @@ func processStackMatches(stackMatches []StackMatch, frames []runtime.Frame) (str
 	return "", nil
 }
 
+// processStackMatchesByCategory finds the best match for each category of StackMatch rules and
+// returns the Display values keyed by category. For each category, the best match is the first
+// frame matching one of that category's rules, with rules tried in priority order.
+func processStackMatchesByCategory(stackMatches []StackMatch, frames []runtime.Frame) (map[string]string, error) {
+	byCategory := make(map[string][]StackMatch)
+	for _, sm := range stackMatches {
+		byCategory[sm.Category] = append(byCategory[sm.Category], sm)
+	}
+	displays := make(map[string]string)
+	for category, matches := range byCategory {
+		display, err := processStackMatches(matches, frames)
+		if err != nil {
+			return nil, err
+		}
+		if display != "" {
+			displays[category] = display
+		}
+	}
+	return displays, nil
+}
+
+// stackDisplay returns the display for the token's stack matches. If the token sets stack
+// categories, the best match for each category is joined in the listed order (e.g., "creating,
+// waiting"); otherwise, the single best match is returned.
+func (t *Token) stackDisplay(stackMatches []StackMatch, frames []runtime.Frame) (string, error) {
+	if len(t.StackCategories) == 0 {
+		return processStackMatches(stackMatches, frames)
+	}
+	displays, err := processStackMatchesByCategory(stackMatches, frames)
+	if err != nil {
+		return "", err
+	}
+	join := ", "
+	if t.StackJoin != nil {
+		join = *t.StackJoin
+	}
+	var parts []string
+	for _, category := range t.StackCategories {
+		if d, ok := displays[category]; ok {
+			parts = append(parts, d)
+		}
+	}
+	return strings.Join(parts, join), nil
+}
+
+// SortStackMatchesByPriority returns a copy of stackMatches sorted by priority, highest first.
+// Rules with equal priority keep their relative order.
+func SortStackMatchesByPriority(stackMatches []StackMatch) []StackMatch {
+	sorted := slices.Clone(stackMatches)
+	slices.SortStableFunc(sorted, func(a, b StackMatch) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+	return sorted
+}
+
 // parseKeyvals parses the provided key-value pairs into a map[string]any.
 // This lays the foundation for flexible calling without requiring devs to manually build a map.
 // For example, if kv is "id", "rds", "service", "Provider", it will return
@@ -553,7 +1408,23 @@ func processStackMatches(stackMatches []StackMatch, frames []runtime.Frame) (str
 //
 // It ensures that the kv length is even and that all keys are strings.
 // If the length is not even or a key is not a string, it panics.
+// Later duplicate keys overwrite earlier ones.
 func parseKeyvals(ctx context.Context, kv ...any) map[string]any {
+	return parseKeyvalsMode(ctx, "last", kv...)
+}
+
+// duplicateKeyvalMode returns the configured duplicate keyval mode, defaulting to "last".
+func duplicateKeyvalMode(cfg *Config) string {
+	if cfg != nil && cfg.Smarterr != nil && cfg.Smarterr.DuplicateKeyvalMode != nil && *cfg.Smarterr.DuplicateKeyvalMode != "" {
+		return *cfg.Smarterr.DuplicateKeyvalMode
+	}
+	return "last"
+}
+
+// parseKeyvalsMode is parseKeyvals with control over duplicate keys: "last" (later values win),
+// "first" (earlier values win), or "collect" (all values for a duplicated key are collected, in
+// order, into a []any). Unknown modes behave like "last".
+func parseKeyvalsMode(ctx context.Context, mode string, kv ...any) map[string]any {
 	callID := globalCallID(ctx)
 
 	// Check if the length of kv is odd
@@ -562,36 +1433,82 @@ func parseKeyvals(ctx context.Context, kv ...any) map[string]any {
 		kv = kv[:len(kv)-1] // Remove the last element
 	}
 	result := make(map[string]any)
+	var collectedKeys map[string]struct{} // keys whose value is a collected []any
 	for i := 0; i < len(kv); i += 2 {
 		key, ok := kv[i].(string)
 		if !ok {
 			Debugf("[parseKeyvals %s] Invalid key type at index %d: expected string, got %T", callID, i, kv[i])
 			return map[string]any{}
 		}
-		result[key] = kv[i+1]
+		existing, dup := result[key]
+		if !dup {
+			result[key] = kv[i+1]
+			continue
+		}
+		Debugf("[parseKeyvals %s] Duplicate key %q (mode: %s)", callID, key, mode)
+		switch mode {
+		case "first":
+			// keep the earlier value
+		case "collect":
+			if _, collected := collectedKeys[key]; !collected {
+				existing = []any{existing}
+				if collectedKeys == nil {
+					collectedKeys = make(map[string]struct{})
+				}
+				collectedKeys[key] = struct{}{}
+			}
+			result[key] = append(existing.([]any), kv[i+1])
+		default:
+			result[key] = kv[i+1]
+		}
 	}
 	return result
 }
 
-// RenderTemplate renders a named template from the config using the provided token values.
+// RenderTemplate renders a named template from the config using the provided token values. If the
+// context has a locale (see WithLocale), it renders the template's variant for the locale, such as
+// error_detail.ja, when the config defines one.
 func (cfg *Config) RenderTemplate(ctx context.Context, name string, values map[string]any) (string, error) {
 	callID := globalCallID(ctx)
 	Debugf("[RenderTemplate %s] Rendering template %q with values: %v", callID, name, values)
+	if cfg == nil {
+		return "", fmt.Errorf("cannot render template %q: config is nil", name)
+	}
 	var tmplStr string
-	for _, tmpl := range cfg.Templates {
-		if tmpl.Name == name {
-			tmplStr = tmpl.Format
-			break
-		}
+	if found := cfg.findTemplate(ctx, name); found != nil {
+		Debugf("[RenderTemplate %s] Using template %q for %q", callID, found.Name, name)
+		tmplStr = found.Format
 	}
 	if tmplStr == "" {
 		return "", fmt.Errorf("template %q not found", name)
 	}
 
-	tmpl, err := template.New(name).Parse(tmplStr)
+	tmpl, err := NewTemplate(name).Parse(tmplStr)
 	if err != nil {
 		return "", err
 	}
+	if len(CollectTemplateReferences(tmpl)) > 0 {
+		if cycle := cfg.templateCycle(); cycle != nil {
+			return "", fmt.Errorf("cannot render template %q: template reference cycle %s", name, strings.Join(cycle, " -> "))
+		}
+	}
+	// Add the Config templates it invokes with {{template "name"}}, and those they invoke, unless
+	// the template defines them itself with {{define}}
+	for pending := CollectTemplateReferences(tmpl); len(pending) > 0; pending = pending[1:] {
+		ref := pending[0]
+		if tmpl.Lookup(ref) != nil {
+			continue
+		}
+		found := cfg.findTemplate(ctx, ref)
+		if found == nil {
+			continue // Execute reports the undefined template
+		}
+		helper, err := tmpl.New(ref).Parse(found.Format)
+		if err != nil {
+			return "", err
+		}
+		pending = append(pending, CollectTemplateReferences(helper)...)
+	}
 
 	// Scan the template AST for all referenced variables
 	vars := CollectTemplateVariables(tmpl)
@@ -611,11 +1528,113 @@ func (cfg *Config) RenderTemplate(ctx context.Context, name string, values map[s
 	return buf.String(), nil
 }
 
+// TruncatedNotice is appended to a detail shortened to max_detail_length.
+const TruncatedNotice = " (truncated)"
+
+// TruncateDetail shortens detail to the config's max_detail_length, in characters, and appends
+// TruncatedNotice. It returns detail unchanged if the setting is unset, not positive, or not exceeded.
+func (cfg *Config) TruncateDetail(detail string) string {
+	return cfg.truncateDetail(detail, 0)
+}
+
+// TruncateDetailWithCode truncates detail like TruncateDetail, keeping room for the error code, and
+// then appends code like AppendErrorCode. So the code is never cut off, and it doesn't push the
+// detail past max_detail_length.
+func (cfg *Config) TruncateDetailWithCode(detail, code string) string {
+	suffix := cfg.AppendErrorCode("", code)
+	return cfg.truncateDetail(detail, utf8.RuneCountInString(suffix)) + suffix
+}
+
+// truncateDetail truncates detail to max_detail_length less reserve characters.
+func (cfg *Config) truncateDetail(detail string, reserve int) string {
+	if cfg == nil || cfg.Smarterr == nil || cfg.Smarterr.MaxDetailLength == nil || *cfg.Smarterr.MaxDetailLength <= 0 {
+		return detail
+	}
+	limit := max(*cfg.Smarterr.MaxDetailLength-reserve, 0)
+	runes := []rune(detail)
+	if len(runes) <= limit {
+		return detail
+	}
+	return string(runes[:limit]) + TruncatedNotice
+}
+
+// OriginalDetailSeparator sets the original error apart from the rendered detail when
+// append_original_detail is enabled.
+const OriginalDetailSeparator = "\n\n--- original error ---\n"
+
+// AppendOriginalDetail appends original, the error or diagnostic detail before rendering, to detail
+// when the config enables append_original_detail. It returns detail unchanged if the setting is
+// off or original is empty. With max_detail_length, it truncates original to the room detail
+// leaves, and leaves original out if there is none.
+func (cfg *Config) AppendOriginalDetail(detail, original string) string {
+	if cfg == nil || cfg.Smarterr == nil || !cfg.Smarterr.AppendOriginalDetail || original == "" {
+		return detail
+	}
+	reserve := utf8.RuneCountInString(detail) + utf8.RuneCountInString(OriginalDetailSeparator)
+	if cfg.Smarterr.MaxDetailLength != nil && *cfg.Smarterr.MaxDetailLength > 0 && reserve >= *cfg.Smarterr.MaxDetailLength {
+		return detail
+	}
+	return detail + OriginalDetailSeparator + cfg.truncateDetail(original, reserve)
+}
+
+// ErrorCodeFormat formats the machine code appended to details when append_error_code is enabled.
+// The delimiters make it easy for automation to find in Terraform output.
+const ErrorCodeFormat = "[smarterr-code: %s]"
+
+// AppendErrorCode appends code, formatted with ErrorCodeFormat, to detail on its own line when the
+// config enables append_error_code. It returns detail unchanged if the setting is off or code is
+// empty.
+func (cfg *Config) AppendErrorCode(detail, code string) string {
+	if cfg == nil || cfg.Smarterr == nil || !cfg.Smarterr.AppendErrorCode || code == "" {
+		return detail
+	}
+	return detail + "\n\n" + fmt.Sprintf(ErrorCodeFormat, code)
+}
+
+// ErrorCode returns the machine code for the runtime's error: the code of a smarterr error in its
+// chain or, if it has none, the code of the first matching hint that has one. It returns "" if
+// neither has a code.
+func (rt *Runtime) ErrorCode(ctx context.Context) string {
+	if rt.Error == nil {
+		return ""
+	}
+	var coder interface{ ErrorCode() string }
+	if errors.As(rt.Error, &coder) && coder != nil && coder.ErrorCode() != "" {
+		return coder.ErrorCode()
+	}
+	if rt.Config == nil {
+		return ""
+	}
+	for _, hint := range rt.Config.matchingHints(ctx, rt.Error) {
+		if hint.Code != "" {
+			return hint.Code
+		}
+	}
+	return ""
+}
+
+// LogFields returns the token values to pass as structured fields with a log template's message.
+// When the config sets log_fields, only those tokens are included, so the rendered message stays
+// the human-readable string and the fields carry just the structured subset. Otherwise, it returns
+// values unchanged.
+func (cfg *Config) LogFields(values map[string]any) map[string]any {
+	if cfg == nil || cfg.Smarterr == nil || cfg.Smarterr.LogFields == nil {
+		return values
+	}
+	fields := make(map[string]any, len(cfg.Smarterr.LogFields))
+	for _, name := range cfg.Smarterr.LogFields {
+		if v, ok := values[name]; ok {
+			fields[name] = v
+		}
+	}
+	return fields
+}
+
 // CollectTemplateVariables walks the template AST and returns a list of all variable names referenced.
 func CollectTemplateVariables(tmpl *template.Template) []string {
 	vars := make(map[string]struct{})
-	for _, t := range tmpl.Templates() {
-		walkNodes(t.Root, vars)
+	for _, path := range CollectTemplateFieldPaths(tmpl) {
+		vars[path[0]] = struct{}{}
 	}
 	result := make([]string, 0, len(vars))
 	for v := range vars {
@@ -624,53 +1643,232 @@ func CollectTemplateVariables(tmpl *template.Template) []string {
 	return result
 }
 
-// walkNodes recursively walks template nodes and collects variable names.
-func walkNodes(node parse.Node, vars map[string]struct{}) {
+// CollectTemplateFieldPaths walks the template AST and returns the field chains referenced from the
+// template's data, such as ["diag", "summary"] for {{.diag.summary}}. Inside {{with .diag}}, a
+// field such as {{.summary}} is relative to the token, so it's returned as ["diag", "summary"].
+// Fields relative to a {{range}} element or another computed value aren't returned, since they
+// don't name tokens.
+func CollectTemplateFieldPaths(tmpl *template.Template) [][]string {
+	var paths [][]string
+	seen := make(map[string]struct{})
+	add := func(path []string) {
+		key := strings.Join(path, ".")
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			paths = append(paths, path)
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walkNodes(t.Root, []string{}, add)
+		}
+	}
+	return paths
+}
+
+// walkNodes recursively walks template nodes and passes each field chain to add. dot is the field
+// chain the current dot refers to, or nil if it doesn't refer to a field of the template's data.
+func walkNodes(node parse.Node, dot []string, add func([]string)) {
 	switch n := node.(type) {
 	case *parse.ListNode:
+		if n == nil {
+			return
+		}
 		for _, child := range n.Nodes {
-			walkNodes(child, vars)
+			walkNodes(child, dot, add)
 		}
 	case *parse.ActionNode:
-		walkNodes(n.Pipe, vars)
+		walkNodes(n.Pipe, dot, add)
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			walkNodes(n.Pipe, dot, add)
+		}
 	case *parse.PipeNode:
 		for _, cmd := range n.Cmds {
-			walkNodes(cmd, vars)
+			walkNodes(cmd, dot, add)
 		}
 	case *parse.CommandNode:
 		for _, arg := range n.Args {
-			walkNodes(arg, vars)
+			walkNodes(arg, dot, add)
 		}
 	case *parse.FieldNode:
-		if len(n.Ident) > 0 {
-			vars[n.Ident[0]] = struct{}{}
+		if dot != nil && len(n.Ident) > 0 {
+			add(append(slices.Clone(dot), n.Ident...))
 		}
 	case *parse.VariableNode:
-		if len(n.Ident) > 0 {
-			vars[n.Ident[0]] = struct{}{}
+		// $ is the template's data; other variables hold computed values
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			add(slices.Clone(n.Ident[1:]))
 		}
 	case *parse.IfNode:
-		walkNodes(n.Pipe, vars)
-		walkNodes(n.List, vars)
-		if n.ElseList != nil {
-			walkNodes(n.ElseList, vars)
-		}
+		walkNodes(n.Pipe, dot, add)
+		walkNodes(n.List, dot, add)
+		walkNodes(n.ElseList, dot, add)
 	case *parse.RangeNode:
-		walkNodes(n.Pipe, vars)
-		walkNodes(n.List, vars)
-		if n.ElseList != nil {
-			walkNodes(n.ElseList, vars)
+		walkNodes(n.Pipe, dot, add)
+		walkNodes(n.List, nil, add)
+		walkNodes(n.ElseList, dot, add)
+	case *parse.WithNode:
+		walkNodes(n.Pipe, dot, add)
+		walkNodes(n.List, pipeFieldPath(n.Pipe, dot), add)
+		walkNodes(n.ElseList, dot, add)
+	}
+}
+
+// pipeFieldPath returns the field chain a pipeline such as .diag evaluates to, or nil if it
+// computes another value.
+func pipeFieldPath(pipe *parse.PipeNode, dot []string) []string {
+	if dot == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return nil
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode:
+		return append(slices.Clone(dot), arg.Ident...)
+	case *parse.DotNode:
+		return dot
+	}
+	return nil
+}
+
+// CollectTemplateReferences returns the names of the templates a template invokes with
+// {{template "name"}}, in order of first use.
+func CollectTemplateReferences(tmpl *template.Template) []string {
+	var refs []string
+	seen := make(map[string]struct{})
+	for _, t := range tmpl.Templates() {
+		walkTemplateRefs(t.Root, func(name string) {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				refs = append(refs, name)
+			}
+		})
+	}
+	return refs
+}
+
+// walkTemplateRefs recursively walks template nodes and calls fn for each {{template}} action.
+func walkTemplateRefs(node parse.Node, fn func(name string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
 		}
+		for _, child := range n.Nodes {
+			walkTemplateRefs(child, fn)
+		}
+	case *parse.TemplateNode:
+		fn(n.Name)
+	case *parse.IfNode:
+		walkTemplateRefs(n.List, fn)
+		walkTemplateRefs(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkTemplateRefs(n.List, fn)
+		walkTemplateRefs(n.ElseList, fn)
 	case *parse.WithNode:
-		walkNodes(n.Pipe, vars)
-		walkNodes(n.List, vars)
-		if n.ElseList != nil {
-			walkNodes(n.ElseList, vars)
+		walkTemplateRefs(n.List, fn)
+		walkTemplateRefs(n.ElseList, fn)
+	}
+}
+
+// TemplateCycle returns a cycle of {{template}} references among the config's templates, such as
+// [a b a] when a invokes b and b invokes a, or nil if there is none. A template defined with
+// {{define}} inside a config template is named after both, such as error_detail/item, and a
+// reference resolves to it before a config template, as when rendering. Rendering a template in a
+// cycle would recurse until text/template's depth limit, so such configs are rejected. Templates
+// that don't parse are skipped.
+func (cfg *Config) TemplateCycle() []string {
+	if cfg == nil {
+		return nil
+	}
+	refs := make(map[string][]string)
+	var names []string
+	for _, tmpl := range cfg.Templates {
+		t, err := NewTemplate(tmpl.Name).Parse(tmpl.Format)
+		if err != nil {
+			continue
+		}
+		if _, ok := refs[tmpl.Name]; !ok {
+			names = append(names, tmpl.Name)
 		}
-		// Add more node types as needed
+		node := func(name string) string {
+			if name == tmpl.Name {
+				return name
+			}
+			return tmpl.Name + "/" + name
+		}
+		for _, defined := range t.Templates() {
+			if defined.Tree == nil {
+				continue
+			}
+			var targets []string
+			walkTemplateRefs(defined.Root, func(ref string) {
+				if t.Lookup(ref) != nil {
+					ref = node(ref)
+				}
+				if !slices.Contains(targets, ref) {
+					targets = append(targets, ref)
+				}
+			})
+			refs[node(defined.Name())] = targets
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			i := slices.Index(path, name)
+			return append(slices.Clone(path[i:]), name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, ref := range refs[name] {
+			if cycle := visit(ref); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+	for _, name := range names {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// templateCycleMemo holds the result of TemplateCycle for a Config whose templates no longer
+// change.
+type templateCycleMemo struct {
+	once  sync.Once
+	cycle []string
+}
+
+// templateCycle returns TemplateCycle, computed once for a Config from LoadConfig, since rendering
+// checks it for every template that invokes another.
+func (cfg *Config) templateCycle() []string {
+	if cfg.cycleMemo == nil {
+		return cfg.TemplateCycle()
 	}
+	cfg.cycleMemo.once.Do(func() {
+		cfg.cycleMemo.cycle = cfg.TemplateCycle()
+	})
+	return cfg.cycleMemo.cycle
 }
 
+// fallbackMessage returns the value used for an unresolved token according to token_error_mode.
+// TokenErrorMode is nil when the config doesn't set it, which, like "", means "empty".
 func fallbackMessage(cfg *Config, tokenName string, msg string) string {
 	mode := "empty"
 	if cfg != nil && cfg.Smarterr != nil && cfg.Smarterr.TokenErrorMode != nil && *cfg.Smarterr.TokenErrorMode != "" {
@@ -691,52 +1889,185 @@ func fallbackMessage(cfg *Config, tokenName string, msg string) string {
 	}
 }
 
-// resolveHints processes hint suggestions for an error string, returning joined suggestions and diagnostics.
-func resolveHints(ctx context.Context, errStr string, cfg *Config) string {
+// hintMatches reports whether a hint matches an error. A match_all hint matches every error.
+// Otherwise, the error must satisfy every criterion the hint sets, at least one any_of condition,
+// and every all_of condition. A hint without criteria matches nothing.
+func hintMatches(callID string, hint Hint, err error) bool {
+	if hint.MatchAll {
+		Debugf("[resolveHints %s] Hint %q matches all errors", callID, hint.Name)
+		return true
+	}
+	hasContains := hint.ErrorContains != nil && *hint.ErrorContains != ""
+	hasRegex := hint.RegexMatch != nil && *hint.RegexMatch != ""
+	hasType := hint.ErrorType != nil && *hint.ErrorType != ""
+	if !hasContains && !hasRegex && !hasType && len(hint.AnyOf) == 0 && len(hint.AllOf) == 0 {
+		Debugf("[resolveHints %s] Hint %q has no match criteria and match_all is not set", callID, hint.Name)
+		return false
+	}
+	var errStr string
+	if err != nil {
+		errStr = err.Error()
+	}
+	matched := true
+	if hasContains {
+		if !strings.Contains(errStr, *hint.ErrorContains) {
+			Debugf("[resolveHints %s] Hint %q did not match error_contains: %s", callID, hint.Name, *hint.ErrorContains)
+			matched = false
+		} else {
+			Debugf("[resolveHints %s] Hint %q matched error_contains: %s", callID, hint.Name, *hint.ErrorContains)
+		}
+	}
+	if hasRegex {
+		re, err := regexp.Compile(*hint.RegexMatch)
+		if err != nil {
+			Debugf("[resolveHints %s] Hint %q regex compile error: %v", callID, hint.Name, err)
+			matched = false
+		} else if !re.MatchString(errStr) {
+			Debugf("[resolveHints %s] Hint %q did not match regex: %s", callID, hint.Name, *hint.RegexMatch)
+			matched = false
+		} else {
+			Debugf("[resolveHints %s] Hint %q matched regex: %s", callID, hint.Name, *hint.RegexMatch)
+		}
+	}
+	if hasType {
+		if !errorMatchesType(err, *hint.ErrorType) {
+			Debugf("[resolveHints %s] Hint %q did not match error_type: %s", callID, hint.Name, *hint.ErrorType)
+			matched = false
+		} else {
+			Debugf("[resolveHints %s] Hint %q matched error_type: %s", callID, hint.Name, *hint.ErrorType)
+		}
+	}
+	if len(hint.AnyOf) > 0 {
+		if !slices.ContainsFunc(hint.AnyOf, func(c Condition) bool { return c.Matches(errStr) }) {
+			Debugf("[resolveHints %s] Hint %q matched none of its any_of conditions", callID, hint.Name)
+			matched = false
+		} else {
+			Debugf("[resolveHints %s] Hint %q matched an any_of condition", callID, hint.Name)
+		}
+	}
+	for i, c := range hint.AllOf {
+		if !c.Matches(errStr) {
+			Debugf("[resolveHints %s] Hint %q did not match all_of condition %d", callID, hint.Name, i)
+			matched = false
+		}
+	}
+	return matched
+}
+
+// Matches reports whether errStr satisfies every criterion the condition sets. A condition without
+// criteria, or with a regex that doesn't compile, matches nothing.
+func (c Condition) Matches(errStr string) bool {
+	hasContains := c.ErrorContains != nil && *c.ErrorContains != ""
+	hasRegex := c.RegexMatch != nil && *c.RegexMatch != ""
+	if !hasContains && !hasRegex {
+		return false
+	}
+	if hasContains && !strings.Contains(errStr, *c.ErrorContains) {
+		return false
+	}
+	if hasRegex {
+		re, err := regexp.Compile(*c.RegexMatch)
+		if err != nil || !re.MatchString(errStr) {
+			return false
+		}
+	}
+	return true
+}
+
+// SuggestionLines returns the hint's suggestion followed by its suggestions, skipping empty text.
+func (h Hint) SuggestionLines() []string {
+	var lines []string
+	if h.Suggestion != "" {
+		lines = append(lines, h.Suggestion)
+	}
+	for _, s := range h.Suggestions {
+		if s != "" {
+			lines = append(lines, s)
+		}
+	}
+	return lines
+}
+
+// hintResult is the outcome of matching hints against an error.
+type hintResult struct {
+	Names       []string // Names of the matching hints, in order
+	Suggestions string   // Suggestions of the matching hints, joined with hint_join_char
+}
+
+// resolveHints matches hints against an error, returning the names of the matching hints and their
+// joined suggestions. It drops repeated suggestions, with hint_dedup, before keeping the first
+// hint_max_suggestions of them.
+func resolveHints(ctx context.Context, err error, cfg *Config) hintResult {
 	callID := globalCallID(ctx)
-	var suggestions []string
-	matchMode := "all"
+	if cfg == nil {
+		Debugf("[resolveHints %s] Configuration is nil; no hints to match", callID)
+		return hintResult{}
+	}
 	joinChar := "\n"
-	if cfg.Smarterr != nil {
-		if cfg.Smarterr.HintMatchMode != nil && *cfg.Smarterr.HintMatchMode != "" {
-			matchMode = *cfg.Smarterr.HintMatchMode
-		}
-		if cfg.Smarterr.HintJoinChar != nil {
-			joinChar = *cfg.Smarterr.HintJoinChar
+	if cfg.Smarterr != nil && cfg.Smarterr.HintJoinChar != nil {
+		joinChar = *cfg.Smarterr.HintJoinChar
+	}
+	var result hintResult
+	var suggestions []string
+	for _, hint := range cfg.matchingHints(ctx, err) {
+		result.Names = append(result.Names, hint.Name)
+		suggestions = append(suggestions, cfg.localizedSuggestions(ctx, hint)...)
+	}
+	if cfg.Smarterr != nil && cfg.Smarterr.HintDedup != nil && *cfg.Smarterr.HintDedup {
+		suggestions = dedupStrings(suggestions)
+	}
+	if cfg.Smarterr != nil && cfg.Smarterr.HintMaxSuggestions != nil && *cfg.Smarterr.HintMaxSuggestions > 0 && len(suggestions) > *cfg.Smarterr.HintMaxSuggestions {
+		Debugf("[resolveHints %s] Keeping the first %d of %d suggestions (hint_max_suggestions)", callID, *cfg.Smarterr.HintMaxSuggestions, len(suggestions))
+		suggestions = suggestions[:*cfg.Smarterr.HintMaxSuggestions]
+	}
+	result.Suggestions = strings.Join(suggestions, joinChar)
+	return result
+}
+
+// dedupStrings returns values without repeats, keeping the first of each in order.
+func dedupStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
 		}
 	}
+	return unique
+}
+
+// MatchingHints returns the names of the hints that match errStr, in order, honoring
+// hint_match_mode. Locale variants aren't included, since they only replace the suggestions of
+// their base hint. Since errStr is only a message, hints that set error_type don't match.
+func (cfg *Config) MatchingHints(ctx context.Context, errStr string) []string {
+	var names []string
+	for _, hint := range cfg.matchingHints(ctx, errors.New(errStr)) {
+		names = append(names, hint.Name)
+	}
+	return names
+}
+
+// matchingHints returns the hints that match err, in order, honoring hint_match_mode. In "first"
+// mode, at most one hint matches.
+func (cfg *Config) matchingHints(ctx context.Context, err error) []Hint {
+	callID := globalCallID(ctx)
+	matchMode := "all"
+	if cfg.Smarterr != nil && cfg.Smarterr.HintMatchMode != nil && *cfg.Smarterr.HintMatchMode != "" {
+		matchMode = *cfg.Smarterr.HintMatchMode
+	}
+	var matched []Hint
 	for _, hint := range cfg.Hints {
-		Debugf("[resolveHints %s] Checking hint %q against error: %s", callID, hint.Name, errStr)
-		matched := true
-		if hint.ErrorContains != nil && *hint.ErrorContains != "" {
-			if !strings.Contains(errStr, *hint.ErrorContains) {
-				Debugf("[resolveHints %s] Hint %q did not match error_contains: %s", callID, hint.Name, *hint.ErrorContains)
-				matched = false
-			} else {
-				Debugf("[resolveHints %s] Hint %q matched error_contains: %s", callID, hint.Name, *hint.ErrorContains)
-			}
+		if cfg.IsHintVariant(hint.Name) || hint.Disabled {
+			continue
 		}
-		if hint.RegexMatch != nil && *hint.RegexMatch != "" {
-			re, err := regexp.Compile(*hint.RegexMatch)
-			if err != nil {
-				Debugf("[resolveHints %s] Hint %q regex compile error: %v", callID, hint.Name, err)
-				matched = false
-			} else if !re.MatchString(errStr) {
-				Debugf("[resolveHints %s] Hint %q did not match regex: %s", callID, hint.Name, *hint.RegexMatch)
-				matched = false
-			} else {
-				Debugf("[resolveHints %s] Hint %q matched regex: %s", callID, hint.Name, *hint.RegexMatch)
-			}
-		}
-		if matched {
-			suggestions = append(suggestions, hint.Suggestion)
+		Debugf("[matchingHints %s] Checking hint %q against error: %v", callID, hint.Name, err)
+		if hintMatches(callID, hint, err) {
+			matched = append(matched, hint)
 			if matchMode == "first" {
 				break
 			}
 		}
-		if matchMode == "first" && len(suggestions) > 0 {
-			break
-		}
 	}
-	return strings.Join(suggestions, joinChar)
+	return matched
 }
@@ -2,11 +2,17 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"text/template"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 )
@@ -64,6 +70,41 @@ func TestParseKeyvals(t *testing.T) {
 	}
 }
 
+func TestParseKeyvalsMode(t *testing.T) {
+	input := []any{"id", "rds", "service", "Provider", "id", "new_rds", "id", "newest_rds"}
+	tests := []struct {
+		mode string
+		want map[string]any
+	}{
+		{mode: "last", want: map[string]any{"id": "newest_rds", "service": "Provider"}},
+		{mode: "first", want: map[string]any{"id": "rds", "service": "Provider"}},
+		{mode: "collect", want: map[string]any{"id": []any{"rds", "new_rds", "newest_rds"}, "service": "Provider"}},
+		{mode: "bogus", want: map[string]any{"id": "newest_rds", "service": "Provider"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got := parseKeyvalsMode(context.Background(), tt.mode, input...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseKeyvalsMode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A single value that is itself a []any is not mistaken for collected values
+	got := parseKeyvalsMode(context.Background(), "collect", "ids", []any{"a"}, "ids", "b")
+	if want := []any{[]any{"a"}, "b"}; !reflect.DeepEqual(got["ids"], want) {
+		t.Errorf("parseKeyvalsMode() ids = %v, want %v", got["ids"], want)
+	}
+
+	// NewRuntime uses the configured mode
+	cfg := &Config{Smarterr: &Smarterr{DuplicateKeyvalMode: strPtr("first")}}
+	rt := NewRuntime(context.Background(), cfg, nil, "id", "rds", "id", "new_rds")
+	if rt.Args["id"] != "rds" {
+		t.Errorf("NewRuntime() Args[id] = %v, want %q", rt.Args["id"], "rds")
+	}
+}
+
 func TestTokenResolve_BasicSources(t *testing.T) {
 	//nolint:staticcheck // smarterr must use string keys for context to interoperate with host apps, per Go context best practices for libraries
 	tests := []struct {
@@ -77,14 +118,14 @@ func TestTokenResolve_BasicSources(t *testing.T) {
 			name:    "parameter found",
 			token:   Token{Source: "parameter", Parameter: stringPtr("foo")},
 			ctx:     context.Background(),
-			runtime: NewRuntime(context.Background(), &Config{Parameters: []Parameter{{Name: "foo", Value: "bar"}}}, nil, nil),
+			runtime: NewRuntime(context.Background(), &Config{Parameters: []Parameter{{Name: "foo", Value: strPtr("bar")}}}, nil, nil),
 			want:    "bar",
 		},
 		{
 			name:    "parameter not found",
 			token:   Token{Source: "parameter", Parameter: stringPtr("baz")},
 			ctx:     context.Background(),
-			runtime: NewRuntime(context.Background(), &Config{Parameters: []Parameter{{Name: "foo", Value: "bar"}}}, nil, nil),
+			runtime: NewRuntime(context.Background(), &Config{Parameters: []Parameter{{Name: "foo", Value: strPtr("bar")}}}, nil, nil),
 			want:    "",
 		},
 		{
@@ -129,6 +170,41 @@ func TestTokenResolve_BasicSources(t *testing.T) {
 			runtime: NewRuntime(context.Background(), &Config{}, nil, "foo", "bar"),
 			want:    "",
 		},
+		{
+			name:    "nested arg found",
+			token:   Token{Source: "arg", Arg: stringPtr("id.primary")},
+			ctx:     context.Background(),
+			runtime: NewRuntime(context.Background(), &Config{}, nil, "id", map[string]any{"primary": "vpc-1"}),
+			want:    "vpc-1",
+		},
+		{
+			name:    "deeply nested arg found",
+			token:   Token{Source: "arg", Arg: stringPtr("id.parts.secondary")},
+			ctx:     context.Background(),
+			runtime: NewRuntime(context.Background(), &Config{}, nil, "id", map[string]any{"parts": map[string]any{"secondary": "subnet-2"}}),
+			want:    "subnet-2",
+		},
+		{
+			name:    "dotted key preferred over nested",
+			token:   Token{Source: "arg", Arg: stringPtr("id.primary")},
+			ctx:     context.Background(),
+			runtime: NewRuntime(context.Background(), &Config{}, nil, "id.primary", "exact", "id", map[string]any{"primary": "vpc-1"}),
+			want:    "exact",
+		},
+		{
+			name:    "nested arg missing key",
+			token:   Token{Source: "arg", Arg: stringPtr("id.secondary")},
+			ctx:     context.Background(),
+			runtime: NewRuntime(context.Background(), &Config{}, nil, "id", map[string]any{"primary": "vpc-1"}),
+			want:    "",
+		},
+		{
+			name:    "nested arg not a map",
+			token:   Token{Source: "arg", Arg: stringPtr("id.primary")},
+			ctx:     context.Background(),
+			runtime: NewRuntime(context.Background(), &Config{}, nil, "id", "vpc-1"),
+			want:    "",
+		},
 		{
 			name:    "unknown source fallback",
 			token:   Token{Source: "unknown"},
@@ -152,7 +228,7 @@ func TestRuntime_BuildTokenValueMap(t *testing.T) {
 	ctxKey := ContextKey("ctxKey")
 	ctx := context.WithValue(context.Background(), ctxKey, "ctxVal")
 	cfg := &Config{
-		Parameters: []Parameter{{Name: "param1", Value: "val1"}},
+		Parameters: []Parameter{{Name: "param1", Value: strPtr("val1")}},
 		Tokens: []Token{
 			{Name: "param_token", Source: "parameter", Parameter: stringPtr("param1")},
 			{Name: "ctx_token", Source: "context", Context: stringPtr("ctxKey")},
@@ -188,7 +264,7 @@ func TestTokenResolve_WithTransforms(t *testing.T) {
 	fixSpace := "fix_space"
 	prefixVal := "PRE_"
 	cfg := &Config{
-		Parameters: []Parameter{{Name: "p", Value: "PRE_  Foo   Bar  "}},
+		Parameters: []Parameter{{Name: "p", Value: strPtr("PRE_  Foo   Bar  ")}},
 		Transforms: []Transform{
 			{
 				Name:  stripPrefix,
@@ -290,6 +366,14 @@ func TestConfig_RenderTemplate_TemplateNotFound(t *testing.T) {
 	}
 }
 
+func TestConfig_RenderTemplate_NilConfig(t *testing.T) {
+	var cfg *Config
+	_, err := cfg.RenderTemplate(context.Background(), "error_summary", map[string]any{})
+	if err == nil || err.Error() != "cannot render template \"error_summary\": config is nil" {
+		t.Errorf("Expected nil config error, got: %v", err)
+	}
+}
+
 func TestConfig_RenderTemplate_SyntaxError(t *testing.T) {
 	cfg := &Config{
 		Templates: []Template{
@@ -302,6 +386,21 @@ func TestConfig_RenderTemplate_SyntaxError(t *testing.T) {
 	}
 }
 
+func TestCollectTemplateFieldPaths(t *testing.T) {
+	tmpl, err := template.New("paths").Parse(`{{.diag.summary}} {{with .diag}}{{.detail}}{{else}}{{.error}}{{end}} {{range .items}}{{.name}}{{end}} {{$.id}} {{$x := .a}}{{$x}}`)
+	if err != nil {
+		t.Fatalf("template parse error: %v", err)
+	}
+	var got []string
+	for _, path := range CollectTemplateFieldPaths(tmpl) {
+		got = append(got, strings.Join(path, "."))
+	}
+	want := []string{"diag.summary", "diag", "diag.detail", "error", "items", "id", "a"}
+	if !slices.Equal(got, want) {
+		t.Errorf("CollectTemplateFieldPaths() = %v, want %v", got, want)
+	}
+}
+
 func TestCollectTemplateVariables(t *testing.T) {
 	tmpl, err := template.New("vars").Parse("Hello, {{.foo}} and {{.bar}}! {{if .baz}}{{.baz}}{{end}}")
 	if err != nil {
@@ -396,6 +495,92 @@ func TestProcessStackMatches_CalledFromPreference(t *testing.T) {
 	}
 }
 
+func TestProcessStackMatches_CalledAfter(t *testing.T) {
+	matches := []StackMatch{
+		{Name: "find_create", CalledFrom: "find.*", CalledAfter: "resource[a-zA-Z0-9]*Create", Display: "finding during create", Priority: 1},
+		{Name: "create", CalledFrom: "resource[a-zA-Z0-9]*Create", Display: "creating"},
+		{Name: "read", CalledFrom: "resource[a-zA-Z0-9]*Read", Display: "reading"},
+		{Name: "find", CalledFrom: "find.*", Display: "finding during operation"},
+	}
+
+	tests := []struct {
+		name   string
+		frames []runtime.Frame
+		want   string
+	}{
+		{
+			name:   "find during create",
+			frames: []runtime.Frame{{Function: "findBar"}, {Function: "resourceFooCreate"}},
+			want:   "finding during create",
+		},
+		{
+			name:   "find during create with frames between",
+			frames: []runtime.Frame{{Function: "findBar"}, {Function: "waitBar"}, {Function: "resourceFooCreate"}, {Function: "main"}},
+			want:   "finding during create",
+		},
+		{
+			name:   "find during read",
+			frames: []runtime.Frame{{Function: "findBar"}, {Function: "resourceFooRead"}},
+			want:   "finding during operation",
+		},
+		{
+			name:   "called_after frame deeper than called_from frame",
+			frames: []runtime.Frame{{Function: "resourceFooCreate"}, {Function: "findBar"}},
+			want:   "creating",
+		},
+		{
+			name:   "find without caller",
+			frames: []runtime.Frame{{Function: "findBar"}},
+			want:   "finding during operation",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			display, err := processStackMatches(matches, tc.frames)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if display != tc.want {
+				t.Errorf("got %q, want %q", display, tc.want)
+			}
+		})
+	}
+
+	bad := []StackMatch{{Name: "bad", CalledFrom: "find.*", CalledAfter: "(", Display: "x"}}
+	if _, err := processStackMatches(bad, []runtime.Frame{{Function: "findBar"}}); err == nil || !strings.Contains(err.Error(), "CalledAfter") {
+		t.Errorf("expected CalledAfter regex error, got %v", err)
+	}
+}
+
+// stackAtDepth calls rt.callStack n frames deep, so the stack is deeper than callStackDepth.
+func stackAtDepth(rt *Runtime, n int) ([]runtime.Frame, error) {
+	if n == 0 {
+		return rt.callStack()
+	}
+	return stackAtDepth(rt, n-1)
+}
+
+func TestRuntime_CallStackDepth(t *testing.T) {
+	shallow := &Config{StackMatches: []StackMatch{{Name: "find", CalledFrom: "find.*"}}}
+	deep := &Config{StackMatches: []StackMatch{{Name: "find_create", CalledFrom: "find.*", CalledAfter: "Create"}}}
+	for _, tc := range []struct {
+		cfg  *Config
+		want int
+	}{
+		{cfg: shallow, want: callStackDepth},
+		{cfg: deep, want: calledAfterStackDepth},
+	} {
+		frames, err := stackAtDepth(&Runtime{Config: tc.cfg}, 40)
+		if err != nil {
+			t.Fatalf("callStack() error: %v", err)
+		}
+		if len(frames) != tc.want {
+			t.Errorf("callStack() gathered %d frames, want %d", len(frames), tc.want)
+		}
+	}
+}
+
 type mockDiag struct{}
 type Severity int
 
@@ -445,4 +630,1288 @@ func TestTokenResolve_DiagnosticSource(t *testing.T) {
 	if diagMap["severity"] != "Error" {
 		t.Errorf("severity should be unchanged: got %q, want %q", diagMap["severity"], "Error")
 	}
+	if diagMap["severity_level"] != "error" {
+		t.Errorf("severity_level = %q, want %q", diagMap["severity_level"], "error")
+	}
+}
+
+func TestProcessStackMatches_Priority(t *testing.T) {
+	frames := []runtime.Frame{{Function: "resourceClusterCreate"}}
+
+	tests := []struct {
+		name    string
+		matches []StackMatch
+		want    string
+	}{
+		{
+			name: "slice order without priority",
+			matches: []StackMatch{
+				{Name: "generic", CalledFrom: "Create", Display: "creating"},
+				{Name: "specific", CalledFrom: "resourceClusterCreate", Display: "creating cluster"},
+			},
+			want: "creating",
+		},
+		{
+			name: "higher priority wins regardless of order",
+			matches: []StackMatch{
+				{Name: "generic", CalledFrom: "Create", Display: "creating"},
+				{Name: "specific", CalledFrom: "resourceClusterCreate", Display: "creating cluster", Priority: 10},
+			},
+			want: "creating cluster",
+		},
+		{
+			name: "negative priority loses to default",
+			matches: []StackMatch{
+				{Name: "any", CalledFrom: ".*", Display: "operating", Priority: -1},
+				{Name: "generic", CalledFrom: "Create", Display: "creating"},
+			},
+			want: "creating",
+		},
+		{
+			name: "equal priority keeps order",
+			matches: []StackMatch{
+				{Name: "specific", CalledFrom: "resourceClusterCreate", Display: "creating cluster", Priority: 5},
+				{Name: "generic", CalledFrom: "Create", Display: "creating", Priority: 5},
+			},
+			want: "creating cluster",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			display, err := processStackMatches(tc.matches, frames)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if display != tc.want {
+				t.Errorf("got %q, want %q", display, tc.want)
+			}
+		})
+	}
+}
+
+func TestProcessStackMatches_PriorityWithinFrame(t *testing.T) {
+	// Priority orders rules at each frame. It doesn't let a rule reach past a closer frame that
+	// another rule matches.
+	frames := []runtime.Frame{{Function: "findCluster"}, {Function: "resourceClusterCreate"}}
+	matches := []StackMatch{
+		{Name: "create", CalledFrom: "Create$", Display: "creating", Priority: 10},
+		{Name: "find", CalledFrom: "^find", Display: "finding"},
+	}
+	display, err := processStackMatches(matches, frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if display != "finding" {
+		t.Errorf("got %q, want %q", display, "finding")
+	}
+}
+
+func TestStackDisplay_Categories(t *testing.T) {
+	frames := []runtime.Frame{
+		{Function: "github.com/hashicorp/terraform-provider-aws/internal/service/rds.waitDBClusterAvailable"},
+		{Function: "github.com/hashicorp/terraform-provider-aws/internal/service/rds.resourceClusterCreate"},
+	}
+	matches := []StackMatch{
+		{Name: "create", CalledFrom: "resource[a-zA-Z0-9]*Create", Display: "creating", Category: "operation"},
+		{Name: "read", CalledFrom: "resource[a-zA-Z0-9]*Read", Display: "reading", Category: "operation"},
+		{Name: "wait", CalledFrom: "wait[a-zA-Z0-9]*", Display: "waiting", Category: "sub_action"},
+		{Name: "find", CalledFrom: "find[a-zA-Z0-9]*", Display: "finding", Category: "sub_action"},
+	}
+
+	tests := []struct {
+		name  string
+		token Token
+		want  string
+	}{
+		{
+			name:  "no categories uses best single match",
+			token: Token{},
+			want:  "waiting",
+		},
+		{
+			name:  "composed in category order",
+			token: Token{StackCategories: []string{"operation", "sub_action"}},
+			want:  "creating, waiting",
+		},
+		{
+			name:  "custom join",
+			token: Token{StackCategories: []string{"operation", "sub_action"}, StackJoin: strPtr(" while ")},
+			want:  "creating while waiting",
+		},
+		{
+			name:  "unmatched category skipped",
+			token: Token{StackCategories: []string{"operation", "missing", "sub_action"}},
+			want:  "creating, waiting",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			display, err := tc.token.stackDisplay(matches, frames)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if display != tc.want {
+				t.Errorf("got %q, want %q", display, tc.want)
+			}
+		})
+	}
+}
+
+// annotatedError mimics smarterr.Error's annotation lookup without importing the root package.
+type annotatedError struct {
+	err         error
+	annotations map[string]string
+}
+
+func (e *annotatedError) Error() string { return e.err.Error() }
+func (e *annotatedError) Unwrap() error { return e.err }
+func (e *annotatedError) Annotation(key string) (string, bool) {
+	v, ok := e.annotations[key]
+	return v, ok
+}
+
+func TestTokenResolve_AnnotationSource(t *testing.T) {
+	ctx := context.Background()
+	annotated := &annotatedError{err: fmt.Errorf("boom"), annotations: map[string]string{"subaction": "waiting for replication"}}
+	wrapped := fmt.Errorf("creating cluster: %w", annotated)
+
+	cfg := &Config{
+		Tokens: []Token{
+			{Name: "error", Source: "error"},
+			{Name: "subaction", Annotation: strPtr("subaction")},
+		},
+		Templates: []Template{{
+			Name:   "error_summary",
+			Format: "{{.subaction}}: {{.error}}",
+		}},
+		Smarterr: &Smarterr{TokenErrorMode: strPtr("placeholder")},
+	}
+
+	rt := NewRuntime(ctx, cfg, wrapped, nil)
+	out, err := cfg.RenderTemplate(ctx, "error_summary", rt.BuildTokenValueMap(ctx))
+	if err != nil {
+		t.Fatalf("RenderTemplate error: %v", err)
+	}
+	if want := "waiting for replication: creating cluster: boom"; out != want {
+		t.Errorf("RenderTemplate output = %q, want %q", out, want)
+	}
+
+	missing := Token{Name: "missing", Source: "annotation", Annotation: strPtr("resource_id")}
+	if got := missing.Resolve(ctx, rt); got != "<missing>" {
+		t.Errorf("Resolve() = %q, want placeholder for missing annotation", got)
+	}
+	if got := missing.Resolve(ctx, NewRuntime(ctx, cfg, fmt.Errorf("plain"), nil)); got != "<missing>" {
+		t.Errorf("Resolve() = %q, want placeholder for unannotated error", got)
+	}
+}
+
+func TestTokenResolve_DurationArg(t *testing.T) {
+	tests := []struct {
+		arg  any
+		want string
+	}{
+		{arg: 5 * time.Minute, want: "5 minutes"},
+		{arg: 90 * time.Minute, want: "1 hour 30 minutes"},
+		{arg: 2*time.Hour + 1*time.Second, want: "2 hours 1 second"},
+		{arg: 1500 * time.Millisecond, want: "2 seconds"},
+		{arg: 500 * time.Millisecond, want: "500ms"},
+		{arg: -time.Minute, want: "-1 minute"},
+		{arg: time.Duration(math.MinInt64), want: "-2562047 hours 47 minutes 16 seconds"},
+		{arg: time.Duration(math.MaxInt64), want: "2562047 hours 47 minutes 16 seconds"},
+		{arg: "5m0s", want: "5m0s"},
+	}
+	token := Token{Name: "timeout", Arg: strPtr("timeout")}
+	for _, tc := range tests {
+		t.Run(fmt.Sprint(tc.arg), func(t *testing.T) {
+			rt := NewRuntime(context.Background(), &Config{}, nil, "timeout", tc.arg)
+			if got := token.Resolve(context.Background(), rt); got != tc.want {
+				t.Errorf("Resolve() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTokenResolve_NilConfig(t *testing.T) {
+	ctx := context.Background()
+	tokens := []Token{
+		{Name: "parameter", Source: "parameter", Parameter: strPtr("foo")},
+		{Name: "context", Source: "context", Context: strPtr("foo")},
+		{Name: "arg", Source: "arg", Arg: strPtr("foo")},
+		{Name: "annotation", Source: "annotation", Annotation: strPtr("foo")},
+		{Name: "error", Source: "error"},
+		{Name: "call_stack", Source: "call_stack", StackMatches: []string{"create"}, Transforms: []string{"lower"}},
+		{Name: "error_stack", Source: "error_stack", StackMatches: []string{"create"}},
+		{Name: "hints", Source: "hints"},
+		{Name: "unknown", Source: "unknown"},
+	}
+	for _, cfg := range []*Config{nil, {}} {
+		rt := NewRuntime(ctx, cfg, fmt.Errorf("boom"), nil)
+		for _, tok := range tokens {
+			t.Run(fmt.Sprintf("%s/nil=%t", tok.Name, cfg == nil), func(t *testing.T) {
+				got := tok.Resolve(ctx, rt)
+				want := ""
+				if tok.Source == "error" {
+					want = "boom"
+				}
+				if got != want {
+					t.Errorf("Resolve() = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+
+	if got := NewRuntime(ctx, nil, nil, nil).BuildTokenValueMap(ctx); len(got) != 0 {
+		t.Errorf("BuildTokenValueMap() = %v, want empty map", got)
+	}
+}
+
+func TestTokenResolve_HintSeparator(t *testing.T) {
+	ctx := context.Background()
+	contains := "throttl"
+	cfg := &Config{
+		Smarterr: &Smarterr{HintSeparator: strPtr("\n\n")},
+		Hints: []Hint{
+			{Name: "throttle", ErrorContains: &contains, Suggestion: "Retry later."},
+		},
+		Tokens: []Token{
+			{Name: "error", Source: "error"},
+			{Name: "hints", Source: "hints"},
+		},
+		Templates: []Template{{Name: "error_detail", Format: "{{.error}}{{.hints}}"}},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "hint matched", err: fmt.Errorf("request throttled"), want: "request throttled\n\nRetry later."},
+		{name: "no hint", err: fmt.Errorf("not found"), want: "not found"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := NewRuntime(ctx, cfg, tc.err, nil)
+			out, err := cfg.RenderTemplate(ctx, "error_detail", rt.BuildTokenValueMap(ctx))
+			if err != nil {
+				t.Fatalf("RenderTemplate error: %v", err)
+			}
+			if out != tc.want {
+				t.Errorf("RenderTemplate output = %q, want %q", out, tc.want)
+			}
+		})
+	}
+}
+
+func TestTransformRegistry(t *testing.T) {
+	want := []string{"arn_short", "base64_decode", "fix_space", "lower", "param_lookup", "remove", "replace", "split", "strip_prefix", "strip_suffix", "trim_space", "truncate", "upper", "url_decode"}
+	if got := TransformStepTypes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("TransformStepTypes() = %v, want %v", got, want)
+	}
+	for _, stepType := range want {
+		if !IsTransformStepType(stepType) {
+			t.Errorf("IsTransformStepType(%q) = false, want true", stepType)
+		}
+	}
+	if IsTransformStepType("bogus") {
+		t.Error("IsTransformStepType(bogus) = true, want false")
+	}
+	var cfg *Config
+	if got := cfg.ApplyTransformStep(" Foo ", TransformStep{Type: "bogus"}); got != " Foo " {
+		t.Errorf("ApplyTransformStep() with unknown type = %q, want value unchanged", got)
+	}
+	if got := cfg.ApplyTransformStep(" Foo  Bar ", TransformStep{Type: "fix_space"}); got != "Foo Bar" {
+		t.Errorf("ApplyTransformStep(fix_space) = %q, want %q", got, "Foo Bar")
+	}
+}
+
+func TestApplyTransformStep_ARNShort(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "type and slash", value: "arn:aws:ec2:us-west-2:123456789012:vpc/vpc-0abc", want: "vpc-0abc"},
+		{name: "slashes in resource", value: "arn:aws:iam::123456789012:role/service-role/my-role", want: "service-role/my-role"},
+		{name: "type and colon", value: "arn:aws:lambda:us-west-2:123456789012:function:my-function", want: "my-function"},
+		{name: "colons in resource", value: "arn:aws:lambda:us-west-2:123456789012:function:my-function:$LATEST", want: "my-function:$LATEST"},
+		{name: "no type", value: "arn:aws:s3:::my-bucket", want: "my-bucket"},
+		{name: "S3 object", value: "arn:aws:s3:::my-bucket/path/to/key", want: "my-bucket/path/to/key"},
+		{name: "S3 access point", value: "arn:aws:s3:us-west-2:123456789012:accesspoint/my-access-point", want: "my-access-point"},
+		{name: "no region or account", value: "arn:aws:route53:::hostedzone/Z123", want: "Z123"},
+		{name: "other partition", value: "arn:aws-us-gov:sns:us-gov-west-1:123456789012:my-topic", want: "my-topic"},
+		{name: "not an ARN", value: "vpc-0abc", want: "vpc-0abc"},
+		{name: "too few parts", value: "arn:aws:s3:my-bucket", want: "arn:aws:s3:my-bucket"},
+		{name: "no resource", value: "arn:aws:s3:::", want: "arn:aws:s3:::"},
+		{name: "not arn prefix", value: "urn:aws:ec2:us-west-2:123456789012:vpc/vpc-0abc", want: "urn:aws:ec2:us-west-2:123456789012:vpc/vpc-0abc"},
+		{name: "empty", value: "", want: ""},
+	}
+	var cfg *Config
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cfg.ApplyTransformStep(tc.value, TransformStep{Type: "arn_short"}); got != tc.want {
+				t.Errorf("ApplyTransformStep(%q) = %q, want %q", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyTransformStep_Truncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		step  TransformStep
+		want  string
+	}{
+		{name: "longer", value: "Throttling: Rate exceeded", step: TransformStep{Max: intPtr(10)}, want: "Throttling…"},
+		{name: "exact length", value: "Throttling", step: TransformStep{Max: intPtr(10)}, want: "Throttling"},
+		{name: "shorter", value: "Denied", step: TransformStep{Max: intPtr(10)}, want: "Denied"},
+		{name: "rune boundary", value: "エラーが発生しました", step: TransformStep{Max: intPtr(3)}, want: "エラー…"},
+		{name: "custom ellipsis", value: "Throttling: Rate exceeded", step: TransformStep{Max: intPtr(10), Ellipsis: strPtr(" [...]")}, want: "Throttling [...]"},
+		{name: "empty ellipsis", value: "Throttling: Rate exceeded", step: TransformStep{Max: intPtr(10), Ellipsis: strPtr("")}, want: "Throttling"},
+		{name: "no max", value: "Throttling: Rate exceeded", step: TransformStep{}, want: "Throttling: Rate exceeded"},
+		{name: "zero max", value: "Throttling: Rate exceeded", step: TransformStep{Max: intPtr(0)}, want: "Throttling: Rate exceeded"},
+	}
+	var cfg *Config
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.step.Type = "truncate"
+			if got := cfg.ApplyTransformStep(tc.value, tc.step); got != tc.want {
+				t.Errorf("ApplyTransformStep(%q) = %q, want %q", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyTransformStep_Split(t *testing.T) {
+	arn := "arn:aws:ec2:us-west-2:123456789012:vpc/vpc-0abc"
+	tests := []struct {
+		name  string
+		value string
+		step  TransformStep
+		want  string
+	}{
+		{name: "index", value: arn, step: TransformStep{Separator: strPtr(":"), Index: intPtr(5)}, want: "vpc/vpc-0abc"},
+		{name: "default index", value: arn, step: TransformStep{Separator: strPtr(":")}, want: "arn"},
+		{name: "last", value: "path/to/resource", step: TransformStep{Separator: strPtr("/"), Index: intPtr(-1)}, want: "resource"},
+		{name: "negative", value: "path/to/resource", step: TransformStep{Separator: strPtr("/"), Index: intPtr(-3)}, want: "path"},
+		{name: "out of range", value: arn, step: TransformStep{Separator: strPtr(":"), Index: intPtr(6)}, want: arn},
+		{name: "negative out of range", value: "a/b", step: TransformStep{Separator: strPtr("/"), Index: intPtr(-3)}, want: "a/b"},
+		{name: "no separator in value", value: "vpc-0abc", step: TransformStep{Separator: strPtr(":"), Index: intPtr(0)}, want: "vpc-0abc"},
+		{name: "no separator", value: arn, step: TransformStep{Index: intPtr(1)}, want: arn},
+	}
+	var cfg *Config
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.step.Type = "split"
+			if got := cfg.ApplyTransformStep(tc.value, tc.step); got != tc.want {
+				t.Errorf("ApplyTransformStep(%q) = %q, want %q", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyTransformStep_Decode(t *testing.T) {
+	tests := []struct {
+		name     string
+		stepType string
+		value    string
+		want     string
+	}{
+		{name: "base64", stepType: "base64_decode", value: "RW5jb2RlZCBtZXNzYWdl", want: "Encoded message"},
+		{name: "base64 unpadded", stepType: "base64_decode", value: "aGk", want: "hi"},
+		{name: "base64 URL-safe", stepType: "base64_decode", value: "Pz8_", want: "???"},
+		{name: "base64 surrounding space", stepType: "base64_decode", value: " aGk=\n", want: "hi"},
+		{name: "base64 malformed", stepType: "base64_decode", value: "not base64!", want: "not base64!"},
+		{name: "base64 binary", stepType: "base64_decode", value: "//79", want: "//79"},
+		{name: "base64 empty", stepType: "base64_decode", value: "", want: ""},
+		{name: "url", stepType: "url_decode", value: "User%3A%20arn%3Aaws%3Aiam%3A%3A123456789012%3Auser%2Fdev", want: "User: arn:aws:iam::123456789012:user/dev"},
+		{name: "url plus kept", stepType: "url_decode", value: "a+b%2Bc", want: "a+b+c"},
+		{name: "url unencoded", stepType: "url_decode", value: "plain text", want: "plain text"},
+		{name: "url malformed", stepType: "url_decode", value: "100%", want: "100%"},
+		{name: "url bad escape", stepType: "url_decode", value: "%zz%20", want: "%zz%20"},
+	}
+	var cfg *Config
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cfg.ApplyTransformStep(tc.value, TransformStep{Type: tc.stepType}); got != tc.want {
+				t.Errorf("ApplyTransformStep(%q) = %q, want %q", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyTransformStep_ParamLookup(t *testing.T) {
+	cfg := &Config{
+		Parameters: []Parameter{
+			{Name: "InvalidParameterCombination", Value: strPtr("conflicting arguments")},
+			{Name: "error_code.Throttling", Value: strPtr("too many requests")},
+		},
+	}
+	tests := []struct {
+		name  string
+		value string
+		step  TransformStep
+		want  string
+	}{
+		{name: "hit", value: "InvalidParameterCombination", step: TransformStep{Type: "param_lookup"}, want: "conflicting arguments"},
+		{name: "hit with prefix", value: " Throttling ", step: TransformStep{Type: "param_lookup", Value: strPtr("error_code.")}, want: "too many requests"},
+		{name: "miss", value: "AccessDenied", step: TransformStep{Type: "param_lookup"}, want: "AccessDenied"},
+		{name: "miss with default", value: "AccessDenied", step: TransformStep{Type: "param_lookup", With: strPtr("unknown error")}, want: "unknown error"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cfg.ApplyTransformStep(tc.value, tc.step); got != tc.want {
+				t.Errorf("ApplyTransformStep() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	var nilCfg *Config
+	if got := nilCfg.ApplyTransformStep("AccessDenied", TransformStep{Type: "param_lookup"}); got != "AccessDenied" {
+		t.Errorf("ApplyTransformStep() with nil config = %q, want value unchanged", got)
+	}
+
+	// Token transforms have access to the runtime's config
+	cfg.Transforms = []Transform{{Name: "translate", Steps: []TransformStep{{Type: "param_lookup"}}}}
+	token := Token{Name: "code", Arg: strPtr("code"), Transforms: []string{"translate"}}
+	rt := NewRuntime(context.Background(), cfg, nil, "code", "InvalidParameterCombination")
+	if got := token.Resolve(context.Background(), rt); got != "conflicting arguments" {
+		t.Errorf("Resolve() = %q, want %q", got, "conflicting arguments")
+	}
+}
+
+func TestTokenResolve_ListParameter(t *testing.T) {
+	cfg := &Config{
+		Parameters: []Parameter{
+			{Name: "retryable_codes", Values: []string{"Throttling", "RequestLimitExceeded"}},
+			{Name: "error_code.Throttling", Values: []string{"too many requests", "slow down"}},
+		},
+		Transforms: []Transform{{Name: "upper", Steps: []TransformStep{{Type: "upper"}}}},
+		Templates:  []Template{{Name: "error_detail", Format: `retries: {{join .codes ", "}}`}},
+	}
+	rt := NewRuntime(context.Background(), cfg, nil)
+
+	token := Token{Name: "codes", Source: "parameter", Parameter: strPtr("retryable_codes"), Transforms: []string{"upper"}}
+	got := token.Resolve(context.Background(), rt)
+	if want := []string{"THROTTLING", "REQUESTLIMITEXCEEDED"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() = %#v, want %#v", got, want)
+	}
+
+	out, err := cfg.RenderTemplate(context.Background(), "error_detail", map[string]any{"codes": got})
+	if err != nil {
+		t.Fatalf("RenderTemplate error: %v", err)
+	}
+	if want := "retries: THROTTLING, REQUESTLIMITEXCEEDED"; out != want {
+		t.Errorf("RenderTemplate() = %q, want %q", out, want)
+	}
+
+	step := TransformStep{Type: "param_lookup", Value: strPtr("error_code.")}
+	if got, want := cfg.ApplyTransformStep("Throttling", step), "too many requests, slow down"; got != want {
+		t.Errorf("ApplyTransformStep() = %q, want %q", got, want)
+	}
+}
+
+func TestResolveHints_MatchAll(t *testing.T) {
+	contains := "throttl"
+	cfg := &Config{
+		Hints: []Hint{
+			{Name: "throttle", ErrorContains: &contains, Suggestion: "Retry later."},
+			{Name: "no_criteria", Suggestion: "Never shown."},
+			{Name: "support", MatchAll: true, Suggestion: "Contact support."},
+		},
+	}
+	ctx := context.Background()
+	tests := map[string]string{
+		"request throttled": "Retry later.\nContact support.",
+		"not found":         "Contact support.",
+		"":                  "Contact support.",
+	}
+	for errStr, want := range tests {
+		if got := resolveHints(ctx, errors.New(errStr), cfg).Suggestions; got != want {
+			t.Errorf("resolveHints(%q) = %q, want %q", errStr, got, want)
+		}
+	}
+}
+
+func TestFallbackMessage_TokenErrorMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "unset", config: `smarterr {}`, want: ""},
+		{name: "empty string", config: `smarterr { token_error_mode = "" }`, want: ""},
+		{name: "detailed", config: `smarterr { token_error_mode = "detailed" }`, want: "[unresolved token: region] (not found)"},
+		{name: "placeholder", config: `smarterr { token_error_mode = "placeholder" }`, want: "<region>"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := &WrappedFS{FS: fstest.MapFS{
+				"smarterr.hcl": &fstest.MapFile{Data: []byte(tc.config)},
+			}}
+			cfg, err := loadConfigFile(context.Background(), fsys, "smarterr.hcl")
+			if err != nil {
+				t.Fatalf("loadConfigFile: %v", err)
+			}
+			if tc.name == "unset" && cfg.Smarterr.TokenErrorMode != nil {
+				t.Errorf("TokenErrorMode = %q, want nil", *cfg.Smarterr.TokenErrorMode)
+			}
+			if got := fallbackMessage(cfg, "region", "not found"); got != tc.want {
+				t.Errorf("fallbackMessage() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+	if got := fallbackMessage(nil, "region", "not found"); got != "" {
+		t.Errorf("fallbackMessage(nil) = %q, want empty", got)
+	}
+}
+
+func TestResolveHints_Suggestions(t *testing.T) {
+	contains := "AccessDenied"
+	cfg := &Config{
+		Smarterr: &Smarterr{HintJoinChar: strPtr("\n- ")},
+		Hints: []Hint{
+			{
+				Name:          "access",
+				ErrorContains: &contains,
+				Suggestion:    "Check your credentials:",
+				Suggestions:   []string{"Confirm the role exists.", "", "Confirm the policy allows the action."},
+			},
+			{Name: "steps", ErrorContains: &contains, Suggestions: []string{"Retry the request."}},
+		},
+	}
+	want := "Check your credentials:\n- Confirm the role exists.\n- Confirm the policy allows the action.\n- Retry the request."
+	if got := resolveHints(context.Background(), errors.New("AccessDenied: nope"), cfg).Suggestions; got != want {
+		t.Errorf("resolveHints() = %q, want %q", got, want)
+	}
+
+	first := "first"
+	cfg.Smarterr.HintMatchMode = &first
+	want = "Check your credentials:\n- Confirm the role exists.\n- Confirm the policy allows the action."
+	if got := resolveHints(context.Background(), errors.New("AccessDenied: nope"), cfg).Suggestions; got != want {
+		t.Errorf("resolveHints() with first match mode = %q, want %q", got, want)
+	}
+}
+
+func TestResolveHints_Names(t *testing.T) {
+	contains := "Throttling"
+	cfg := &Config{
+		Hints: []Hint{
+			{Name: "throttling", ErrorContains: &contains, Suggestion: "Retry later."},
+			{Name: "throttling.ja", Suggestion: "後で再試行してください。"},
+			{Name: "support", MatchAll: true, Suggestion: "Contact support."},
+		},
+	}
+	got := resolveHints(context.Background(), errors.New("Throttling: slow down"), cfg)
+	if want := []string{"throttling", "support"}; !slices.Equal(got.Names, want) {
+		t.Errorf("resolveHints().Names = %q, want %q", got.Names, want)
+	}
+	if want := "Retry later.\nContact support."; got.Suggestions != want {
+		t.Errorf("resolveHints().Suggestions = %q, want %q", got.Suggestions, want)
+	}
+
+	cfg.Tokens = []Token{{Name: "category", Source: "hint_name"}}
+	rt := NewRuntime(context.Background(), cfg, errors.New("Throttling: slow down"))
+	if got, want := cfg.Tokens[0].Resolve(context.Background(), rt), "throttling, support"; got != want {
+		t.Errorf("hint_name token = %q, want %q", got, want)
+	}
+}
+
+func TestResolveHints_MaxSuggestionsAndDedup(t *testing.T) {
+	throttling, rate := "Throttling", "Rate exceeded"
+	cfg := &Config{
+		Smarterr: &Smarterr{},
+		Hints: []Hint{
+			{Name: "throttling", ErrorContains: &throttling, Suggestion: "Retry later."},
+			{Name: "rate", ErrorContains: &rate, Suggestion: "Retry later.", Suggestions: []string{"Request a quota increase."}},
+			{Name: "support", MatchAll: true, Suggestion: "Contact support."},
+		},
+	}
+	err := errors.New("Throttling: Rate exceeded")
+	first := "first"
+	tests := []struct {
+		name      string
+		max       *int
+		dedup     *bool
+		matchMode *string
+		wantNames []string
+		want      string
+	}{
+		{name: "unlimited", wantNames: []string{"throttling", "rate", "support"}, want: "Retry later.\nRetry later.\nRequest a quota increase.\nContact support."},
+		{name: "zero is unlimited", max: intPtr(0), wantNames: []string{"throttling", "rate", "support"}, want: "Retry later.\nRetry later.\nRequest a quota increase.\nContact support."},
+		{name: "max", max: intPtr(2), wantNames: []string{"throttling", "rate", "support"}, want: "Retry later.\nRetry later."},
+		{name: "dedup", dedup: boolPtr(true), wantNames: []string{"throttling", "rate", "support"}, want: "Retry later.\nRequest a quota increase.\nContact support."},
+		{name: "dedup off", dedup: boolPtr(false), wantNames: []string{"throttling", "rate", "support"}, want: "Retry later.\nRetry later.\nRequest a quota increase.\nContact support."},
+		{name: "dedup before max", max: intPtr(3), dedup: boolPtr(true), wantNames: []string{"throttling", "rate", "support"}, want: "Retry later.\nRequest a quota increase.\nContact support."},
+		{name: "first mode wins", max: intPtr(2), matchMode: &first, wantNames: []string{"throttling"}, want: "Retry later."},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg.Smarterr.HintMaxSuggestions, cfg.Smarterr.HintDedup, cfg.Smarterr.HintMatchMode = tc.max, tc.dedup, tc.matchMode
+			got := resolveHints(context.Background(), err, cfg)
+			if !slices.Equal(got.Names, tc.wantNames) {
+				t.Errorf("Names = %q, want %q", got.Names, tc.wantNames)
+			}
+			if got.Suggestions != tc.want {
+				t.Errorf("Suggestions = %q, want %q", got.Suggestions, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveHints_Conditions(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+hint "throttling" {
+  any_of {
+    error_contains = "Throttling"
+  }
+  any_of {
+    error_contains = "TooManyRequests"
+  }
+  any_of {
+    regex_match = "(?i)rate exceeded"
+  }
+  suggestion = "Retry later."
+}
+
+hint "ec2_throttling" {
+  error_contains = "ec2"
+  any_of {
+    error_contains = "Throttling"
+  }
+  all_of {
+    error_contains = "RequestLimitExceeded"
+  }
+  all_of {
+    regex_match = "status code: 5\\d\\d"
+  }
+  suggestion = "Reduce EC2 API calls."
+}
+`), "smarterr.hcl")
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	tests := []struct {
+		err  string
+		want []string
+	}{
+		{err: "Throttling: slow down", want: []string{"throttling"}},
+		{err: "TooManyRequests", want: []string{"throttling"}},
+		{err: "Rate Exceeded", want: []string{"throttling"}},
+		{err: "AccessDenied", want: nil},
+		{err: "ec2: Throttling: RequestLimitExceeded, status code: 503", want: []string{"throttling", "ec2_throttling"}},
+		{err: "ec2: Throttling: RequestLimitExceeded, status code: 400", want: []string{"throttling"}},
+		{err: "rds: Throttling: RequestLimitExceeded, status code: 503", want: []string{"throttling"}},
+	}
+	for _, tc := range tests {
+		if got := resolveHints(context.Background(), errors.New(tc.err), cfg).Names; !slices.Equal(got, tc.want) {
+			t.Errorf("resolveHints(%q).Names = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestTokenResolve_EnvSource(t *testing.T) {
+	t.Setenv("SMARTERR_TEST_BUILD_ID", "build-42")
+	t.Setenv("SMARTERR_TEST_EMPTY", "")
+	t.Setenv("TEST_SECRET", "hunter2")
+	cfg := &Config{Smarterr: &Smarterr{TokenErrorMode: strPtr("placeholder")}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		token Token
+		want  string
+	}{
+		{name: "inferred", token: Token{Name: "build", Env: strPtr("SMARTERR_TEST_BUILD_ID")}, want: "build-42"},
+		{name: "explicit", token: Token{Name: "build", Source: "env", Env: strPtr("SMARTERR_TEST_BUILD_ID")}, want: "build-42"},
+		{name: "transforms", token: Token{Name: "build", Env: strPtr("SMARTERR_TEST_BUILD_ID"), Transforms: []string{"up"}}, want: "BUILD-42"},
+		{name: "empty", token: Token{Name: "build", Env: strPtr("SMARTERR_TEST_EMPTY")}, want: "<build>"},
+		{name: "unset", token: Token{Name: "build", Env: strPtr("SMARTERR_TEST_UNSET")}, want: "<build>"},
+		{name: "no env", token: Token{Name: "build", Source: "env"}, want: "<build>"},
+		{name: "no prefix", token: Token{Name: "build", Env: strPtr("TEST_SECRET")}, want: "<build>"},
+	}
+	cfg.Transforms = []Transform{{Name: "up", Steps: []TransformStep{{Type: "upper"}}}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.token.Resolve(ctx, NewRuntime(ctx, cfg, nil)); got != tc.want {
+				t.Errorf("Resolve() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTokenResolve_RegexCaptureSource(t *testing.T) {
+	cfg := &Config{
+		Smarterr:   &Smarterr{TokenErrorMode: strPtr("placeholder")},
+		Transforms: []Transform{{Name: "short", Steps: []TransformStep{{Type: "arn_short"}}}},
+	}
+	ctx := context.Background()
+	err := errors.New("operation error EC2: DescribeVpcs, RequestID: 1a2b-3c4d, api error: arn:aws:ec2:us-west-2:123456789012:vpc/vpc-0abc not found")
+	requestID := `RequestID: ([0-9a-f-]+)`
+	arn := `(arn:[^ ]+) not found`
+
+	tests := []struct {
+		name  string
+		token Token
+		err   error
+		want  string
+	}{
+		{name: "default group", token: Token{CaptureRegex: &requestID}, err: err, want: "1a2b-3c4d"},
+		{name: "whole match", token: Token{CaptureRegex: &requestID, CaptureGroup: intPtr(0)}, err: err, want: "RequestID: 1a2b-3c4d"},
+		{name: "transforms", token: Token{CaptureRegex: &arn, Transforms: []string{"short"}}, err: err, want: "vpc-0abc"},
+		{name: "no match", token: Token{CaptureRegex: &requestID}, err: errors.New("boom"), want: "<token>"},
+		{name: "nil error", token: Token{CaptureRegex: &requestID}, err: nil, want: "<token>"},
+		{name: "group out of range", token: Token{CaptureRegex: &requestID, CaptureGroup: intPtr(2)}, err: err, want: "<token>"},
+		{name: "invalid regex", token: Token{CaptureRegex: strPtr("(")}, err: err, want: "<token>"},
+		{name: "no regex", token: Token{}, err: err, want: "<token>"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.token.Name, tc.token.Source = "token", "regex_capture"
+			if got := tc.token.Resolve(ctx, NewRuntime(ctx, cfg, tc.err)); got != tc.want {
+				t.Errorf("Resolve() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRuntime_CallStackSharedAcrossTokens(t *testing.T) {
+	cfg := &Config{
+		StackMatches: []StackMatch{
+			{Name: "test", CalledFrom: "TestRuntime_CallStackSharedAcrossTokens", Display: "testing"},
+			{Name: "create", CalledFrom: "resourceCreate$", Display: "creating"},
+		},
+		Tokens: []Token{
+			{Name: "first", Source: "call_stack", StackMatches: []string{"test", "create"}},
+			{Name: "second", Source: "call_stack", StackMatches: []string{"test", "create"}},
+		},
+	}
+	rt := NewRuntime(context.Background(), cfg, nil)
+	values := rt.BuildTokenValueMap(context.Background())
+	if values["first"] != "testing" || values["second"] != "testing" {
+		t.Fatalf("BuildTokenValueMap() = %v, want both tokens %q", values, "testing")
+	}
+	if !rt.stackGathered {
+		t.Fatal("expected call stack to be gathered")
+	}
+
+	// Later tokens use the stack gathered first rather than walking it again
+	rt.stackFrames = []runtime.Frame{{Function: "example.com/provider/service.resourceCreate"}}
+	if got := cfg.Tokens[1].Resolve(context.Background(), rt); got != "creating" {
+		t.Errorf("Resolve() with shared stack = %q, want %q", got, "creating")
+	}
+}
+
+func TestConfig_TruncateDetail(t *testing.T) {
+	limit := func(n int) *Config { return &Config{Smarterr: &Smarterr{MaxDetailLength: &n}} }
+	tests := []struct {
+		name   string
+		cfg    *Config
+		detail string
+		want   string
+	}{
+		{name: "nil config", cfg: nil, detail: "abcdef", want: "abcdef"},
+		{name: "unset", cfg: &Config{Smarterr: &Smarterr{}}, detail: "abcdef", want: "abcdef"},
+		{name: "under limit", cfg: limit(10), detail: "abcdef", want: "abcdef"},
+		{name: "at limit", cfg: limit(6), detail: "abcdef", want: "abcdef"},
+		{name: "over limit", cfg: limit(3), detail: "abcdef", want: "abc (truncated)"},
+		{name: "multibyte", cfg: limit(2), detail: "äöü", want: "äö (truncated)"},
+		{name: "zero", cfg: limit(0), detail: "abcdef", want: "abcdef"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.cfg.TruncateDetail(tc.detail); got != tc.want {
+				t.Errorf("TruncateDetail(%q) = %q, want %q", tc.detail, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfig_TruncateDetailWithCode(t *testing.T) {
+	cfg := func(limit int, appendCode bool) *Config {
+		return &Config{Smarterr: &Smarterr{MaxDetailLength: &limit, AppendErrorCode: appendCode}}
+	}
+	// The code suffix, "\n\n[smarterr-code: T]", is 20 characters
+	tests := []struct {
+		name   string
+		cfg    *Config
+		detail string
+		code   string
+		want   string
+	}{
+		{name: "nil config", cfg: nil, detail: "abcdefghij", code: "T", want: "abcdefghij"},
+		{name: "fits", cfg: cfg(30, true), detail: "abcdefghij", code: "T", want: "abcdefghij\n\n[smarterr-code: T]"},
+		{name: "keeps room for code", cfg: cfg(25, true), detail: "abcdefghij", code: "T", want: "abcde (truncated)\n\n[smarterr-code: T]"},
+		{name: "code longer than limit", cfg: cfg(5, true), detail: "abcdefghij", code: "T", want: " (truncated)\n\n[smarterr-code: T]"},
+		{name: "no code", cfg: cfg(5, true), detail: "abcdefghij", code: "", want: "abcde (truncated)"},
+		{name: "disabled", cfg: cfg(5, false), detail: "abcdefghij", code: "T", want: "abcde (truncated)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.cfg.TruncateDetailWithCode(tc.detail, tc.code); got != tc.want {
+				t.Errorf("TruncateDetailWithCode(%q, %q) = %q, want %q", tc.detail, tc.code, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfig_AppendOriginalDetail(t *testing.T) {
+	cfg := func(limit int) *Config {
+		return &Config{Smarterr: &Smarterr{MaxDetailLength: &limit, AppendOriginalDetail: true}}
+	}
+	// The separator, "\n\n--- original error ---\n", is 25 characters
+	tests := []struct {
+		name     string
+		cfg      *Config
+		detail   string
+		original string
+		want     string
+	}{
+		{name: "nil config", cfg: nil, detail: "abc", original: "boom", want: "abc"},
+		{name: "disabled", cfg: &Config{Smarterr: &Smarterr{}}, detail: "abc", original: "boom", want: "abc"},
+		{name: "no limit", cfg: cfg(0), detail: "abc", original: "boom", want: "abc" + OriginalDetailSeparator + "boom"},
+		{name: "fits", cfg: cfg(32), detail: "abc", original: "boom", want: "abc" + OriginalDetailSeparator + "boom"},
+		{name: "truncates original", cfg: cfg(30), detail: "abc", original: "boom", want: "abc" + OriginalDetailSeparator + "bo (truncated)"},
+		{name: "no room", cfg: cfg(28), detail: "abc", original: "boom", want: "abc"},
+		{name: "empty original", cfg: cfg(0), detail: "abc", original: "", want: "abc"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.cfg.AppendOriginalDetail(tc.detail, tc.original); got != tc.want {
+				t.Errorf("AppendOriginalDetail(%q, %q) = %q, want %q", tc.detail, tc.original, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTokenResolve_StructArg(t *testing.T) {
+	type vpc struct {
+		VpcId     *string
+		CidrBlock string
+		Tags      map[string]string
+		state     string
+	}
+	type output struct {
+		Vpc      *vpc
+		Missing  *vpc
+		internal string
+	}
+	vpcID := "vpc-1"
+	out := &output{
+		Vpc:      &vpc{VpcId: &vpcID, CidrBlock: "10.0.0.0/16", Tags: map[string]string{"Name": "main"}, state: "available"},
+		internal: "hidden",
+	}
+
+	tests := map[string]string{
+		"output.Vpc.VpcId":      "vpc-1",
+		"output.Vpc.CidrBlock":  "10.0.0.0/16",
+		"output.Vpc.Tags.Name":  "main",
+		"output.Vpc.state":      "",
+		"output.internal":       "",
+		"output.Missing.VpcId":  "",
+		"output.Vpc.Bogus":      "",
+		"wrapped.out.Vpc.VpcId": "vpc-1",
+	}
+	for arg, want := range tests {
+		t.Run(arg, func(t *testing.T) {
+			rt := NewRuntime(context.Background(), &Config{}, nil, "output", out, "wrapped", map[string]any{"out": *out})
+			token := Token{Name: "t", Source: "arg", Arg: &arg}
+			if got := token.Resolve(context.Background(), rt); got != want {
+				t.Errorf("Resolve(%q) = %q, want %q", arg, got, want)
+			}
+		})
+	}
+}
+
+func TestConfig_TemplateCycle(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates []Template
+		want      []string
+	}{
+		{
+			name: "no references",
+			templates: []Template{
+				{Name: "error_summary", Format: "{{.error}}"},
+			},
+		},
+		{
+			name: "acyclic",
+			templates: []Template{
+				{Name: "error_summary", Format: `{{template "prefix" .}}: {{.error}}`},
+				{Name: "error_detail", Format: `{{template "prefix" .}}`},
+				{Name: "prefix", Format: "{{.service}}"},
+			},
+		},
+		{
+			name: "self reference",
+			templates: []Template{
+				{Name: "error_summary", Format: `{{if .error}}{{template "error_summary" .}}{{end}}`},
+			},
+			want: []string{"error_summary", "error_summary"},
+		},
+		{
+			name: "indirect cycle",
+			templates: []Template{
+				{Name: "error_detail", Format: "{{.error}}"},
+				{Name: "a", Format: `{{template "b" .}}`},
+				{Name: "b", Format: `{{range .items}}{{template "c" .}}{{end}}`},
+				{Name: "c", Format: `{{template "a" .}}`},
+			},
+			want: []string{"a", "b", "c", "a"},
+		},
+		{
+			name: "define invoking itself",
+			templates: []Template{
+				{Name: "error_detail", Format: `{{define "item"}}{{template "item" .}}{{end}}{{template "item" .}}`},
+			},
+			want: []string{"error_detail/item", "error_detail/item"},
+		},
+		{
+			name: "define invoking its config template",
+			templates: []Template{
+				{Name: "error_detail", Format: `{{define "item"}}{{template "error_detail" .}}{{end}}{{template "item" .}}`},
+			},
+			want: []string{"error_detail", "error_detail/item", "error_detail"},
+		},
+		{
+			name: "define shadowing a config template",
+			templates: []Template{
+				{Name: "error_summary", Format: `{{define "prefix"}}{{.service}}{{end}}{{template "prefix" .}}`},
+				{Name: "prefix", Format: `{{template "error_summary" .}}`},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{Templates: tc.templates}
+			if got := cfg.TemplateCycle(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("TemplateCycle() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfig_TemplateCycle_Memo(t *testing.T) {
+	cfg := &Config{
+		Templates: []Template{{Name: "error_summary", Format: `{{template "error_summary" .}}`}},
+		cycleMemo: &templateCycleMemo{},
+	}
+	want := []string{"error_summary", "error_summary"}
+	if got := cfg.templateCycle(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("templateCycle() = %v, want %v", got, want)
+	}
+	// A loaded config's templates don't change, so the first result is reused
+	cfg.Templates = nil
+	if got := cfg.templateCycle(); !reflect.DeepEqual(got, want) {
+		t.Errorf("templateCycle() = %v, want the memoized %v", got, want)
+	}
+}
+
+func TestConfig_RenderTemplate_Cycle(t *testing.T) {
+	cfg := &Config{Templates: []Template{
+		{Name: "error_summary", Format: `{{template "error_summary" .}}`},
+	}}
+	_, err := cfg.RenderTemplate(context.Background(), "error_summary", map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "template reference cycle error_summary -> error_summary") {
+		t.Errorf("expected cycle error, got: %v", err)
+	}
+}
+
+func TestConfig_RenderTemplate_Helpers(t *testing.T) {
+	cfg := &Config{Templates: []Template{
+		{Name: "error_summary", Format: `{{template "prefix" .}}: {{.error}}`},
+		{Name: "error_detail", Format: `{{define "prefix"}}inline {{.service}}{{end}}{{template "prefix" .}}`},
+		{Name: "prefix", Format: `{{template "service" .}} {{.operation}}`},
+		{Name: "service", Format: "{{.service}}"},
+	}}
+	got, err := cfg.RenderTemplate(context.Background(), "error_summary", map[string]any{
+		"error":     "boom",
+		"operation": "creating",
+		"service":   "S3",
+	})
+	if err != nil {
+		t.Fatalf("RenderTemplate() error: %v", err)
+	}
+	if want := "S3 creating: boom"; got != want {
+		t.Errorf("RenderTemplate() = %q, want %q", got, want)
+	}
+
+	// A {{define}} in the template itself wins over a Config template with the same name
+	got, err = cfg.RenderTemplate(context.Background(), "error_detail", map[string]any{"service": "S3"})
+	if err != nil {
+		t.Fatalf("RenderTemplate() error: %v", err)
+	}
+	if want := "inline S3"; got != want {
+		t.Errorf("RenderTemplate() = %q, want %q", got, want)
+	}
+}
+
+func TestTokenResolve_FromToken(t *testing.T) {
+	cfg := &Config{
+		Smarterr: &Smarterr{TokenErrorMode: strPtr("placeholder")},
+		Tokens: []Token{
+			// Listed before the token it's derived from
+			{Name: "id_upper", FromToken: strPtr("id_clean"), Transforms: []string{"upper"}},
+			{Name: "id_clean", FromToken: strPtr("id"), Transforms: []string{"trim"}},
+			{Name: "id", Arg: strPtr("id")},
+			{Name: "missing", FromToken: strPtr("bogus")},
+			{Name: "loop_a", FromToken: strPtr("loop_b")},
+			{Name: "loop_b", FromToken: strPtr("loop_a")},
+		},
+		Transforms: []Transform{
+			{Name: "upper", Steps: []TransformStep{{Type: "upper"}}},
+			{Name: "trim", Steps: []TransformStep{{Type: "trim_space"}}},
+		},
+	}
+	ctx := context.Background()
+	rt := NewRuntime(ctx, cfg, nil, "id", "  vpc-1  ")
+	values := rt.BuildTokenValueMap(ctx)
+
+	want := map[string]any{
+		"id":       "  vpc-1  ",
+		"id_clean": "vpc-1",
+		"id_upper": "VPC-1",
+		"missing":  "<missing>",
+		"loop_b":   "<loop_a>",
+		"loop_a":   "<loop_a>",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("BuildTokenValueMap() = %v, want %v", values, want)
+	}
+
+	// Resolving directly, without BuildTokenValueMap, resolves the token it's derived from
+	direct := NewRuntime(ctx, cfg, nil, "id", "vpc-2")
+	if got := cfg.Tokens[0].Resolve(ctx, direct); got != "VPC-2" {
+		t.Errorf("Resolve() = %q, want %q", got, "VPC-2")
+	}
+}
+
+func TestTokenOrder(t *testing.T) {
+	tokens := []Token{
+		{Name: "c", FromToken: strPtr("b")},
+		{Name: "b", FromToken: strPtr("a")},
+		{Name: "a"},
+		{Name: "d"},
+	}
+	order, cycle := TokenOrder(tokens)
+	var names []string
+	for _, tok := range order {
+		names = append(names, tok.Name)
+	}
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("TokenOrder() = %v, want %v", names, want)
+	}
+	if cycle != nil {
+		t.Errorf("TokenOrder() cycle = %v, want nil", cycle)
+	}
+
+	_, cycle = TokenOrder([]Token{
+		{Name: "a", FromToken: strPtr("b")},
+		{Name: "b", FromToken: strPtr("a")},
+	})
+	if want := []string{"a", "b", "a"}; !reflect.DeepEqual(cycle, want) {
+		t.Errorf("TokenOrder() cycle = %v, want %v", cycle, want)
+	}
+}
+
+func TestNewRuntime_ReservedKeyvals(t *testing.T) {
+	ctx := context.Background()
+	rt := NewRuntime(ctx, nil, nil, "id", "vpc-1", SummaryOverrideKey, "Custom summary")
+	if want := map[string]any{"id": "vpc-1"}; !reflect.DeepEqual(rt.Args, want) {
+		t.Errorf("Args = %v, want %v", rt.Args, want)
+	}
+	if want := map[string]any{SummaryOverrideKey: "Custom summary"}; !reflect.DeepEqual(rt.Reserved, want) {
+		t.Errorf("Reserved = %v, want %v", rt.Reserved, want)
+	}
+
+	rt = NewRuntimeForDiagnostic(ctx, nil, nil, SummaryOverrideKey, "Custom summary")
+	if len(rt.Args) != 0 || rt.Reserved[SummaryOverrideKey] != "Custom summary" {
+		t.Errorf("NewRuntimeForDiagnostic Args = %v, Reserved = %v", rt.Args, rt.Reserved)
+	}
+
+	rt = NewRuntime(ctx, nil, nil, "id", "vpc-1")
+	if rt.Reserved != nil {
+		t.Errorf("Reserved = %v, want nil without reserved keyvals", rt.Reserved)
+	}
+}
+
+func TestSplitReserved(t *testing.T) {
+	tests := []struct {
+		name         string
+		kv           []any
+		wantRest     []any
+		wantReserved map[string]any
+	}{
+		{
+			name:     "none reserved",
+			kv:       []any{"id", "vpc-1"},
+			wantRest: []any{"id", "vpc-1"},
+		},
+		{
+			name:         "reserved between args",
+			kv:           []any{"id", "vpc-1", SummaryOverrideKey, "Custom", "name", "main"},
+			wantRest:     []any{"id", "vpc-1", "name", "main"},
+			wantReserved: map[string]any{SummaryOverrideKey: "Custom"},
+		},
+		{
+			name:         "later reserved value wins",
+			kv:           []any{SummaryOverrideKey, "first", SummaryOverrideKey, "second"},
+			wantRest:     []any{},
+			wantReserved: map[string]any{SummaryOverrideKey: "second"},
+		},
+		{
+			name:         "odd trailing element is kept for parseKeyvals to drop",
+			kv:           []any{SummaryOverrideKey, "Custom", "dangling"},
+			wantRest:     []any{"dangling"},
+			wantReserved: map[string]any{SummaryOverrideKey: "Custom"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rest, reserved := splitReserved(tc.kv)
+			if !reflect.DeepEqual(rest, tc.wantRest) {
+				t.Errorf("rest = %v, want %v", rest, tc.wantRest)
+			}
+			if !reflect.DeepEqual(reserved, tc.wantReserved) {
+				t.Errorf("reserved = %v, want %v", reserved, tc.wantReserved)
+			}
+		})
+	}
+}
+
+func TestNewRuntime_ReservedKeyvalsIgnoreDuplicateMode(t *testing.T) {
+	cfg := &Config{Smarterr: &Smarterr{DuplicateKeyvalMode: strPtr("collect")}}
+	rt := NewRuntime(context.Background(), cfg, nil, SummaryOverrideKey, "first", "id", "a", SummaryOverrideKey, "second", "id", "b")
+	if got := rt.Reserved[SummaryOverrideKey]; got != "second" {
+		t.Errorf("Reserved[%q] = %v, want %q", SummaryOverrideKey, got, "second")
+	}
+	if want := map[string]any{"id": []any{"a", "b"}}; !reflect.DeepEqual(rt.Args, want) {
+		t.Errorf("Args = %v, want %v", rt.Args, want)
+	}
+}
+
+func TestNewRuntime_StrictKeyvals(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		kv        []any
+		wantPanic string
+	}{
+		{name: "valid", kv: []any{"id", "vpc-1"}},
+		{name: "non-string key", kv: []any{"id", "vpc-1", 42, "x"}, wantPanic: "key at index 2 is int (42), not string"},
+		{name: "odd number", kv: []any{"id", "vpc-1", "name"}, wantPanic: `key "name" at index 2 has no value`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Lenient mode never panics
+			lenient := NewRuntime(ctx, nil, nil, tc.kv...)
+			if tc.wantPanic != "" && tc.name == "non-string key" && len(lenient.Args) != 0 {
+				t.Errorf("lenient Args = %v, want keyvals ignored", lenient.Args)
+			}
+
+			SetStrictKeyvals(true)
+			t.Cleanup(func() { SetStrictKeyvals(false) })
+			defer func() {
+				r := recover()
+				if tc.wantPanic == "" {
+					if r != nil {
+						t.Errorf("unexpected panic: %v", r)
+					}
+					return
+				}
+				if msg, ok := r.(string); !ok || !strings.Contains(msg, tc.wantPanic) {
+					t.Errorf("panic = %v, want it to contain %q", r, tc.wantPanic)
+				}
+			}()
+			NewRuntime(ctx, nil, nil, tc.kv...)
+		})
+	}
+}
+
+func TestPackageService(t *testing.T) {
+	tests := []struct {
+		name      string
+		functions []string
+		want      string
+		wantOK    bool
+	}{
+		{
+			name: "service package",
+			functions: []string{
+				"github.com/YakDriver/smarterr.AddError",
+				"github.com/hashicorp/terraform-provider-aws/internal/service/ec2.resourceVPCCreate",
+			},
+			want:   "ec2",
+			wantOK: true,
+		},
+		{
+			name: "closure and method",
+			functions: []string{
+				"github.com/hashicorp/terraform-provider-aws/internal/service/elbv2.(*listenerResource).Create.func1",
+			},
+			want:   "elbv2",
+			wantOK: true,
+		},
+		{
+			name: "nearest frame outside smarterr wins",
+			functions: []string{
+				"github.com/YakDriver/smarterr/internal.(*Runtime).callStack",
+				"github.com/hashicorp/terraform-provider-aws/internal/service/iam.findRole",
+				"github.com/hashicorp/terraform-provider-aws/internal/service/ec2.resourceVPCCreate",
+			},
+			want:   "iam",
+			wantOK: true,
+		},
+		{
+			name: "no service package",
+			functions: []string{
+				"github.com/hashicorp/terraform-provider-aws/internal/conns.(*AWSClient).EC2Client",
+				"main.main",
+			},
+		},
+		{
+			name:      "smarterr frames only",
+			functions: []string{"github.com/YakDriver/smarterr/internal/service/x.f"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var frames []runtime.Frame
+			for _, fn := range tc.functions {
+				frames = append(frames, runtime.Frame{Function: fn})
+			}
+			got, ok := packageService(frames)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("packageService() = %q, %t, want %q, %t", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestTokenResolve_PackageService(t *testing.T) {
+	cfg := &Config{
+		Smarterr: &Smarterr{TokenErrorMode: strPtr("placeholder")},
+		Tokens: []Token{
+			{Name: "service", Source: "package_service", Transforms: []string{"upper"}},
+			{Name: "mapped", Source: "package_service", ServiceMap: map[string]string{"elbv2": "ELBv2"}},
+		},
+		Transforms: []Transform{{Name: "upper", Steps: []TransformStep{{Type: "upper"}}}},
+	}
+	ctx := context.Background()
+	rt := NewRuntime(ctx, cfg, nil)
+	rt.stackGathered = true
+	rt.stackFrames = []runtime.Frame{{Function: "github.com/hashicorp/terraform-provider-aws/internal/service/elbv2.resourceListenerCreate"}}
+	values := rt.BuildTokenValueMap(ctx)
+	if values["service"] != "ELBV2" || values["mapped"] != "ELBv2" {
+		t.Errorf("BuildTokenValueMap() = %v, want service ELBV2 and mapped ELBv2", values)
+	}
+
+	// Called from smarterr's own tests, the live stack has no service package
+	rt = NewRuntime(ctx, cfg, nil)
+	if got := cfg.Tokens[1].Resolve(ctx, rt); got != "<mapped>" {
+		t.Errorf("Resolve() = %v, want fallback %q", got, "<mapped>")
+	}
 }
@@ -8,76 +8,153 @@ const (
 	// ConfigFileName is the name of the configuration file
 	// that contains the configuration for smarterr.
 	ConfigFileName = "smarterr.hcl"
+
+	// JSONConfigFileName is the name of a configuration file written as JSON instead of HCL. If
+	// a directory has both, ConfigFileName is used.
+	JSONConfigFileName = "smarterr.json"
 )
 
 const (
 	SmarterrContextKey = "smarterrCallID"
 )
 
+const (
+	// MinSchemaVersion and SchemaVersion are the oldest and newest config schema versions this
+	// version of smarterr supports. A config declares its version with the top-level version
+	// attribute.
+	MinSchemaVersion = 1
+	SchemaVersion    = 1
+)
+
 // Config represents the top-level configuration for smarterr.
 type Config struct {
-	Smarterr     *Smarterr    `hcl:"smarterr,block"`
-	Tokens       []Token      `hcl:"token,block"`
-	Hints        []Hint       `hcl:"hint,block"`
-	Parameters   []Parameter  `hcl:"parameter,block"`
-	StackMatches []StackMatch `hcl:"stack_match,block"`
-	Templates    []Template   `hcl:"template,block"`
-	Transforms   []Transform  `hcl:"transform,block"`
+	Version      *int         `hcl:"version,optional" json:"version"` // Config schema version (default: unversioned)
+	Smarterr     *Smarterr    `hcl:"smarterr,block" json:"smarterr"`
+	Tokens       []Token      `hcl:"token,block" json:"token,omitempty"`
+	Hints        []Hint       `hcl:"hint,block" json:"hint,omitempty"`
+	Parameters   []Parameter  `hcl:"parameter,block" json:"parameter,omitempty"`
+	StackMatches []StackMatch `hcl:"stack_match,block" json:"stack_match,omitempty"`
+	Templates    []Template   `hcl:"template,block" json:"template,omitempty"`
+	Transforms   []Transform  `hcl:"transform,block" json:"transform,omitempty"`
+
+	cycleMemo *templateCycleMemo // TemplateCycle, once computed, for a merged config
 }
 
 // Smarterr represents settings for how smarterr works such as debugging, token error mode, etc.
 type Smarterr struct {
-	Debug          bool    `hcl:"debug,optional"`
-	TokenErrorMode *string `hcl:"token_error_mode,optional"` // "detailed", "placeholder", "empty" (default: "empty")
-	HintJoinChar   *string `hcl:"hint_join_char,optional"`
-	HintMatchMode  *string `hcl:"hint_match_mode,optional"` // "all" (default), "first"
+	Debug                bool              `hcl:"debug,optional" json:"debug,omitempty"`
+	TokenErrorMode       *string           `hcl:"token_error_mode,optional" json:"token_error_mode"` // "detailed", "placeholder", "empty" (default: "empty")
+	HintJoinChar         *string           `hcl:"hint_join_char,optional" json:"hint_join_char"`
+	HintMatchMode        *string           `hcl:"hint_match_mode,optional" json:"hint_match_mode"`                         // "all" (default), "first"
+	HintSeparator        *string           `hcl:"hint_separator,optional" json:"hint_separator"`                           // Prepended to the hints token when any hint matches (default: "")
+	HintMaxSuggestions   *int              `hcl:"hint_max_suggestions,optional" json:"hint_max_suggestions"`               // Keep at most this many suggestion lines (default: 0, no limit)
+	HintDedup            *bool             `hcl:"hint_dedup,optional" json:"hint_dedup"`                                   // Drop repeated suggestions before joining (default: false)
+	DuplicateKeyvalMode  *string           `hcl:"duplicate_keyval_mode,optional" json:"duplicate_keyval_mode"`             // "last" (default), "first", "collect"
+	MaxDetailLength      *int              `hcl:"max_detail_length,optional" json:"max_detail_length"`                     // Truncate longer diagnostic details (default: no limit)
+	AppendOriginalDetail bool              `hcl:"append_original_detail,optional" json:"append_original_detail,omitempty"` // Append the original error to rendered details, for authoring templates
+	AppendErrorCode      bool              `hcl:"append_error_code,optional" json:"append_error_code,omitempty"`           // Append the error's machine code, such as [smarterr-code: THROTTLING], to rendered details
+	LogFields            []string          `hcl:"log_fields,optional" json:"log_fields,omitempty"`                         // Tokens passed as fields to log templates (default: all tokens)
+	MergePrecedence      map[string]string `hcl:"merge_precedence,optional" json:"merge_precedence,omitempty"`             // Block type to "local" (default) or "base", the config whose block wins when layered configs merge
+}
+
+// Merge precedences for smarterr.merge_precedence
+const (
+	MergePrecedenceLocal = "local" // The more specific config's block wins (default)
+	MergePrecedenceBase  = "base"  // The less specific config's block wins, such as global templates
+)
+
+// MergeBlockTypes are the block types smarterr.merge_precedence can set a precedence for.
+var MergeBlockTypes = []string{"token", "hint", "parameter", "stack_match", "template", "transform"}
+
+// MergePrecedenceFor returns the merge precedence for blockType, such as "template", or
+// MergePrecedenceLocal if s is nil or doesn't set one.
+func (s *Smarterr) MergePrecedenceFor(blockType string) string {
+	if s == nil || s.MergePrecedence[blockType] == "" {
+		return MergePrecedenceLocal
+	}
+	return s.MergePrecedence[blockType]
 }
 
 // Template represents a named text/template for formatting error messages or diagnostics.
 type Template struct {
-	Name   string `hcl:"name,label"`
-	Format string `hcl:"format"`
+	Name        string `hcl:"name,label" json:"name"`
+	Format      string `hcl:"format" json:"format"`
+	Description string `hcl:"description,optional" json:"description,omitempty"` // Documentation only; not used at runtime
 }
 
 type TransformStep struct {
-	Type    string  `hcl:"type,label"`
-	Value   *string `hcl:"value,optional"`
-	Regex   *string `hcl:"regex,optional"`
-	With    *string `hcl:"with,optional"`
-	Recurse *bool   `hcl:"recurse,optional"`
+	Type      string  `hcl:"type,label" json:"type"`
+	Value     *string `hcl:"value,optional" json:"value"`
+	Regex     *string `hcl:"regex,optional" json:"regex"`
+	With      *string `hcl:"with,optional" json:"with"`
+	Recurse   *bool   `hcl:"recurse,optional" json:"recurse"`
+	Max       *int    `hcl:"max,optional" json:"max"`             // For truncate, the maximum length in characters, not counting the ellipsis
+	Ellipsis  *string `hcl:"ellipsis,optional" json:"ellipsis"`   // For truncate, appended to a cut value (default: "…")
+	Separator *string `hcl:"separator,optional" json:"separator"` // For split, the separator between parts
+	Index     *int    `hcl:"index,optional" json:"index"`         // For split, the part returned; negative counts from the end (default: 0)
 }
 
 type Transform struct {
-	Name  string          `hcl:"name,label"`
-	Steps []TransformStep `hcl:"step,block"`
+	Name  string          `hcl:"name,label" json:"name"`
+	Steps []TransformStep `hcl:"step,block" json:"step,omitempty"`
 }
 
 // Token represents a token in the configuration, which can be used for error message formatting.
 type Token struct {
-	Name            string              `hcl:"name,label"`
-	Source          string              `hcl:"source,optional"`
-	Parameter       *string             `hcl:"parameter,optional"`
-	StackMatches    []string            `hcl:"stack_matches,optional"`
-	Arg             *string             `hcl:"arg,optional"`
-	Context         *string             `hcl:"context,optional"`
-	Transforms      []string            `hcl:"transforms,optional"`
-	FieldTransforms map[string][]string `hcl:"field_transforms,optional"`
+	Name            string              `hcl:"name,label" json:"name"`
+	Source          string              `hcl:"source,optional" json:"source,omitempty"`
+	Parameter       *string             `hcl:"parameter,optional" json:"parameter"`
+	StackMatches    []string            `hcl:"stack_matches,optional" json:"stack_matches,omitempty"`
+	Arg             *string             `hcl:"arg,optional" json:"arg"`
+	Context         *string             `hcl:"context,optional" json:"context"`
+	Annotation      *string             `hcl:"annotation,optional" json:"annotation"`
+	ResourceData    *string             `hcl:"resource_data,optional" json:"resource_data"` // Attribute read from resource data in the context (see WithResourceData)
+	Env             *string             `hcl:"env,optional" json:"env"`                     // Environment variable read from the process
+	CaptureRegex    *string             `hcl:"capture_regex,optional" json:"capture_regex"` // For source = "regex_capture", the regex run against the error
+	CaptureGroup    *int                `hcl:"capture_group,optional" json:"capture_group"` // For source = "regex_capture", the capture group returned (default: 1)
+	FromToken       *string             `hcl:"from_token,optional" json:"from_token"`       // Another token whose resolved value this token transforms
+	Transforms      []string            `hcl:"transforms,optional" json:"transforms,omitempty"`
+	FieldTransforms map[string][]string `hcl:"field_transforms,optional" json:"field_transforms,omitempty"`
+	Description     string              `hcl:"description,optional" json:"description,omitempty"`           // Documentation only; not used at runtime
+	StackCategories []string            `hcl:"stack_categories,optional" json:"stack_categories,omitempty"` // Compose the best match per stack_match category, in order
+	StackJoin       *string             `hcl:"stack_join,optional" json:"stack_join"`                       // Separator for composed displays (default: ", ")
+	ServiceMap      map[string]string   `hcl:"service_map,optional" json:"service_map,omitempty"`           // For source = "package_service", service names for irregular package names
+	Disabled        bool                `hcl:"disabled,optional" json:"disabled,omitempty"`                 // Suppresses a token inherited from a less specific config; it resolves as not found
 }
 
 type Parameter struct {
-	Name  string `hcl:"name,label"`
-	Value string `hcl:"value,attr"`
+	Name   string   `hcl:"name,label" json:"name"`
+	Value  *string  `hcl:"value,optional" json:"value"`
+	Values []string `hcl:"values,optional" json:"values,omitempty"` // A list, such as retryable error codes, instead of value
 }
 
 type Hint struct {
-	Name          string  `hcl:"name,label"`
-	ErrorContains *string `hcl:"error_contains,optional"`
-	RegexMatch    *string `hcl:"regex_match,optional"`
-	Suggestion    string  `hcl:"suggestion"`
+	Name          string      `hcl:"name,label" json:"name"`
+	ErrorContains *string     `hcl:"error_contains,optional" json:"error_contains"`
+	RegexMatch    *string     `hcl:"regex_match,optional" json:"regex_match"`
+	ErrorType     *string     `hcl:"error_type,optional" json:"error_type"`         // Name of an error type registered with RegisterErrorType
+	AnyOf         []Condition `hcl:"any_of,block" json:"any_of,omitempty"`          // At least one of these conditions must match
+	AllOf         []Condition `hcl:"all_of,block" json:"all_of,omitempty"`          // Every one of these conditions must match
+	MatchAll      bool        `hcl:"match_all,optional" json:"match_all,omitempty"` // Match every error (catch-all); criteria are ignored
+	Suggestion    string      `hcl:"suggestion,optional" json:"suggestion,omitempty"`
+	Suggestions   []string    `hcl:"suggestions,optional" json:"suggestions,omitempty"` // Ordered steps shown after suggestion, joined like separate hints
+	Code          string      `hcl:"code,optional" json:"code,omitempty"`               // Stable machine code, such as "THROTTLING", for errors the hint matches
+	Description   string      `hcl:"description,optional" json:"description,omitempty"` // Documentation only; not used at runtime
+	Disabled      bool        `hcl:"disabled,optional" json:"disabled,omitempty"`       // Suppresses a hint inherited from a less specific config; it never matches
+}
+
+// Condition is one match criterion in a hint's any_of or all_of group. The error must satisfy
+// every field the condition sets.
+type Condition struct {
+	ErrorContains *string `hcl:"error_contains,optional" json:"error_contains"`
+	RegexMatch    *string `hcl:"regex_match,optional" json:"regex_match"`
 }
 
 type StackMatch struct {
-	Name       string `hcl:"name,label"`
-	CalledFrom string `hcl:"called_from,optional"`
-	Display    string `hcl:"display"`
+	Name        string `hcl:"name,label" json:"name"`
+	CalledFrom  string `hcl:"called_from,optional" json:"called_from,omitempty"`
+	CalledAfter string `hcl:"called_after,optional" json:"called_after,omitempty"` // Only matches if a frame further up the stack matches this regex
+	Display     string `hcl:"display" json:"display"`
+	Priority    int    `hcl:"priority,optional" json:"priority,omitempty"` // Higher priority rules are tried first within a frame (default: 0)
+	Category    string `hcl:"category,optional" json:"category,omitempty"` // Groups rules for composed displays (see Token.StackCategories)
 }
@@ -0,0 +1,154 @@
+// upgrade.go
+// Upgrades of configs written for older schema versions
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// unversionedSchemaVersion is the schema version of a config that doesn't declare one, since
+// configs written before versioning use the first schema.
+const unversionedSchemaVersion = 1
+
+// schemaUpgrades rewrite a config from schema version from to from+1, in HCL with apply and in
+// JSON with applyJSON. Each reports whether it changed anything. HCL upgrades only rename or
+// restructure in place, so line numbers in errors still match the file on disk. When a schema
+// change renames or restructures a field, raise SchemaVersion and add the upgrade from the previous
+// version here.
+var schemaUpgrades []schemaUpgrade
+
+// schemaUpgrade is one entry of schemaUpgrades.
+type schemaUpgrade struct {
+	from      int
+	apply     func(body *hclwrite.Body) bool
+	applyJSON func(top map[string]json.RawMessage) (bool, error)
+}
+
+// declaredSchemaVersion returns the schema version body declares with the top-level version
+// attribute. ok is false if the version isn't an integer literal, which decoding reports.
+func declaredSchemaVersion(body *hclwrite.Body) (version int, ok bool) {
+	attr := body.GetAttribute("version")
+	if attr == nil {
+		return unversionedSchemaVersion, true
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes())))
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// upgradeConfigFile applies the schema upgrades from the version file declares up to
+// SchemaVersion. It reports whether it changed anything.
+func upgradeConfigFile(file *hclwrite.File) bool {
+	version, ok := declaredSchemaVersion(file.Body())
+	if !ok {
+		return false
+	}
+	changed := false
+	for _, u := range schemaUpgrades {
+		if u.from >= version && u.apply(file.Body()) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// upgradeSource upgrades src, the content of a config file, in memory so configs written for an
+// older schema keep working. It returns src unchanged if it needs no upgrade or doesn't parse,
+// leaving parse errors for the caller to report.
+func upgradeSource(src []byte, filename string) []byte {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() || !upgradeConfigFile(file) {
+		return src
+	}
+	Debugf("[upgradeSource] upgraded %s to schema version %d in memory; run smarterr migrate-config to update the file", filename, SchemaVersion)
+	return file.Bytes()
+}
+
+// upgradeJSONConfig applies the schema upgrades to top, a JSON config's top-level object, from the
+// version it declares up to SchemaVersion, and declares SchemaVersion. changed is false if top
+// already declares the current schema version.
+func upgradeJSONConfig(top map[string]json.RawMessage, filename string) (changed bool, err error) {
+	version := unversionedSchemaVersion
+	if raw, ok := top["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return false, fmt.Errorf("%s: version must be an integer", filename)
+		}
+	}
+	if version >= SchemaVersion {
+		return false, nil
+	}
+	for _, u := range schemaUpgrades {
+		if u.from < version {
+			continue
+		}
+		if _, err := u.applyJSON(top); err != nil {
+			return false, err
+		}
+	}
+	top["version"] = json.RawMessage(strconv.Itoa(SchemaVersion))
+	return true, nil
+}
+
+// upgradeJSONSource upgrades src, the content of a JSON config file, in memory like upgradeSource.
+func upgradeJSONSource(src []byte, filename string) []byte {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(src, &top); err != nil || top == nil {
+		return src
+	}
+	if changed, err := upgradeJSONConfig(top, filename); err != nil || !changed {
+		return src
+	}
+	upgraded, err := json.Marshal(top)
+	if err != nil {
+		return src
+	}
+	Debugf("[upgradeJSONSource] upgraded %s to schema version %d in memory; run smarterr migrate-config to update the file", filename, SchemaVersion)
+	return upgraded
+}
+
+// UpgradeConfigSource rewrites src, the content of a config file, for the current schema and
+// declares version = SchemaVersion, preserving comments and formatting. changed is false if src
+// already declares the current schema version. A JSON config, named *.json, is re-indented, and
+// its top-level keys are sorted.
+func UpgradeConfigSource(src []byte, filename string) (upgraded []byte, changed bool, err error) {
+	if strings.HasSuffix(filename, ".json") {
+		var top map[string]json.RawMessage
+		if err := json.Unmarshal(src, &top); err != nil {
+			return nil, false, fmt.Errorf("parse error: %w", err)
+		}
+		if changed, err := upgradeJSONConfig(top, filename); err != nil || !changed {
+			return src, false, err
+		}
+		upgraded, err := json.MarshalIndent(top, "", "  ")
+		if err != nil {
+			return nil, false, err
+		}
+		return append(upgraded, '\n'), true, nil
+	}
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, false, fmt.Errorf("parse error: %s", diags.Error())
+	}
+	version, ok := declaredSchemaVersion(file.Body())
+	if !ok {
+		return nil, false, fmt.Errorf("%s: version must be an integer", filename)
+	}
+	if version >= SchemaVersion {
+		return src, false, nil
+	}
+	upgradeConfigFile(file)
+	if file.Body().GetAttribute("version") != nil {
+		file.Body().SetAttributeValue("version", cty.NumberIntVal(SchemaVersion))
+		return file.Bytes(), true, nil
+	}
+	return append(fmt.Appendf(nil, "version = %d\n\n", SchemaVersion), file.Bytes()...), true, nil
+}
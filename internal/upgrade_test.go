@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// v0Config is written for schema version 0, which the tests pretend named hint_join_char
+// hint_joiner, so they can exercise the upgrades while the schema has had only one version.
+const v0Config = `version = 0
+
+# Shared settings
+smarterr {
+  hint_joiner = "\n- " # One hint per line
+}
+
+token "error" {
+  source = "error"
+}
+`
+
+// withV0Upgrade replaces schemaUpgrades for the test with an upgrade from version 0 that renames
+// smarterr.hint_joiner to hint_join_char.
+func withV0Upgrade(t *testing.T) {
+	t.Helper()
+	prev := schemaUpgrades
+	schemaUpgrades = []schemaUpgrade{{
+		from: 0,
+		apply: func(body *hclwrite.Body) bool {
+			changed := false
+			for _, block := range body.Blocks() {
+				if block.Type() == "smarterr" && block.Body().RenameAttribute("hint_joiner", "hint_join_char") {
+					changed = true
+				}
+			}
+			return changed
+		},
+		applyJSON: func(top map[string]json.RawMessage) (bool, error) {
+			var block map[string]json.RawMessage
+			if err := json.Unmarshal(top["smarterr"], &block); err != nil {
+				return false, nil
+			}
+			value, ok := block["hint_joiner"]
+			if !ok {
+				return false, nil
+			}
+			block["hint_join_char"] = value
+			delete(block, "hint_joiner")
+			upgraded, err := json.Marshal(block)
+			if err != nil {
+				return false, err
+			}
+			top["smarterr"] = upgraded
+			return true, nil
+		},
+	}}
+	t.Cleanup(func() { schemaUpgrades = prev })
+}
+
+func TestSchemaUpgrades(t *testing.T) {
+	// Each upgrade goes one version further, ending at the current version
+	for i, u := range schemaUpgrades {
+		if want := SchemaVersion - len(schemaUpgrades) + i; u.from != want {
+			t.Errorf("schemaUpgrades[%d] upgrades from version %d, want %d", i, u.from, want)
+		}
+	}
+}
+
+func TestParseConfig_Upgrades(t *testing.T) {
+	withV0Upgrade(t)
+
+	for filename, src := range map[string]string{
+		"smarterr.hcl":  v0Config,
+		"smarterr.json": `{"version": 0, "smarterr": {"hint_joiner": "\n- "}, "token": [{"name": "error", "source": "error"}]}`,
+	} {
+		cfg, err := ParseConfig([]byte(src), filename)
+		if err != nil {
+			t.Fatalf("ParseConfig(%s) error: %v", filename, err)
+		}
+		if cfg.Smarterr == nil || cfg.Smarterr.HintJoinChar == nil || *cfg.Smarterr.HintJoinChar != "\n- " {
+			t.Errorf("ParseConfig(%s): expected hint_joiner to upgrade to hint_join_char, got %+v", filename, cfg.Smarterr)
+		}
+		if len(cfg.Tokens) != 1 {
+			t.Errorf("ParseConfig(%s): expected 1 token, got %d", filename, len(cfg.Tokens))
+		}
+	}
+
+	// A config for the current version isn't upgraded, so the old name is unknown
+	current := strings.Replace(v0Config, "version = 0", "version = 1", 1)
+	if _, err := ParseConfig([]byte(current), "smarterr.hcl"); err == nil || !strings.Contains(err.Error(), "hint_joiner") {
+		t.Errorf("expected an error for the old name, got: %v", err)
+	}
+}
+
+func TestParseConfig_UpgradeKeepsLineNumbers(t *testing.T) {
+	withV0Upgrade(t)
+
+	_, err := ParseConfig([]byte(v0Config+"token \"bad\" {\n  bogus = 1\n}\n"), "smarterr.hcl")
+	if err == nil || !strings.Contains(err.Error(), "smarterr.hcl:12") {
+		t.Errorf("expected an error on line 12, got: %v", err)
+	}
+}
+
+func TestUpgradeConfigSource(t *testing.T) {
+	withV0Upgrade(t)
+
+	unversioned := "token \"error\" {\n  source = \"error\"\n}\n"
+	tests := []struct {
+		name        string
+		src         string
+		wantChanged bool
+		want        string
+	}{
+		{
+			name:        "version 0",
+			src:         v0Config,
+			wantChanged: true,
+			want:        strings.NewReplacer("version = 0", "version = 1", "hint_joiner", "hint_join_char").Replace(v0Config),
+		},
+		{
+			name: "unversioned",
+			src:  unversioned,
+			want: unversioned,
+		},
+		{
+			name: "current version",
+			src:  "version = 1\n\n" + unversioned,
+			want: "version = 1\n\n" + unversioned,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, changed, err := UpgradeConfigSource([]byte(tc.src), "smarterr.hcl")
+			if err != nil {
+				t.Fatalf("UpgradeConfigSource error: %v", err)
+			}
+			if changed != tc.wantChanged {
+				t.Errorf("changed = %t, want %t", changed, tc.wantChanged)
+			}
+			if string(got) != tc.want {
+				t.Errorf("UpgradeConfigSource() =\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpgradeConfigSource_Errors(t *testing.T) {
+	if _, _, err := UpgradeConfigSource([]byte(`token "x" {`), "smarterr.hcl"); err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("expected parse error, got: %v", err)
+	}
+	if _, _, err := UpgradeConfigSource([]byte(`version = "two"`), "smarterr.hcl"); err == nil || !strings.Contains(err.Error(), "version must be an integer") {
+		t.Errorf("expected version error, got: %v", err)
+	}
+}
+
+func TestUpgradeConfigSource_JSON(t *testing.T) {
+	withV0Upgrade(t)
+
+	got, changed, err := UpgradeConfigSource([]byte(`{"version": 0, "smarterr": {"hint_joiner": "; ", "debug": true}}`), "smarterr.json")
+	if err != nil {
+		t.Fatalf("UpgradeConfigSource error: %v", err)
+	}
+	want := "{\n  \"smarterr\": {\n    \"debug\": true,\n    \"hint_join_char\": \"; \"\n  },\n  \"version\": 1\n}\n"
+	if !changed || string(got) != want {
+		t.Errorf("UpgradeConfigSource() = %q, %t, want %q, true", got, changed, want)
+	}
+
+	current := `{"version": 1}`
+	if got, changed, err := UpgradeConfigSource([]byte(current), "smarterr.json"); err != nil || changed || string(got) != current {
+		t.Errorf("UpgradeConfigSource() = %q, %t, %v, want the current config unchanged", got, changed, err)
+	}
+	if _, _, err := UpgradeConfigSource([]byte(`{"version": "two"}`), "smarterr.json"); err == nil || !strings.Contains(err.Error(), "version must be an integer") {
+		t.Errorf("expected version error, got: %v", err)
+	}
+	if _, _, err := UpgradeConfigSource([]byte(`{`), "smarterr.json"); err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("expected parse error, got: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package smarterr
+
+import (
+	"reflect"
+	"strings"
+)
+
+// KeyvalsFromStruct returns keyvals for AddError or Append from the fields of v, a struct or a
+// pointer to one, that have a smarterr tag. The tag names the keyval key, such as
+// `smarterr:"id"`; add ",omitempty" to skip the field when it has its zero value. Fields are
+// included in order, and the fields of untagged embedded structs are included as if they were v's.
+// Untagged, unexported, and `smarterr:"-"` fields are skipped. If v isn't a struct or is a nil
+// pointer, it returns nil.
+//
+//	type vpcKeyvals struct {
+//		ID     string `smarterr:"id"`
+//		Name   string `smarterr:"resource_name,omitempty"`
+//		Region string // Not a keyval
+//	}
+//
+//	smarterr.AddError(ctx, &resp.Diagnostics, err, smarterr.KeyvalsFromStruct(vpcKeyvals{ID: id, Name: name})...)
+func KeyvalsFromStruct(v any) []any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		Debugf("KeyvalsFromStruct called with %T, not a struct", v)
+		return nil
+	}
+	return appendStructKeyvals(nil, rv)
+}
+
+// appendStructKeyvals appends the keyvals for the tagged fields of rv, a struct, to keyvals.
+func appendStructKeyvals(keyvals []any, rv reflect.Value) []any {
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		tag, tagged := field.Tag.Lookup("smarterr")
+		if !tagged {
+			if field.Anonymous {
+				if fv, ok := embeddedStruct(rv.Field(i)); ok {
+					keyvals = appendStructKeyvals(keyvals, fv)
+				}
+			}
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" || name == "" || !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+		keyvals = append(keyvals, name, fv.Interface())
+	}
+	return keyvals
+}
+
+// embeddedStruct returns the struct an embedded field holds, dereferencing a non-nil pointer.
+func embeddedStruct(fv reflect.Value) (reflect.Value, bool) {
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return reflect.Value{}, false
+		}
+		fv = fv.Elem()
+	}
+	return fv, fv.Kind() == reflect.Struct
+}
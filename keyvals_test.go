@@ -0,0 +1,76 @@
+package smarterr
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+type keyvalsBase struct {
+	Service string `smarterr:"service_name"`
+}
+
+type vpcKeyvals struct {
+	keyvalsBase
+	ID       string  `smarterr:"id"`
+	Name     string  `smarterr:"resource_name,omitempty"`
+	Attempts int     `smarterr:"attempts"`
+	Owner    *string `smarterr:"owner,omitempty"`
+	Region   string
+	Skipped  string `smarterr:"-"`
+	internal string `smarterr:"internal"`
+}
+
+func TestKeyvalsFromStruct(t *testing.T) {
+	owner := "team-a"
+	tests := []struct {
+		name string
+		v    any
+		want []any
+	}{
+		{
+			name: "all fields",
+			v:    vpcKeyvals{keyvalsBase: keyvalsBase{Service: "EC2"}, ID: "vpc-1", Name: "main", Attempts: 3, Owner: &owner, Region: "us-west-2", Skipped: "x", internal: "y"},
+			want: []any{"service_name", "EC2", "id", "vpc-1", "resource_name", "main", "attempts", 3, "owner", &owner},
+		},
+		{
+			name: "omitempty",
+			v:    &vpcKeyvals{ID: "vpc-1"},
+			want: []any{"service_name", "", "id", "vpc-1", "attempts", 0},
+		},
+		{name: "nil pointer", v: (*vpcKeyvals)(nil)},
+		{name: "not a struct", v: "vpc-1"},
+		{name: "nil", v: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := KeyvalsFromStruct(tc.v); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("KeyvalsFromStruct() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyvalsFromStruct_AddError(t *testing.T) {
+	setTestConfig(t, `
+token "identifier" {
+  arg = "id"
+}
+
+token "name" {
+  arg = "resource_name"
+}
+
+template "error_summary" {
+  format = "reading VPC {{.name}} ({{.identifier}})"
+}
+`)
+	var diags fwdiag.Diagnostics
+	AddError(context.Background(), &diags, errors.New("boom"), KeyvalsFromStruct(vpcKeyvals{ID: "vpc-1", Name: "main"})...)
+	if got, want := diags[0].Summary(), "reading VPC main (vpc-1)"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+package smarterr
+
+import (
+	"context"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+// WithLocale returns a context that selects localized variants of templates and hints for
+// locale, such as "ja" or "pt-BR". A variant's name is the base name, a dot, and the locale, as in
+// template "error_detail.ja" or hint "throttling.ja". smarterr tries the full locale, then the
+// language alone, then falls back to the base template or hint:
+//
+//	ctx = smarterr.WithLocale(ctx, "ja")
+//	smarterr.AddError(ctx, &resp.Diagnostics, err)
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return internal.WithLocale(ctx, locale)
+}
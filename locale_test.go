@@ -0,0 +1,58 @@
+package smarterr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithLocale(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+token "hints" {
+  source = "hints"
+}
+
+hint "throttling" {
+  error_contains = "Throttling"
+  suggestion     = "Wait and try again."
+}
+
+hint "throttling.ja" {
+  suggestion = "しばらく待ってから再試行してください。"
+}
+
+template "error_summary" {
+  format = "request failed"
+}
+
+template "error_summary.ja" {
+  format = "リクエストが失敗しました"
+}
+
+template "error_detail" {
+  format = "{{.error}} {{.hints}}"
+}
+`)
+	err := errors.New("Throttling:")
+
+	diags := Append(context.Background(), nil, err)
+	if got, want := diags[0].Summary, "request failed"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	if got, want := diags[0].Detail, "Throttling: Wait and try again."; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	diags = Append(WithLocale(context.Background(), "ja"), nil, err)
+	if got, want := diags[0].Summary, "リクエストが失敗しました"; got != want {
+		t.Errorf("localized summary = %q, want %q", got, want)
+	}
+	// error_detail has no ja variant, so it falls back to the default with the localized suggestion
+	if got, want := diags[0].Detail, "Throttling: しばらく待ってから再試行してください。"; got != want {
+		t.Errorf("localized detail = %q, want %q", got, want)
+	}
+}
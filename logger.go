@@ -11,6 +11,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 
 	tflog "github.com/hashicorp/terraform-plugin-log/tflog"
@@ -25,14 +28,25 @@ type Logger interface {
 	Error(ctx context.Context, msg string, keyvals map[string]any)
 }
 
-// globalLogger is the user-facing logger used by smarterr for emitting logs to consumers.
+// globalLogger is the user-facing logger used by smarterr for emitting logs to consumers. settingsMu
+// guards it.
 var globalLogger Logger
 
-// SetLogger sets the global user-facing logger for smarterr.
+// SetLogger sets the global user-facing logger for smarterr. It is safe to call while other
+// goroutines add diagnostics.
 func SetLogger(logger Logger) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
 	globalLogger = logger
 }
 
+// currentLogger returns the logger set with SetLogger.
+func currentLogger() Logger {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return globalLogger
+}
+
 // StdLogger is an adapter that emits user-facing logs using the standard Go log package.
 type StdLogger struct{}
 
@@ -78,3 +92,44 @@ func (l TFLogLogger) Warn(ctx context.Context, msg string, keyvals map[string]an
 func (l TFLogLogger) Error(ctx context.Context, msg string, keyvals map[string]any) {
 	tflog.Error(ctx, msg, keyvals)
 }
+
+// SlogLogger is an adapter that emits user-facing logs using a log/slog Logger. Each keyval becomes
+// an attribute, in key order.
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger that emits user-facing logs to logger, or to slog.Default() if
+// logger is nil.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return SlogLogger{Logger: logger}
+}
+
+func (l SlogLogger) Debug(ctx context.Context, msg string, keyvals map[string]any) {
+	l.log(ctx, slog.LevelDebug, msg, keyvals)
+}
+func (l SlogLogger) Info(ctx context.Context, msg string, keyvals map[string]any) {
+	l.log(ctx, slog.LevelInfo, msg, keyvals)
+}
+func (l SlogLogger) Warn(ctx context.Context, msg string, keyvals map[string]any) {
+	l.log(ctx, slog.LevelWarn, msg, keyvals)
+}
+func (l SlogLogger) Error(ctx context.Context, msg string, keyvals map[string]any) {
+	l.log(ctx, slog.LevelError, msg, keyvals)
+}
+
+// log emits msg at level with keyvals as attributes, sorted by key so output is stable.
+func (l SlogLogger) log(ctx context.Context, level slog.Level, msg string, keyvals map[string]any) {
+	logger := l.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	attrs := make([]slog.Attr, 0, len(keyvals))
+	for _, k := range slices.Sorted(maps.Keys(keyvals)) {
+		attrs = append(attrs, slog.Any(k, keyvals[k]))
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
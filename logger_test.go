@@ -0,0 +1,76 @@
+package smarterr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger := NewSlogLogger(slog.New(handler))
+	ctx := context.Background()
+
+	tests := []struct {
+		log  func(ctx context.Context, msg string, keyvals map[string]any)
+		want string
+	}{
+		{logger.Debug, "level=DEBUG msg=logged"},
+		{logger.Info, "level=INFO msg=logged"},
+		{logger.Warn, "level=WARN msg=logged"},
+		{logger.Error, "level=ERROR msg=logged"},
+	}
+	for _, tc := range tests {
+		buf.Reset()
+		tc.log(ctx, "logged", map[string]any{"service": "EC2", "attempts": 3})
+		if got, want := strings.TrimSpace(buf.String()), tc.want+" attempts=3 service=EC2"; got != want {
+			t.Errorf("log = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSlogLogger_LogTemplates(t *testing.T) {
+	setTestConfig(t, `
+token "service" {
+  arg = "service"
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "error_detail" {
+  format = "detail"
+}
+
+template "log_error" {
+  format = "{{.service}} failed"
+}
+`)
+	var buf bytes.Buffer
+	SetLogger(NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	t.Cleanup(func() { SetLogger(nil) })
+
+	var diags fwdiag.Diagnostics
+	AddError(context.Background(), &diags, errors.New("boom"), "service", "EC2")
+	got := buf.String()
+	for _, want := range []string{`"level":"ERROR"`, `"msg":"EC2 failed"`, `"service":"EC2"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected log to contain %s, got %s", want, got)
+		}
+	}
+}
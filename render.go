@@ -0,0 +1,53 @@
+// render.go
+// Renders a single template outside of diagnostics, for integrations with other sinks.
+
+package smarterr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+// Render renders the named template for err and keyvals, with the same config, tokens,
+// transforms, and hints as AddError, and returns the text instead of adding a diagnostic. Use it
+// to write enriched error text to a file, a log, or another non-Terraform sink. It returns an
+// error if no FileSystem is set (see SetFS), the config can't be loaded, or the template is
+// missing or fails to render. If smarterr panics, such as on malformed keyvals in strict mode, Render
+// recovers and returns the same fallback text AddError would use as the detail, the error followed
+// by the panic suffix (see SetPanicSuffix), along with an error.
+//
+// Example:
+//
+//	msg, renderErr := smarterr.Render(ctx, "error_detail", err, "id", id)
+func Render(ctx context.Context, templateName string, err error, keyvals ...any) (text string, renderErr error) {
+	ctx, callID := globalCallID(ctx)
+	Debugf("[Render %s] called with template: %q, error: %v, keyvals: %v", callID, templateName, err, keyvals)
+	defer func() {
+		if r := recover(); r != nil {
+			Debugf("[Render %s] Panic recovered: %v", callID, r)
+			recordStatus(ctx, StatusPanic)
+			// After a panic, Render returns the named results
+			text = panicDetail(err, r)
+			renderErr = fmt.Errorf("smarterr panic: %v", r)
+		}
+	}()
+	fsys, baseDir := currentFS()
+	if fsys == nil {
+		return "", errors.New("smarterr initialization: filesystem not set, use SetFS()")
+	}
+	relStackPaths := collectRelStackPaths(ctx, baseDir)
+	cfg, cfgErr := internal.LoadConfig(ctx, fsys, relStackPaths, baseDir)
+	if cfgErr != nil {
+		return "", fmt.Errorf("loading smarterr config: %w", cfgErr)
+	}
+
+	rt := internal.NewRuntime(ctx, cfg, err, keyvals...)
+	if diag, ok := asDiagnostic(err); ok {
+		rt.Diagnostic = diag
+	}
+	values := rt.BuildTokenValueMap(ctx)
+	return cfg.RenderTemplate(ctx, templateName, values)
+}
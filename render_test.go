@@ -0,0 +1,76 @@
+package smarterr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Render(ctx, "error_detail", errors.New("boom")); err == nil || !strings.Contains(err.Error(), "SetFS") {
+		t.Errorf("expected an error without a FileSystem, got %v", err)
+	}
+
+	setTestConfig(t, `
+token "error" {
+  source     = "error"
+  transforms = ["upper"]
+}
+
+token "id" {
+  arg = "id"
+}
+
+token "hints" {
+  source = "hints"
+}
+
+transform "upper" {
+  step "upper" {}
+}
+
+hint "throttling" {
+  error_contains = "throttl"
+  suggestion     = "Retry later."
+}
+
+template "file_line" {
+  format = "{{.id}}: {{.error}} ({{.hints}})"
+}
+`)
+	got, err := Render(ctx, "file_line", errors.New("throttled"), "id", "vpc-1")
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if want := "vpc-1: THROTTLED (Retry later.)"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	if _, err := Render(ctx, "missing", errors.New("boom")); err == nil || !strings.Contains(err.Error(), `template "missing" not found`) {
+		t.Errorf("expected a missing template error, got %v", err)
+	}
+}
+
+func TestRender_Panic(t *testing.T) {
+	setTestConfig(t, `
+template "file_line" {
+  format = "{{.error}}"
+}
+`)
+	SetStrict(true)
+	t.Cleanup(func() { SetStrict(false) })
+
+	ctx := WithStatus(context.Background())
+	got, err := Render(ctx, "file_line", errors.New("boom"), ID)
+	if err == nil || !strings.Contains(err.Error(), "smarterr panic: strict keyvals") {
+		t.Errorf("expected a panic error, got %v", err)
+	}
+	if want := `boom [smarterr panic: strict keyvals: odd number of keyvals (1); key "id" at index 0 has no value]`; got != want {
+		t.Errorf("Render() after panic = %q, want %q", got, want)
+	}
+	if got := LastStatus(ctx); got != StatusPanic {
+		t.Errorf("LastStatus() = %q, want %q", got, StatusPanic)
+	}
+}
@@ -0,0 +1,20 @@
+package smarterr
+
+import (
+	"context"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+// ResourceData reads resource attribute values for resource_data tokens. The Terraform Plugin SDK's
+// *schema.ResourceData satisfies it.
+type ResourceData = internal.ResourceData
+
+// WithResourceData returns a context carrying d so that tokens with a resource_data source can
+// read its attributes. Pass the returned context to Append or AddError:
+//
+//	ctx = smarterr.WithResourceData(ctx, d)
+//	return smarterr.Append(ctx, diags, err)
+func WithResourceData(ctx context.Context, d ResourceData) context.Context {
+	return internal.WithResourceData(ctx, d)
+}
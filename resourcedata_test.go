@@ -0,0 +1,36 @@
+package smarterr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type testResourceData map[string]any
+
+func (d testResourceData) Get(key string) any { return d[key] }
+
+func TestWithResourceData(t *testing.T) {
+	setTestConfig(t, `
+token "name" {
+  resource_data = "name"
+}
+
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "creating {{.name}}"
+}
+
+template "error_detail" {
+  format = "{{.error}}"
+}
+`)
+	ctx := WithResourceData(context.Background(), testResourceData{"name": "main-vpc"})
+	diags := Append(ctx, nil, errors.New("boom"))
+	if got, want := diags[0].Summary, "creating main-vpc"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
@@ -2,8 +2,12 @@ package smarterr
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/YakDriver/smarterr/internal"
@@ -23,10 +27,16 @@ const (
 	DiagnosticDetailKey  = "diagnostic_detail"
 	ErrorSummaryKey      = "error_summary"
 	ErrorDetailKey       = "error_detail"
+	WarningSummaryKey    = "warning_summary"
+	WarningDetailKey     = "warning_detail"
 	LogErrorKey          = "log_error"
 	LogWarnKey           = "log_warn"
 	LogInfoKey           = "log_info"
 
+	// SummaryOverride is a reserved keyval key. Passing it to AddError or Append uses the value
+	// as the diagnostic summary instead of rendering the error_summary template.
+	SummaryOverride = internal.SummaryOverrideKey
+
 	SeverityError   = internal.SeverityError
 	SeverityWarning = internal.SeverityWarning
 	SeverityInfo    = internal.SeverityInfo
@@ -37,17 +47,190 @@ type ContextKey internal.ContextKey
 var (
 	globalIDCtxKey = ContextKey("smarterr:global_call_id")
 
+	fsMu           sync.RWMutex // Guards wrappedFS and wrappedBaseDir, since SetFS may race with AddError
 	wrappedFS      FileSystem
 	wrappedBaseDir string
+
+	settingsMu         sync.RWMutex // Guards the settings below and globalLogger, since their setters may race with AddError
+	fallbackMessage    func(err error) string
+	ignoredErrors      []error
+	diagnosticObserver func(ctx context.Context, summary, detail, severity string)
+	panicSuffix        = DefaultPanicSuffix
 )
 
 var glblCallID atomic.Uint64 // atomic counter for tracing
 
+// ReservedKeys returns the keyval keys, such as SummaryOverride, that control smarterr's behavior.
+// smarterr consumes them, so tokens with arg set to a reserved key don't resolve their values.
+// ID, ResourceName, and ServiceName aren't reserved; they're conventional names for token args.
+func ReservedKeys() []string {
+	return slices.Clone(internal.ReservedKeyvals)
+}
+
 // SetFS allows the host application to provide a FileSystem implementation and the base directory for path normalization.
+// It is safe to call while other goroutines add diagnostics; each call uses the FileSystem set when it starts.
 func SetFS(fs FileSystem, baseDir string) {
 	Debugf("SetFS called with baseDir=%q", baseDir)
+	fsMu.Lock()
+	defer fsMu.Unlock()
 	wrappedFS = fs
 	wrappedBaseDir = baseDir
+	// Configs parsed from the previous FileSystem no longer apply
+	internal.ClearConfigCache()
+}
+
+// ClearConfigCache discards the parsed config files smarterr caches, so the next diagnostic
+// reads and parses them again. SetFS clears the cache, so hosts rarely need this; it's mainly for
+// tests that change config files on a real filesystem.
+func ClearConfigCache() {
+	Debugf("ClearConfigCache called")
+	internal.ClearConfigCache()
+}
+
+// currentFS returns the FileSystem and base directory set with SetFS, read together so a
+// concurrent SetFS can't pair one call's FileSystem with another's base directory.
+func currentFS() (FileSystem, string) {
+	fsMu.RLock()
+	defer fsMu.RUnlock()
+	return wrappedFS, wrappedBaseDir
+}
+
+// SetFallbackMessage allows the host application to customize the message added to the detail of
+// diagnostics when smarterr can't format them because no FileSystem is set (see SetFS). fn receives
+// the original error, which may be nil, and returns text appended to the error, such as a pointer
+// to the host's documentation. Passing nil restores the default message.
+func SetFallbackMessage(fn func(err error) string) {
+	Debugf("SetFallbackMessage called (custom: %t)", fn != nil)
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	fallbackMessage = fn
+}
+
+// currentFallbackMessage returns the function set with SetFallbackMessage.
+func currentFallbackMessage() func(err error) string {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return fallbackMessage
+}
+
+// SetIgnoredErrors sets errors that AddError and Append pass through without enrichment, such as
+// context.Canceled. smarterr matches them with errors.Is, so wrapped errors match too. It adds
+// an ignored error as a plain diagnostic, like when Config can't load, and doesn't render templates
+// or log it. Calling SetIgnoredErrors again replaces the targets; calling it with none clears them.
+//
+//	smarterr.SetIgnoredErrors(context.Canceled, context.DeadlineExceeded)
+func SetIgnoredErrors(targets ...error) {
+	Debugf("SetIgnoredErrors called with %d targets", len(targets))
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	ignoredErrors = slices.Clone(targets)
+}
+
+// isIgnoredError reports whether err matches a target set with SetIgnoredErrors.
+func isIgnoredError(err error) bool {
+	if err == nil {
+		return false
+	}
+	settingsMu.RLock()
+	targets := ignoredErrors
+	settingsMu.RUnlock()
+	return slices.ContainsFunc(targets, func(target error) bool {
+		return errors.Is(err, target)
+	})
+}
+
+// SetDiagnosticObserver sets a function that smarterr calls with each diagnostic AddError, Append,
+// AddEnrich, and AppendEnrich add, after enrichment, such as to collect diagnostics in integration
+// tests or stream them to a UI. This includes the fallback diagnostics they add when smarterr
+// can't enrich them or recovers from a panic. severity is SeverityError, SeverityWarning, or
+// SeverityInfo. Diagnostics are still added as usual. fn is called synchronously, so it should
+// return quickly, such as by sending to a buffered channel. Calling SetDiagnosticObserver(nil)
+// removes the observer.
+//
+//	ch := make(chan string, 100)
+//	smarterr.SetDiagnosticObserver(func(ctx context.Context, summary, detail, severity string) {
+//		ch <- severity + ": " + summary
+//	})
+func SetDiagnosticObserver(fn func(ctx context.Context, summary, detail, severity string)) {
+	Debugf("SetDiagnosticObserver called (set: %t)", fn != nil)
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	diagnosticObserver = fn
+}
+
+// currentDiagnosticObserver returns the function set with SetDiagnosticObserver.
+func currentDiagnosticObserver() func(ctx context.Context, summary, detail, severity string) {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return diagnosticObserver
+}
+
+// observeDiagnostic passes a diagnostic smarterr added to the observer, if one is set. A panic in
+// the observer is recovered, so it's safe to call from the fallback after a recovered panic.
+func observeDiagnostic(ctx context.Context, summary, detail, severity string) {
+	observer := currentDiagnosticObserver()
+	if observer == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			Debugf("observeDiagnostic: panic in diagnostic observer: %v", r)
+		}
+	}()
+	observer(ctx, summary, detail, severity)
+}
+
+// DefaultPanicSuffix is the suffix AddError and Append append to the detail of the diagnostic they
+// add after recovering from a panic. smarterr replaces %s with the panic message.
+const DefaultPanicSuffix = " [smarterr panic: %s]"
+
+// SetPanicSuffix sets the suffix AddError and Append append to the original error in the detail
+// after recovering from a panic, replacing %s with the panic message. SetPanicSuffix("") hides the
+// panic from end users, such as in production, so the detail is just the error. The panic is
+// still recorded as StatusPanic and in the debug output.
+//
+//	smarterr.SetPanicSuffix(" (internal error: %s)")
+func SetPanicSuffix(suffix string) {
+	Debugf("SetPanicSuffix called with %q", suffix)
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	panicSuffix = suffix
+}
+
+// currentPanicSuffix returns the suffix set with SetPanicSuffix.
+func currentPanicSuffix() string {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return panicSuffix
+}
+
+// panicDetail returns the detail of the fallback diagnostic for err after recovering r: the error
+// followed by the panic suffix.
+func panicDetail(err error, r any) string {
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	}
+	var panicMsg string
+	switch v := r.(type) {
+	case error:
+		panicMsg = v.Error()
+	case string:
+		panicMsg = v
+	default:
+		panicMsg = "unknown panic"
+	}
+	return detail + strings.ReplaceAll(currentPanicSuffix(), "%s", panicMsg)
+}
+
+// SetStrict turns strict keyval checking on or off. By default, smarterr is lenient: if keyvals
+// have a non-string key, it logs a debug message and ignores all the keyvals, and it drops an
+// unpaired last keyval. In strict mode, smarterr panics instead, naming the index and type of the
+// bad element. AddError, Append, and the other entry points recover the panic and add it to the
+// diagnostic detail, so mistakes are visible during development.
+func SetStrict(strict bool) {
+	Debugf("SetStrict called (strict: %t)", strict)
+	internal.SetStrictKeyvals(strict)
 }
 
 // AddEnrich is a plugin Framework helper function that enriches diagnostics with smarterr information.
@@ -67,43 +250,43 @@ func AddEnrich(ctx context.Context, existing *fwdiag.Diagnostics, incoming fwdia
 	// enables it because without config we don't know if debug is enabled. Subsequent
 	// calls after config load will show debug if enabled.
 	Debugf("[AddEnrich %s] called with len(incoming): %d, keyvals: %v", callID, len(incoming), keyvals)
+	// addUnenriched adds the incoming diagnostics as they are when smarterr can't enrich them
+	addUnenriched := func() {
+		for _, diag := range incoming {
+			if diag == nil || existing.Contains(diag) {
+				continue
+			}
+			existing.Append(diag)
+			observeDiagnostic(ctx, diag.Summary(), diag.Detail(), diag.Severity().String())
+		}
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			Debugf("[AddEnrich %s] Panic recovered: %v", callID, r)
-			for _, diag := range incoming {
-				if diag == nil || existing.Contains(diag) {
-					continue
-				}
-				existing.Append(diag)
-			}
+			recordStatus(ctx, StatusPanic)
+			addUnenriched()
 		}
 	}()
 	if len(incoming) == 0 {
 		return
 	}
-	if wrappedFS == nil {
-		Debugf("[AddEnrich %s] No wrappedFS set; cannot enrich diagnostics", callID)
-		for _, diag := range incoming {
-			if diag == nil || existing.Contains(diag) {
-				continue
-			}
-			existing.Append(diag)
-		}
+	fsys, baseDir := currentFS()
+	if fsys == nil {
+		Debugf("[AddEnrich %s] No FileSystem set; cannot enrich diagnostics", callID)
+		recordStatus(ctx, StatusNoFS)
+		addUnenriched()
 		return
 	}
-	relStackPaths := collectRelStackPaths(ctx, wrappedBaseDir)
-	cfg, cfgErr := internal.LoadConfig(ctx, wrappedFS, relStackPaths, wrappedBaseDir)
+	relStackPaths := collectRelStackPaths(ctx, baseDir)
+	cfg, cfgErr := internal.LoadConfig(ctx, fsys, relStackPaths, baseDir)
 	if cfgErr != nil {
 		Debugf("[AddEnrich %s] Config load error: %v", callID, cfgErr)
-		for _, diag := range incoming {
-			if diag == nil || existing.Contains(diag) {
-				continue
-			}
-			existing.Append(diag)
-		}
+		recordStatus(ctx, StatusConfigError)
+		addUnenriched()
 		return
 	}
 	Debugf("[AddEnrich %s] diagnostics, len(incoming): %d", callID, len(incoming))
+	recordStatus(ctx, StatusSuccess)
 	for _, diag := range incoming {
 		if diag == nil {
 			continue
@@ -113,35 +296,13 @@ func AddEnrich(ctx context.Context, existing *fwdiag.Diagnostics, incoming fwdia
 			continue
 		}
 		Debugf("[AddEnrich %s] enriching diagnostic: %+v", callID, diag)
-		// Enrich: build runtime with diagnostic as a field, not in args
-		rt := internal.NewRuntimeForDiagnostic(ctx, cfg, diag, keyvals...)
-		values := rt.BuildTokenValueMap(ctx)
-		// Render summary/detail using diagnostic templates if present, else fallback to original
-		summary, detail := diag.Summary(), diag.Detail()
-		if s, err := cfg.RenderTemplate(ctx, DiagnosticSummaryKey, values); err == nil && s != "" {
-			Debugf("[AddEnrich %s] rendered %s: %q", callID, DiagnosticSummaryKey, s)
-			summary = s
-		}
-		if d, err := cfg.RenderTemplate(ctx, DiagnosticDetailKey, values); err == nil && d != "" {
-			Debugf("[AddEnrich %s] rendered %s: %q", callID, DiagnosticDetailKey, d)
-			detail = d
-		}
-		// Create enriched diagnostic preserving original severity
-		var enriched fwdiag.Diagnostic
-		switch diag.Severity().String() {
-		case SeverityWarning:
-			enriched = fwdiag.NewWarningDiagnostic(summary, detail)
-		case SeverityError:
-			enriched = fwdiag.NewErrorDiagnostic(summary, detail)
-		default:
-			// Fallback to error for unknown severities
-			enriched = fwdiag.NewErrorDiagnostic(summary, detail)
-		}
+		enriched, values := enrichFrameworkDiagnostic(ctx, cfg, diag, keyvals...)
 		// Deduplicate after enrichment
 		if existing.Contains(enriched) {
 			continue
 		}
 		existing.Append(enriched)
+		observeDiagnostic(ctx, enriched.Summary(), enriched.Detail(), enriched.Severity().String())
 
 		// Emit log for this diagnostic's severity
 		if diag.Severity().String() == SeverityError || diag.Severity().String() == SeverityWarning || diag.Severity().String() == SeverityInfo {
@@ -150,10 +311,23 @@ func AddEnrich(ctx context.Context, existing *fwdiag.Diagnostics, incoming fwdia
 	}
 }
 
-// EnrichAppend is an alias for AddEnrich to maintain backward compatibility.
+// enrichAppendDeprecation warns once per process that EnrichAppend is deprecated.
+var enrichAppendDeprecation sync.Once
+
+// enrichAppendDeprecationMessage is the warning logged the first time EnrichAppend is called.
+const enrichAppendDeprecationMessage = "smarterr.EnrichAppend is deprecated; use smarterr.AddEnrich instead (smarterr migrate rewrites calls)"
+
+// EnrichAppend is an alias for AddEnrich to maintain backward compatibility. The first time it's
+// called, it writes a deprecation warning to the debug output and, if set, the Logger.
 //
 // Deprecated: Use AddEnrich instead to align with Framework "Add" verb convention
 func EnrichAppend(ctx context.Context, existing *fwdiag.Diagnostics, incoming fwdiag.Diagnostics, keyvals ...any) {
+	enrichAppendDeprecation.Do(func() {
+		Debugf("%s", enrichAppendDeprecationMessage)
+		if logger := currentLogger(); logger != nil {
+			logger.Warn(ctx, enrichAppendDeprecationMessage, nil)
+		}
+	})
 	AddEnrich(ctx, existing, incoming, keyvals...)
 }
 
@@ -165,36 +339,41 @@ func EnrichAppend(ctx context.Context, existing *fwdiag.Diagnostics, incoming fw
 //   - These templates control the summary and detail for diagnostics created from errors via AddError.
 //   - If these templates are not defined, a fallback using the original error is used.
 //   - Note: All output is a diagnostic; the template name refers to the input type (error vs. diagnostic).
+//
+// If err is nil, AddError adds nothing, so it's safe to call in deferred code where err may be nil.
 func AddError(ctx context.Context, diags *fwdiag.Diagnostics, err error, keyvals ...any) {
 	ctx, callID := globalCallID(ctx)
 	Debugf("[AddError %s] called with error: %v", callID, err)
+	if err == nil {
+		Debugf("[AddError %s] Nil error; adding nothing", callID)
+		return
+	}
+	severity := errorSeverity(err)
+	add := func(summary, detail string) {
+		Debugf("[AddError %s] add %s: summary=%q detail=%q", callID, severity, summary, detail)
+		if b := batchFromContext(ctx); b != nil {
+			b.add(severity, summary, detail)
+			return
+		}
+		if severity == SeverityWarning {
+			diags.AddWarning(summary, detail)
+			return
+		}
+		diags.AddError(summary, detail)
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			Debugf("[AddError %s] Panic recovered: %v", callID, r)
-			// Fallback: original error summary, panic at end of detail
+			recordStatus(ctx, StatusPanic)
+			// Fallback: original error summary, panic at end of detail, added like any other
+			// diagnostic, so a batch collects it
 			summary := firstNWords(err, 3)
-			detail := ""
-			if err != nil {
-				detail = err.Error()
-			}
-			panicMsg := " [smarterr panic: "
-			switch v := r.(type) {
-			case error:
-				panicMsg += v.Error()
-			case string:
-				panicMsg += v
-			default:
-				panicMsg += "unknown panic"
-			}
-			panicMsg += "]"
-			detail += panicMsg
-			diags.AddError(summary, detail)
+			detail := panicDetail(err, r)
+			add(summary, detail)
+			observeDiagnostic(ctx, summary, detail, severity)
 		}
 	}()
-	appendCommon(ctx, func(summary, detail string) {
-		Debugf("[AddError %s] add error: summary=%q detail=%q", callID, summary, detail)
-		diags.AddError(summary, detail)
-	}, err, keyvals...)
+	appendCommon(ctx, add, err, severity, keyvals...)
 }
 
 // Append adds a formatted error to Terraform Plugin SDK diagnostics and returns the updated diagnostics slice.
@@ -204,47 +383,89 @@ func AddError(ctx context.Context, diags *fwdiag.Diagnostics, err error, keyvals
 //   - These templates control the summary and detail for diagnostics created from errors via Append.
 //   - If these templates are not defined, a fallback using the original error is used.
 //   - Note: All output is a diagnostic; the template name refers to the input type (error vs. diagnostic).
+//
+// If err is nil, Append returns diags unchanged.
 func Append(ctx context.Context, diags sdkdiag.Diagnostics, err error, keyvals ...any) sdkdiag.Diagnostics {
+	return appendWithSeverity(ctx, diags, err, errorSeverity(err), keyvals...)
+}
+
+// AppendWithSeverity is Append with the severity of the diagnostic set by the caller: SeverityError,
+// SeverityWarning, or SeverityInfo. It renders the same templates as Append, preferring
+// warning_summary and warning_detail for a warning, and emits the log template for the severity,
+// such as log_info. The SDK has no info severity, so an info diagnostic is added as a warning. An
+// unknown severity is treated as SeverityError.
+//
+// If err is nil, AppendWithSeverity returns diags unchanged.
+func AppendWithSeverity(ctx context.Context, diags sdkdiag.Diagnostics, err error, severity string, keyvals ...any) sdkdiag.Diagnostics {
+	return appendWithSeverity(ctx, diags, err, severity, keyvals...)
+}
+
+// appendWithSeverity implements Append and AppendWithSeverity. Both call it directly so the caller's
+// frame is the same depth below appendCommon for either, which collectRelStackPaths relies on.
+func appendWithSeverity(ctx context.Context, diags sdkdiag.Diagnostics, err error, severity string, keyvals ...any) (result sdkdiag.Diagnostics) {
 	ctx, callID := globalCallID(ctx)
-	Debugf("[Append %s] called with error: %v", callID, err)
-	defer func() {
-		if r := recover(); r != nil {
-			Debugf("[Append %s] Panic recovered: %v", callID, r)
-			// Fallback: original error summary, panic at end of detail
-			summary := firstNWords(err, 3)
-			detail := ""
-			if err != nil {
-				detail = err.Error()
-			}
-			panicMsg := " [smarterr panic: "
-			switch v := r.(type) {
-			case error:
-				panicMsg += v.Error()
-			case string:
-				panicMsg += v
-			default:
-				panicMsg += "unknown panic"
+	Debugf("[Append %s] called with error: %v, severity: %s", callID, err, severity)
+	if err == nil {
+		Debugf("[Append %s] Nil error; appending nothing", callID)
+		return diags
+	}
+	switch severity {
+	case SeverityError, SeverityWarning, SeverityInfo:
+	default:
+		Debugf("[Append %s] Unknown severity %q; using %s", callID, severity, SeverityError)
+		severity = SeverityError
+	}
+	add := func(summary, detail string) {
+		Debugf("[Append %s] add %s: summary=%q detail=%q", callID, severity, summary, detail)
+		if b := batchFromContext(ctx); b != nil {
+			batchSeverity := severity
+			if severity == SeverityInfo {
+				batchSeverity = SeverityWarning
 			}
-			panicMsg += "]"
-			detail += panicMsg
-			diags = append(diags, sdkdiag.Diagnostic{
-				Severity: sdkdiag.Error,
-				Summary:  summary,
-				Detail:   detail,
-			})
+			b.add(batchSeverity, summary, detail)
+			return
 		}
-	}()
-	appendCommon(ctx, func(summary, detail string) {
-		Debugf("[Append %s] add error: summary=%q detail=%q", callID, summary, detail)
 		diags = append(diags, sdkdiag.Diagnostic{
-			Severity: sdkdiag.Error,
+			Severity: sdkSeverity(severity),
 			Summary:  summary,
 			Detail:   detail,
 		})
-	}, err, keyvals...)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			Debugf("[Append %s] Panic recovered: %v", callID, r)
+			recordStatus(ctx, StatusPanic)
+			// Fallback: original error summary, panic at end of detail, added like any other
+			// diagnostic, so a batch collects it
+			summary := firstNWords(err, 3)
+			detail := panicDetail(err, r)
+			add(summary, detail)
+			observeDiagnostic(ctx, summary, detail, severity)
+			// After a panic, Append returns the named result
+			result = diags
+		}
+	}()
+	appendCommon(ctx, add, err, severity, keyvals...)
 	return diags
 }
 
+// sdkSeverityName returns SeverityWarning for sdkdiag.Warning and otherwise SeverityError.
+func sdkSeverityName(severity sdkdiag.Severity) string {
+	if severity == sdkdiag.Warning {
+		return SeverityWarning
+	}
+	return SeverityError
+}
+
+// sdkSeverity returns the SDK diagnostic severity for severity: sdkdiag.Warning for SeverityWarning
+// or SeverityInfo, which the SDK doesn't have, and otherwise sdkdiag.Error.
+func sdkSeverity(severity string) sdkdiag.Severity {
+	if severity == SeverityWarning || severity == SeverityInfo {
+		return sdkdiag.Warning
+	}
+	return sdkdiag.Error
+}
+
 // AddOne appends a single diagnostic to existing Framework diagnostics with enrichment
 func AddOne(ctx context.Context, existing *fwdiag.Diagnostics, incoming fwdiag.Diagnostic, keyvals ...any) {
 	// Create a temporary diagnostics slice with the single diagnostic
@@ -262,7 +483,7 @@ func AppendOne(ctx context.Context, existing sdkdiag.Diagnostics, incoming sdkdi
 }
 
 // AppendEnrich appends incoming SDK diagnostics to existing SDK diagnostics with enrichment
-func AppendEnrich(ctx context.Context, existing sdkdiag.Diagnostics, incoming sdkdiag.Diagnostics, keyvals ...any) sdkdiag.Diagnostics {
+func AppendEnrich(ctx context.Context, existing sdkdiag.Diagnostics, incoming sdkdiag.Diagnostics, keyvals ...any) (result sdkdiag.Diagnostics) {
 	ctx, callID := globalCallID(ctx)
 	Debugf("[AppendEnrich %s] called with len(incoming): %d, keyvals: %v", callID, len(incoming), keyvals)
 
@@ -271,75 +492,201 @@ func AppendEnrich(ctx context.Context, existing sdkdiag.Diagnostics, incoming sd
 		return existing
 	}
 
+	// appendUnenriched appends the incoming diagnostics as they are when smarterr can't enrich them
+	appendUnenriched := func(existing sdkdiag.Diagnostics) sdkdiag.Diagnostics {
+		for _, diag := range incoming {
+			observeDiagnostic(ctx, diag.Summary, diag.Detail, sdkSeverityName(diag.Severity))
+		}
+		return append(existing, incoming...)
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			Debugf("[AppendEnrich %s] Panic recovered: %v", callID, r)
-			existing = append(existing, incoming...)
+			recordStatus(ctx, StatusPanic)
+			result = appendUnenriched(existing)
 		}
 	}()
 
-	if wrappedFS == nil {
-		Debugf("[AppendEnrich %s] No wrappedFS set; cannot enrich diagnostics", callID)
-		return append(existing, incoming...)
+	fsys, baseDir := currentFS()
+	if fsys == nil {
+		Debugf("[AppendEnrich %s] No FileSystem set; cannot enrich diagnostics", callID)
+		recordStatus(ctx, StatusNoFS)
+		return appendUnenriched(existing)
 	}
 
-	relStackPaths := collectRelStackPaths(ctx, wrappedBaseDir)
-	cfg, cfgErr := internal.LoadConfig(ctx, wrappedFS, relStackPaths, wrappedBaseDir)
+	relStackPaths := collectRelStackPaths(ctx, baseDir)
+	cfg, cfgErr := internal.LoadConfig(ctx, fsys, relStackPaths, baseDir)
 	if cfgErr != nil {
 		Debugf("[AppendEnrich %s] Config load error: %v", callID, cfgErr)
-		return append(existing, incoming...)
+		recordStatus(ctx, StatusConfigError)
+		return appendUnenriched(existing)
 	}
 
+	recordStatus(ctx, StatusSuccess)
 	// For each diagnostic in incoming, enrich it and append to existing
 	for _, diag := range incoming {
 		Debugf("[AppendEnrich %s] enriching diagnostic: %+v", callID, diag)
 
-		// Create a fake error for enrichment context
-		var err error
-		if diag.Summary != "" || diag.Detail != "" {
-			err = fmt.Errorf("%s: %s", diag.Summary, diag.Detail)
+		enriched, values := enrichSDKDiagnostic(ctx, cfg, diag, keyvals...)
+		existing = append(existing, enriched)
+
+		// Emit log for this diagnostic's severity
+		severityStr := sdkSeverityName(diag.Severity)
+		observeDiagnostic(ctx, enriched.Summary, enriched.Detail, severityStr)
+		if severityStr == SeverityError || severityStr == SeverityWarning || severityStr == SeverityInfo {
+			emitLogTemplates(ctx, cfg, values, severityStr)
 		}
+	}
 
-		// Build runtime with diagnostic context
-		rt := internal.NewRuntime(ctx, cfg, err, keyvals...)
-		values := rt.BuildTokenValueMap(ctx)
+	return existing
+}
 
-		// Render summary/detail using error templates if present, else fallback to original
-		summary, detail := diag.Summary, diag.Detail
-		if s, renderErr := cfg.RenderTemplate(ctx, ErrorSummaryKey, values); renderErr == nil && s != "" {
-			Debugf("[AppendEnrich %s] rendered %s: %q", callID, ErrorSummaryKey, s)
-			summary = s
-		}
-		if d, renderErr := cfg.RenderTemplate(ctx, ErrorDetailKey, values); renderErr == nil && d != "" {
-			Debugf("[AppendEnrich %s] rendered %s: %q", callID, ErrorDetailKey, d)
-			detail = d
+// AddDiagnostic adds a single pre-built diagnostic to existing Framework diagnostics, rewriting
+// its summary and detail with the `diagnostic_summary` and `diagnostic_detail` templates.
+// Unlike AddOne, it does not deduplicate against existing diagnostics or emit log templates; use
+// it when you've already handled logging. If enrichment isn't possible, the diagnostic is added
+// unchanged.
+func AddDiagnostic(ctx context.Context, existing *fwdiag.Diagnostics, incoming fwdiag.Diagnostic, keyvals ...any) {
+	ctx, callID := globalCallID(ctx)
+	Debugf("[AddDiagnostic %s] called with keyvals: %v", callID, keyvals)
+	if incoming == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			Debugf("[AddDiagnostic %s] Panic recovered: %v", callID, r)
+			recordStatus(ctx, StatusPanic)
+			*existing = append(*existing, incoming)
 		}
+	}()
+	cfg, err := loadCallerConfig(ctx)
+	if err != nil {
+		Debugf("[AddDiagnostic %s] cannot enrich diagnostic: %v", callID, err)
+		recordStatus(ctx, fallbackStatus())
+		*existing = append(*existing, incoming)
+		return
+	}
+	recordStatus(ctx, StatusSuccess)
+	enriched, _ := enrichFrameworkDiagnostic(ctx, cfg, incoming, keyvals...)
+	// Append directly since Diagnostics.Append deduplicates
+	*existing = append(*existing, enriched)
+}
 
-		// Create enriched diagnostic preserving original severity
-		enriched := sdkdiag.Diagnostic{
-			Severity: diag.Severity,
-			Summary:  summary,
-			Detail:   detail,
+// AppendDiagnostic appends a single pre-built diagnostic to existing SDK diagnostics, rewriting
+// its summary and detail with the `error_summary` and `error_detail` templates. Unlike
+// AppendOne, it does not emit log templates; use it when you've already handled logging. If
+// enrichment isn't possible, the diagnostic is appended unchanged.
+func AppendDiagnostic(ctx context.Context, existing sdkdiag.Diagnostics, incoming sdkdiag.Diagnostic, keyvals ...any) (result sdkdiag.Diagnostics) {
+	ctx, callID := globalCallID(ctx)
+	Debugf("[AppendDiagnostic %s] called with keyvals: %v", callID, keyvals)
+	defer func() {
+		if r := recover(); r != nil {
+			Debugf("[AppendDiagnostic %s] Panic recovered: %v", callID, r)
+			recordStatus(ctx, StatusPanic)
+			result = append(existing, incoming)
 		}
+	}()
+	cfg, err := loadCallerConfig(ctx)
+	if err != nil {
+		Debugf("[AppendDiagnostic %s] cannot enrich diagnostic: %v", callID, err)
+		recordStatus(ctx, fallbackStatus())
+		return append(existing, incoming)
+	}
+	recordStatus(ctx, StatusSuccess)
+	enriched, _ := enrichSDKDiagnostic(ctx, cfg, incoming, keyvals...)
+	return append(existing, enriched)
+}
 
-		existing = append(existing, enriched)
+// fallbackStatus returns the status for a failed loadCallerConfig: StatusNoFS if no FileSystem is
+// set, otherwise StatusConfigError.
+func fallbackStatus() string {
+	if fsys, _ := currentFS(); fsys == nil {
+		return StatusNoFS
+	}
+	return StatusConfigError
+}
 
-		// Emit log for this diagnostic's severity
-		severityStr := ""
-		switch diag.Severity {
-		case sdkdiag.Error:
-			severityStr = SeverityError
-		case sdkdiag.Warning:
-			severityStr = SeverityWarning
-		default:
-			severityStr = SeverityError
-		}
-		if severityStr == SeverityError || severityStr == SeverityWarning || severityStr == SeverityInfo {
-			emitLogTemplates(ctx, cfg, values, severityStr)
-		}
+// loadCallerConfig loads the config relevant to the caller's call stack.
+func loadCallerConfig(ctx context.Context) (*internal.Config, error) {
+	fsys, baseDir := currentFS()
+	if fsys == nil {
+		return nil, fmt.Errorf("no filesystem set, use SetFS()")
 	}
+	relStackPaths := collectRelStackPaths(ctx, baseDir)
+	return internal.LoadConfig(ctx, fsys, relStackPaths, baseDir)
+}
 
-	return existing
+// enrichFrameworkDiagnostic renders the diagnostic templates for a Framework diagnostic, preserving
+// its severity. It returns the enriched diagnostic and the resolved token values.
+func enrichFrameworkDiagnostic(ctx context.Context, cfg *internal.Config, diag fwdiag.Diagnostic, keyvals ...any) (fwdiag.Diagnostic, map[string]any) {
+	ctx, callID := globalCallID(ctx)
+	// Enrich: build runtime with diagnostic as a field, not in args
+	rt := internal.NewRuntimeForDiagnostic(ctx, cfg, diag, keyvals...)
+	values := rt.BuildTokenValueMap(ctx)
+	// Render summary/detail using diagnostic templates if present, else fallback to original
+	summary, detail := diag.Summary(), diag.Detail()
+	if s, err := cfg.RenderTemplate(ctx, DiagnosticSummaryKey, values); err == nil && s != "" {
+		Debugf("[enrichFrameworkDiagnostic %s] rendered %s: %q", callID, DiagnosticSummaryKey, s)
+		summary = s
+	}
+	if d, err := cfg.RenderTemplate(ctx, DiagnosticDetailKey, values); err == nil && d != "" {
+		Debugf("[enrichFrameworkDiagnostic %s] rendered %s: %q", callID, DiagnosticDetailKey, d)
+		detail = d
+	}
+	code := rt.ErrorCode(ctx)
+	detail = cfg.TruncateDetailWithCode(detail, code)
+	if detail != cfg.AppendErrorCode(diag.Detail(), code) {
+		// Only a rendered or truncated detail differs from the original
+		detail = cfg.AppendOriginalDetail(detail, diag.Detail())
+	}
+	// Create enriched diagnostic preserving original severity
+	switch diag.Severity().String() {
+	case SeverityWarning:
+		return fwdiag.NewWarningDiagnostic(summary, detail), values
+	default:
+		// Errors and unknown severities
+		return fwdiag.NewErrorDiagnostic(summary, detail), values
+	}
+}
+
+// enrichSDKDiagnostic renders the error templates for an SDK diagnostic, preserving its severity.
+// It returns the enriched diagnostic and the resolved token values.
+func enrichSDKDiagnostic(ctx context.Context, cfg *internal.Config, diag sdkdiag.Diagnostic, keyvals ...any) (sdkdiag.Diagnostic, map[string]any) {
+	ctx, callID := globalCallID(ctx)
+	// Create a fake error for enrichment context
+	var err error
+	if diag.Summary != "" || diag.Detail != "" {
+		err = fmt.Errorf("%s: %s", diag.Summary, diag.Detail)
+	}
+
+	// Build runtime with diagnostic context
+	rt := internal.NewRuntime(ctx, cfg, err, keyvals...)
+	values := rt.BuildTokenValueMap(ctx)
+
+	// Render summary/detail using error templates if present, else fallback to original
+	summary, detail := diag.Summary, diag.Detail
+	if s, renderErr := cfg.RenderTemplate(ctx, ErrorSummaryKey, values); renderErr == nil && s != "" {
+		Debugf("[enrichSDKDiagnostic %s] rendered %s: %q", callID, ErrorSummaryKey, s)
+		summary = s
+	}
+	if d, renderErr := cfg.RenderTemplate(ctx, ErrorDetailKey, values); renderErr == nil && d != "" {
+		Debugf("[enrichSDKDiagnostic %s] rendered %s: %q", callID, ErrorDetailKey, d)
+		detail = d
+	}
+
+	code := rt.ErrorCode(ctx)
+	detail = cfg.TruncateDetailWithCode(detail, code)
+	if detail != cfg.AppendErrorCode(diag.Detail, code) {
+		// Only a rendered or truncated detail differs from the original
+		detail = cfg.AppendOriginalDetail(detail, diag.Detail)
+	}
+	// Create enriched diagnostic preserving original severity
+	return sdkdiag.Diagnostic{
+		Severity: diag.Severity,
+		Summary:  summary,
+		Detail:   detail,
+	}, values
 }
 
 func globalCallID(ctx context.Context) (context.Context, string) {
@@ -359,30 +706,54 @@ func globalCallID(ctx context.Context) (context.Context, string) {
 // the caller's directory, then builds a runtime to render the final error message. If any step fails,
 // it appends a fallback error message that always includes the original error (if present) in the summary.
 // The add function is used to append the error to the diagnostics in a way appropriate for the caller.
-func appendCommon(ctx context.Context, add func(summary, detail string), err error, keyvals ...any) {
+func appendCommon(ctx context.Context, add func(summary, detail string), err error, severity string, keyvals ...any) {
 	ctx, callID := globalCallID(ctx)
 	Debugf("[appendCommon %s] called with error: %v, keyvals: %v", callID, err, keyvals)
-	if wrappedFS == nil {
-		Debugf("[appendCommon %s] No wrappedFS set; calling addFallbackInitError", callID)
+	if currentDiagnosticObserver() != nil {
+		addDiag := add
+		add = func(summary, detail string) {
+			addDiag(summary, detail)
+			observeDiagnostic(ctx, summary, detail, severity)
+		}
+	}
+	if isIgnoredError(err) {
+		Debugf("[appendCommon %s] Ignored error; adding it without enrichment", callID)
+		recordStatus(ctx, StatusIgnored)
+		add(firstNWords(err, 3), err.Error())
+		return
+	}
+	fsys, baseDir := currentFS()
+	if fsys == nil {
+		Debugf("[appendCommon %s] No FileSystem set; calling addFallbackInitError", callID)
+		recordStatus(ctx, StatusNoFS)
 		addFallbackInitError(add, err)
 		return
 	}
-	relStackPaths := collectRelStackPaths(ctx, wrappedBaseDir)
+	relStackPaths := collectRelStackPaths(ctx, baseDir)
 	Debugf("[appendCommon %s] collectRelStackPaths returned: %v", callID, relStackPaths)
-	cfg, cfgErr := internal.LoadConfig(ctx, wrappedFS, relStackPaths, wrappedBaseDir)
+	cfg, cfgErr := internal.LoadConfig(ctx, fsys, relStackPaths, baseDir)
 	if cfgErr != nil {
 		Debugf("[appendCommon %s] Config load error: %v", callID, cfgErr)
+		recordStatus(ctx, StatusConfigError)
 		addFallbackConfigError(add, err, cfgErr)
 		return
 	}
 
 	rt := internal.NewRuntime(ctx, cfg, err, keyvals...)
+	if diag, ok := asDiagnostic(err); ok {
+		// Let diagnostic tokens resolve a diagnostic converted with DiagnosticError
+		rt.Diagnostic = diag
+	}
 	values := rt.BuildTokenValueMap(ctx)
 
-	summary, detail := renderDiagnostics(ctx, cfg, err, values)
+	summary, detail := renderDiagnostics(ctx, cfg, err, values, summaryOverride(rt.Reserved), severity, rt.ErrorCode(ctx))
 	Debugf("[appendCommon %s] renderDiagnostics returned summary=%q detail=%q", callID, summary, detail)
+	var se *Error
+	if errors.As(err, &se) {
+		se.setRendered(summary, detail)
+	}
 	add(summary, detail)
-	emitLogTemplates(ctx, cfg, values, SeverityError)
+	emitLogTemplates(ctx, cfg, values, severity)
 }
 
 // captureStack returns a slice of runtime.Frames for the current call stack, skipping 'skip' frames.
@@ -409,7 +780,11 @@ func addFallbackInitError(add func(summary, detail string), err error) {
 	if err != nil {
 		detail = err.Error()
 	}
-	detail += " [smarterr initialization: Embedded filesystem not set, use SetFS()]"
+	if fn := currentFallbackMessage(); fn != nil {
+		detail += " " + fn(err)
+	} else {
+		detail += " [smarterr initialization: Embedded filesystem not set, use SetFS()]"
+	}
 	add(summary, detail)
 }
 
@@ -425,11 +800,13 @@ func addFallbackConfigError(add func(summary, detail string), err error, cfgErr
 	add(summary, detail)
 }
 
-// collectRelStackPaths normalizes call stack file paths relative to wrappedBaseDir.
+// collectRelStackPaths normalizes call stack file paths relative to baseDir.
 func collectRelStackPaths(ctx context.Context, baseDir string) []string {
 	_, callID := globalCallID(ctx)
 	Debugf("[collectRelStackPaths %s] called with baseDir=%q", callID, baseDir)
-	const stackDepth = 5
+	// Enough for smarterr's own frames, up to appendCommon, appendWithSeverity, and Append, then two
+	// levels of provider wrappers, such as smerr.Append, and the resource function that calls them
+	const stackDepth = 6
 	pcs := make([]uintptr, stackDepth)
 	n := runtime.Callers(2, pcs)
 	frames := runtime.CallersFrames(pcs[:n])
@@ -451,11 +828,53 @@ func collectRelStackPaths(ctx context.Context, baseDir string) []string {
 	return relStackPaths
 }
 
-// renderDiagnostics renders summary and detail, with fallback if templates fail.
-func renderDiagnostics(ctx context.Context, cfg *internal.Config, err error, values map[string]any) (string, string) {
+// summaryOverride returns the value of the reserved SummaryOverride keyval, if set.
+func summaryOverride(reserved map[string]any) string {
+	if v, ok := reserved[SummaryOverride]; ok && v != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
+
+// severityTemplateKey returns the severity-specific name for an error template, e.g.,
+// warning_detail for error_detail when the severity is a warning. Otherwise, it returns key.
+func severityTemplateKey(key, severity string) string {
+	if severity != SeverityWarning {
+		return key
+	}
+	switch key {
+	case ErrorSummaryKey:
+		return WarningSummaryKey
+	case ErrorDetailKey:
+		return WarningDetailKey
+	}
+	return key
+}
+
+// renderSeverityTemplate renders the severity-specific template for key if the config defines it,
+// falling back to the generic key.
+func renderSeverityTemplate(ctx context.Context, cfg *internal.Config, key, severity string, values map[string]any) (string, error) {
+	if name := severityTemplateKey(key, severity); name != key && cfg != nil && slices.ContainsFunc(cfg.Templates, func(t internal.Template) bool { return t.Name == name }) {
+		key = name
+	}
+	return cfg.RenderTemplate(ctx, key, values)
+}
+
+// renderDiagnostics renders summary and detail, with fallback if templates fail. A non-empty
+// override is used as the summary without rendering the summary template. Templates specific to
+// the severity (e.g., warning_detail) are preferred over the generic error templates. The detail is
+// truncated to max_detail_length, if set, and code is appended when append_error_code is enabled.
+func renderDiagnostics(ctx context.Context, cfg *internal.Config, err error, values map[string]any, override, severity, code string) (string, string) {
 	ctx, callID := globalCallID(ctx)
-	Debugf("[renderDiagnostics %s] called with error: %v, values: %v", callID, err, values)
-	summaryTmpl, summaryErr := cfg.RenderTemplate(ctx, ErrorSummaryKey, values)
+	Debugf("[renderDiagnostics %s] called with error: %v, severity: %s, values: %v", callID, err, severity, values)
+	var summaryTmpl string
+	var summaryErr error
+	if override != "" {
+		Debugf("[renderDiagnostics %s] Using summary override: %q", callID, override)
+		summaryTmpl = override
+	} else {
+		summaryTmpl, summaryErr = renderSeverityTemplate(ctx, cfg, ErrorSummaryKey, severity, values)
+	}
 	var summary string
 	if summaryErr != nil {
 		Debugf("Summary template error: %v", summaryErr)
@@ -463,7 +882,7 @@ func renderDiagnostics(ctx context.Context, cfg *internal.Config, err error, val
 	} else {
 		summary = summaryTmpl
 	}
-	detailTmpl, detailErr := cfg.RenderTemplate(ctx, ErrorDetailKey, values)
+	detailTmpl, detailErr := renderSeverityTemplate(ctx, cfg, ErrorDetailKey, severity, values)
 	var detail string
 	if detailErr != nil || summaryErr != nil {
 		Debugf("Detail template error: %v", detailErr)
@@ -479,17 +898,26 @@ func renderDiagnostics(ctx context.Context, cfg *internal.Config, err error, val
 			problems += " [smarterr detail template error: " + detailErr.Error() + "]"
 		}
 		detail += problems
+		recordStatus(ctx, StatusTemplateError)
 	} else {
 		detail = detailTmpl
+		recordStatus(ctx, StatusSuccess)
+	}
+	detail = cfg.TruncateDetailWithCode(detail, code)
+	if summaryErr == nil && detailErr == nil && err != nil {
+		// On a template error, the detail already is the original error
+		detail = cfg.AppendOriginalDetail(detail, err.Error())
 	}
 	return summary, detail
 }
 
 // emitLogTemplates checks for log_error, log_warn, and log_info templates and emits logs if present.
+// The rendered template is the message, and the log_fields tokens, or all tokens, are the fields.
 func emitLogTemplates(ctx context.Context, cfg *internal.Config, values map[string]any, severity string) {
 	ctx, callID := globalCallID(ctx)
 	Debugf("[emitLogTemplates %s] called with severity: %s", callID, severity)
-	if globalLogger == nil {
+	logger := currentLogger()
+	if logger == nil {
 		Debugf("[emitLogTemplates %s] No globalLogger set; skipping user-facing log emission")
 		return
 	}
@@ -506,13 +934,14 @@ func emitLogTemplates(ctx context.Context, cfg *internal.Config, values map[stri
 	}
 	if tmpl, err := cfg.RenderTemplate(ctx, key, values); err == nil && tmpl != "" {
 		Debugf("[emitLogTemplates %s] Emitting user-facing %s: %q", callID, key, tmpl)
+		fields := cfg.LogFields(values)
 		switch severity {
 		case SeverityError:
-			globalLogger.Error(ctx, tmpl, values)
+			logger.Error(ctx, tmpl, fields)
 		case SeverityWarning:
-			globalLogger.Warn(ctx, tmpl, values)
+			logger.Warn(ctx, tmpl, fields)
 		case SeverityInfo:
-			globalLogger.Info(ctx, tmpl, values)
+			logger.Info(ctx, tmpl, fields)
 		}
 	}
 }
@@ -2,12 +2,29 @@ package smarterr
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
+	"github.com/YakDriver/smarterr/internal"
 	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
 	sdkdiag "github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 )
 
+// setTestConfig installs content as the global smarterr config for the duration of the test.
+func setTestConfig(t *testing.T, content string) {
+	t.Helper()
+	SetFS(&WrappedFS{FS: fstest.MapFS{
+		"smarterr/smarterr.hcl": &fstest.MapFile{Data: []byte(content)},
+	}}, "smarterr")
+	t.Cleanup(func() { SetFS(nil, "") })
+}
+
 func TestAddOne_PreservesDiagnosticSeverity(t *testing.T) {
 	ctx := context.Background()
 
@@ -173,3 +190,1132 @@ func TestAppendEnrich_PreservesSDKDiagnosticSeverity(t *testing.T) {
 		})
 	}
 }
+
+func TestAddError_SummaryOverride(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "templated summary"
+}
+
+template "error_detail" {
+  format = "{{.error}}"
+}
+`)
+	ctx := context.Background()
+	err := errors.New("boom")
+
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, err)
+	if got := diags[0].Summary(); got != "templated summary" {
+		t.Fatalf("expected error_summary without override, got %q", got)
+	}
+
+	diags = nil
+	AddError(ctx, &diags, err, SummaryOverride, "Custom summary")
+	if got := diags[0].Summary(); got != "Custom summary" {
+		t.Errorf("expected override summary, got %q", got)
+	}
+	if got := diags[0].Detail(); got != "boom" {
+		t.Errorf("expected detail from error_detail, got %q", got)
+	}
+
+	sdiags := Append(ctx, nil, err, SummaryOverride, "Custom summary")
+	if got := sdiags[0].Summary; got != "Custom summary" {
+		t.Errorf("expected override summary from Append, got %q", got)
+	}
+}
+
+func TestReservedKeys(t *testing.T) {
+	keys := ReservedKeys()
+	if !slices.Contains(keys, SummaryOverride) {
+		t.Errorf("ReservedKeys() = %v, want it to include %q", keys, SummaryOverride)
+	}
+	for _, k := range []string{ID, ResourceName, ServiceName} {
+		if slices.Contains(keys, k) {
+			t.Errorf("ReservedKeys() includes token arg key %q", k)
+		}
+	}
+	keys[0] = "changed"
+	if ReservedKeys()[0] == "changed" {
+		t.Error("ReservedKeys() returned a slice that aliases smarterr's list")
+	}
+}
+
+func TestAddError_ReservedKeyNotArg(t *testing.T) {
+	setTestConfig(t, `
+token "override" {
+  arg = "summary_override"
+}
+
+token "id" {
+  arg = "id"
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "error_detail" {
+  format = "[{{.override}}] {{.id}}"
+}
+`)
+	ctx := context.Background()
+
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, errors.New("boom"), SummaryOverride, "Custom summary", ID, "vpc-1")
+	if got := diags[0].Summary(); got != "Custom summary" {
+		t.Errorf("summary = %q, want %q", got, "Custom summary")
+	}
+	if got, want := diags[0].Detail(), "[] vpc-1"; got != want {
+		t.Errorf("detail = %q, want %q (reserved keyval leaked into arg token)", got, want)
+	}
+}
+
+func TestSetStrict(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "error_detail" {
+  format = "{{.error}}"
+}
+`)
+	ctx := context.Background()
+
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, errors.New("boom"), ID, "vpc-1", 42, "x")
+	if got := diags[0].Detail(); got != "boom" {
+		t.Errorf("lenient detail = %q, want %q", got, "boom")
+	}
+
+	SetStrict(true)
+	t.Cleanup(func() { SetStrict(false) })
+	diags = nil
+	AddError(ctx, &diags, errors.New("boom"), ID, "vpc-1", 42, "x")
+	if got, want := diags[0].Detail(), "boom [smarterr panic: strict keyvals: key at index 2 is int (42), not string]"; got != want {
+		t.Errorf("strict detail = %q, want %q", got, want)
+	}
+	sdiags := Append(ctx, nil, errors.New("boom"), ID)
+	if got := sdiags[0].Detail; !strings.Contains(got, "odd number of keyvals") {
+		t.Errorf("strict Append detail = %q, want a keyval error", got)
+	}
+}
+
+func TestSetPanicSuffix(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "error_detail" {
+  format = "{{.error}}"
+}
+`)
+	SetStrict(true)
+	t.Cleanup(func() { SetStrict(false) })
+	t.Cleanup(func() { SetPanicSuffix(DefaultPanicSuffix) })
+
+	tests := []struct {
+		name   string
+		suffix string
+		want   string
+	}{
+		{name: "default", suffix: DefaultPanicSuffix, want: `boom [smarterr panic: strict keyvals: odd number of keyvals (1); key "id" at index 0 has no value]`},
+		{name: "hidden", suffix: "", want: "boom"},
+		{name: "custom", suffix: " (internal error: %s)", want: `boom (internal error: strict keyvals: odd number of keyvals (1); key "id" at index 0 has no value)`},
+		{name: "without panic message", suffix: " (internal error)", want: "boom (internal error)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			SetPanicSuffix(tc.suffix)
+			ctx := WithStatus(context.Background())
+
+			var diags fwdiag.Diagnostics
+			AddError(ctx, &diags, errors.New("boom"), ID)
+			if len(diags) != 1 {
+				t.Fatalf("expected 1 diagnostic, got %d", len(diags))
+			}
+			if got := diags[0].Detail(); got != tc.want {
+				t.Errorf("AddError detail = %q, want %q", got, tc.want)
+			}
+			if got := LastStatus(ctx); got != StatusPanic {
+				t.Errorf("LastStatus() = %q, want %q", got, StatusPanic)
+			}
+
+			sdiags := Append(ctx, nil, errors.New("boom"), ID)
+			if len(sdiags) != 1 || sdiags[0].Detail != tc.want {
+				t.Errorf("Append diagnostics = %+v, want detail %q", sdiags, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddErrorAppend_NilError(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "failed: {{.error}}"
+}
+`)
+	ctx := context.Background()
+
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, nil, ID, "vpc-1")
+	if len(diags) != 0 {
+		t.Errorf("AddError with nil error added %d diagnostics, want 0: %+v", len(diags), diags)
+	}
+
+	existing := sdkdiag.Diagnostics{{Severity: sdkdiag.Warning, Summary: "existing"}}
+	sdiags := Append(ctx, existing, nil, ID, "vpc-1")
+	if len(sdiags) != 1 || sdiags[0].Summary != "existing" {
+		t.Errorf("Append with nil error = %+v, want diags unchanged", sdiags)
+	}
+}
+
+func TestSetIgnoredErrors_PassesThroughWithoutEnrichment(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "enriched"
+}
+
+template "error_detail" {
+  format = "enriched: {{.error}}"
+}
+
+template "log_error" {
+  format = "logged: {{.error}}"
+}
+`)
+	logger := &countingLogger{}
+	SetLogger(logger)
+	t.Cleanup(func() { SetLogger(nil) })
+	SetIgnoredErrors(context.Canceled)
+	t.Cleanup(func() { SetIgnoredErrors() })
+
+	ctx := WithStatus(context.Background())
+	canceled := fmt.Errorf("reading VPC: %w", context.Canceled)
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, canceled)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diags))
+	}
+	if got := diags[0].Summary(); got != "reading VPC: context" {
+		t.Errorf("unexpected summary: %q", got)
+	}
+	if got := diags[0].Detail(); got != canceled.Error() {
+		t.Errorf("unexpected detail: %q", got)
+	}
+	if got := LastStatus(ctx); got != StatusIgnored {
+		t.Errorf("LastStatus() = %q, want %q", got, StatusIgnored)
+	}
+
+	sdkDiags := Append(ctx, nil, canceled)
+	if len(sdkDiags) != 1 || sdkDiags[0].Detail != canceled.Error() {
+		t.Errorf("unexpected Append diagnostics: %+v", sdkDiags)
+	}
+	if logger.calls != 0 {
+		t.Errorf("expected no log emission for ignored errors, got %d calls", logger.calls)
+	}
+
+	// Other errors are still enriched
+	diags = nil
+	AddError(ctx, &diags, errors.New("boom"))
+	if len(diags) != 1 || diags[0].Summary() != "enriched" {
+		t.Errorf("expected enriched diagnostic, got %+v", diags)
+	}
+	if logger.calls != 1 {
+		t.Errorf("expected 1 log call for an enriched error, got %d", logger.calls)
+	}
+}
+
+func TestSetDiagnosticObserver(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+token "id" {
+  arg = "id"
+}
+
+template "error_summary" {
+  format = "reading VPC ({{.id}})"
+}
+
+template "error_detail" {
+  format = "detail: {{.error}}"
+}
+
+template "diagnostic_summary" {
+  format = "enriched {{.id}}"
+}
+`)
+	type observed struct {
+		summary, detail, severity string
+	}
+	ch := make(chan observed, 10)
+	SetDiagnosticObserver(func(ctx context.Context, summary, detail, severity string) {
+		ch <- observed{summary, detail, severity}
+	})
+	t.Cleanup(func() { SetDiagnosticObserver(nil) })
+
+	ctx := context.Background()
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, errors.New("boom"), "id", "vpc-1")
+	_ = Append(ctx, nil, errors.New("bang"), "id", "vpc-2")
+	AddEnrich(ctx, &diags, fwdiag.Diagnostics{fwdiag.NewWarningDiagnostic("conversion", "value")}, "id", "vpc-3")
+	_ = AppendEnrich(ctx, nil, sdkdiag.Diagnostics{{Severity: sdkdiag.Error, Summary: "sdk", Detail: "sdk detail"}}, "id", "vpc-4")
+
+	want := []observed{
+		{"reading VPC (vpc-1)", "detail: boom", SeverityError},
+		{"reading VPC (vpc-2)", "detail: bang", SeverityError},
+		{"enriched vpc-3", "value", SeverityWarning},
+		{"reading VPC (vpc-4)", "detail: sdk: sdk detail", SeverityError},
+	}
+	if len(ch) != len(want) {
+		t.Fatalf("observer called %d times, want %d", len(ch), len(want))
+	}
+	for i, w := range want {
+		if got := <-ch; got != w {
+			t.Errorf("observed diagnostic %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	// Diagnostics are still added, and removing the observer stops notifications
+	SetDiagnosticObserver(nil)
+	AddError(ctx, &diags, errors.New("boom"), "id", "vpc-5")
+	if len(diags) != 3 {
+		t.Errorf("expected 3 diagnostics, got %d", len(diags))
+	}
+	if len(ch) != 0 {
+		t.Errorf("observer called after being removed")
+	}
+}
+
+func TestSetDiagnosticObserver_Fallbacks(t *testing.T) {
+	setTestConfig(t, `
+template "error_summary" {
+  format = "failed"
+}
+`)
+	var observed []string
+	SetDiagnosticObserver(func(ctx context.Context, summary, detail, severity string) {
+		observed = append(observed, severity+": "+detail)
+	})
+	t.Cleanup(func() { SetDiagnosticObserver(nil) })
+
+	// Diagnostics added after recovering a panic are observed
+	SetStrict(true)
+	t.Cleanup(func() { SetStrict(false) })
+	ctx := context.Background()
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, errors.New("boom"), ID)
+	_ = AppendWithSeverity(ctx, nil, errors.New("bang"), SeverityWarning, ID)
+	want := []string{
+		`Error: boom [smarterr panic: strict keyvals: odd number of keyvals (1); key "id" at index 0 has no value]`,
+		`Warning: bang [smarterr panic: strict keyvals: odd number of keyvals (1); key "id" at index 0 has no value]`,
+	}
+	if !slices.Equal(observed, want) {
+		t.Errorf("observed after panic = %q, want %q", observed, want)
+	}
+
+	// Diagnostics the enrich functions add unchanged are observed
+	observed = nil
+	SetFS(nil, "")
+	AddEnrich(ctx, &diags, fwdiag.Diagnostics{fwdiag.NewWarningDiagnostic("conversion", "value")})
+	_ = AppendEnrich(ctx, nil, sdkdiag.Diagnostics{{Severity: sdkdiag.Error, Summary: "sdk", Detail: "sdk detail"}})
+	want = []string{"Warning: value", "Error: sdk detail"}
+	if !slices.Equal(observed, want) {
+		t.Errorf("observed without FileSystem = %q, want %q", observed, want)
+	}
+
+	// A panicking observer doesn't add a second diagnostic
+	SetDiagnosticObserver(func(ctx context.Context, summary, detail, severity string) { panic("observer") })
+	diags = nil
+	AddError(ctx, &diags, errors.New("boom"), ID)
+	if len(diags) != 1 {
+		t.Errorf("expected 1 diagnostic with a panicking observer, got %d", len(diags))
+	}
+}
+
+func TestEmitLogTemplates_LogFields(t *testing.T) {
+	config := `
+token "service" {
+  parameter = "service"
+}
+
+token "identifier" {
+  arg = "id"
+}
+
+token "error" {
+  source = "error"
+}
+
+parameter "service" {
+  value = "EC2"
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "log_error" {
+  format = "reading {{.service}} ({{.identifier}}): {{.error}}"
+}
+`
+	tests := []struct {
+		name      string
+		smarterr  string
+		wantField []string
+	}{
+		{name: "all tokens by default", wantField: []string{"error", "identifier", "service"}},
+		{name: "only log_fields", smarterr: `smarterr { log_fields = ["service", "identifier"] }`, wantField: []string{"identifier", "service"}},
+		{name: "no fields", smarterr: `smarterr { log_fields = [] }`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestConfig(t, config+tc.smarterr)
+			logger := &recordingLogger{}
+			SetLogger(logger)
+			t.Cleanup(func() { SetLogger(nil) })
+
+			var diags fwdiag.Diagnostics
+			AddError(context.Background(), &diags, errors.New("boom"), ID, "vpc-1")
+
+			if want := "reading EC2 (vpc-1): boom"; logger.msg != want {
+				t.Errorf("message = %q, want %q", logger.msg, want)
+			}
+			got := slices.Sorted(maps.Keys(logger.fields))
+			if !slices.Equal(got, tc.wantField) {
+				t.Errorf("fields = %v, want %v", got, tc.wantField)
+			}
+			if v, ok := logger.fields["identifier"]; ok && v != "vpc-1" {
+				t.Errorf("identifier field = %v, want %q", v, "vpc-1")
+			}
+		})
+	}
+}
+
+func TestAddDiagnostic_SeverityLevelTemplate(t *testing.T) {
+	setTestConfig(t, `
+token "diag" {
+  source = "diagnostic"
+}
+
+template "diagnostic_summary" {
+  format = "{{if eq .diag.severity_level \"warning\"}}Heads up{{else}}Failed{{end}}: {{.diag.summary}}"
+}
+`)
+	ctx := context.Background()
+	var diags fwdiag.Diagnostics
+	AddDiagnostic(ctx, &diags, fwdiag.NewWarningDiagnostic("deprecated argument", "detail"))
+	AddDiagnostic(ctx, &diags, fwdiag.NewErrorDiagnostic("value conversion", "detail"))
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %d", len(diags))
+	}
+	if got, want := diags[0].Summary(), "Heads up: deprecated argument"; got != want {
+		t.Errorf("warning summary = %q, want %q", got, want)
+	}
+	if got, want := diags[1].Summary(), "Failed: value conversion"; got != want {
+		t.Errorf("error summary = %q, want %q", got, want)
+	}
+}
+
+func TestEnrichAppend_WarnsOnce(t *testing.T) {
+	setTestConfig(t, `
+token "diag" {
+  source = "diagnostic"
+}
+
+template "diagnostic_summary" {
+  format = "enriched: {{.diag.summary}}"
+}
+`)
+	enrichAppendDeprecation = sync.Once{}
+	logger := &recordingLogger{}
+	SetLogger(logger)
+	t.Cleanup(func() { SetLogger(nil) })
+	ctx := context.Background()
+	incoming := fwdiag.Diagnostics{fwdiag.NewErrorDiagnostic("value conversion", "detail")}
+
+	var diags fwdiag.Diagnostics
+	EnrichAppend(ctx, &diags, incoming)
+	if logger.msg != enrichAppendDeprecationMessage {
+		t.Errorf("expected deprecation warning on first call, got %q", logger.msg)
+	}
+	if len(diags) != 1 || diags[0].Summary() != "enriched: value conversion" {
+		t.Errorf("expected EnrichAppend to enrich like AddEnrich, got %+v", diags)
+	}
+
+	logger.msg = ""
+	EnrichAppend(ctx, &diags, incoming)
+	if logger.msg != "" {
+		t.Errorf("expected no warning on second call, got %q", logger.msg)
+	}
+}
+
+// recordingLogger records the last user-facing log call.
+type recordingLogger struct {
+	msg    string
+	fields map[string]any
+}
+
+func (l *recordingLogger) Debug(_ context.Context, msg string, fields map[string]any) {
+	l.msg, l.fields = msg, fields
+}
+func (l *recordingLogger) Info(_ context.Context, msg string, fields map[string]any) {
+	l.msg, l.fields = msg, fields
+}
+func (l *recordingLogger) Warn(_ context.Context, msg string, fields map[string]any) {
+	l.msg, l.fields = msg, fields
+}
+func (l *recordingLogger) Error(_ context.Context, msg string, fields map[string]any) {
+	l.msg, l.fields = msg, fields
+}
+
+// countingLogger counts user-facing log calls.
+type countingLogger struct{ calls int }
+
+func (l *countingLogger) Debug(context.Context, string, map[string]any) { l.calls++ }
+func (l *countingLogger) Info(context.Context, string, map[string]any)  { l.calls++ }
+func (l *countingLogger) Warn(context.Context, string, map[string]any)  { l.calls++ }
+func (l *countingLogger) Error(context.Context, string, map[string]any) { l.calls++ }
+
+func TestAddDiagnostic_EnrichesWithoutDedupOrLogs(t *testing.T) {
+	setTestConfig(t, `
+token "id" {
+  arg = "id"
+}
+
+token "diag" {
+  source = "diagnostic"
+}
+
+template "diagnostic_summary" {
+  format = "{{.diag.summary}} (ID {{.id}})"
+}
+
+template "log_warn" {
+  format = "logged"
+}
+`)
+	logger := &countingLogger{}
+	SetLogger(logger)
+	t.Cleanup(func() { SetLogger(nil) })
+	ctx := context.Background()
+	incoming := fwdiag.NewWarningDiagnostic("value conversion", "detail")
+
+	var existing fwdiag.Diagnostics
+	AddDiagnostic(ctx, &existing, incoming, "id", "db-1")
+	AddDiagnostic(ctx, &existing, incoming, "id", "db-1")
+
+	if len(existing) != 2 {
+		t.Fatalf("expected 2 diagnostics (no dedup), got %d", len(existing))
+	}
+	if got := existing[0].Summary(); got != "value conversion (ID db-1)" {
+		t.Errorf("unexpected summary: %q", got)
+	}
+	if got := existing[0].Severity().String(); got != SeverityWarning {
+		t.Errorf("expected severity %s, got %s", SeverityWarning, got)
+	}
+	if logger.calls != 0 {
+		t.Errorf("expected no log emission, got %d calls", logger.calls)
+	}
+
+	AddOne(ctx, &existing, incoming, "id", "db-1")
+	if len(existing) != 2 {
+		t.Errorf("expected AddOne to deduplicate, got %d diagnostics", len(existing))
+	}
+	if logger.calls != 0 {
+		t.Errorf("expected AddOne to skip logging for a duplicate, got %d calls", logger.calls)
+	}
+}
+
+func TestAppendDiagnostic_EnrichesWithoutLogs(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "enriched: {{.error}}"
+}
+
+template "log_error" {
+  format = "logged"
+}
+`)
+	logger := &countingLogger{}
+	SetLogger(logger)
+	t.Cleanup(func() { SetLogger(nil) })
+	ctx := context.Background()
+	incoming := sdkdiag.Diagnostic{Severity: sdkdiag.Error, Summary: "summary", Detail: "detail"}
+
+	got := AppendDiagnostic(ctx, nil, incoming)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(got))
+	}
+	if got[0].Summary != "enriched: summary: detail" {
+		t.Errorf("unexpected summary: %q", got[0].Summary)
+	}
+	if got[0].Detail != "detail" {
+		t.Errorf("expected original detail without error_detail template, got %q", got[0].Detail)
+	}
+	if logger.calls != 0 {
+		t.Errorf("expected no log emission, got %d calls", logger.calls)
+	}
+
+	AppendOne(ctx, nil, incoming)
+	if logger.calls != 1 {
+		t.Errorf("expected AppendOne to emit a log, got %d calls", logger.calls)
+	}
+}
+
+func TestAppendWithSeverity(t *testing.T) {
+	setTestConfig(t, `
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "error: {{.error}}"
+}
+
+template "error_detail" {
+  format = "detail"
+}
+
+template "warning_summary" {
+  format = "warning: {{.error}}"
+}
+
+template "log_error" {
+  format = "logged error"
+}
+
+template "log_warn" {
+  format = "logged warning"
+}
+
+template "log_info" {
+  format = "logged info"
+}
+`)
+	logger := &recordingLogger{}
+	SetLogger(logger)
+	t.Cleanup(func() { SetLogger(nil) })
+	ctx := context.Background()
+
+	tests := []struct {
+		severity     string
+		wantSeverity sdkdiag.Severity
+		wantSummary  string
+		wantLog      string
+	}{
+		{SeverityError, sdkdiag.Error, "error: boom", "logged error"},
+		{SeverityWarning, sdkdiag.Warning, "warning: boom", "logged warning"},
+		{SeverityInfo, sdkdiag.Warning, "error: boom", "logged info"},
+		{"critical", sdkdiag.Error, "error: boom", "logged error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			logger.msg = ""
+			diags := AppendWithSeverity(ctx, nil, errors.New("boom"), tt.severity)
+			if len(diags) != 1 {
+				t.Fatalf("expected 1 diagnostic, got %d", len(diags))
+			}
+			if diags[0].Severity != tt.wantSeverity {
+				t.Errorf("Severity = %v, want %v", diags[0].Severity, tt.wantSeverity)
+			}
+			if diags[0].Summary != tt.wantSummary {
+				t.Errorf("Summary = %q, want %q", diags[0].Summary, tt.wantSummary)
+			}
+			if logger.msg != tt.wantLog {
+				t.Errorf("log = %q, want %q", logger.msg, tt.wantLog)
+			}
+		})
+	}
+
+	if diags := AppendWithSeverity(ctx, nil, nil, SeverityWarning); diags != nil {
+		t.Errorf("expected nil error to append nothing, got %v", diags)
+	}
+}
+
+func TestRenderDiagnostics_SeverityTemplates(t *testing.T) {
+	ctx := context.Background()
+	err := errors.New("boom")
+	values := map[string]any{"error": "boom"}
+	base := []internal.Template{
+		{Name: ErrorSummaryKey, Format: "error summary"},
+		{Name: ErrorDetailKey, Format: "error detail: {{.error}}"},
+	}
+
+	tests := []struct {
+		name        string
+		templates   []internal.Template
+		severity    string
+		wantSummary string
+		wantDetail  string
+	}{
+		{
+			name:        "error uses error templates",
+			templates:   append(slices.Clone(base), internal.Template{Name: WarningDetailKey, Format: "warning detail"}),
+			severity:    SeverityError,
+			wantSummary: "error summary",
+			wantDetail:  "error detail: boom",
+		},
+		{
+			name:        "warning uses warning_detail",
+			templates:   append(slices.Clone(base), internal.Template{Name: WarningDetailKey, Format: "warning detail: {{.error}}"}),
+			severity:    SeverityWarning,
+			wantSummary: "error summary",
+			wantDetail:  "warning detail: boom",
+		},
+		{
+			name:        "warning falls back to error templates",
+			templates:   base,
+			severity:    SeverityWarning,
+			wantSummary: "error summary",
+			wantDetail:  "error detail: boom",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &internal.Config{Templates: tc.templates}
+			summary, detail := renderDiagnostics(ctx, cfg, err, maps.Clone(values), "", tc.severity, "")
+			if summary != tc.wantSummary {
+				t.Errorf("summary = %q, want %q", summary, tc.wantSummary)
+			}
+			if detail != tc.wantDetail {
+				t.Errorf("detail = %q, want %q", detail, tc.wantDetail)
+			}
+		})
+	}
+}
+
+func TestAddError_NoFSFallbackMessage(t *testing.T) {
+	ctx := context.Background()
+	err := errors.New("creating cluster: boom")
+
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, err)
+	if got, want := diags[0].Detail(), "creating cluster: boom [smarterr initialization: Embedded filesystem not set, use SetFS()]"; got != want {
+		t.Errorf("default detail = %q, want %q", got, want)
+	}
+
+	SetFallbackMessage(func(err error) string {
+		return "See https://example.com/docs/errors for help."
+	})
+	t.Cleanup(func() { SetFallbackMessage(nil) })
+
+	diags = nil
+	AddError(ctx, &diags, err)
+	if got, want := diags[0].Summary(), "creating cluster: boom"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	if got, want := diags[0].Detail(), "creating cluster: boom See https://example.com/docs/errors for help."; got != want {
+		t.Errorf("custom detail = %q, want %q", got, want)
+	}
+
+	sdiags := Append(ctx, nil, err)
+	if got, want := sdiags[0].Detail, "creating cluster: boom See https://example.com/docs/errors for help."; got != want {
+		t.Errorf("custom Append detail = %q, want %q", got, want)
+	}
+}
+
+func TestMaxDetailLength(t *testing.T) {
+	setTestConfig(t, `
+smarterr {
+  max_detail_length = 10
+}
+
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "error_detail" {
+  format = "{{.error}}"
+}
+`)
+	ctx := context.Background()
+
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, errors.New("0123456789abcdef"))
+	if got, want := diags[0].Detail(), "0123456789"+internal.TruncatedNotice; got != want {
+		t.Errorf("AddError detail = %q, want %q", got, want)
+	}
+
+	diags = nil
+	AddError(ctx, &diags, errors.New("short"))
+	if got, want := diags[0].Detail(), "short"; got != want {
+		t.Errorf("AddError detail under limit = %q, want %q", got, want)
+	}
+
+	sdiags := Append(ctx, nil, errors.New("0123456789abcdef"))
+	if got, want := sdiags[0].Detail, "0123456789 (truncated)"; got != want {
+		t.Errorf("Append detail = %q, want %q", got, want)
+	}
+
+	diags = nil
+	AddEnrich(ctx, &diags, fwdiag.Diagnostics{fwdiag.NewErrorDiagnostic("summary", "long diagnostic detail")})
+	if got, want := diags[0].Detail(), "long diagn (truncated)"; got != want {
+		t.Errorf("AddEnrich detail = %q, want %q", got, want)
+	}
+}
+
+func TestAppendOriginalDetail(t *testing.T) {
+	const config = `
+smarterr {
+  append_original_detail = %t
+}
+
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "error_detail" {
+  format = "Could not do it: {{.error}}"
+}
+`
+	ctx := context.Background()
+
+	setTestConfig(t, fmt.Sprintf(config, true))
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, errors.New("boom"))
+	if got, want := diags[0].Detail(), "Could not do it: boom"+internal.OriginalDetailSeparator+"boom"; got != want {
+		t.Errorf("AddError detail = %q, want %q", got, want)
+	}
+
+	sdiags := Append(ctx, nil, errors.New("boom"))
+	if got, want := sdiags[0].Detail, "Could not do it: boom"+internal.OriginalDetailSeparator+"boom"; got != want {
+		t.Errorf("Append detail = %q, want %q", got, want)
+	}
+
+	// Without a diagnostic_detail template, the enriched detail is the original, so nothing is appended
+	diags = nil
+	AddEnrich(ctx, &diags, fwdiag.Diagnostics{fwdiag.NewErrorDiagnostic("summary", "original detail")})
+	if got, want := diags[0].Detail(), "original detail"; got != want {
+		t.Errorf("AddEnrich detail = %q, want %q", got, want)
+	}
+
+	setTestConfig(t, fmt.Sprintf(config, false))
+	diags = nil
+	AddError(ctx, &diags, errors.New("boom"))
+	if got, want := diags[0].Detail(), "Could not do it: boom"; got != want {
+		t.Errorf("AddError detail when disabled = %q, want %q", got, want)
+	}
+}
+
+func TestAppendErrorCode(t *testing.T) {
+	const config = `
+smarterr {
+  append_error_code = %t
+}
+
+token "code" {
+  source = "error_code"
+}
+
+hint "throttling" {
+  error_contains = "Throttling"
+  suggestion     = "Retry later."
+  code           = "THROTTLING"
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "error_detail" {
+  format = "Could not do it ({{.code}})"
+}
+`
+	ctx := context.Background()
+
+	setTestConfig(t, fmt.Sprintf(config, true))
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, WithCode(errors.New("Throttling: slow down"), "RATE_EXCEEDED"))
+	if got, want := diags[0].Detail(), "Could not do it (RATE_EXCEEDED)\n\n[smarterr-code: RATE_EXCEEDED]"; got != want {
+		t.Errorf("AddError detail with WithCode = %q, want %q", got, want)
+	}
+
+	sdiags := Append(ctx, nil, errors.New("Throttling: slow down"))
+	if got, want := sdiags[0].Detail, "Could not do it (THROTTLING)\n\n[smarterr-code: THROTTLING]"; got != want {
+		t.Errorf("Append detail with matching hint = %q, want %q", got, want)
+	}
+
+	diags = nil
+	AddError(ctx, &diags, errors.New("boom"))
+	if got, want := diags[0].Detail(), "Could not do it ()"; got != want {
+		t.Errorf("AddError detail without a code = %q, want %q", got, want)
+	}
+
+	// Enrichment appends the code of an SDK diagnostic that matches a hint
+	sdiags = AppendEnrich(ctx, nil, sdkdiag.Diagnostics{{Severity: sdkdiag.Error, Summary: "Throttling", Detail: "slow down"}})
+	if got, want := sdiags[0].Detail, "Could not do it (THROTTLING)\n\n[smarterr-code: THROTTLING]"; got != want {
+		t.Errorf("AppendEnrich detail with matching hint = %q, want %q", got, want)
+	}
+
+	// The code fits within max_detail_length instead of being appended past it
+	setTestConfig(t, strings.Replace(fmt.Sprintf(config, true), "smarterr {", "smarterr {\n  max_detail_length = 40", 1))
+	sdiags = Append(ctx, nil, errors.New("Throttling: slow down"))
+	if got, want := sdiags[0].Detail, "Could not d (truncated)\n\n[smarterr-code: THROTTLING]"; got != want {
+		t.Errorf("Append detail with max_detail_length = %q, want %q", got, want)
+	}
+
+	setTestConfig(t, fmt.Sprintf(config, false))
+	diags = nil
+	AddError(ctx, &diags, WithCode(errors.New("boom"), "RATE_EXCEEDED"))
+	if got, want := diags[0].Detail(), "Could not do it (RATE_EXCEEDED)"; got != want {
+		t.Errorf("AddError detail when disabled = %q, want %q", got, want)
+	}
+}
+
+func TestAddError_Concurrent(t *testing.T) {
+	setTestConfig(t, `
+token "id" {
+  arg = "id"
+}
+
+token "error" {
+  source     = "error"
+  transforms = ["clean"]
+}
+
+token "hints" {
+  source = "hints"
+}
+
+token "subaction" {
+  stack_matches = ["waiting"]
+}
+
+stack_match "waiting" {
+  called_from = "wait"
+  display     = "waiting for"
+}
+
+transform "clean" {
+  step "remove" {
+    regex = "RequestID: [a-z0-9-]+,?\\s*"
+  }
+}
+
+hint "throttling" {
+  regex_match = "Throttl(ed|ing)"
+  suggestion  = "Retry later."
+}
+
+template "error_summary" {
+  format = "creating {{.id}}"
+}
+
+template "error_detail" {
+  format = "{{.error}}\n{{.hints}}"
+}
+`)
+	ctx := context.Background()
+
+	const goroutines = 50
+	details := make([]string, goroutines)
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Go(func() {
+			var diags fwdiag.Diagnostics
+			AddError(ctx, &diags, errors.New("Throttling: RequestID: abc-123, slow down"), ID, "vpc-1")
+			if len(diags) != 1 {
+				t.Errorf("goroutine %d: got %d diagnostics, want 1", i, len(diags))
+				return
+			}
+			details[i] = diags[0].Summary() + "|" + diags[0].Detail()
+		})
+	}
+	wg.Wait()
+
+	for i, got := range details {
+		if got != details[0] {
+			t.Errorf("goroutine %d rendered %q, want %q like goroutine 0", i, got, details[0])
+		}
+	}
+	if want := "creating vpc-1|Throttling: slow down\nRetry later."; details[0] != want {
+		t.Errorf("rendered %q, want %q", details[0], want)
+	}
+}
+
+func TestSetters_Concurrent(t *testing.T) {
+	setTestConfig(t, `
+template "error_summary" {
+  format = "failed"
+}
+
+template "log_error" {
+  format = "failed"
+}
+`)
+	t.Cleanup(func() {
+		SetLogger(nil)
+		SetFallbackMessage(nil)
+		SetIgnoredErrors()
+		SetDiagnosticObserver(nil)
+		SetPanicSuffix(DefaultPanicSuffix)
+		SetStrict(false)
+	})
+	ctx := context.Background()
+
+	// Run with -race to check that the setters don't race with adding diagnostics
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			SetLogger(StdLogger{})
+			SetFallbackMessage(func(err error) string { return "see the docs" })
+			SetIgnoredErrors(context.Canceled)
+			SetDiagnosticObserver(func(ctx context.Context, summary, detail, severity string) {})
+			SetPanicSuffix(" (internal error: %s)")
+			SetStrict(i%2 == 0)
+		})
+		wg.Go(func() {
+			var diags fwdiag.Diagnostics
+			AddError(ctx, &diags, errors.New("boom"), ID)
+			_ = Append(ctx, nil, context.Canceled)
+			if len(diags) != 1 {
+				t.Errorf("got %d diagnostics, want 1", len(diags))
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestHintNameToken(t *testing.T) {
+	setTestConfig(t, `
+smarterr {
+  hint_match_mode = "first"
+}
+
+token "category" {
+  source = "hint_name"
+}
+
+token "hints" {
+  source = "hints"
+}
+
+hint "throttling" {
+  regex_match = "Throttl(ed|ing)"
+  suggestion  = "Retry later."
+}
+
+template "error_summary" {
+  format = "[{{.category}}] request failed"
+}
+
+template "error_detail" {
+  format = "{{.hints}}"
+}
+`)
+	ctx := context.Background()
+
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, errors.New("Throttling: slow down"))
+	if got, want := diags[0].Summary(), "[throttling] request failed"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	if got, want := diags[0].Detail(), "Retry later."; got != want {
+		t.Errorf("detail = %q, want %q", got, want)
+	}
+
+	diags = nil
+	AddError(ctx, &diags, errors.New("boom"))
+	if got, want := diags[0].Summary(), "[] request failed"; got != want {
+		t.Errorf("summary without a matching hint = %q, want %q", got, want)
+	}
+}
+
+func TestAppend_WrappedCallerFindsServiceConfig(t *testing.T) {
+	SetFS(&WrappedFS{FS: fstest.MapFS{
+		"smarterr/smarterr.hcl": &fstest.MapFile{Data: []byte(`
+parameter "service" {
+  value = "global"
+}
+
+token "service" {
+  parameter = "service"
+}
+
+template "error_summary" {
+  format = "{{.service}}"
+}
+
+template "error_detail" {
+  format = "{{.service}}"
+}
+`)},
+		"service/ec2/smarterr.hcl": &fstest.MapFile{Data: []byte(`
+parameter "service" {
+  value = "EC2"
+}
+`)},
+	}}, "internal")
+	t.Cleanup(func() { SetFS(nil, "") })
+
+	// With no severity, the wrappers call Append
+	for _, severity := range []string{"", SeverityError} {
+		diags := fakeEC2ResourceCreate(context.Background(), severity)
+		if len(diags) != 1 || diags[0].Summary != "EC2" {
+			t.Errorf("severity %q: expected the service config to apply through two wrappers, got: %+v", severity, diags)
+		}
+	}
+}
+
+// outerAppendWrapper and innerAppendWrapper stand in for a provider's wrappers, such as
+// smerr.Append calling a helper that calls smarterr.
+
+//go:noinline
+func outerAppendWrapper(ctx context.Context, diags sdkdiag.Diagnostics, err error, severity string) sdkdiag.Diagnostics {
+	return innerAppendWrapper(ctx, diags, err, severity)
+}
+
+//go:noinline
+func innerAppendWrapper(ctx context.Context, diags sdkdiag.Diagnostics, err error, severity string) sdkdiag.Diagnostics {
+	if severity == "" {
+		return Append(ctx, diags, err)
+	}
+	return AppendWithSeverity(ctx, diags, err, severity)
+}
+
+// fakeEC2ResourceCreate reports an error from a provider's EC2 service package. It must stay last
+// in the file, since the line directive gives it, and everything after it, that package's path.
+//
+//line /src/internal/provider/internal/service/ec2/instance.go:1
+//go:noinline
+func fakeEC2ResourceCreate(ctx context.Context, severity string) sdkdiag.Diagnostics {
+	return outerAppendWrapper(ctx, nil, errors.New("creating instance: boom"), severity)
+}
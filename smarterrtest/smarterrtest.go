@@ -0,0 +1,41 @@
+// Package smarterrtest provides helpers for testing smarterr Config, such as checking that a
+// provider's tokens resolve as expected, without setting up a filesystem or diagnostics.
+package smarterrtest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YakDriver/smarterr/internal"
+)
+
+// ResolveToken parses config, the content of a smarterr.hcl file, and resolves the named token
+// for err and keyvals as AddError or Append would, including transforms. Structured values, such
+// as those of diagnostic tokens, are formatted with %v; use ResolveTokens to inspect them. It
+// returns an error if the config doesn't parse or doesn't define the token.
+//
+//	got, err := smarterrtest.ResolveToken(ctx, config, "identifier", nil, smarterr.ID, "vpc-1")
+func ResolveToken(ctx context.Context, config, name string, err error, keyvals ...any) (string, error) {
+	cfg, parseErr := internal.ParseConfig([]byte(config), "smarterr.hcl")
+	if parseErr != nil {
+		return "", parseErr
+	}
+	for i := range cfg.Tokens {
+		if cfg.Tokens[i].Name == name {
+			rt := internal.NewRuntime(ctx, cfg, err, keyvals...)
+			return fmt.Sprintf("%v", cfg.Tokens[i].Resolve(ctx, rt)), nil
+		}
+	}
+	return "", fmt.Errorf("token %q not defined in config", name)
+}
+
+// ResolveTokens parses config, the content of a smarterr.hcl file, and resolves all its tokens
+// for err and keyvals, returning the values templates would receive.
+func ResolveTokens(ctx context.Context, config string, err error, keyvals ...any) (map[string]any, error) {
+	cfg, parseErr := internal.ParseConfig([]byte(config), "smarterr.hcl")
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	rt := internal.NewRuntime(ctx, cfg, err, keyvals...)
+	return rt.BuildTokenValueMap(ctx), nil
+}
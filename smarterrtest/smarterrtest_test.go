@@ -0,0 +1,84 @@
+package smarterrtest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+parameter "service" {
+  value = "EC2"
+}
+
+token "identifier" {
+  arg        = "id"
+  transforms = ["upper"]
+}
+
+token "service" {
+  parameter = "service"
+}
+
+token "error" {
+  source = "error"
+}
+
+transform "upper" {
+  step "upper" {}
+}
+`
+
+func TestResolveToken(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		token   string
+		err     error
+		keyvals []any
+		want    string
+	}{
+		{name: "arg with transform", token: "identifier", keyvals: []any{"id", "vpc-1"}, want: "VPC-1"},
+		{name: "parameter", token: "service", want: "EC2"},
+		{name: "error", token: "error", err: errors.New("boom"), want: "boom"},
+		{name: "missing arg", token: "identifier", want: ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ResolveToken(ctx, testConfig, tc.token, tc.err, tc.keyvals...)
+			if err != nil {
+				t.Fatalf("ResolveToken error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("ResolveToken(%q) = %q, want %q", tc.token, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveToken_Errors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := ResolveToken(ctx, testConfig, "bogus", nil); err == nil || !strings.Contains(err.Error(), `token "bogus" not defined`) {
+		t.Errorf("expected undefined token error, got: %v", err)
+	}
+	if _, err := ResolveToken(ctx, `token "x" {`, "x", nil); err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("expected parse error, got: %v", err)
+	}
+}
+
+func TestResolveTokens(t *testing.T) {
+	values, err := ResolveTokens(context.Background(), testConfig, errors.New("boom"), "id", "vpc-1")
+	if err != nil {
+		t.Fatalf("ResolveTokens error: %v", err)
+	}
+	want := map[string]string{"identifier": "VPC-1", "service": "EC2", "error": "boom"}
+	if len(values) != len(want) {
+		t.Errorf("ResolveTokens() = %v, want %v", values, want)
+	}
+	for name, v := range want {
+		if values[name] != v {
+			t.Errorf("ResolveTokens()[%q] = %v, want %q", name, values[name], v)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// status.go
+// Records which formatting path smarterr took, to explain fallbacks without enabling debug output.
+
+package smarterr
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// StatusSuccess means the config loaded and the templates rendered.
+	StatusSuccess = "success"
+	// StatusNoFS means no FileSystem was set (see SetFS), so the fallback message was used.
+	StatusNoFS = "no_fs"
+	// StatusConfigError means the config couldn't be loaded, so the original error was used.
+	StatusConfigError = "config_error"
+	// StatusTemplateError means the summary or detail template failed to render, so the
+	// original error was used.
+	StatusTemplateError = "template_error"
+	// StatusPanic means smarterr recovered from a panic and used the original error.
+	StatusPanic = "panic"
+	// StatusIgnored means the error matched SetIgnoredErrors, so the original error was used.
+	StatusIgnored = "ignored"
+)
+
+var statusCtxKey = ContextKey("smarterr:status")
+
+// statusRecorder holds the status of the most recent smarterr call made with a context.
+type statusRecorder struct {
+	mu     sync.Mutex
+	status string
+}
+
+// WithStatus returns a context that records the status of smarterr calls made with it. Pass the
+// returned context to AddError, Append, and the other helpers, then call LastStatus to see which
+// path the most recent call took.
+func WithStatus(ctx context.Context) context.Context {
+	return context.WithValue(ctx, statusCtxKey, &statusRecorder{})
+}
+
+// LastStatus returns the status of the most recent smarterr call made with ctx, such as
+// StatusSuccess or StatusConfigError. It answers "why didn't my config apply?" without enabling
+// debug output. It returns "" if ctx wasn't created with WithStatus or no call has completed.
+func LastStatus(ctx context.Context) string {
+	rec, ok := ctx.Value(statusCtxKey).(*statusRecorder)
+	if !ok {
+		return ""
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.status
+}
+
+// recordStatus records status for ctx if it was created with WithStatus.
+func recordStatus(ctx context.Context, status string) {
+	rec, ok := ctx.Value(statusCtxKey).(*statusRecorder)
+	if !ok {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.status = status
+}
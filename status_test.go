@@ -0,0 +1,85 @@
+package smarterr
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestLastStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string // "" means no FileSystem is set
+		want   string
+	}{
+		{
+			name: "no filesystem",
+			want: StatusNoFS,
+		},
+		{
+			name:   "config error",
+			config: `template "error_summary" {`,
+			want:   StatusConfigError,
+		},
+		{
+			name: "template error",
+			config: `
+template "error_detail" {
+  format = "{{.error}}"
+}
+`,
+			want: StatusTemplateError,
+		},
+		{
+			name: "success",
+			config: `
+token "error" {
+  source = "error"
+}
+
+template "error_summary" {
+  format = "failed"
+}
+
+template "error_detail" {
+  format = "{{.error}}"
+}
+`,
+			want: StatusSuccess,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.config != "" {
+				setTestConfig(t, tc.config)
+			}
+			ctx := WithStatus(context.Background())
+			if got := LastStatus(ctx); got != "" {
+				t.Errorf("LastStatus() before any call = %q, want empty", got)
+			}
+
+			var diags fwdiag.Diagnostics
+			AddError(ctx, &diags, errors.New("boom"))
+			if got := LastStatus(ctx); got != tc.want {
+				t.Errorf("LastStatus() after AddError = %q, want %q", got, tc.want)
+			}
+
+			ctx = WithStatus(context.Background())
+			Append(ctx, nil, errors.New("boom"))
+			if got := LastStatus(ctx); got != tc.want {
+				t.Errorf("LastStatus() after Append = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLastStatus_WithoutWithStatus(t *testing.T) {
+	ctx := context.Background()
+	var diags fwdiag.Diagnostics
+	AddError(ctx, &diags, errors.New("boom"))
+	if got := LastStatus(ctx); got != "" {
+		t.Errorf("LastStatus() = %q, want empty", got)
+	}
+}
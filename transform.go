@@ -0,0 +1,22 @@
+package smarterr
+
+import "github.com/YakDriver/smarterr/internal"
+
+// TransformStep is a single step of a Config transform, as passed to custom transform functions.
+type TransformStep = internal.TransformStep
+
+// RegisterTransform adds a custom transform step type so Config can use it like a built-in, for
+// example, to parse ARNs:
+//
+//	smarterr.RegisterTransform("arn_resource", func(value string, step smarterr.TransformStep) string {
+//		if i := strings.LastIndex(value, "/"); i >= 0 {
+//			return value[i+1:]
+//		}
+//		return value
+//	})
+//
+// Call it during initialization, before smarterr formats errors. Registering a type again replaces
+// the earlier registration. It panics if typeName is empty or a built-in type, or if fn is nil.
+func RegisterTransform(typeName string, fn func(value string, step TransformStep) string) {
+	internal.RegisterTransformStep(typeName, fn)
+}
@@ -0,0 +1,60 @@
+package smarterr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	fwdiag "github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestRegisterTransform(t *testing.T) {
+	RegisterTransform("test_arn_resource", func(value string, step TransformStep) string {
+		if i := strings.LastIndex(value, "/"); i >= 0 {
+			return value[i+1:]
+		}
+		return value
+	})
+	setTestConfig(t, `
+token "resource" {
+  arg        = "arn"
+  transforms = ["arn_resource"]
+}
+
+transform "arn_resource" {
+  step "test_arn_resource" {}
+}
+
+template "error_summary" {
+  format = "reading {{.resource}}"
+}
+
+template "error_detail" {
+  format = "failed"
+}
+`)
+
+	var diags fwdiag.Diagnostics
+	AddError(context.Background(), &diags, errors.New("boom"), "arn", "arn:aws:rds:us-west-2:123456789012:cluster/my-cluster")
+	if got := diags[0].Summary(); got != "reading my-cluster" {
+		t.Errorf("expected custom transform to apply, got %q", got)
+	}
+}
+
+func TestRegisterTransform_Invalid(t *testing.T) {
+	for name, register := range map[string]func(){
+		"empty name": func() { RegisterTransform("", func(v string, _ TransformStep) string { return v }) },
+		"nil func":   func() { RegisterTransform("test_nil", nil) },
+		"built-in":   func() { RegisterTransform("lower", func(v string, _ TransformStep) string { return v }) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			register()
+		})
+	}
+}